@@ -1,12 +1,18 @@
 package atRisk
 
 import (
+	"context"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 	"www-api/config"
 	"www-api/internal/constants"
 	"www-api/internal/datatypes"
 	"www-api/internal/logger"
+	"www-api/pkg/alert"
+	"www-api/pkg/aws/s3"
+	"www-api/pkg/cache"
 	service "www-api/service/at-risk"
 	"www-api/utils"
 
@@ -14,37 +20,133 @@ import (
 )
 
 type RiskAPI struct {
-	config        config.Config
-	log           logger.ZapLogger
-	createCache   func(key, value string) (datatypes.AtRiskResponse, error)
-	deleteCache   func(key string) (datatypes.AtRiskResponse, error)
-	getScore      func(email string) ([]datatypes.RiskScore, error)
-	extentTTL     func(email string, ttl int) error
-	getEventScore func(email, timestamp, mid string) (datatypes.EventScoreResponse, error)
+	config         config.Config
+	log            logger.ZapLogger
+	createCache    func(key, value string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error)
+	deleteCache    func(key string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error)
+	batchCache     func(operations []datatypes.BatchCacheOperation, actor string) (datatypes.BatchCacheResponse, error)
+	getScore       func(emails []string, category string) (datatypes.RiskScoresResponse, error)
+	extentTTL      func(email string, ttl int, category, actor string, dryRun bool) (datatypes.ExtendTTLResponse, error)
+	getTTL         func(email string) (datatypes.AtRiskTTLResponse, error)
+	getEventScore  func(email, timestamp, mid, category string) (datatypes.EventScoreResponse, error)
+	getEvents      func(request datatypes.AtRiskEventsRequest) (datatypes.AtRiskEventsResponse, error)
+	getEventByMid  func(email, mid string) (datatypes.EventScoreResponse, error)
+	domainSummary  func(domain string, limit int, bands []int) (datatypes.AtRiskDomainSummaryResponse, error)
+	getAudit       func(request datatypes.AtRiskAuditRequest) (datatypes.AtRiskAuditResponse, error)
+	restoreArchive func(email, actor string) (datatypes.AtRiskRestoreResponse, error)
+	subscribe      func(ctx context.Context, emails []string, domain string) (<-chan datatypes.AtRiskChange, error)
 }
 
 func NewRiskAPI(conf config.Config, log logger.ZapLogger, connections *datatypes.Connections) RiskAPI {
 	connections.Redis[constants.AtRiskReadRedisKey].Options().DB = constants.RedisDB6
 	connections.Redis[constants.AtRiskWriteRedisKey].Options().DB = constants.RedisDB6
-	serv := service.NewRiskService(log, connections)
+	serv := service.NewRiskService(log, connections).WithAlerts(conf.Alerts.Thresholds, newAlertSink(conf, connections, log))
+	serv = withArchive(serv, conf, log).WithChangeFeed()
+	withReconciler(serv, conf, log)
 	return RiskAPI{
-		config:        conf,
-		log:           log,
-		createCache:   serv.CreateCache,
-		deleteCache:   serv.DeleteCache,
-		getScore:      serv.GetScore,
-		extentTTL:     serv.ExtendTTL,
-		getEventScore: serv.GetEventScore,
+		config:         conf,
+		log:            log,
+		createCache:    serv.CreateCache,
+		deleteCache:    serv.DeleteCache,
+		batchCache:     serv.BatchCache,
+		getScore:       serv.GetScore,
+		extentTTL:      serv.ExtendTTL,
+		getTTL:         serv.GetTTL,
+		getEventScore:  serv.GetEventScore,
+		getEvents:      serv.GetEvents,
+		getEventByMid:  serv.GetEventByMid,
+		domainSummary:  serv.GetDomainSummary,
+		getAudit:       serv.GetAudit,
+		restoreArchive: serv.RestoreArchive,
+		subscribe:      serv.SubscribeChanges,
 	}
 }
 
+// withArchive attaches the s3 archive configured for the deployment to the service and
+// starts the archiver of expiring events, the archive is disabled when no bucket is configured
+func withArchive(serv service.RiskService, conf config.Config, log logger.ZapLogger) service.RiskService {
+	archive := conf.Archive
+	if archive.Bucket == "" {
+		return serv
+	}
+	if archive.Prefix == "" {
+		archive.Prefix = constants.DefaultArchivePrefix
+	}
+	if archive.WindowSeconds <= 0 {
+		archive.WindowSeconds = constants.DefaultArchiveWindowSeconds
+	}
+	if archive.IntervalSeconds <= 0 {
+		archive.IntervalSeconds = constants.DefaultArchiveIntervalSeconds
+	}
+
+	store, err := s3.NewS3Wrapper(conf.Region)
+	if err != nil {
+		log.Error("unable to create s3 client, archive is disabled", map[string]interface{}{"region": conf.Region, "error": err})
+		return serv
+	}
+
+	serv = serv.WithArchive(store, archive.Bucket, archive.Prefix)
+	go serv.RunArchiver(context.Background(), time.Duration(archive.IntervalSeconds)*time.Second, time.Duration(archive.WindowSeconds)*time.Second)
+	return serv
+}
+
+// withReconciler starts the scheduled reconciliation of the redis totals with the AtRiskScore
+// table, it is disabled when no interval is configured
+func withReconciler(serv service.RiskService, conf config.Config, log logger.ZapLogger) {
+	reconcile := conf.Reconcile
+	if reconcile.IntervalSeconds <= 0 {
+		return
+	}
+	if reconcile.Repair != "" && reconcile.Repair != constants.RepairMySQL && reconcile.Repair != constants.RepairLedger {
+		log.Error("invalid reconcile repair configured, reconciliation is disabled", map[string]interface{}{"repair": reconcile.Repair})
+		return
+	}
+
+	go serv.RunReconciler(context.Background(), time.Duration(reconcile.IntervalSeconds)*time.Second, reconcile.Repair, reconcile.Examples)
+}
+
+// newAlertSink returns the threshold alert sink configured for the deployment, alerts
+// are disabled when no sink is configured
+func newAlertSink(conf config.Config, connections *datatypes.Connections, log logger.ZapLogger) alert.Sink {
+	switch conf.Alerts.Sink {
+	case constants.AlertSinkWebhook:
+		webhook := conf.Alerts.Webhook
+		if webhook.Retries == 0 {
+			webhook.Retries = constants.DefaultWebhookRetries
+		}
+		if webhook.TimeoutSeconds == 0 {
+			webhook.TimeoutSeconds = constants.DefaultWebhookTimeoutSeconds
+		}
+		if webhook.BackoffMilliseconds == 0 {
+			webhook.BackoffMilliseconds = constants.DefaultWebhookBackoffMilliseconds
+		}
+		return alert.NewWebhook(webhook.URL, webhook.Secret, webhook.Retries, time.Duration(webhook.TimeoutSeconds)*time.Second, time.Duration(webhook.BackoffMilliseconds)*time.Millisecond, log)
+	case constants.AlertSinkStream:
+		stream := conf.Alerts.Stream
+		if stream.Name == "" {
+			stream.Name = constants.DefaultAlertStream
+		}
+		if stream.MaxLen == 0 {
+			stream.MaxLen = constants.DefaultAlertStreamMaxLen
+		}
+		redis := cache.NewRedis(connections.Redis[constants.AtRiskReadRedisKey], connections.Redis[constants.AtRiskWriteRedisKey], log, context.Background())
+		return alert.NewStream(redis, stream.Name, stream.MaxLen, log)
+	}
+	return nil
+}
+
 // @Summary      Create a cache
-// @Description  add/update a value in cache
+// @Description  add/update a value in cache, the value is sent either as atRiskValue (score:category:mid) or as a structured event
 // @Tags         AtRisk
 // @Produce      json
-// @Success      200 {object} datatypes.AtRiskResponse
+// @Param        Idempotency-Key header string false "repeats with the same key and body replay the first response"
+// @Param        If-Match header string false "version of the event from its ETag, * when it must exist"
+// @Param        If-None-Match header string false "* when the event must not exist"
+// @Success      200 {object} datatypes.CreateCacheResponse
 // @Failure      400 {object} string
 // @Failure      404 {object} string
+// @Failure      409 {object} string
+// @Failure      412 {object} string
 // @Failure      500 {object} string
 // @Router       /at-risk/cache/create [post]
 func (r RiskAPI) CreateCache(c *gin.Context) {
@@ -62,30 +164,56 @@ func (r RiskAPI) CreateCache(c *gin.Context) {
 		return
 	}
 
-	err = utils.ValidateAtRiskValue(request.AtRiskValue, r.log)
+	value := request.Value()
+	err = utils.ValidateAtRiskValue(value, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	scoring := r.scoringStrategy(request.Scoring)
+	err = utils.ValidateScoringStrategy(scoring, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	precondition, err := utils.ParsePrecondition(c.GetHeader(constants.IfMatchHeader), c.GetHeader(constants.IfNoneMatchHeader), r.log)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
 
-	score, err := r.createCache(request.AtRiskKey, request.AtRiskValue)
+	score, err := r.createCache(request.AtRiskKey, value, scoring, c.GetString(constants.TokenSubjectKey), precondition)
 	if err != nil {
+		if err == constants.PreconditionFailed {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"message": err.Error()})
+			return
+		}
 		r.log.Error("error occured while setting key to cache", map[string]interface{}{"error": err})
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
 		return
 	}
 
-	r.log.Info("cache created", map[string]interface{}{"key": request.AtRiskKey, "value": request.AtRiskValue, "totalAtRiskScore": score})
-	c.JSON(http.StatusOK, gin.H{"totalAtRiskScore": score})
+	r.log.Info("cache created", map[string]interface{}{"key": request.AtRiskKey, "value": value, "totalAtRiskScore": score.AtRiskScore, "scoring": score.Scoring.Strategy, "version": score.Version})
+	if score.Version > 0 {
+		c.Header(constants.ETagHeader, utils.FormatETag(score.Version))
+	}
+	c.JSON(http.StatusOK, datatypes.CreateCacheResponse{TotalAtRiskScore: score.AtRiskScore, Scoring: score.Scoring, Version: score.Version})
 }
 
 // @Summary      Delete a cache key
 // @Description  removes a key from cache
 // @Tags         AtRisk
 // @Produce      json
+// @Param        Idempotency-Key header string false "repeats with the same key and body replay the first response"
+// @Param        If-Match header string false "version of the event from its ETag, * when it must exist"
+// @Param        If-None-Match header string false "* when the event must not exist"
 // @Success      200 {object} datatypes.AtRiskResponse
 // @Failure      400 {object} string
 // @Failure      404 {object} string
+// @Failure      409 {object} string
+// @Failure      412 {object} string
 // @Failure      500 {object} string
 // @Router       /at-risk/cache/delete [delete]
 func (r RiskAPI) DeleteCache(c *gin.Context) {
@@ -102,26 +230,123 @@ func (r RiskAPI) DeleteCache(c *gin.Context) {
 		return
 	}
 
-	score, err := r.deleteCache(request.AtRiskKey)
+	scoring := r.scoringStrategy(request.Scoring)
+	err = utils.ValidateScoringStrategy(scoring, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	precondition, err := utils.ParsePrecondition(c.GetHeader(constants.IfMatchHeader), c.GetHeader(constants.IfNoneMatchHeader), r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	score, err := r.deleteCache(request.AtRiskKey, scoring, c.GetString(constants.TokenSubjectKey), precondition)
 	if err != nil {
 		if err == constants.ResourceNotFound {
 			c.JSON(http.StatusBadRequest, gin.H{"message": "key doesn't exists"})
 			return
 		}
+		if err == constants.PreconditionFailed {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"message": err.Error()})
+			return
+		}
 		r.log.Error("error occured while deleting cache", map[string]interface{}{"error": err})
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
 		return
 	}
 
-	r.log.Info("cache deleted", map[string]interface{}{"key": request.AtRiskKey, "totalAtRiskScore": score})
+	r.log.Info("cache deleted", map[string]interface{}{"key": request.AtRiskKey, "totalAtRiskScore": score.AtRiskScore, "scoring": score.Scoring.Strategy})
 	c.JSON(http.StatusOK, score)
 }
 
+// scoringStrategy returns the scoring strategy of a request, fields missing in the
+// request are taken from the deployment config and then from the defaults
+func (r RiskAPI) scoringStrategy(requested *datatypes.ScoringStrategy) datatypes.ScoringStrategy {
+	scoring := datatypes.ScoringStrategy{
+		Strategy:     r.config.Scoring.Strategy,
+		WindowDays:   r.config.Scoring.WindowDays,
+		HalfLifeDays: r.config.Scoring.HalfLifeDays,
+	}
+	if requested != nil && requested.Strategy != "" {
+		scoring.Strategy = requested.Strategy
+	}
+	if requested != nil && requested.WindowDays != 0 {
+		scoring.WindowDays = requested.WindowDays
+	}
+	if requested != nil && requested.HalfLifeDays != 0 {
+		scoring.HalfLifeDays = requested.HalfLifeDays
+	}
+
+	if scoring.Strategy == "" {
+		scoring.Strategy = constants.ScoringFlat
+	}
+	if scoring.WindowDays == 0 {
+		scoring.WindowDays = constants.DefaultScoringWindowDays
+	}
+	if scoring.HalfLifeDays == 0 {
+		scoring.HalfLifeDays = constants.DefaultScoringHalfLifeDays
+	}
+
+	//only the parameter of the selected strategy is reported back
+	switch scoring.Strategy {
+	case constants.ScoringWindow:
+		scoring.HalfLifeDays = 0
+	case constants.ScoringDecay:
+		scoring.WindowDays = 0
+	default:
+		scoring.WindowDays, scoring.HalfLifeDays = 0, 0
+	}
+	return scoring
+}
+
+// @Summary      Batch create/delete cache keys
+// @Description  applies a list of create/delete operations to cache in one pipeline
+// @Tags         AtRisk
+// @Produce      json
+// @Success      200 {object} datatypes.BatchCacheResponse
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Router       /at-risk/cache/batch [post]
+func (r RiskAPI) BatchCache(c *gin.Context) {
+	var request datatypes.BatchCacheRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if len(request.Operations) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"message": constants.EmptyBatch.Error()})
+		return
+	}
+
+	if len(request.Operations) > constants.MaxBatchOperations {
+		r.log.Error("batch size exceeds limit", map[string]interface{}{"operations": len(request.Operations), "limit": constants.MaxBatchOperations})
+		c.JSON(http.StatusBadRequest, gin.H{"message": constants.InvalidBatchSize.Error()})
+		return
+	}
+
+	response, err := r.batchCache(request.Operations, c.GetString(constants.TokenSubjectKey))
+	if err != nil {
+		r.log.Error("error occured while applying batch to cache", map[string]interface{}{"error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	r.log.Info("cache batch applied", map[string]interface{}{"operations": len(request.Operations), "totalAtRiskScores": response.TotalAtRiskScores})
+	c.JSON(http.StatusOK, response)
+}
+
 // @Summary      Get a score
-// @Description  fetches score from database
+// @Description  fetches scores from database for userEmail or a list of userEmails, grouped per email, with the total of the cached events of each email broken down by category, an email is not found when it has neither
 // @Tags         AtRisk
 // @Produce      json
-// @Success      200 {array} datatypes.RiskScore
+// @Param        category query string false "only count the cached events of this category"
+// @Success      200 {object} datatypes.RiskScoresResponse
 // @Failure      400 {object} string
 // @Failure      404 {object} string
 // @Failure      500 {object} string
@@ -134,30 +359,79 @@ func (r RiskAPI) Score(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
-	err = utils.ValidateEmail(request.UserEmail, r.log)
-	if err != nil {
+
+	emails := scoreEmails(request)
+	if len(emails) == 0 {
+		err = utils.ValidateEmail("", r.log)
 		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 		return
 	}
 
-	scores, err := r.getScore(request.UserEmail)
+	maxBatchSize := r.config.Score.MaxBatchSize
+	if maxBatchSize == 0 {
+		maxBatchSize = constants.DefaultScoreBatchSize
+	}
+	if len(emails) > maxBatchSize {
+		r.log.Error("too many emails in score request", map[string]interface{}{"emails": len(emails), "maxBatchSize": maxBatchSize})
+		c.JSON(http.StatusBadRequest, gin.H{"message": constants.InvalidEmailBatchSize.Error()})
+		return
+	}
+
+	for _, email := range emails {
+		err = utils.ValidateEmail(email, r.log)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	if request.Category != "" {
+		err = utils.ValidateCategory(request.Category, r.log)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	scores, err := r.getScore(emails, request.Category)
 	if err != nil {
 		r.log.Error("error occured while fetching scores from database", map[string]interface{}{"error": err})
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
 		return
 	}
 
-	r.log.Info("successfully fetched atRiskScore", map[string]interface{}{"email": request.UserEmail, "atRiskScores": scores})
+	r.log.Info("successfully fetched atRiskScore", map[string]interface{}{"emails": emails, "category": request.Category, "atRiskScores": scores.AtRiskScores, "notFound": scores.NotFound})
 	c.JSON(http.StatusOK, scores)
 }
 
+// scoreEmails returns userEmail followed by userEmails of a score request without
+// repeating an email, blank entries of userEmails are kept to fail validation
+func scoreEmails(request datatypes.AtRiskRequest) []string {
+	emails := []string{}
+	if request.UserEmail != "" {
+		emails = append(emails, request.UserEmail)
+	}
+
+	seen := map[string]bool{strings.ToLower(request.UserEmail): request.UserEmail != ""}
+	for _, email := range request.UserEmails {
+		if email != "" && seen[strings.ToLower(email)] {
+			continue
+		}
+		seen[strings.ToLower(email)] = true
+		emails = append(emails, email)
+	}
+	return emails
+}
+
 // @Summary      Extent TTL
-// @Description  extends the expiry for a key in cache
+// @Description  extends the expiry for a key in cache and lists the ttl of each key before and after, a dry run changes nothing
 // @Tags         AtRisk
 // @Produce      json
 // @Param        userEmail query string true "user email"
 // @Param        timestamp query string true "timestamp"
-// @Success      200 {object} string
+// @Param        dryRun query bool false "only report the keys that would be extended"
+// @Param        category query string false "only extend the events of this category"
+// @Success      200 {object} datatypes.ExtendTTLResponse
 // @Failure      400 {object} string
 // @Failure      404 {object} string
 // @Failure      500 {object} string
@@ -191,21 +465,65 @@ func (r RiskAPI) ExtendTTL(c *gin.Context) {
 		}
 	}
 
-	err = r.extentTTL(request.UserEmail, ttl)
+	if request.Category != "" {
+		err = utils.ValidateCategory(request.Category, r.log)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	response, err := r.extentTTL(request.UserEmail, ttl, request.Category, c.GetString(constants.TokenSubjectKey), request.DryRun)
 	if err != nil {
 		r.log.Error("error occured while extending ttl", map[string]interface{}{"email": request.UserEmail, "ttl": ttl})
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
 		return
 	}
 
-	r.log.Info("successfully set ttl", map[string]interface{}{"email": request.UserEmail, "ttl": ttl})
-	c.JSON(http.StatusOK, "ttl extended")
+	r.log.Info("successfully set ttl", map[string]interface{}{"email": request.UserEmail, "ttl": ttl, "category": request.Category, "keys": len(response.Keys), "dryRun": request.DryRun})
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary      Get TTL
+// @Description  lists the remaining ttl in seconds of every cached event of a user, -1 for an event without expiry
+// @Tags         AtRisk
+// @Produce      json
+// @Param        userEmail query string true "user email"
+// @Success      200 {object} datatypes.AtRiskTTLResponse
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Router       /at-risk/ttl [get]
+func (r RiskAPI) TTL(c *gin.Context) {
+	var request datatypes.AtRiskRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateEmail(request.UserEmail, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	response, err := r.getTTL(request.UserEmail)
+	if err != nil {
+		r.log.Error("error occured while fetching ttl", map[string]interface{}{"email": request.UserEmail, "error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	r.log.Info("successfully fetched ttl", map[string]interface{}{"email": request.UserEmail, "keys": len(response.Keys)})
+	c.JSON(http.StatusOK, response)
 }
 
 // @Summary      Get event score details
-// @Description  fetches score for a specific event
+// @Description  fetches score for a specific event, the ETag header carries the version of the event
 // @Tags         AtRisk
 // @Produce      json
+// @Param        category query string false "only return the event when it is of this category"
 // @Success      200 {object} datatypes.EventScoreResponse
 // @Failure      400 {object} string
 // @Failure      404 {object} string
@@ -232,7 +550,15 @@ func (r RiskAPI) EventScore(c *gin.Context) {
 		return
 	}
 
-	score, err := r.getEventScore(request.UserEmail, request.Timestamp, request.Mid)
+	if request.Category != "" {
+		err = utils.ValidateCategory(request.Category, r.log)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	score, err := r.getEventScore(request.UserEmail, request.Timestamp, request.Mid, request.Category)
 	if err != nil {
 		if err == constants.ResourceNotFound {
 			c.JSON(http.StatusBadRequest, gin.H{"message": "key doesn't exists"})
@@ -243,6 +569,351 @@ func (r RiskAPI) EventScore(c *gin.Context) {
 		return
 	}
 
-	r.log.Info("successfully fetched EventScoreDetails", map[string]interface{}{"key": score.AtRiskKey, "value": score.AtRiskValue, "score": score.AtRiskScore})
+	r.log.Info("successfully fetched EventScoreDetails", map[string]interface{}{"key": score.AtRiskKey, "event": score.Event, "score": score.AtRiskScore, "version": score.Version})
+	c.Header(constants.ETagHeader, utils.FormatETag(score.Version))
+	c.JSON(http.StatusOK, score)
+}
+
+// @Summary      Get event by message id
+// @Description  fetches score for the event of a user carrying a message id
+// @Tags         AtRisk
+// @Produce      json
+// @Success      200 {object} datatypes.EventScoreResponse
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Router       /at-risk/event-by-mid [get]
+func (r RiskAPI) EventByMid(c *gin.Context) {
+	var request datatypes.AtRiskRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateEmail(request.UserEmail, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateMid(request.Mid, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	score, err := r.getEventByMid(request.UserEmail, request.Mid)
+	if err != nil {
+		if err == constants.ResourceNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "key doesn't exists"})
+			return
+		}
+		r.log.Error("error occured while fetching event by mid, error", map[string]interface{}{"error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	r.log.Info("successfully fetched event by mid", map[string]interface{}{"key": score.AtRiskKey, "mid": request.Mid, "score": score.AtRiskScore, "version": score.Version})
+	c.Header(constants.ETagHeader, utils.FormatETag(score.Version))
 	c.JSON(http.StatusOK, score)
 }
+
+// @Summary      Get event history
+// @Description  lists cached events of a user ordered by timestamp with cursor pagination
+// @Tags         AtRisk
+// @Produce      json
+// @Success      200 {object} datatypes.AtRiskEventsResponse
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Router       /at-risk/events [get]
+func (r RiskAPI) Events(c *gin.Context) {
+	var request datatypes.AtRiskEventsRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateEmail(request.UserEmail, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	for _, timestamp := range []string{request.From, request.To, request.Cursor} {
+		if timestamp == "" {
+			continue
+		}
+		err = utils.ValidateTimestamp(timestamp, r.log)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	if request.Order == "" {
+		request.Order = constants.OrderDesc
+	}
+	if request.Order != constants.OrderAsc && request.Order != constants.OrderDesc {
+		r.log.Error("invalid order received", map[string]interface{}{"order": request.Order})
+		c.JSON(http.StatusBadRequest, gin.H{"message": constants.InvalidOrderParam.Error()})
+		return
+	}
+
+	if request.Limit == 0 {
+		request.Limit = constants.DefaultEventsLimit
+	}
+	if request.Limit < 0 || request.Limit > constants.MaxEventsLimit {
+		r.log.Error("invalid limit received", map[string]interface{}{"limit": request.Limit})
+		c.JSON(http.StatusBadRequest, gin.H{"message": constants.InvalidLimitParam.Error()})
+		return
+	}
+
+	events, err := r.getEvents(request)
+	if err != nil {
+		r.log.Error("error occured while fetching events", map[string]interface{}{"email": request.UserEmail, "error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	r.log.Info("successfully fetched events", map[string]interface{}{"email": request.UserEmail, "events": len(events.Events), "nextCursor": events.NextCursor})
+	c.JSON(http.StatusOK, events)
+}
+
+// @Summary      Get domain summary
+// @Description  lists the students of a domain with the highest total score, the number of students per score band and the number of active events
+// @Tags         AtRisk
+// @Produce      json
+// @Success      200 {object} datatypes.AtRiskDomainSummaryResponse
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Router       /at-risk/domain/summary [get]
+func (r RiskAPI) DomainSummary(c *gin.Context) {
+	var request datatypes.AtRiskDomainSummaryRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateDomain(request.Domain, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if request.Limit == 0 {
+		request.Limit = constants.DefaultSummaryLimit
+	}
+	if request.Limit < 0 || request.Limit > constants.MaxSummaryLimit {
+		r.log.Error("invalid limit received", map[string]interface{}{"limit": request.Limit})
+		c.JSON(http.StatusBadRequest, gin.H{"message": constants.InvalidSummaryLimit.Error()})
+		return
+	}
+
+	bands := r.config.Summary.Bands
+	if len(bands) == 0 {
+		bands = constants.DefaultScoreBands
+	}
+
+	summary, err := r.domainSummary(request.Domain, request.Limit, bands)
+	if err != nil {
+		r.log.Error("error occured while fetching domain summary", map[string]interface{}{"domain": request.Domain, "error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	r.log.Info("successfully fetched domain summary", map[string]interface{}{"domain": summary.Domain, "students": summary.Students, "activeEvents": summary.ActiveEvents})
+	c.JSON(http.StatusOK, summary)
+}
+
+// @Summary      Get audit log
+// @Description  lists changes to cached events newest first with cursor pagination, filtered by userEmail, actor and a from/to unix time range
+// @Tags         AtRisk
+// @Produce      json
+// @Success      200 {object} datatypes.AtRiskAuditResponse
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Router       /at-risk/audit [get]
+func (r RiskAPI) Audit(c *gin.Context) {
+	var request datatypes.AtRiskAuditRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if request.UserEmail != "" {
+		err = utils.ValidateEmail(request.UserEmail, r.log)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	for _, param := range []string{request.From, request.To, request.Cursor} {
+		if param == "" {
+			continue
+		}
+		err = utils.ValidateTimestamp(param, r.log)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	if request.Limit == 0 {
+		request.Limit = constants.DefaultEventsLimit
+	}
+	if request.Limit < 0 || request.Limit > constants.MaxEventsLimit {
+		r.log.Error("invalid limit received", map[string]interface{}{"limit": request.Limit})
+		c.JSON(http.StatusBadRequest, gin.H{"message": constants.InvalidLimitParam.Error()})
+		return
+	}
+
+	audit, err := r.getAudit(request)
+	if err != nil {
+		r.log.Error("error occured while fetching audit log", map[string]interface{}{"request": request, "error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	r.log.Info("successfully fetched audit log", map[string]interface{}{"email": request.UserEmail, "actor": request.Actor, "entries": len(audit.Entries), "nextCursor": audit.NextCursor})
+	c.JSON(http.StatusOK, audit)
+}
+
+// @Summary      Restore archived events
+// @Description  creates the archived events of a user again, events that are still in cache are skipped
+// @Tags         AtRisk
+// @Produce      json
+// @Param        userEmail query string true "user email"
+// @Success      200 {object} datatypes.AtRiskRestoreResponse
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Failure      503 {object} string
+// @Router       /at-risk/restore [post]
+func (r RiskAPI) Restore(c *gin.Context) {
+	var request datatypes.AtRiskRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateEmail(request.UserEmail, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	response, err := r.restoreArchive(request.UserEmail, c.GetString(constants.TokenSubjectKey))
+	if err == constants.ArchiveNotConfigured {
+		r.log.Error("restore requested without archive", map[string]interface{}{"email": request.UserEmail})
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		r.log.Error("error occured while restoring archived events", map[string]interface{}{"email": request.UserEmail, "error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	r.log.Info("successfully restored archived events", map[string]interface{}{"email": request.UserEmail, "restored": len(response.Restored), "skipped": len(response.Skipped)})
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary      Stream changes
+// @Description  streams a server-sent event for every create, delete or ttl extension of the events of the userEmails or of every user of the domain, the event name is the action
+// @Tags         AtRisk
+// @Produce      text/event-stream
+// @Param        userEmail query string false "user email, can be repeated"
+// @Param        domain query string false "domain of the users"
+// @Success      200 {object} datatypes.AtRiskChange
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Router       /at-risk/stream [get]
+func (r RiskAPI) Stream(c *gin.Context) {
+	var request datatypes.AtRiskStreamRequest
+	err := c.ShouldBindQuery(&request)
+	if err != nil {
+		r.log.Error("error binding request query", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	if len(request.UserEmails) == 0 && request.Domain == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": constants.InvalidStreamFilter.Error()})
+		return
+	}
+
+	maxBatchSize := r.config.Score.MaxBatchSize
+	if maxBatchSize == 0 {
+		maxBatchSize = constants.DefaultScoreBatchSize
+	}
+	if len(request.UserEmails) > maxBatchSize {
+		r.log.Error("too many emails in stream request", map[string]interface{}{"emails": len(request.UserEmails), "maxBatchSize": maxBatchSize})
+		c.JSON(http.StatusBadRequest, gin.H{"message": constants.InvalidEmailBatchSize.Error()})
+		return
+	}
+
+	for _, email := range request.UserEmails {
+		err = utils.ValidateEmail(email, r.log)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	if request.Domain != "" {
+		err = utils.ValidateDomain(request.Domain, r.log)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	//the subscription ends with the request, i.e. when the client disconnects
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	changes, err := r.subscribe(ctx, request.UserEmails, request.Domain)
+	if err != nil {
+		r.log.Error("error occured while subscribing to changes", map[string]interface{}{"request": request, "error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	r.log.Info("streaming changes", map[string]interface{}{"emails": request.UserEmails, "domain": request.Domain, "subject": c.GetString(constants.TokenSubjectKey)})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	//a comment line every heartbeat keeps proxies from closing an idle stream
+	heartbeat := time.NewTicker(constants.StreamHeartbeatSeconds * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			_, err = c.Writer.WriteString(": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			c.SSEvent(change.Action, change)
+			c.Writer.Flush()
+		}
+	}
+}
@@ -2,6 +2,7 @@ package atRisk
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -55,27 +56,51 @@ func TestNewRisk(t *testing.T) {
 			if riskService.deleteCache == nil {
 				t.Errorf("expected deleteCache but got nil")
 			}
+			if riskService.batchCache == nil {
+				t.Errorf("expected batchCache but got nil")
+			}
 			if riskService.getScore == nil {
 				t.Errorf("expected getScore but got nil")
 			}
 			if riskService.extentTTL == nil {
 				t.Errorf("expected extentTTL but got nil")
 			}
+			if riskService.getTTL == nil {
+				t.Errorf("expected getTTL but got nil")
+			}
 			if riskService.getEventScore == nil {
 				t.Errorf("expected getEventScore but got nil")
 			}
+			if riskService.getEvents == nil {
+				t.Errorf("expected getEvents but got nil")
+			}
+			if riskService.restoreArchive == nil {
+				t.Errorf("expected restoreArchive but got nil")
+			}
 		})
 	}
 }
 
+// testScoringConfig returns a deployment config with a non default scoring strategy
+func testScoringConfig() config.Config {
+	conf := config.Config{}
+	conf.Scoring.Strategy = "window"
+	conf.Scoring.WindowDays = 14
+	conf.Scoring.HalfLifeDays = 2.5
+	return conf
+}
+
 func TestCreateCache(t *testing.T) {
 	type tests struct {
 		name             string
 		params           map[string]string
 		body             map[string]interface{}
-		createCache      func(key, value string) (datatypes.AtRiskResponse, error)
+		headers          map[string]string
+		config           config.Config
+		createCache      func(key, value string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error)
 		expectedStatus   int
 		expectedResponse string
+		expectedETag     string
 	}
 
 	// Convert the data to JSON
@@ -87,11 +112,103 @@ func TestCreateCache(t *testing.T) {
 				"atRiskKey":   "some_key@securly.com:16546548465",
 				"atRiskValue": "35:docs:4854184194",
 			},
-			createCache: func(key, value string) (datatypes.AtRiskResponse, error) {
-				return datatypes.AtRiskResponse{10}, nil
+			createCache: func(key, value string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
+				if actor != "subject" {
+					return datatypes.AtRiskResponse{}, test.InternalServerErr
+				}
+				return datatypes.AtRiskResponse{AtRiskScore: 10, Scoring: scoring}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"totalAtRiskScore\":10,\"scoring\":{\"strategy\":\"flat\"}}",
+		},
+		{
+			name: "valid case, scoring strategy from request",
+			body: map[string]interface{}{
+				"atRiskKey":   "some_key@securly.com:16546548465",
+				"atRiskValue": "35:docs:4854184194",
+				"scoring":     map[string]interface{}{"strategy": "window", "windowDays": 3},
+			},
+			createCache: func(key, value string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
+				return datatypes.AtRiskResponse{AtRiskScore: 10, Scoring: scoring}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"totalAtRiskScore\":10,\"scoring\":{\"strategy\":\"window\",\"windowDays\":3}}",
+		},
+		{
+			name: "valid case, scoring strategy from config",
+			body: map[string]interface{}{
+				"atRiskKey":   "some_key@securly.com:16546548465",
+				"atRiskValue": "35:docs:4854184194",
+			},
+			config: testScoringConfig(),
+			createCache: func(key, value string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
+				return datatypes.AtRiskResponse{AtRiskScore: 10, Scoring: scoring}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"totalAtRiskScore\":10,\"scoring\":{\"strategy\":\"window\",\"windowDays\":14}}",
+		},
+		{
+			name: "valid case, conditional create",
+			body: map[string]interface{}{
+				"atRiskKey":   "some_key@securly.com:16546548465",
+				"atRiskValue": "35:docs:4854184194",
+			},
+			headers: map[string]string{"If-Match": "\"3\", W/\"4\""},
+			createCache: func(key, value string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
+				if precondition != (datatypes.Precondition{IfMatch: "3,4"}) {
+					return datatypes.AtRiskResponse{}, test.InternalServerErr
+				}
+				return datatypes.AtRiskResponse{AtRiskScore: 10, Scoring: scoring, Version: 8}, nil
 			},
 			expectedStatus:   http.StatusOK,
-			expectedResponse: "{\"totalAtRiskScore\":10}",
+			expectedResponse: "{\"totalAtRiskScore\":10,\"scoring\":{\"strategy\":\"flat\"},\"version\":8}",
+			expectedETag:     "\"8\"",
+		},
+		{
+			name: "fail case, precondition failed",
+			body: map[string]interface{}{
+				"atRiskKey":   "some_key@securly.com:16546548465",
+				"atRiskValue": "35:docs:4854184194",
+			},
+			headers: map[string]string{"If-None-Match": "*"},
+			createCache: func(key, value string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
+				return datatypes.AtRiskResponse{}, constants.PreconditionFailed
+			},
+			expectedStatus:   http.StatusPreconditionFailed,
+			expectedResponse: "{\"message\":\"precondition failed, atRiskKey was changed or does not match If-Match/If-None-Match\"}",
+		},
+		{
+			name: "fail case, invalid If-Match header",
+			body: map[string]interface{}{
+				"atRiskKey":   "some_key@securly.com:16546548465",
+				"atRiskValue": "35:docs:4854184194",
+			},
+			headers:          map[string]string{"If-Match": "3"},
+			createCache:      nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid If-Match or If-None-Match header, should be * or a list of quoted versions\"}",
+		},
+		{
+			name: "fail case, invalid scoring strategy",
+			body: map[string]interface{}{
+				"atRiskKey":   "some_key@securly.com:16546548465",
+				"atRiskValue": "35:docs:4854184194",
+				"scoring":     map[string]interface{}{"strategy": "linear"},
+			},
+			createCache:      nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid scoring strategy, should be flat, window or decay\"}",
+		},
+		{
+			name: "fail case, invalid scoring window",
+			body: map[string]interface{}{
+				"atRiskKey":   "some_key@securly.com:16546548465",
+				"atRiskValue": "35:docs:4854184194",
+				"scoring":     map[string]interface{}{"strategy": "window", "windowDays": 90},
+			},
+			createCache:      nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid windowDays, should be between 1 and 60\"}",
 		},
 		{
 			name: "invalid request body",
@@ -122,6 +239,16 @@ func TestCreateCache(t *testing.T) {
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: "{\"message\":\"atRiskValue missing in request body\"}",
 		},
+		{
+			name: "fail case, invalid structured event",
+			body: map[string]interface{}{
+				"atRiskKey": "some_key@securly.com:16546548465",
+				"event":     map[string]interface{}{"score": 35, "category": "docs:drive", "messageId": "4854184194"},
+			},
+			createCache:      nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid value format, atRiskValue should be seperated by two colon\"}",
+		},
 		{
 			name: "fail case, error createCache func",
 			// params: map[string]string{"atRiskKey": "some_key@securly.com:16546548465", "atRiskValue": "84:gmail:546515615"},
@@ -129,8 +256,8 @@ func TestCreateCache(t *testing.T) {
 				"atRiskKey":   "some_key@securly.com:16546548465",
 				"atRiskValue": "35:docs:4854184194",
 			},
-			createCache: func(key, value string) (datatypes.AtRiskResponse, error) {
-				return datatypes.AtRiskResponse{0}, test.InternalServerErr
+			createCache: func(key, value string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
+				return datatypes.AtRiskResponse{}, test.InternalServerErr
 			},
 			expectedStatus:   http.StatusInternalServerError,
 			expectedResponse: "{\"message\":\"internal server error\"}",
@@ -138,7 +265,7 @@ func TestCreateCache(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			riskService := RiskAPI{config.Config{}, logger.ZapLogger{Logger: zap.NewExample()}, tc.createCache, nil, nil, nil, nil}
+			riskService := RiskAPI{config: tc.config, log: logger.ZapLogger{Logger: zap.NewExample()}, createCache: tc.createCache}
 			u, err := url.Parse("")
 			assert.NoError(t, err)
 
@@ -155,6 +282,9 @@ func TestCreateCache(t *testing.T) {
 			}
 			req, err := http.NewRequest("POST", u.String(), bytes.NewBuffer(jsonData))
 			assert.NoError(t, err)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
 
 			// Create a new recorder to capture the response
 			recorder := httptest.NewRecorder()
@@ -162,6 +292,7 @@ func TestCreateCache(t *testing.T) {
 			// Create a mock Gin context using the recorder and request
 			c, _ := gin.CreateTestContext(recorder)
 			c.Request = req
+			c.Set(constants.TokenSubjectKey, "subject")
 
 			// Call your handler function, passing in the mock context
 			riskService.CreateCache(c)
@@ -169,6 +300,7 @@ func TestCreateCache(t *testing.T) {
 			// Assert the expected response
 			assert.Equal(t, tc.expectedStatus, recorder.Code)
 			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+			assert.Equal(t, tc.expectedETag, recorder.Header().Get("ETag"))
 		})
 	}
 }
@@ -178,9 +310,12 @@ func TestDeleteCache(t *testing.T) {
 		name             string
 		params           map[string]string
 		body             map[string]interface{}
-		deleteCache      func(key string) (datatypes.AtRiskResponse, error)
+		headers          map[string]string
+		config           config.Config
+		deleteCache      func(key string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error)
 		expectedStatus   int
 		expectedResponse string
+		expectedETag     string
 	}
 	testCases := []tests{
 		{
@@ -188,11 +323,34 @@ func TestDeleteCache(t *testing.T) {
 			body: map[string]interface{}{
 				"atRiskKey": "some_key@securly.com:16546548465",
 			},
-			deleteCache: func(key string) (datatypes.AtRiskResponse, error) {
-				return datatypes.AtRiskResponse{10}, nil
+			deleteCache: func(key string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
+				return datatypes.AtRiskResponse{AtRiskScore: 10, Scoring: scoring}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"AtRiskScore\":10,\"scoring\":{\"strategy\":\"flat\"}}",
+		},
+		{
+			name: "valid case, decay scoring with half life from config",
+			body: map[string]interface{}{
+				"atRiskKey": "some_key@securly.com:16546548465",
+				"scoring":   map[string]interface{}{"strategy": "decay"},
+			},
+			config: testScoringConfig(),
+			deleteCache: func(key string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
+				return datatypes.AtRiskResponse{AtRiskScore: 10, Scoring: scoring}, nil
 			},
 			expectedStatus:   http.StatusOK,
-			expectedResponse: "{\"totalAtRiskScore\":10}",
+			expectedResponse: "{\"AtRiskScore\":10,\"scoring\":{\"strategy\":\"decay\",\"halfLifeDays\":2.5}}",
+		},
+		{
+			name: "fail case, invalid scoring half life",
+			body: map[string]interface{}{
+				"atRiskKey": "some_key@securly.com:16546548465",
+				"scoring":   map[string]interface{}{"strategy": "decay", "halfLifeDays": -1},
+			},
+			deleteCache:      nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid halfLifeDays, should be greater than 0\"}",
 		},
 		{
 			name: "invalid request body",
@@ -215,19 +373,56 @@ func TestDeleteCache(t *testing.T) {
 			body: map[string]interface{}{
 				"atRiskKey": "some_key@securly.com:16546548465",
 			},
-			deleteCache: func(key string) (datatypes.AtRiskResponse, error) {
+			deleteCache: func(key string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
 				return datatypes.AtRiskResponse{}, constants.ResourceNotFound
 			},
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: "{\"message\":\"key doesn't exists\"}",
 		},
+		{
+			name: "valid case, conditional delete",
+			body: map[string]interface{}{
+				"atRiskKey": "some_key@securly.com:16546548465",
+			},
+			headers: map[string]string{"If-Match": "\"6\""},
+			deleteCache: func(key string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
+				if precondition != (datatypes.Precondition{IfMatch: "6"}) {
+					return datatypes.AtRiskResponse{}, test.InternalServerErr
+				}
+				return datatypes.AtRiskResponse{AtRiskScore: 10, Scoring: scoring}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"AtRiskScore\":10,\"scoring\":{\"strategy\":\"flat\"}}",
+		},
+		{
+			name: "fail case, precondition failed",
+			body: map[string]interface{}{
+				"atRiskKey": "some_key@securly.com:16546548465",
+			},
+			headers: map[string]string{"If-Match": "\"6\""},
+			deleteCache: func(key string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
+				return datatypes.AtRiskResponse{}, constants.PreconditionFailed
+			},
+			expectedStatus:   http.StatusPreconditionFailed,
+			expectedResponse: "{\"message\":\"precondition failed, atRiskKey was changed or does not match If-Match/If-None-Match\"}",
+		},
+		{
+			name: "fail case, invalid If-None-Match header",
+			body: map[string]interface{}{
+				"atRiskKey": "some_key@securly.com:16546548465",
+			},
+			headers:          map[string]string{"If-None-Match": "\"v1\""},
+			deleteCache:      nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid If-Match or If-None-Match header, should be * or a list of quoted versions\"}",
+		},
 		{
 			name: "fail case, error deleteCache func",
 			body: map[string]interface{}{
 				"atRiskKey": "some_key@securly.com:16546548465",
 			},
-			deleteCache: func(key string) (datatypes.AtRiskResponse, error) {
-				return datatypes.AtRiskResponse{0}, test.InternalServerErr
+			deleteCache: func(key string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
+				return datatypes.AtRiskResponse{}, test.InternalServerErr
 			},
 			expectedStatus:   http.StatusInternalServerError,
 			expectedResponse: "{\"message\":\"internal server error\"}",
@@ -235,7 +430,7 @@ func TestDeleteCache(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			riskService := RiskAPI{config.Config{}, logger.ZapLogger{Logger: zap.NewExample()}, nil, tc.deleteCache, nil, nil, nil}
+			riskService := RiskAPI{config: tc.config, log: logger.ZapLogger{Logger: zap.NewExample()}, deleteCache: tc.deleteCache}
 			u, err := url.Parse("")
 			assert.NoError(t, err)
 
@@ -252,6 +447,9 @@ func TestDeleteCache(t *testing.T) {
 			}
 			req, err := http.NewRequest("DELETE", u.String(), bytes.NewBuffer(jsonData))
 			assert.NoError(t, err)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
 
 			// Create a new recorder to capture the response
 			recorder := httptest.NewRecorder()
@@ -266,6 +464,80 @@ func TestDeleteCache(t *testing.T) {
 			// Assert the expected response
 			assert.Equal(t, tc.expectedStatus, recorder.Code)
 			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+			assert.Equal(t, tc.expectedETag, recorder.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestBatchCache(t *testing.T) {
+	type tests struct {
+		name             string
+		body             map[string]interface{}
+		batchCache       func(operations []datatypes.BatchCacheOperation, actor string) (datatypes.BatchCacheResponse, error)
+		expectedStatus   int
+		expectedResponse string
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			body: map[string]interface{}{
+				"operations": []map[string]interface{}{
+					{"action": "create", "atRiskKey": "some_key@securly.com:16546548465", "atRiskValue": "35:docs:4854184194"},
+				},
+			},
+			batchCache: func(operations []datatypes.BatchCacheOperation, actor string) (datatypes.BatchCacheResponse, error) {
+				return datatypes.BatchCacheResponse{
+					Results:           []datatypes.BatchCacheResult{{Action: "create", AtRiskKey: "some_key@securly.com:16546548465", Status: "created"}},
+					TotalAtRiskScores: map[string]int{"some_key@securly.com": 35},
+				}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"results\":[{\"action\":\"create\",\"atRiskKey\":\"some_key@securly.com:16546548465\",\"status\":\"created\"}],\"totalAtRiskScores\":{\"some_key@securly.com\":35}}",
+		},
+		{
+			name:             "invalid request body",
+			body:             map[string]interface{}{"operations": 1},
+			batchCache:       nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"json: cannot unmarshal number into Go struct field BatchCacheRequest.operations of type []datatypes.BatchCacheOperation\"}",
+		},
+		{
+			name:             "fail case, missing operations",
+			body:             map[string]interface{}{},
+			batchCache:       nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"operations missing in request body\"}",
+		},
+		{
+			name: "fail case, error batchCache func",
+			body: map[string]interface{}{
+				"operations": []map[string]interface{}{
+					{"action": "delete", "atRiskKey": "some_key@securly.com:16546548465"},
+				},
+			},
+			batchCache: func(operations []datatypes.BatchCacheOperation, actor string) (datatypes.BatchCacheResponse, error) {
+				return datatypes.BatchCacheResponse{}, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			riskService := RiskAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, batchCache: tc.batchCache}
+			jsonData, err := json.Marshal(tc.body)
+			assert.NoError(t, err)
+			req, err := http.NewRequest("POST", "", bytes.NewBuffer(jsonData))
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+
+			riskService.BatchCache(c)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
 		})
 	}
 }
@@ -275,7 +547,8 @@ func TestScore(t *testing.T) {
 		name             string
 		params           map[string]string
 		body             map[string]interface{}
-		getScore         func(email string) ([]datatypes.RiskScore, error)
+		maxBatchSize     int
+		getScore         func(emails []string, category string) (datatypes.RiskScoresResponse, error)
 		expectedStatus   int
 		expectedResponse string
 	}
@@ -283,23 +556,58 @@ func TestScore(t *testing.T) {
 		{
 			name: "valid case",
 			body: map[string]interface{}{"userEmail": "some1@email.com"},
-			getScore: func(email string) ([]datatypes.RiskScore, error) {
-				return []datatypes.RiskScore{
-					{Email: "some1@email.com", SelfHarmScore: "65"},
-					{Email: "some1@email.com", SelfHarmScore: "16"},
+			getScore: func(emails []string, category string) (datatypes.RiskScoresResponse, error) {
+				return datatypes.RiskScoresResponse{
+					AtRiskScores: []datatypes.EmailRiskScores{{UserEmail: "some1@email.com", SelfHarmScores: []string{"65", "16"}, TotalAtRiskScore: 72, Categories: []datatypes.CategoryScore{{Category: "chat", Score: 27, Events: 1}, {Category: "scan", Score: 45, Events: 1}}}},
+					NotFound:     []string{},
+				}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"atRiskScores\":[{\"userEmail\":\"some1@email.com\",\"selfHarmScores\":[\"65\",\"16\"],\"totalAtRiskScore\":72,\"categories\":[{\"category\":\"chat\",\"score\":27,\"events\":1},{\"category\":\"scan\",\"score\":45,\"events\":1}]}],\"notFound\":[]}",
+		},
+		{
+			name: "valid case, category",
+			body: map[string]interface{}{"userEmail": "some1@email.com", "category": "scan"},
+			getScore: func(emails []string, category string) (datatypes.RiskScoresResponse, error) {
+				if category != "scan" {
+					return datatypes.RiskScoresResponse{}, test.InternalServerErr
+				}
+				return datatypes.RiskScoresResponse{
+					Category:     category,
+					AtRiskScores: []datatypes.EmailRiskScores{{UserEmail: "some1@email.com", SelfHarmScores: []string{"65"}, TotalAtRiskScore: 45, Categories: []datatypes.CategoryScore{{Category: "scan", Score: 45, Events: 1}}}},
+					NotFound:     []string{},
+				}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"category\":\"scan\",\"atRiskScores\":[{\"userEmail\":\"some1@email.com\",\"selfHarmScores\":[\"65\"],\"totalAtRiskScore\":45,\"categories\":[{\"category\":\"scan\",\"score\":45,\"events\":1}]}],\"notFound\":[]}",
+		},
+		{
+			name:             "fail case, invalid category",
+			body:             map[string]interface{}{"userEmail": "some1@email.com", "category": "sc:an"},
+			getScore:         nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid category, should not be blank or contain a colon\"}",
+		},
+		{
+			name: "valid case, multiple emails without repeats",
+			body: map[string]interface{}{"userEmail": "some1@email.com", "userEmails": []string{"some2@email.com", "Some1@email.com", "some2@email.com"}},
+			getScore: func(emails []string, category string) (datatypes.RiskScoresResponse, error) {
+				if len(emails) != 2 || emails[0] != "some1@email.com" || emails[1] != "some2@email.com" {
+					return datatypes.RiskScoresResponse{}, test.InternalServerErr
+				}
+				return datatypes.RiskScoresResponse{
+					AtRiskScores: []datatypes.EmailRiskScores{{UserEmail: "some1@email.com", SelfHarmScores: []string{"65"}, Categories: []datatypes.CategoryScore{}}},
+					NotFound:     []string{"some2@email.com"},
 				}, nil
 			},
 			expectedStatus:   http.StatusOK,
-			expectedResponse: "{\"atRiskScores\":[{\"Email\":\"some1@email.com\",\"SelfHarmScore\":\"65\"},{\"Email\":\"some1@email.com\",\"SelfHarmScore\":\"16\"}]}",
+			expectedResponse: "{\"atRiskScores\":[{\"userEmail\":\"some1@email.com\",\"selfHarmScores\":[\"65\"],\"totalAtRiskScore\":0,\"categories\":[]}],\"notFound\":[\"some2@email.com\"]}",
 		},
 		{
 			name: "invalid request body",
 			body: map[string]interface{}{"userEmail": 1},
-			getScore: func(email string) ([]datatypes.RiskScore, error) {
-				return []datatypes.RiskScore{
-					{Email: "some1@email.com", SelfHarmScore: "65"},
-					{Email: "some1@email.com", SelfHarmScore: "16"},
-				}, nil
+			getScore: func(emails []string, category string) (datatypes.RiskScoresResponse, error) {
+				return datatypes.RiskScoresResponse{}, nil
 			},
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: "{\"message\":\"json: cannot unmarshal number into Go struct field AtRiskRequest.userEmail of type string\"}",
@@ -311,11 +619,33 @@ func TestScore(t *testing.T) {
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: "{\"message\":\"email missing in request body\"}",
 		},
+		{
+			name:             "fail case, blank email in userEmails",
+			body:             map[string]interface{}{"userEmails": []string{"some1@email.com", ""}},
+			getScore:         nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"email missing in request body\"}",
+		},
+		{
+			name:             "fail case, invalid email in userEmails",
+			body:             map[string]interface{}{"userEmails": []string{"some1@email.com", "some2"}},
+			getScore:         nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid userEmail\"}",
+		},
+		{
+			name:             "fail case, too many emails",
+			body:             map[string]interface{}{"userEmails": []string{"some1@email.com", "some2@email.com", "some3@email.com"}},
+			maxBatchSize:     2,
+			getScore:         nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"too many emails in request body\"}",
+		},
 		{
 			name: "fail case, error getScore func",
 			body: map[string]interface{}{"userEmail": "some1@email.com"},
-			getScore: func(email string) ([]datatypes.RiskScore, error) {
-				return nil, test.InternalServerErr
+			getScore: func(emails []string, category string) (datatypes.RiskScoresResponse, error) {
+				return datatypes.RiskScoresResponse{}, test.InternalServerErr
 			},
 			expectedStatus:   http.StatusInternalServerError,
 			expectedResponse: "{\"message\":\"internal server error\"}",
@@ -323,7 +653,9 @@ func TestScore(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			riskService := RiskAPI{config.Config{}, logger.ZapLogger{Logger: zap.NewExample()}, nil, nil, tc.getScore, nil, nil}
+			conf := config.Config{}
+			conf.Score.MaxBatchSize = tc.maxBatchSize
+			riskService := RiskAPI{config: conf, log: logger.ZapLogger{Logger: zap.NewExample()}, getScore: tc.getScore}
 			u, err := url.Parse("")
 			assert.NoError(t, err)
 
@@ -363,7 +695,7 @@ func TestExtendTTL(t *testing.T) {
 		name             string
 		params           map[string]string
 		body             map[string]interface{}
-		extentTTL        func(email string, ttl int) error
+		extentTTL        func(email string, ttl int, category, actor string, dryRun bool) (datatypes.ExtendTTLResponse, error)
 		expectedStatus   int
 		expectedResponse string
 	}
@@ -371,11 +703,39 @@ func TestExtendTTL(t *testing.T) {
 		{
 			name: "valid case",
 			body: map[string]interface{}{"userEmail": "some1@email.com"},
-			extentTTL: func(email string, ttl int) error {
-				return nil
+			extentTTL: func(email string, ttl int, category, actor string, dryRun bool) (datatypes.ExtendTTLResponse, error) {
+				if ttl != 7776000 || dryRun {
+					return datatypes.ExtendTTLResponse{}, test.InternalServerErr
+				}
+				return datatypes.ExtendTTLResponse{UserEmail: email, Keys: []datatypes.ExtendedKeyTTL{{AtRiskKey: "some1@email.com:1684323604", OldTTL: 300, NewTTL: ttl}}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"userEmail\":\"some1@email.com\",\"dryRun\":false,\"keys\":[{\"atRiskKey\":\"some1@email.com:1684323604\",\"oldTtl\":300,\"newTtl\":7776000}]}",
+		},
+		{
+			name: "valid case, dry run",
+			body: map[string]interface{}{"userEmail": "some1@email.com", "ttl": "60", "dryRun": true},
+			extentTTL: func(email string, ttl int, category, actor string, dryRun bool) (datatypes.ExtendTTLResponse, error) {
+				return datatypes.ExtendTTLResponse{UserEmail: email, DryRun: dryRun, Keys: []datatypes.ExtendedKeyTTL{{AtRiskKey: "some1@email.com:1684323604", OldTTL: -1, NewTTL: ttl}}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"userEmail\":\"some1@email.com\",\"dryRun\":true,\"keys\":[{\"atRiskKey\":\"some1@email.com:1684323604\",\"oldTtl\":-1,\"newTtl\":60}]}",
+		},
+		{
+			name: "valid case, category",
+			body: map[string]interface{}{"userEmail": "some1@email.com", "ttl": "60", "category": "chat"},
+			extentTTL: func(email string, ttl int, category, actor string, dryRun bool) (datatypes.ExtendTTLResponse, error) {
+				return datatypes.ExtendTTLResponse{UserEmail: email, Category: category, Keys: []datatypes.ExtendedKeyTTL{}}, nil
 			},
 			expectedStatus:   http.StatusOK,
-			expectedResponse: "{\"message\":\"ttl extended\"}",
+			expectedResponse: "{\"userEmail\":\"some1@email.com\",\"category\":\"chat\",\"dryRun\":false,\"keys\":[]}",
+		},
+		{
+			name:             "fail case, invalid category",
+			body:             map[string]interface{}{"userEmail": "some1@email.com", "category": " "},
+			extentTTL:        nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid category, should not be blank or contain a colon\"}",
 		},
 		{
 			name:             "invalid request body",
@@ -401,8 +761,8 @@ func TestExtendTTL(t *testing.T) {
 		{
 			name: "fail case, error extentTTL func",
 			body: map[string]interface{}{"userEmail": "some1@email.com"},
-			extentTTL: func(email string, ttl int) error {
-				return test.InternalServerErr
+			extentTTL: func(email string, ttl int, category, actor string, dryRun bool) (datatypes.ExtendTTLResponse, error) {
+				return datatypes.ExtendTTLResponse{}, test.InternalServerErr
 			},
 			expectedStatus:   http.StatusInternalServerError,
 			expectedResponse: "{\"message\":\"internal server error\"}",
@@ -410,7 +770,7 @@ func TestExtendTTL(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			riskService := RiskAPI{config.Config{}, logger.ZapLogger{Logger: zap.NewExample()}, nil, nil, nil, tc.extentTTL, nil}
+			riskService := RiskAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, extentTTL: tc.extentTTL}
 			u, err := url.Parse("")
 			assert.NoError(t, err)
 
@@ -445,24 +805,87 @@ func TestExtendTTL(t *testing.T) {
 	}
 }
 
+func TestTTL(t *testing.T) {
+	type tests struct {
+		name             string
+		body             map[string]interface{}
+		getTTL           func(email string) (datatypes.AtRiskTTLResponse, error)
+		expectedStatus   int
+		expectedResponse string
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			body: map[string]interface{}{"userEmail": "some1@email.com"},
+			getTTL: func(email string) (datatypes.AtRiskTTLResponse, error) {
+				return datatypes.AtRiskTTLResponse{UserEmail: email, Keys: []datatypes.AtRiskKeyTTL{{AtRiskKey: "some1@email.com:1684323604", TTL: 300}}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"userEmail\":\"some1@email.com\",\"keys\":[{\"atRiskKey\":\"some1@email.com:1684323604\",\"ttl\":300}]}",
+		},
+		{
+			name:             "fail case, missing email in request body",
+			body:             map[string]interface{}{},
+			getTTL:           nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"email missing in request body\"}",
+		},
+		{
+			name: "fail case, error getTTL func",
+			body: map[string]interface{}{"userEmail": "some1@email.com"},
+			getTTL: func(email string) (datatypes.AtRiskTTLResponse, error) {
+				return datatypes.AtRiskTTLResponse{}, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			riskService := RiskAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, getTTL: tc.getTTL}
+			jsonData, err := json.Marshal(tc.body)
+			assert.NoError(t, err)
+			req, err := http.NewRequest("GET", "", bytes.NewBuffer(jsonData))
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+
+			riskService.TTL(c)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
 func TestEventScore(t *testing.T) {
 	type tests struct {
 		name             string
 		params           map[string]string
 		body             map[string]interface{}
-		getEventScore    func(email string, timestamp string, mid string) (datatypes.EventScoreResponse, error)
+		getEventScore    func(email string, timestamp string, mid string, category string) (datatypes.EventScoreResponse, error)
 		expectedStatus   int
 		expectedResponse string
+		expectedETag     string
 	}
 	testCases := []tests{
 		{
 			name: "valid case",
 			body: map[string]interface{}{"userEmail": "some_email@securly.com", "timestamp": "1684323604", "mid": "<<somemid"},
-			getEventScore: func(email string, timestamp string, mid string) (datatypes.EventScoreResponse, error) {
-				return datatypes.EventScoreResponse{"key", "value", 45}, nil
+			getEventScore: func(email string, timestamp string, mid string, category string) (datatypes.EventScoreResponse, error) {
+				return datatypes.EventScoreResponse{
+					AtRiskKey:   "key",
+					AtRiskValue: "45:scan:<<somemid",
+					AtRiskScore: 45,
+					Event:       datatypes.AtRiskEvent{Score: 45, Category: "scan", MessageID: "<<somemid"},
+					Version:     3,
+				}, nil
 			},
 			expectedStatus:   http.StatusOK,
-			expectedResponse: "{\"atRiskKey\":\"key\",\"atRiskScore\":45,\"atRiskValue\":\"value\"}",
+			expectedResponse: "{\"AtRiskKey\":\"key\",\"AtRiskValue\":\"45:scan:\\u003c\\u003csomemid\",\"AtRiskScore\":45,\"event\":{\"score\":45,\"category\":\"scan\",\"messageId\":\"\\u003c\\u003csomemid\"},\"version\":3}",
+			expectedETag:     "\"3\"",
 		},
 		{
 			name:             "invalid request body",
@@ -485,10 +908,17 @@ func TestEventScore(t *testing.T) {
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: "{\"message\":\"timestamp missing in request body\"}",
 		},
+		{
+			name:             "fail case, invalid category",
+			body:             map[string]interface{}{"userEmail": "some_email@securly.com", "timestamp": "1684323604", "category": "a:b"},
+			getEventScore:    nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid category, should not be blank or contain a colon\"}",
+		},
 		{
 			name: "fail case, error resource not found",
 			body: map[string]interface{}{"userEmail": "some_email@securly.com", "timestamp": "1684323604", "mid": "<<somemid"},
-			getEventScore: func(email string, timestamp string, mid string) (datatypes.EventScoreResponse, error) {
+			getEventScore: func(email string, timestamp string, mid string, category string) (datatypes.EventScoreResponse, error) {
 				return datatypes.EventScoreResponse{}, constants.ResourceNotFound
 			},
 			expectedStatus:   http.StatusBadRequest,
@@ -497,7 +927,7 @@ func TestEventScore(t *testing.T) {
 		{
 			name: "fail case, error extentTTL func",
 			body: map[string]interface{}{"userEmail": "some_email@securly.com", "timestamp": "1684323604", "mid": "<<somemid"},
-			getEventScore: func(email string, timestamp string, mid string) (datatypes.EventScoreResponse, error) {
+			getEventScore: func(email string, timestamp string, mid string, category string) (datatypes.EventScoreResponse, error) {
 				return datatypes.EventScoreResponse{}, test.InternalServerErr
 			},
 			expectedStatus:   http.StatusInternalServerError,
@@ -506,7 +936,7 @@ func TestEventScore(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			riskService := RiskAPI{config.Config{}, logger.ZapLogger{Logger: zap.NewExample()}, nil, nil, nil, nil, tc.getEventScore}
+			riskService := RiskAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, getEventScore: tc.getEventScore}
 			u, err := url.Parse("")
 			assert.NoError(t, err)
 
@@ -537,6 +967,504 @@ func TestEventScore(t *testing.T) {
 			// Assert the expected response
 			assert.Equal(t, tc.expectedStatus, recorder.Code)
 			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+			assert.Equal(t, tc.expectedETag, recorder.Header().Get("ETag"))
+		})
+	}
+}
+
+func TestEventByMid(t *testing.T) {
+	type tests struct {
+		name             string
+		body             map[string]interface{}
+		getEventByMid    func(email, mid string) (datatypes.EventScoreResponse, error)
+		expectedStatus   int
+		expectedResponse string
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			body: map[string]interface{}{"userEmail": "some_email@securly.com", "mid": "somemid"},
+			getEventByMid: func(email, mid string) (datatypes.EventScoreResponse, error) {
+				return datatypes.EventScoreResponse{
+					AtRiskKey:   "some_email@securly.com:1684323604",
+					AtRiskValue: "45:scan:somemid",
+					AtRiskScore: 45,
+					Event:       datatypes.AtRiskEvent{Score: 45, Category: "scan", MessageID: "somemid"},
+					Version:     2,
+				}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"AtRiskKey\":\"some_email@securly.com:1684323604\",\"AtRiskValue\":\"45:scan:somemid\",\"AtRiskScore\":45,\"event\":{\"score\":45,\"category\":\"scan\",\"messageId\":\"somemid\"},\"version\":2}",
+		},
+		{
+			name:             "invalid request body",
+			body:             map[string]interface{}{"userEmail": "some_email@securly.com", "mid": 1},
+			getEventByMid:    nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"json: cannot unmarshal number into Go struct field AtRiskRequest.mid of type string\"}",
+		},
+		{
+			name:             "fail case, invalid userEmail",
+			body:             map[string]interface{}{"userEmail": "some_email", "mid": "somemid"},
+			getEventByMid:    nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid userEmail\"}",
+		},
+		{
+			name:             "fail case, missing mid",
+			body:             map[string]interface{}{"userEmail": "some_email@securly.com"},
+			getEventByMid:    nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"mid missing in request body\"}",
+		},
+		{
+			name: "fail case, error resource not found",
+			body: map[string]interface{}{"userEmail": "some_email@securly.com", "mid": "somemid"},
+			getEventByMid: func(email, mid string) (datatypes.EventScoreResponse, error) {
+				return datatypes.EventScoreResponse{}, constants.ResourceNotFound
+			},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"key doesn't exists\"}",
+		},
+		{
+			name: "fail case, error getEventByMid func",
+			body: map[string]interface{}{"userEmail": "some_email@securly.com", "mid": "somemid"},
+			getEventByMid: func(email, mid string) (datatypes.EventScoreResponse, error) {
+				return datatypes.EventScoreResponse{}, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			riskService := RiskAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, getEventByMid: tc.getEventByMid}
+
+			jsonData, err := json.Marshal(tc.body)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return
+			}
+			req, err := http.NewRequest("GET", "", bytes.NewBuffer(jsonData))
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+
+			riskService.EventByMid(c)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestEvents(t *testing.T) {
+	type tests struct {
+		name             string
+		body             map[string]interface{}
+		getEvents        func(request datatypes.AtRiskEventsRequest) (datatypes.AtRiskEventsResponse, error)
+		expectedStatus   int
+		expectedResponse string
+	}
+	testCases := []tests{
+		{
+			name: "valid case, defaults applied",
+			body: map[string]interface{}{"userEmail": "some_email@securly.com"},
+			getEvents: func(request datatypes.AtRiskEventsRequest) (datatypes.AtRiskEventsResponse, error) {
+				if request.Order != "desc" || request.Limit != 50 {
+					return datatypes.AtRiskEventsResponse{}, test.InternalServerErr
+				}
+				return datatypes.AtRiskEventsResponse{
+					Events: []datatypes.AtRiskEventItem{
+						{AtRiskKey: "some_email@securly.com:1684323604", Timestamp: "1684323604", Event: datatypes.AtRiskEvent{Score: 45, Category: "scan", MessageID: "somemid"}},
+					},
+					NextCursor: "1684323604",
+				}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"events\":[{\"atRiskKey\":\"some_email@securly.com:1684323604\",\"timestamp\":\"1684323604\",\"event\":{\"score\":45,\"category\":\"scan\",\"messageId\":\"somemid\"}}],\"nextCursor\":\"1684323604\"}",
+		},
+		{
+			name:             "fail case, missing userEmail",
+			body:             map[string]interface{}{"order": "asc"},
+			getEvents:        nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"email missing in request body\"}",
+		},
+		{
+			name:             "fail case, invalid from",
+			body:             map[string]interface{}{"userEmail": "some_email@securly.com", "from": "yesterday"},
+			getEvents:        nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid timestamp, should be numeric\"}",
+		},
+		{
+			name:             "fail case, invalid order",
+			body:             map[string]interface{}{"userEmail": "some_email@securly.com", "order": "newest"},
+			getEvents:        nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid order, should be asc or desc\"}",
+		},
+		{
+			name:             "fail case, invalid limit",
+			body:             map[string]interface{}{"userEmail": "some_email@securly.com", "limit": 1000},
+			getEvents:        nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid limit, should be between 1 and 500\"}",
+		},
+		{
+			name: "fail case, error getEvents func",
+			body: map[string]interface{}{"userEmail": "some_email@securly.com"},
+			getEvents: func(request datatypes.AtRiskEventsRequest) (datatypes.AtRiskEventsResponse, error) {
+				return datatypes.AtRiskEventsResponse{}, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			riskService := RiskAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, getEvents: tc.getEvents}
+			jsonData, err := json.Marshal(tc.body)
+			assert.NoError(t, err)
+			req, err := http.NewRequest("GET", "", bytes.NewBuffer(jsonData))
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+
+			riskService.Events(c)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestDomainSummary(t *testing.T) {
+	type tests struct {
+		name             string
+		body             map[string]interface{}
+		bands            []int
+		domainSummary    func(domain string, limit int, bands []int) (datatypes.AtRiskDomainSummaryResponse, error)
+		expectedStatus   int
+		expectedResponse string
+	}
+	max := 50
+	testCases := []tests{
+		{
+			name:  "valid case, defaults applied",
+			body:  map[string]interface{}{"domain": "securly.com"},
+			bands: nil,
+			domainSummary: func(domain string, limit int, bands []int) (datatypes.AtRiskDomainSummaryResponse, error) {
+				if domain != "securly.com" || limit != 10 || len(bands) != 4 {
+					return datatypes.AtRiskDomainSummaryResponse{}, test.InternalServerErr
+				}
+				return datatypes.AtRiskDomainSummaryResponse{
+					Domain:       "securly.com",
+					Students:     1,
+					ActiveEvents: 2,
+					TopStudents:  []datatypes.AtRiskStudent{{UserEmail: "some_email@securly.com", TotalAtRiskScore: 60, ActiveEvents: 2}},
+					ScoreBands:   []datatypes.ScoreBand{{Min: 0, Max: &max, Students: 0}, {Min: 50, Students: 1}},
+				}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"domain\":\"securly.com\",\"students\":1,\"activeEvents\":2,\"topStudents\":[{\"userEmail\":\"some_email@securly.com\",\"totalAtRiskScore\":60,\"activeEvents\":2}],\"scoreBands\":[{\"min\":0,\"max\":50,\"students\":0},{\"min\":50,\"students\":1}]}",
+		},
+		{
+			name:  "valid case, configured bands",
+			body:  map[string]interface{}{"domain": "securly.com", "limit": 5},
+			bands: []int{0, 50},
+			domainSummary: func(domain string, limit int, bands []int) (datatypes.AtRiskDomainSummaryResponse, error) {
+				if limit != 5 || len(bands) != 2 {
+					return datatypes.AtRiskDomainSummaryResponse{}, test.InternalServerErr
+				}
+				return datatypes.AtRiskDomainSummaryResponse{Domain: "securly.com", TopStudents: []datatypes.AtRiskStudent{}, ScoreBands: []datatypes.ScoreBand{}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"domain\":\"securly.com\",\"students\":0,\"activeEvents\":0,\"topStudents\":[],\"scoreBands\":[]}",
+		},
+		{
+			name:             "fail case, missing domain",
+			body:             map[string]interface{}{"limit": 5},
+			domainSummary:    nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"domain missing in request body\"}",
+		},
+		{
+			name:             "fail case, invalid domain",
+			body:             map[string]interface{}{"domain": "some_email@securly.com"},
+			domainSummary:    nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid domain\"}",
+		},
+		{
+			name:             "fail case, invalid limit",
+			body:             map[string]interface{}{"domain": "securly.com", "limit": 500},
+			domainSummary:    nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid limit, should be between 1 and 100\"}",
+		},
+		{
+			name: "fail case, error domainSummary func",
+			body: map[string]interface{}{"domain": "securly.com"},
+			domainSummary: func(domain string, limit int, bands []int) (datatypes.AtRiskDomainSummaryResponse, error) {
+				return datatypes.AtRiskDomainSummaryResponse{}, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := config.Config{}
+			conf.Summary.Bands = tc.bands
+			riskService := RiskAPI{config: conf, log: logger.ZapLogger{Logger: zap.NewExample()}, domainSummary: tc.domainSummary}
+			jsonData, err := json.Marshal(tc.body)
+			assert.NoError(t, err)
+			req, err := http.NewRequest("GET", "", bytes.NewBuffer(jsonData))
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+
+			riskService.DomainSummary(c)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestAudit(t *testing.T) {
+	type tests struct {
+		name             string
+		body             map[string]interface{}
+		getAudit         func(request datatypes.AtRiskAuditRequest) (datatypes.AtRiskAuditResponse, error)
+		expectedStatus   int
+		expectedResponse string
+	}
+	after := "45:scan:somemid"
+	testCases := []tests{
+		{
+			name: "valid case, defaults applied",
+			body: map[string]interface{}{"actor": "subject", "from": "1684323600"},
+			getAudit: func(request datatypes.AtRiskAuditRequest) (datatypes.AtRiskAuditResponse, error) {
+				if request.Actor != "subject" || request.From != "1684323600" || request.Limit != 50 {
+					return datatypes.AtRiskAuditResponse{}, test.InternalServerErr
+				}
+				return datatypes.AtRiskAuditResponse{
+					Entries: []datatypes.AtRiskAuditEntry{
+						{ID: 3, Actor: "subject", Action: "create", UserEmail: "some_email@securly.com", AtRiskKey: "some_email@securly.com:1684323604", AfterValue: &after, TotalAtRiskScore: 45, CreatedAt: 1684323605},
+					},
+					NextCursor: "3",
+				}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"entries\":[{\"id\":3,\"actor\":\"subject\",\"action\":\"create\",\"userEmail\":\"some_email@securly.com\",\"atRiskKey\":\"some_email@securly.com:1684323604\",\"beforeValue\":null,\"afterValue\":\"45:scan:somemid\",\"totalAtRiskScore\":45,\"createdAt\":1684323605}],\"nextCursor\":\"3\"}",
+		},
+		{
+			name:             "fail case, invalid userEmail",
+			body:             map[string]interface{}{"userEmail": "some_email"},
+			getAudit:         nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid userEmail\"}",
+		},
+		{
+			name:             "fail case, invalid to",
+			body:             map[string]interface{}{"to": "today"},
+			getAudit:         nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid timestamp, should be numeric\"}",
+		},
+		{
+			name:             "fail case, invalid limit",
+			body:             map[string]interface{}{"limit": -1},
+			getAudit:         nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid limit, should be between 1 and 500\"}",
+		},
+		{
+			name: "fail case, error getAudit func",
+			body: map[string]interface{}{"userEmail": "some_email@securly.com"},
+			getAudit: func(request datatypes.AtRiskAuditRequest) (datatypes.AtRiskAuditResponse, error) {
+				return datatypes.AtRiskAuditResponse{}, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			riskService := RiskAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, getAudit: tc.getAudit}
+			jsonData, err := json.Marshal(tc.body)
+			assert.NoError(t, err)
+			req, err := http.NewRequest("GET", "", bytes.NewBuffer(jsonData))
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+
+			riskService.Audit(c)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	type tests struct {
+		name             string
+		body             map[string]interface{}
+		restoreArchive   func(email, actor string) (datatypes.AtRiskRestoreResponse, error)
+		expectedStatus   int
+		expectedResponse string
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			body: map[string]interface{}{"userEmail": "some1@email.com"},
+			restoreArchive: func(email, actor string) (datatypes.AtRiskRestoreResponse, error) {
+				assert.Equal(t, "subject", actor)
+				return datatypes.AtRiskRestoreResponse{UserEmail: email, Restored: []string{"some1@email.com:1684323604"}, Skipped: []string{}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"userEmail\":\"some1@email.com\",\"restored\":[\"some1@email.com:1684323604\"],\"skipped\":[]}",
+		},
+		{
+			name:             "fail case, missing email in request body",
+			body:             map[string]interface{}{},
+			restoreArchive:   nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"email missing in request body\"}",
+		},
+		{
+			name: "fail case, archive not configured",
+			body: map[string]interface{}{"userEmail": "some1@email.com"},
+			restoreArchive: func(email, actor string) (datatypes.AtRiskRestoreResponse, error) {
+				return datatypes.AtRiskRestoreResponse{}, constants.ArchiveNotConfigured
+			},
+			expectedStatus:   http.StatusServiceUnavailable,
+			expectedResponse: "{\"message\":\"at-risk archive is not configured\"}",
+		},
+		{
+			name: "fail case, error restoreArchive func",
+			body: map[string]interface{}{"userEmail": "some1@email.com"},
+			restoreArchive: func(email, actor string) (datatypes.AtRiskRestoreResponse, error) {
+				return datatypes.AtRiskRestoreResponse{}, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			riskService := RiskAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, restoreArchive: tc.restoreArchive}
+			jsonData, err := json.Marshal(tc.body)
+			assert.NoError(t, err)
+			req, err := http.NewRequest("POST", "", bytes.NewBuffer(jsonData))
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+			c.Set(constants.TokenSubjectKey, "subject")
+
+			riskService.Restore(c)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	type tests struct {
+		name             string
+		query            string
+		subscribe        func(ctx context.Context, emails []string, domain string) (<-chan datatypes.AtRiskChange, error)
+		expectedStatus   int
+		expectedResponse string
+	}
+	streamed := func(changes ...datatypes.AtRiskChange) (<-chan datatypes.AtRiskChange, error) {
+		stream := make(chan datatypes.AtRiskChange, len(changes))
+		for _, change := range changes {
+			stream <- change
+		}
+		close(stream)
+		return stream, nil
+	}
+	testCases := []tests{
+		{
+			name:  "valid case",
+			query: "userEmail=some1@email.com&userEmail=some2@email.com",
+			subscribe: func(ctx context.Context, emails []string, domain string) (<-chan datatypes.AtRiskChange, error) {
+				assert.Equal(t, []string{"some1@email.com", "some2@email.com"}, emails)
+				assert.Equal(t, "", domain)
+				return streamed(
+					datatypes.AtRiskChange{Action: "create", UserEmail: "some1@email.com", Domain: "email.com", AtRiskKey: "some1@email.com:1684323604", TotalAtRiskScore: 45, Version: 3, CreatedAt: 1684323605},
+					datatypes.AtRiskChange{Action: "delete", UserEmail: "some2@email.com", Domain: "email.com", AtRiskKey: "some2@email.com:1684323604", CreatedAt: 1684323606},
+				)
+			},
+			expectedStatus: http.StatusOK,
+			expectedResponse: "event:create\ndata:{\"action\":\"create\",\"userEmail\":\"some1@email.com\",\"domain\":\"email.com\",\"atRiskKey\":\"some1@email.com:1684323604\",\"totalAtRiskScore\":45,\"version\":3,\"createdAt\":1684323605}\n\n" +
+				"event:delete\ndata:{\"action\":\"delete\",\"userEmail\":\"some2@email.com\",\"domain\":\"email.com\",\"atRiskKey\":\"some2@email.com:1684323604\",\"totalAtRiskScore\":0,\"createdAt\":1684323606}\n\n",
+		},
+		{
+			name:  "valid case, domain",
+			query: "domain=email.com",
+			subscribe: func(ctx context.Context, emails []string, domain string) (<-chan datatypes.AtRiskChange, error) {
+				assert.Equal(t, "email.com", domain)
+				return streamed()
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "",
+		},
+		{
+			name:             "fail case, missing email and domain",
+			query:            "",
+			subscribe:        nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"userEmail or domain is required\"}",
+		},
+		{
+			name:             "fail case, invalid email",
+			query:            "userEmail=some1",
+			subscribe:        nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid userEmail\"}",
+		},
+		{
+			name:  "fail case, error subscribe func",
+			query: "domain=email.com",
+			subscribe: func(ctx context.Context, emails []string, domain string) (<-chan datatypes.AtRiskChange, error) {
+				return nil, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			riskService := RiskAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, subscribe: tc.subscribe}
+			req, err := http.NewRequest("GET", "/stream?"+tc.query, nil)
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+			c.Set(constants.TokenSubjectKey, "subject")
+
+			riskService.Stream(c)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
 		})
 	}
 }
@@ -1,12 +1,15 @@
 package customer
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"www-api/config"
 	"www-api/internal/constants"
 	"www-api/internal/datatypes"
 	"www-api/internal/logger"
+	"www-api/pkg/cache"
 	service "www-api/service/customer"
 	"www-api/utils"
 
@@ -16,29 +19,48 @@ import (
 type CustomerAPI struct {
 	config               config.Config
 	log                  logger.ZapLogger
-	getPrivacyStatus     func(fid string) (map[string]int, error)
+	getPrivacyStatus     func(fid string) (datatypes.PrivacyStatus, error)
 	getTimezone          func(fid string) (datatypes.TimezoneResponse, error)
+	getTimezones         func(fids []string) ([]datatypes.TimezoneResponse, error)
 	getNotificationEmail func(fid string) (datatypes.Notification, error)
+	getNotifications     func(fid string) ([]datatypes.NotificationConfig, error)
+	createNotification   func(fid string, emails []string) (datatypes.NotificationConfig, error)
+	updateNotification   func(id int, fid string, emails []string, basegen int) (datatypes.NotificationConfig, error)
+	deleteNotification   func(id int, fid string, basegen int) error
 	getFilterType        func(fid string) (string, error)
+	replacePrivacyFlags  func(fid string, flags []string, actor string) (datatypes.PrivacyFlagsResponse, error)
+	updatePrivacyFlags   func(fid string, set, clear []string, actor string) (datatypes.PrivacyFlagsResponse, error)
+	invalidateLookups    func(fid string, entities []string) ([]string, error)
+	lookupStats          func() []datatypes.LookupStats
 }
 
 func NewCustomerAPI(conf config.Config, log logger.ZapLogger, connections *datatypes.Connections) CustomerAPI {
-	serv := service.NewCustomerService(log, connections)
+	lookups := cache.NewPinnedRedis(connections.Redis[constants.WWWReadRedisKey], connections.Redis[constants.WWWWriteRedisKey], constants.LookupRedisDB, log, context.Background())
+	serv := service.NewCustomerService(log, connections).WithLookupCache(lookups, conf.LookupCache.TTLSeconds, conf.LookupCache.NegativeTTLSeconds)
 	return CustomerAPI{
 		config:               conf,
 		log:                  log,
 		getPrivacyStatus:     serv.ProuctPrivacyStatus,
 		getTimezone:          serv.Timezone,
+		getTimezones:         serv.Timezones,
 		getNotificationEmail: serv.Notification,
+		getNotifications:     serv.NotificationConfigs,
+		createNotification:   serv.CreateNotificationConfig,
+		updateNotification:   serv.UpdateNotificationConfig,
+		deleteNotification:   serv.DeleteNotificationConfig,
 		getFilterType:        serv.GetFilterType,
+		replacePrivacyFlags:  serv.ReplacePrivacyFlags,
+		updatePrivacyFlags:   serv.UpdatePrivacyFlags,
+		invalidateLookups:    serv.InvalidateLookups,
+		lookupStats:          serv.LookupStats,
 	}
 }
 
 // @Summary      Get Privacy Status
-// @Description  fetches info of a student
+// @Description  decodes every privacy mode of the domain of a fid along with its bit vector and why 24 and Responder were or were not granted
 // @Tags         Customer
 // @Produce      json
-// @Success      200 {object} map[string]int
+// @Success      200 {object} datatypes.PrivacyStatus
 // @Failure      400 {object} string
 // @Failure      404 {object} string
 // @Failure      500 {object} string
@@ -74,7 +96,7 @@ func (r CustomerAPI) PrivacyStatus(c *gin.Context) {
 }
 
 // @Summary      Get Timezone
-// @Description  fetches timezone for a fid
+// @Description  fetches timezone for a fid with its current offset, DST, local time and next transition, status is unknown or invalid when the fid has no valid timezone
 // @Tags         Customer
 // @Produce      json
 // @Success      200 {object} datatypes.TimezoneResponse
@@ -111,6 +133,72 @@ func (r CustomerAPI) Timezone(c *gin.Context) {
 	c.JSON(http.StatusOK, timezone)
 }
 
+// @Summary      Get Timezones
+// @Description  fetches timezone for fid and every fid of fids, a fid without a timezone is returned with status unknown
+// @Tags         Customer
+// @Produce      json
+// @Success      200 {object} datatypes.TimezonesResponse
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Router       /api/customer/timezones [get]
+func (r CustomerAPI) Timezones(c *gin.Context) {
+	var request datatypes.CustomerRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	fids := timezoneFids(request)
+	if len(fids) == 0 {
+		err = utils.ValidateFid("", r.log)
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if len(fids) > constants.DefaultTimezoneBatchSize {
+		r.log.Error("too many fids in timezones request", map[string]interface{}{"fids": len(fids), "maxBatchSize": constants.DefaultTimezoneBatchSize})
+		c.JSON(http.StatusBadRequest, gin.H{"message": constants.InvalidFidBatchSize.Error()})
+		return
+	}
+
+	for _, fid := range fids {
+		err = utils.ValidateFid(fid, r.log)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	timezones, err := r.getTimezones(fids)
+	if err != nil {
+		r.log.Error("error occured while fetching timezones", map[string]interface{}{"error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, datatypes.TimezonesResponse{Timezones: timezones})
+}
+
+// timezoneFids returns fid followed by fids of a request without repeating a fid,
+// blank entries of fids are kept to fail validation
+func timezoneFids(request datatypes.CustomerRequest) []string {
+	fids := []string{}
+	if request.Fid != "" {
+		fids = append(fids, request.Fid)
+	}
+
+	seen := map[string]bool{strings.ToLower(request.Fid): request.Fid != ""}
+	for _, fid := range request.Fids {
+		if fid != "" && seen[strings.ToLower(fid)] {
+			continue
+		}
+		seen[strings.ToLower(fid)] = true
+		fids = append(fids, fid)
+	}
+	return fids
+}
+
 // @Summary      Get Notification
 // @Description  fetches notification email
 // @Tags         Customer
@@ -150,6 +238,179 @@ func (r CustomerAPI) Notification(c *gin.Context) {
 	c.JSON(http.StatusOK, notification)
 }
 
+// @Summary      List Notifications
+// @Description  fetches every aware email notification config of a fid with its recipients
+// @Tags         Customer
+// @Produce      json
+// @Success      200 {object} datatypes.NotificationConfigsResponse
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Router       /api/customer/notification/configs/aware [get]
+func (r CustomerAPI) Notifications(c *gin.Context) {
+	var request datatypes.CustomerRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateFid(request.Fid, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	configs, err := r.getNotifications(request.Fid)
+	if err != nil {
+		r.log.Error("error occured while fetching notification configs", map[string]interface{}{"error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, datatypes.NotificationConfigsResponse{Fid: request.Fid, Configs: configs})
+}
+
+// @Summary      Create Notification
+// @Description  creates an aware email notification config of a fid sending to every address of emails, it starts at basegen 1
+// @Tags         Customer
+// @Produce      json
+// @Success      200 {object} datatypes.NotificationConfig
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Router       /api/customer/notification/config/aware [post]
+func (r CustomerAPI) CreateNotification(c *gin.Context) {
+	var request datatypes.NotificationConfigRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateFid(request.Fid, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	err = utils.ValidateNotificationEmails(request.Emails, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	config, err := r.createNotification(request.Fid, request.Emails)
+	if err != nil {
+		r.log.Error("error occured while creating notification config", map[string]interface{}{"error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// @Summary      Update Notification
+// @Description  replaces the recipients of the aware email notification config id of a fid, basegen must be the current basegen of the config and is bumped on success
+// @Tags         Customer
+// @Produce      json
+// @Success      200 {object} datatypes.NotificationConfig
+// @Failure      400 {object} string
+// @Failure      409 {object} string
+// @Failure      500 {object} string
+// @Router       /api/customer/notification/config/aware [put]
+func (r CustomerAPI) UpdateNotification(c *gin.Context) {
+	var request datatypes.NotificationConfigRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = r.validateNotificationID(request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	err = utils.ValidateNotificationEmails(request.Emails, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	config, err := r.updateNotification(request.ID, request.Fid, request.Emails, request.Basegen)
+	if err != nil {
+		if err == constants.ResourceNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("notification config %d of %s doesn't exists", request.ID, request.Fid)})
+			return
+		}
+		if err == constants.NotificationChanged {
+			c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+			return
+		}
+		r.log.Error("error occured while updating notification config", map[string]interface{}{"error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// @Summary      Delete Notification
+// @Description  deletes the aware email notification config id of a fid, basegen must be the current basegen of the config
+// @Tags         Customer
+// @Produce      json
+// @Success      200 {object} string
+// @Failure      400 {object} string
+// @Failure      409 {object} string
+// @Failure      500 {object} string
+// @Router       /api/customer/notification/config/aware [delete]
+func (r CustomerAPI) DeleteNotification(c *gin.Context) {
+	var request datatypes.NotificationConfigRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = r.validateNotificationID(request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = r.deleteNotification(request.ID, request.Fid, request.Basegen)
+	if err != nil {
+		if err == constants.ResourceNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("notification config %d of %s doesn't exists", request.ID, request.Fid)})
+			return
+		}
+		if err == constants.NotificationChanged {
+			c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+			return
+		}
+		r.log.Error("error occured while deleting notification config", map[string]interface{}{"error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "notification config deleted"})
+}
+
+// validateNotificationID checks the fid and id of a request on an existing notification config
+func (r CustomerAPI) validateNotificationID(request datatypes.NotificationConfigRequest) error {
+	err := utils.ValidateFid(request.Fid, r.log)
+	if err != nil {
+		return err
+	}
+	if request.ID <= 0 {
+		r.log.Error("blank id in request body", map[string]interface{}{"id": request.ID})
+		return constants.BlankNotificationID
+	}
+	return nil
+}
+
 // @Summary      Get Filter
 // @Description  fetches filters for an fid
 // @Tags         Customer
@@ -183,3 +444,136 @@ func (r CustomerAPI) FilterType(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"filteringType": filterType})
 }
+
+// @Summary      Replace Privacy Flags
+// @Description  sets the given privacy flags of a fid and its domain and clears every other one in a single atomic write recorded under the caller, flags are the privacy modes Filter, Aware, Responder, 24 and suppBully and the fid bits PN:9, PN:10, PF:3:0 to PF:3:3 and PN:RESPONDER:0
+// @Tags         Customer
+// @Accept       json
+// @Produce      json
+// @Param        request body datatypes.PrivacyFlagsRequest true "fid and flags"
+// @Success      200 {object} datatypes.PrivacyFlagsResponse
+// @Failure      400 {object} string
+// @Failure      409 {object} string
+// @Failure      500 {object} string
+// @Router       /api/customer/privacy [put]
+func (r CustomerAPI) ReplacePrivacyFlags(c *gin.Context) {
+	var request datatypes.PrivacyFlagsRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateFid(request.Fid, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	response, err := r.replacePrivacyFlags(request.Fid, request.Flags, c.GetString(constants.TokenSubjectKey))
+	r.privacyFlagsResponse(c, request, response, err)
+}
+
+// @Summary      Update Privacy Flags
+// @Description  sets and clears the given privacy flags of a fid and its domain in a single atomic write recorded under the caller, flags are the privacy modes Filter, Aware, Responder, 24 and suppBully and the fid bits PN:9, PN:10, PF:3:0 to PF:3:3 and PN:RESPONDER:0
+// @Tags         Customer
+// @Accept       json
+// @Produce      json
+// @Param        request body datatypes.PrivacyFlagsRequest true "fid with flags to set and clear"
+// @Success      200 {object} datatypes.PrivacyFlagsResponse
+// @Failure      400 {object} string
+// @Failure      409 {object} string
+// @Failure      500 {object} string
+// @Router       /api/customer/privacy [patch]
+func (r CustomerAPI) UpdatePrivacyFlags(c *gin.Context) {
+	var request datatypes.PrivacyFlagsRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateFid(request.Fid, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	response, err := r.updatePrivacyFlags(request.Fid, request.Set, request.Clear, c.GetString(constants.TokenSubjectKey))
+	r.privacyFlagsResponse(c, request, response, err)
+}
+
+// privacyFlagsResponse writes the response of a write of privacy flags
+func (r CustomerAPI) privacyFlagsResponse(c *gin.Context, request datatypes.PrivacyFlagsRequest, response datatypes.PrivacyFlagsResponse, err error) {
+	if err == constants.InvalidPrivacyCombination {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error(), "violations": response.Violations})
+		return
+	}
+	if err == constants.InvalidPrivacyFlag || err == constants.ConflictingPrivacyFlags || err == constants.NoPrivacyFlags || err == constants.EmptyFid {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if err == constants.PrivacyFlagsChanged {
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		r.log.Error("error occured while writing privacy flags", map[string]interface{}{"fid": request.Fid, "error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	r.log.Info("privacy flags written", map[string]interface{}{"fid": request.Fid, "changed": response.Changed, "actor": c.GetString(constants.TokenSubjectKey)})
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary      Invalidate Cached Lookups
+// @Description  drops the cached timezone, notification and filter type lookups of a fid, only the given entities when there are some
+// @Tags         Customer
+// @Accept       json
+// @Produce      json
+// @Param        request body datatypes.LookupCacheRequest true "fid and entities"
+// @Success      200 {object} datatypes.LookupCacheInvalidation
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Router       /api/customer/cache [delete]
+func (r CustomerAPI) InvalidateCache(c *gin.Context) {
+	var request datatypes.LookupCacheRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateFid(request.Fid, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	invalidated, err := r.invalidateLookups(request.Fid, request.Entities)
+	if err == constants.InvalidLookupEntity {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		r.log.Error("error occured while invalidating lookups", map[string]interface{}{"fid": request.Fid, "error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, datatypes.LookupCacheInvalidation{Fid: request.Fid, Invalidated: invalidated})
+}
+
+// @Summary      Cached Lookup Stats
+// @Description  returns the hits, negative hits, misses and errors of every cached lookup entity since the instance started
+// @Tags         Customer
+// @Produce      json
+// @Success      200 {object} map[string][]datatypes.LookupStats
+// @Router       /api/customer/cache/stats [get]
+func (r CustomerAPI) CacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"lookups": r.lookupStats()})
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 )
@@ -36,7 +37,12 @@ func TestNewCustomer(t *testing.T) {
 			config: config.Config{},
 			connections: &datatypes.Connections{
 				DB: map[string]*sqlx.DB{
-					constants.SchoolsReadDBKey: &sqlx.DB{},
+					constants.SchoolsReadDBKey:  &sqlx.DB{},
+					constants.SchoolsWriteDBKey: &sqlx.DB{},
+				},
+				Redis: map[string]*redis.Client{
+					constants.WWWReadRedisKey:  redis.NewClient(&redis.Options{}),
+					constants.WWWWriteRedisKey: redis.NewClient(&redis.Options{}),
 				},
 			},
 		},
@@ -56,12 +62,39 @@ func TestNewCustomer(t *testing.T) {
 			if customerService.getTimezone == nil {
 				t.Errorf("expected getTimezone but got nil")
 			}
+			if customerService.getTimezones == nil {
+				t.Errorf("expected getTimezones but got nil")
+			}
+			if customerService.getNotifications == nil {
+				t.Errorf("expected getNotifications but got nil")
+			}
+			if customerService.createNotification == nil {
+				t.Errorf("expected createNotification but got nil")
+			}
+			if customerService.updateNotification == nil {
+				t.Errorf("expected updateNotification but got nil")
+			}
+			if customerService.deleteNotification == nil {
+				t.Errorf("expected deleteNotification but got nil")
+			}
 			if customerService.getNotificationEmail == nil {
 				t.Errorf("expected getNotificationEmail but got nil")
 			}
 			if customerService.getFilterType == nil {
 				t.Errorf("expected getFilterType but got nil")
 			}
+			if customerService.replacePrivacyFlags == nil {
+				t.Errorf("expected replacePrivacyFlags but got nil")
+			}
+			if customerService.updatePrivacyFlags == nil {
+				t.Errorf("expected updatePrivacyFlags but got nil")
+			}
+			if customerService.invalidateLookups == nil {
+				t.Errorf("expected invalidateLookups but got nil")
+			}
+			if customerService.lookupStats == nil {
+				t.Errorf("expected lookupStats but got nil")
+			}
 		})
 	}
 }
@@ -72,7 +105,7 @@ func TestPrivacyStatus(t *testing.T) {
 		header           map[string]string
 		params           map[string]string
 		body             map[string]interface{}
-		getPrivacyStatus func(fid string) (map[string]int, error)
+		getPrivacyStatus func(fid string) (datatypes.PrivacyStatus, error)
 		expectedStatus   int
 		expectedResponse string
 	}
@@ -85,17 +118,18 @@ func TestPrivacyStatus(t *testing.T) {
 			body: map[string]interface{}{
 				"fid": "some_key@securly.com",
 			},
-			getPrivacyStatus: func(fid string) (map[string]int, error) {
-				return map[string]int{
-					"24":        1,
-					"Filter":    0,
-					"Aware":     0,
-					"Responder": 0,
-					"suppBully": 0,
+			getPrivacyStatus: func(fid string) (datatypes.PrivacyStatus, error) {
+				return datatypes.PrivacyStatus{
+					TwentyFour: 1,
+					BitVector:  8,
+					Modes:      []string{"24"},
+					Eligibility: datatypes.PrivacyEligibility{
+						Reasons: []string{"Aware privacy is not set"},
+					},
 				}, nil
 			},
 			expectedStatus:   http.StatusOK,
-			expectedResponse: "{\"24\":1,\"Aware\":0,\"Filter\":0,\"Responder\":0,\"suppBully\":0}",
+			expectedResponse: "{\"Filter\":0,\"Aware\":0,\"24\":1,\"Responder\":0,\"suppBully\":0,\"bitVector\":8,\"modes\":[\"24\"],\"eligibility\":{\"checked\":false,\"24\":false,\"Responder\":false,\"reasons\":[\"Aware privacy is not set\"]}}",
 		},
 		{
 			name: "invalid request body",
@@ -116,8 +150,8 @@ func TestPrivacyStatus(t *testing.T) {
 			body: map[string]interface{}{
 				"fid": "some_key@securly.com",
 			},
-			getPrivacyStatus: func(fid string) (map[string]int, error) {
-				return map[string]int{}, constants.ResourceNotFound
+			getPrivacyStatus: func(fid string) (datatypes.PrivacyStatus, error) {
+				return datatypes.PrivacyStatus{}, constants.ResourceNotFound
 			},
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: "{\"message\":\"user some_key@securly.com doesn't exists\"}",
@@ -127,8 +161,8 @@ func TestPrivacyStatus(t *testing.T) {
 			body: map[string]interface{}{
 				"fid": "some_key@securly.com",
 			},
-			getPrivacyStatus: func(fid string) (map[string]int, error) {
-				return map[string]int{}, test.InternalServerErr
+			getPrivacyStatus: func(fid string) (datatypes.PrivacyStatus, error) {
+				return datatypes.PrivacyStatus{}, test.InternalServerErr
 			},
 			expectedStatus:   http.StatusInternalServerError,
 			expectedResponse: "{\"message\":\"internal server error\"}",
@@ -136,7 +170,7 @@ func TestPrivacyStatus(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			custService := CustomerAPI{config.Config{}, logger.ZapLogger{Logger: zap.NewExample()}, tc.getPrivacyStatus, nil, nil, nil}
+			custService := CustomerAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, getPrivacyStatus: tc.getPrivacyStatus}
 			u, err := url.Parse("")
 			assert.NoError(t, err)
 
@@ -192,10 +226,10 @@ func TestTimezone(t *testing.T) {
 				"fid": "some_key@securly.com",
 			},
 			getTimezone: func(fid string) (datatypes.TimezoneResponse, error) {
-				return datatypes.TimezoneResponse{"Asia/Kolkata", "IST"}, nil
+				return datatypes.TimezoneResponse{Tz: "Asia/Kolkata", TzAbbr: "IST", Status: "ok", Offset: "+05:30", OffsetSeconds: 19800, LocalTime: "2024-01-15T17:30:00+05:30"}, nil
 			},
 			expectedStatus:   http.StatusOK,
-			expectedResponse: "{\"tz\":\"Asia/Kolkata\",\"tzAbbr\":\"IST\"}",
+			expectedResponse: "{\"Tz\":\"Asia/Kolkata\",\"TzAbbr\":\"IST\",\"status\":\"ok\",\"offset\":\"+05:30\",\"offsetSeconds\":19800,\"isDst\":false,\"localTime\":\"2024-01-15T17:30:00+05:30\"}",
 		},
 		{
 			name: "invalid request body",
@@ -236,7 +270,7 @@ func TestTimezone(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			custService := CustomerAPI{config.Config{}, logger.ZapLogger{Logger: zap.NewExample()}, nil, tc.getTimezone, nil, nil}
+			custService := CustomerAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, getTimezone: tc.getTimezone}
 			u, err := url.Parse("")
 			assert.NoError(t, err)
 
@@ -338,7 +372,7 @@ func TestNotification(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			custService := CustomerAPI{config.Config{}, logger.ZapLogger{Logger: zap.NewExample()}, nil, nil, tc.getNotificationEmail, nil}
+			custService := CustomerAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, getNotificationEmail: tc.getNotificationEmail}
 			u, err := url.Parse("")
 			assert.NoError(t, err)
 
@@ -440,7 +474,7 @@ func TestFilterType(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			custService := CustomerAPI{config.Config{}, logger.ZapLogger{Logger: zap.NewExample()}, nil, nil, nil, tc.getFilterType}
+			custService := CustomerAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, getFilterType: tc.getFilterType}
 			u, err := url.Parse("")
 			assert.NoError(t, err)
 
@@ -479,3 +513,524 @@ func TestFilterType(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdatePrivacyFlags(t *testing.T) {
+	type tests struct {
+		name               string
+		method             string
+		body               map[string]interface{}
+		updatePrivacyFlags func(fid string, set, clear []string, actor string) (datatypes.PrivacyFlagsResponse, error)
+		expectedStatus     int
+		expectedResponse   string
+	}
+
+	status := datatypes.PrivacyStatus{Filter: 1, BitVector: 1, Modes: []string{"Filter"}, Eligibility: datatypes.PrivacyEligibility{Reasons: []string{"Aware privacy is not set"}}}
+	testCases := []tests{
+		{
+			name:   "valid case, patch",
+			method: "PATCH",
+			body:   map[string]interface{}{"fid": "some_key@securly.com", "set": []string{"Filter"}, "clear": []string{"suppBully"}},
+			updatePrivacyFlags: func(fid string, set, clear []string, actor string) (datatypes.PrivacyFlagsResponse, error) {
+				assert.Equal(t, []string{"Filter"}, set)
+				assert.Equal(t, []string{"suppBully"}, clear)
+				assert.Equal(t, "subject", actor)
+				return datatypes.PrivacyFlagsResponse{Fid: fid, Changed: []string{"Filter"}, AuditID: "1684231487000-0", Status: status}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"fid\":\"some_key@securly.com\",\"changed\":[\"Filter\"],\"auditId\":\"1684231487000-0\",\"status\":{\"Filter\":1,\"Aware\":0,\"24\":0,\"Responder\":0,\"suppBully\":0,\"bitVector\":1,\"modes\":[\"Filter\"],\"eligibility\":{\"checked\":false,\"24\":false,\"Responder\":false,\"reasons\":[\"Aware privacy is not set\"]}}}",
+		},
+		{
+			name:   "valid case, put",
+			method: "PUT",
+			body:   map[string]interface{}{"fid": "some_key@securly.com", "flags": []string{"Filter"}},
+			updatePrivacyFlags: func(fid string, set, clear []string, actor string) (datatypes.PrivacyFlagsResponse, error) {
+				assert.Equal(t, []string{"Filter"}, set)
+				assert.Nil(t, clear)
+				return datatypes.PrivacyFlagsResponse{Fid: fid, Changed: []string{}, Status: status}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"fid\":\"some_key@securly.com\",\"changed\":[],\"status\":{\"Filter\":1,\"Aware\":0,\"24\":0,\"Responder\":0,\"suppBully\":0,\"bitVector\":1,\"modes\":[\"Filter\"],\"eligibility\":{\"checked\":false,\"24\":false,\"Responder\":false,\"reasons\":[\"Aware privacy is not set\"]}}}",
+		},
+		{
+			name:             "fail case, missing fid in request body",
+			method:           "PATCH",
+			body:             map[string]interface{}{"set": []string{"Filter"}},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"fid missing in request body\"}",
+		},
+		{
+			name:   "fail case, unknown flag",
+			method: "PATCH",
+			body:   map[string]interface{}{"fid": "some_key@securly.com", "set": []string{"Unknown"}},
+			updatePrivacyFlags: func(fid string, set, clear []string, actor string) (datatypes.PrivacyFlagsResponse, error) {
+				return datatypes.PrivacyFlagsResponse{}, constants.InvalidPrivacyFlag
+			},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"unknown privacy flag\"}",
+		},
+		{
+			name:   "fail case, invalid combination",
+			method: "PATCH",
+			body:   map[string]interface{}{"fid": "some_key@securly.com", "set": []string{"24"}},
+			updatePrivacyFlags: func(fid string, set, clear []string, actor string) (datatypes.PrivacyFlagsResponse, error) {
+				return datatypes.PrivacyFlagsResponse{Fid: fid, Changed: []string{"24"}, Violations: []string{"24 requires integration enabled (PF:3:3)"}}, constants.InvalidPrivacyCombination
+			},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid combination of privacy flags\",\"violations\":[\"24 requires integration enabled (PF:3:3)\"]}",
+		},
+		{
+			name:   "fail case, flags kept changing",
+			method: "PATCH",
+			body:   map[string]interface{}{"fid": "some_key@securly.com", "set": []string{"Filter"}},
+			updatePrivacyFlags: func(fid string, set, clear []string, actor string) (datatypes.PrivacyFlagsResponse, error) {
+				return datatypes.PrivacyFlagsResponse{}, constants.PrivacyFlagsChanged
+			},
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "{\"message\":\"privacy flags changed while being written, try again\"}",
+		},
+		{
+			name:   "fail case, error updatePrivacyFlags func",
+			method: "PATCH",
+			body:   map[string]interface{}{"fid": "some_key@securly.com", "set": []string{"Filter"}},
+			updatePrivacyFlags: func(fid string, set, clear []string, actor string) (datatypes.PrivacyFlagsResponse, error) {
+				return datatypes.PrivacyFlagsResponse{}, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			custService := CustomerAPI{
+				config:             config.Config{},
+				log:                logger.ZapLogger{Logger: zap.NewExample()},
+				updatePrivacyFlags: tc.updatePrivacyFlags,
+				replacePrivacyFlags: func(fid string, flags []string, actor string) (datatypes.PrivacyFlagsResponse, error) {
+					return tc.updatePrivacyFlags(fid, flags, nil, actor)
+				},
+			}
+			jsonData, err := json.Marshal(tc.body)
+			assert.NoError(t, err)
+			req, err := http.NewRequest(tc.method, "", bytes.NewBuffer(jsonData))
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+			c.Set(constants.TokenSubjectKey, "subject")
+
+			if tc.method == "PUT" {
+				custService.ReplacePrivacyFlags(c)
+			} else {
+				custService.UpdatePrivacyFlags(c)
+			}
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestInvalidateCache(t *testing.T) {
+	type tests struct {
+		name              string
+		body              map[string]interface{}
+		invalidateLookups func(fid string, entities []string) ([]string, error)
+		expectedStatus    int
+		expectedResponse  string
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case",
+			body: map[string]interface{}{"fid": "some_key@securly.com", "entities": []string{"timezone"}},
+			invalidateLookups: func(fid string, entities []string) ([]string, error) {
+				assert.Equal(t, []string{"timezone"}, entities)
+				return entities, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"fid\":\"some_key@securly.com\",\"invalidated\":[\"timezone\"]}",
+		},
+		{
+			name:             "fail case, missing fid in request body",
+			body:             map[string]interface{}{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"fid missing in request body\"}",
+		},
+		{
+			name: "fail case, unknown entity",
+			body: map[string]interface{}{"fid": "some_key@securly.com", "entities": []string{"privacy"}},
+			invalidateLookups: func(fid string, entities []string) ([]string, error) {
+				return nil, constants.InvalidLookupEntity
+			},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"unknown lookup entity, should be timezone, notification or filter-type\"}",
+		},
+		{
+			name: "fail case, error invalidateLookups func",
+			body: map[string]interface{}{"fid": "some_key@securly.com"},
+			invalidateLookups: func(fid string, entities []string) ([]string, error) {
+				return nil, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			custService := CustomerAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, invalidateLookups: tc.invalidateLookups}
+			jsonData, err := json.Marshal(tc.body)
+			assert.NoError(t, err)
+			req, err := http.NewRequest("DELETE", "", bytes.NewBuffer(jsonData))
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+
+			custService.InvalidateCache(c)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	custService := CustomerAPI{
+		config: config.Config{},
+		log:    logger.ZapLogger{Logger: zap.NewExample()},
+		lookupStats: func() []datatypes.LookupStats {
+			return []datatypes.LookupStats{{Entity: "timezone", TTLSeconds: 86400, NegativeTTLSeconds: 300, Hits: 4, NegativeHits: 1, Misses: 2}}
+		},
+	}
+	req, err := http.NewRequest("GET", "", nil)
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = req
+
+	custService.CacheStats(c)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "{\"lookups\":[{\"entity\":\"timezone\",\"ttlSeconds\":86400,\"negativeTtlSeconds\":300,\"hits\":4,\"negativeHits\":1,\"misses\":2,\"errors\":0}]}", recorder.Body.String())
+}
+
+func TestTimezones(t *testing.T) {
+	type tests struct {
+		name             string
+		body             map[string]interface{}
+		getTimezones     func(fids []string) ([]datatypes.TimezoneResponse, error)
+		expectedStatus   int
+		expectedResponse string
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case",
+			body: map[string]interface{}{"fid": "a@securly.com", "fids": []string{"A@securly.com", "b@securly.com"}},
+			getTimezones: func(fids []string) ([]datatypes.TimezoneResponse, error) {
+				assert.Equal(t, []string{"a@securly.com", "b@securly.com"}, fids)
+				return []datatypes.TimezoneResponse{{Fid: "a@securly.com", Tz: "Asia/invalid", Status: "invalid"}, {Fid: "b@securly.com", Status: "unknown"}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"timezones\":[{\"Tz\":\"Asia/invalid\",\"TzAbbr\":\"\",\"fid\":\"a@securly.com\",\"status\":\"invalid\",\"offsetSeconds\":0,\"isDst\":false},{\"Tz\":\"\",\"TzAbbr\":\"\",\"fid\":\"b@securly.com\",\"status\":\"unknown\",\"offsetSeconds\":0,\"isDst\":false}]}",
+		},
+		{
+			name:             "fail case, missing fid in request body",
+			body:             map[string]interface{}{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"fid missing in request body\"}",
+		},
+		{
+			name:             "fail case, invalid fid in fids",
+			body:             map[string]interface{}{"fids": []string{"a@securly.com", "invalid"}},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"" + constants.InvalidFidParam.Error() + "\"}",
+		},
+		{
+			name: "fail case, too many fids",
+			body: map[string]interface{}{"fids": func() []string {
+				fids := []string{}
+				for i := 0; i <= constants.DefaultTimezoneBatchSize; i++ {
+					fids = append(fids, fmt.Sprintf("%d@securly.com", i))
+				}
+				return fids
+			}()},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"too many fids in request body\"}",
+		},
+		{
+			name: "fail case, error getTimezones func",
+			body: map[string]interface{}{"fid": "a@securly.com"},
+			getTimezones: func(fids []string) ([]datatypes.TimezoneResponse, error) {
+				return nil, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			custService := CustomerAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, getTimezones: tc.getTimezones}
+			jsonData, err := json.Marshal(tc.body)
+			assert.NoError(t, err)
+			req, err := http.NewRequest("GET", "", bytes.NewBuffer(jsonData))
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+
+			custService.Timezones(c)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	type tests struct {
+		name             string
+		body             map[string]interface{}
+		getNotifications func(fid string) ([]datatypes.NotificationConfig, error)
+		expectedStatus   int
+		expectedResponse string
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case",
+			body: map[string]interface{}{"fid": "admin@securly.com"},
+			getNotifications: func(fid string) ([]datatypes.NotificationConfig, error) {
+				return []datatypes.NotificationConfig{{ID: 7, Fid: fid, Emails: []string{"a@securly.com", "b@securly.com"}, Basegen: 2}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"fid\":\"admin@securly.com\",\"configs\":[{\"id\":7,\"fid\":\"admin@securly.com\",\"emails\":[\"a@securly.com\",\"b@securly.com\"],\"basegen\":2}]}",
+		},
+		{
+			name:             "fail case, missing fid in request body",
+			body:             map[string]interface{}{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"fid missing in request body\"}",
+		},
+		{
+			name: "fail case, error getNotifications func",
+			body: map[string]interface{}{"fid": "admin@securly.com"},
+			getNotifications: func(fid string) ([]datatypes.NotificationConfig, error) {
+				return nil, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			custService := CustomerAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, getNotifications: tc.getNotifications}
+			recorder := notificationRequest(t, "GET", tc.body, custService.Notifications)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestCreateNotification(t *testing.T) {
+	type tests struct {
+		name               string
+		body               map[string]interface{}
+		createNotification func(fid string, emails []string) (datatypes.NotificationConfig, error)
+		expectedStatus     int
+		expectedResponse   string
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case",
+			body: map[string]interface{}{"fid": "admin@securly.com", "emails": []string{"a@securly.com", "b@securly.com"}},
+			createNotification: func(fid string, emails []string) (datatypes.NotificationConfig, error) {
+				return datatypes.NotificationConfig{ID: 7, Fid: fid, Emails: emails, Basegen: 1}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"id\":7,\"fid\":\"admin@securly.com\",\"emails\":[\"a@securly.com\",\"b@securly.com\"],\"basegen\":1}",
+		},
+		{
+			name:             "fail case, missing emails in request body",
+			body:             map[string]interface{}{"fid": "admin@securly.com"},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"emails missing in request body\"}",
+		},
+		{
+			name:             "fail case, invalid email",
+			body:             map[string]interface{}{"fid": "admin@securly.com", "emails": []string{"a@securly.com", "Admin <b@securly.com>"}},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid email address in emails\"}",
+		},
+		{
+			name: "fail case, error createNotification func",
+			body: map[string]interface{}{"fid": "admin@securly.com", "emails": []string{"a@securly.com"}},
+			createNotification: func(fid string, emails []string) (datatypes.NotificationConfig, error) {
+				return datatypes.NotificationConfig{}, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			custService := CustomerAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, createNotification: tc.createNotification}
+			recorder := notificationRequest(t, "POST", tc.body, custService.CreateNotification)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestUpdateNotification(t *testing.T) {
+	type tests struct {
+		name               string
+		body               map[string]interface{}
+		updateNotification func(id int, fid string, emails []string, basegen int) (datatypes.NotificationConfig, error)
+		expectedStatus     int
+		expectedResponse   string
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case",
+			body: map[string]interface{}{"id": 7, "fid": "admin@securly.com", "emails": []string{"a@securly.com"}, "basegen": 2},
+			updateNotification: func(id int, fid string, emails []string, basegen int) (datatypes.NotificationConfig, error) {
+				return datatypes.NotificationConfig{ID: id, Fid: fid, Emails: emails, Basegen: basegen + 1}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"id\":7,\"fid\":\"admin@securly.com\",\"emails\":[\"a@securly.com\"],\"basegen\":3}",
+		},
+		{
+			name:             "fail case, missing id in request body",
+			body:             map[string]interface{}{"fid": "admin@securly.com", "emails": []string{"a@securly.com"}},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"id missing in request body\"}",
+		},
+		{
+			name: "fail case, config not found",
+			body: map[string]interface{}{"id": 7, "fid": "admin@securly.com", "emails": []string{"a@securly.com"}, "basegen": 2},
+			updateNotification: func(id int, fid string, emails []string, basegen int) (datatypes.NotificationConfig, error) {
+				return datatypes.NotificationConfig{}, constants.ResourceNotFound
+			},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"notification config 7 of admin@securly.com doesn't exists\"}",
+		},
+		{
+			name: "fail case, config changed",
+			body: map[string]interface{}{"id": 7, "fid": "admin@securly.com", "emails": []string{"a@securly.com"}, "basegen": 2},
+			updateNotification: func(id int, fid string, emails []string, basegen int) (datatypes.NotificationConfig, error) {
+				return datatypes.NotificationConfig{}, constants.NotificationChanged
+			},
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "{\"message\":\"basegen does not match, notification config was changed\"}",
+		},
+		{
+			name: "fail case, error updateNotification func",
+			body: map[string]interface{}{"id": 7, "fid": "admin@securly.com", "emails": []string{"a@securly.com"}, "basegen": 2},
+			updateNotification: func(id int, fid string, emails []string, basegen int) (datatypes.NotificationConfig, error) {
+				return datatypes.NotificationConfig{}, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			custService := CustomerAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, updateNotification: tc.updateNotification}
+			recorder := notificationRequest(t, "PUT", tc.body, custService.UpdateNotification)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestDeleteNotification(t *testing.T) {
+	type tests struct {
+		name               string
+		body               map[string]interface{}
+		deleteNotification func(id int, fid string, basegen int) error
+		expectedStatus     int
+		expectedResponse   string
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case",
+			body: map[string]interface{}{"id": 7, "fid": "admin@securly.com", "basegen": 2},
+			deleteNotification: func(id int, fid string, basegen int) error {
+				if basegen != 2 {
+					t.Errorf("expected basegen 2 got %d", basegen)
+				}
+				return nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"message\":\"notification config deleted\"}",
+		},
+		{
+			name:             "fail case, invalid fid in request body",
+			body:             map[string]interface{}{"id": 7, "fid": "admin"},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"" + constants.InvalidFidParam.Error() + "\"}",
+		},
+		{
+			name: "fail case, config not found",
+			body: map[string]interface{}{"id": 7, "fid": "admin@securly.com"},
+			deleteNotification: func(id int, fid string, basegen int) error {
+				return constants.ResourceNotFound
+			},
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"notification config 7 of admin@securly.com doesn't exists\"}",
+		},
+		{
+			name: "fail case, basegen changed",
+			body: map[string]interface{}{"id": 7, "fid": "admin@securly.com", "basegen": 1},
+			deleteNotification: func(id int, fid string, basegen int) error {
+				return constants.NotificationChanged
+			},
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "{\"message\":\"basegen does not match, notification config was changed\"}",
+		},
+		{
+			name: "fail case, error deleteNotification func",
+			body: map[string]interface{}{"id": 7, "fid": "admin@securly.com"},
+			deleteNotification: func(id int, fid string, basegen int) error {
+				return test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			custService := CustomerAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, deleteNotification: tc.deleteNotification}
+			recorder := notificationRequest(t, "DELETE", tc.body, custService.DeleteNotification)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+// notificationRequest sends body to handler with method and returns its response
+func notificationRequest(t *testing.T, method string, body map[string]interface{}, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	jsonData, err := json.Marshal(body)
+	assert.NoError(t, err)
+	req, err := http.NewRequest(method, "", bytes.NewBuffer(jsonData))
+	assert.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = req
+
+	handler(c)
+	return recorder
+}
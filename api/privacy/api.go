@@ -0,0 +1,184 @@
+package privacy
+
+import (
+	"fmt"
+	"net/http"
+	"www-api/config"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/aws/s3"
+	"www-api/pkg/elastic"
+	atrisk "www-api/service/at-risk"
+	service "www-api/service/privacy"
+	"www-api/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PrivacyAPI struct {
+	config        config.Config
+	log           logger.ZapLogger
+	eraseStudent  func(email, actor string, dryRun bool) (datatypes.ErasureReceipt, error)
+	exportStudent func(request datatypes.StudentExportRequest, actor string) ([]byte, datatypes.StudentExportReference, error)
+}
+
+func NewPrivacyAPI(conf config.Config, log logger.ZapLogger, connections *datatypes.Connections) PrivacyAPI {
+	connections.Redis[constants.AtRiskReadRedisKey].Options().DB = constants.RedisDB6
+	connections.Redis[constants.AtRiskWriteRedisKey].Options().DB = constants.RedisDB6
+	serv := service.NewPrivacyService(log, connections, newRiskService(conf, log, connections), conf.Privacy.ReceiptSecret)
+	if client, ok := connections.Elastic[constants.ElasticKey]; ok && len(conf.Privacy.ElasticIndices) != 0 {
+		serv = serv.WithElastic(elastic.NewElasticClient(client), conf.Privacy.ElasticIndices, conf.Privacy.ElasticField)
+	}
+	if conf.Privacy.ExportBucket != "" {
+		store, err := s3.NewS3Wrapper(conf.Region)
+		if err != nil {
+			log.Error("unable to create s3 client, exports are not uploaded", map[string]interface{}{"region": conf.Region, "error": err})
+		} else {
+			serv = serv.WithExport(store, conf.Privacy.ExportBucket, conf.Privacy.ExportPrefix)
+		}
+	}
+
+	return PrivacyAPI{
+		config:        conf,
+		log:           log,
+		eraseStudent:  serv.EraseStudent,
+		exportStudent: serv.ExportStudent,
+	}
+}
+
+// newRiskService returns the at-risk service with the archive and alert stream of the
+// deployment, so that archived events and alerts are erased as well
+func newRiskService(conf config.Config, log logger.ZapLogger, connections *datatypes.Connections) atrisk.RiskService {
+	risk := atrisk.NewRiskService(log, connections)
+	if conf.Alerts.Sink == constants.AlertSinkStream {
+		stream := conf.Alerts.Stream.Name
+		if stream == "" {
+			stream = constants.DefaultAlertStream
+		}
+		risk = risk.WithAlertStream(stream)
+	}
+	if conf.Archive.Bucket == "" {
+		return risk
+	}
+
+	store, err := s3.NewS3Wrapper(conf.Region)
+	if err != nil {
+		log.Error("unable to create s3 client, archives are not erased", map[string]interface{}{"region": conf.Region, "error": err})
+		return risk
+	}
+
+	prefix := conf.Archive.Prefix
+	if prefix == "" {
+		prefix = constants.DefaultArchivePrefix
+	}
+	return risk.WithArchive(store, conf.Archive.Bucket, prefix)
+}
+
+// @Summary      Erase a student
+// @Description  deletes the at-risk keys, AtRiskScore rows, event ledger rows, archived events, alert stream entries, elastic documents and uploaded exports of a student, redacts the at-risk values of its audit entries and returns a signed receipt, a dry run only lists what would be deleted
+// @Tags         Privacy
+// @Produce      json
+// @Param        userEmail query string true "user email"
+// @Param        dryRun query boolean false "only list what would be deleted"
+// @Success      200 {object} datatypes.ErasureReceipt
+// @Failure      400 {object} string
+// @Failure      409 {object} string
+// @Failure      500 {object} string
+// @Failure      503 {object} string
+// @Router       /privacy/student [delete]
+func (r PrivacyAPI) EraseStudent(c *gin.Context) {
+	var request datatypes.StudentErasureRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateEmail(request.UserEmail, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	receipt, err := r.eraseStudent(request.UserEmail, c.GetString(constants.TokenSubjectKey), request.DryRun)
+	if err == constants.ReceiptSecretNotConfigured {
+		r.log.Error("erasure requested without receipt secret", map[string]interface{}{"email": request.UserEmail})
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": err.Error()})
+		return
+	}
+	if err == constants.EmailLocked || err == constants.ErasureIncomplete {
+		r.log.Error("student changed while being erased", map[string]interface{}{"email": request.UserEmail, "error": err})
+		c.JSON(http.StatusConflict, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		r.log.Error("error occured while erasing student", map[string]interface{}{"email": request.UserEmail, "dryRun": request.DryRun, "error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	r.log.Info("successfully erased student", map[string]interface{}{"email": request.UserEmail, "dryRun": request.DryRun, "receiptId": receipt.ReceiptID})
+	c.JSON(http.StatusOK, receipt)
+}
+
+// @Summary      Export a student
+// @Description  bundles the student info, cached at-risk events, AtRiskScore rows and the settings of the customer fid as json or as a zip with a file per section, an uploaded bundle is not returned and the response tells where it can be downloaded from
+// @Tags         Privacy
+// @Accept       json
+// @Produce      json
+// @Produce      application/zip
+// @Param        request body datatypes.StudentExportRequest true "userEmail, optional fid, format (json or zip, json by default) and upload to s3"
+// @Success      200 {object} datatypes.StudentExport
+// @Success      200 {object} datatypes.StudentExportReference
+// @Failure      400 {object} string
+// @Failure      500 {object} string
+// @Failure      503 {object} string
+// @Router       /privacy/student/export [get]
+func (r PrivacyAPI) ExportStudent(c *gin.Context) {
+	var request datatypes.StudentExportRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		r.log.Error("error binding request body", map[string]interface{}{"error": err})
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
+	err = utils.ValidateEmail(request.UserEmail, r.log)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if request.Fid != "" {
+		err = utils.ValidateFid(request.Fid, r.log)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	bundle, reference, err := r.exportStudent(request, c.GetString(constants.TokenSubjectKey))
+	if err == constants.InvalidExportFormat {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if err == constants.ExportStoreNotConfigured {
+		r.log.Error("upload of export requested without export bucket", map[string]interface{}{"email": request.UserEmail})
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		r.log.Error("error occured while exporting student", map[string]interface{}{"email": request.UserEmail, "fid": request.Fid, "error": err})
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	r.log.Info("successfully exported student", map[string]interface{}{"email": request.UserEmail, "reference": reference})
+	if request.Upload {
+		c.JSON(http.StatusOK, reference)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", reference.ExportID, reference.Format))
+	c.Data(http.StatusOK, reference.ContentType, bundle)
+}
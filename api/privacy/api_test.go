@@ -0,0 +1,215 @@
+package privacy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"www-api/config"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/test"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestEraseStudent(t *testing.T) {
+	type tests struct {
+		name             string
+		body             map[string]interface{}
+		eraseStudent     func(email, actor string, dryRun bool) (datatypes.ErasureReceipt, error)
+		expectedStatus   int
+		expectedResponse string
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			body: map[string]interface{}{"userEmail": "some1@email.com", "dryRun": true},
+			eraseStudent: func(email, actor string, dryRun bool) (datatypes.ErasureReceipt, error) {
+				assert.Equal(t, "subject", actor)
+				assert.True(t, dryRun)
+				return datatypes.ErasureReceipt{
+					ReceiptID:        "5f1c2b",
+					UserEmail:        email,
+					Actor:            actor,
+					DryRun:           dryRun,
+					ErasedAt:         1684323604,
+					AtRisk:           datatypes.AtRiskErasure{RedisKeys: []string{"some1@email.com:1684323604"}, AtRiskScoreRows: 1, LedgerRows: 1, ArchiveObjects: []string{}, AuditRows: 2, AlertEntries: 1},
+					ElasticIndices:   []string{},
+					ElasticDocuments: 0,
+					ExportObjects:    []string{},
+					Signature:        "sha256=abc",
+				}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"receiptId\":\"5f1c2b\",\"userEmail\":\"some1@email.com\",\"actor\":\"subject\",\"dryRun\":true,\"erasedAt\":1684323604,\"atRisk\":{\"redisKeys\":[\"some1@email.com:1684323604\"],\"atRiskScoreRows\":1,\"ledgerRows\":1,\"archiveObjects\":[],\"auditRows\":2,\"alertEntries\":1},\"elasticIndices\":[],\"elasticDocuments\":0,\"exportObjects\":[],\"signature\":\"sha256=abc\"}",
+		},
+		{
+			name:             "fail case, missing email in request body",
+			body:             map[string]interface{}{},
+			eraseStudent:     nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"email missing in request body\"}",
+		},
+		{
+			name: "fail case, receipt secret not configured",
+			body: map[string]interface{}{"userEmail": "some1@email.com"},
+			eraseStudent: func(email, actor string, dryRun bool) (datatypes.ErasureReceipt, error) {
+				return datatypes.ErasureReceipt{}, constants.ReceiptSecretNotConfigured
+			},
+			expectedStatus:   http.StatusServiceUnavailable,
+			expectedResponse: "{\"message\":\"erasure receipt secret is not configured\"}",
+		},
+		{
+			name: "fail case, email locked",
+			body: map[string]interface{}{"userEmail": "some1@email.com"},
+			eraseStudent: func(email, actor string, dryRun bool) (datatypes.ErasureReceipt, error) {
+				return datatypes.ErasureReceipt{}, constants.EmailLocked
+			},
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "{\"message\":\"email is being archived or erased, try again\"}",
+		},
+		{
+			name: "fail case, error eraseStudent func",
+			body: map[string]interface{}{"userEmail": "some1@email.com"},
+			eraseStudent: func(email, actor string, dryRun bool) (datatypes.ErasureReceipt, error) {
+				return datatypes.ErasureReceipt{}, test.InternalServerErr
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			privacyAPI := PrivacyAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, eraseStudent: tc.eraseStudent}
+			jsonData, err := json.Marshal(tc.body)
+			assert.NoError(t, err)
+			req, err := http.NewRequest("DELETE", "", bytes.NewBuffer(jsonData))
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+			c.Set(constants.TokenSubjectKey, "subject")
+
+			privacyAPI.EraseStudent(c)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
+
+func TestExportStudent(t *testing.T) {
+	type tests struct {
+		name                string
+		body                map[string]interface{}
+		exportStudent       func(request datatypes.StudentExportRequest, actor string) ([]byte, datatypes.StudentExportReference, error)
+		expectedStatus      int
+		expectedContentType string
+		expectedResponse    string
+	}
+	testCases := []tests{
+		{
+			name: "valid case, bundle in response",
+			body: map[string]interface{}{"userEmail": "some1@email.com", "fid": "admin@email.com", "format": "zip"},
+			exportStudent: func(request datatypes.StudentExportRequest, actor string) ([]byte, datatypes.StudentExportReference, error) {
+				assert.Equal(t, "subject", actor)
+				assert.Equal(t, "admin@email.com", request.Fid)
+				return []byte("PK"), datatypes.StudentExportReference{ExportID: "5f1c2b", UserEmail: request.UserEmail, Format: "zip", ContentType: "application/zip", Size: 2}, nil
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/zip",
+			expectedResponse:    "PK",
+		},
+		{
+			name: "valid case, uploaded",
+			body: map[string]interface{}{"userEmail": "some1@email.com", "upload": true},
+			exportStudent: func(request datatypes.StudentExportRequest, actor string) ([]byte, datatypes.StudentExportReference, error) {
+				return nil, datatypes.StudentExportReference{
+					ExportID:    "5f1c2b",
+					UserEmail:   request.UserEmail,
+					Format:      "json",
+					ContentType: "application/json",
+					Size:        120,
+					Bucket:      "export-bucket",
+					Key:         "privacy-exports/some1@email.com/5f1c2b.json",
+					Location:    "s3://export-bucket/privacy-exports/some1@email.com/5f1c2b.json",
+				}, nil
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedResponse:    "{\"exportId\":\"5f1c2b\",\"userEmail\":\"some1@email.com\",\"format\":\"json\",\"contentType\":\"application/json\",\"size\":120,\"bucket\":\"export-bucket\",\"key\":\"privacy-exports/some1@email.com/5f1c2b.json\",\"location\":\"s3://export-bucket/privacy-exports/some1@email.com/5f1c2b.json\"}",
+		},
+		{
+			name:                "fail case, missing email in request body",
+			body:                map[string]interface{}{},
+			exportStudent:       nil,
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedResponse:    "{\"message\":\"email missing in request body\"}",
+		},
+		{
+			name:                "fail case, invalid fid",
+			body:                map[string]interface{}{"userEmail": "some1@email.com", "fid": "admin"},
+			exportStudent:       nil,
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedResponse:    "{\"message\":\"" + constants.InvalidFidParam.Error() + "\"}",
+		},
+		{
+			name: "fail case, invalid format",
+			body: map[string]interface{}{"userEmail": "some1@email.com", "format": "csv"},
+			exportStudent: func(request datatypes.StudentExportRequest, actor string) ([]byte, datatypes.StudentExportReference, error) {
+				return nil, datatypes.StudentExportReference{}, constants.InvalidExportFormat
+			},
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedResponse:    "{\"message\":\"format must be json or zip\"}",
+		},
+		{
+			name: "fail case, export bucket not configured",
+			body: map[string]interface{}{"userEmail": "some1@email.com", "upload": true},
+			exportStudent: func(request datatypes.StudentExportRequest, actor string) ([]byte, datatypes.StudentExportReference, error) {
+				return nil, datatypes.StudentExportReference{}, constants.ExportStoreNotConfigured
+			},
+			expectedStatus:      http.StatusServiceUnavailable,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedResponse:    "{\"message\":\"export bucket is not configured\"}",
+		},
+		{
+			name: "fail case, error exportStudent func",
+			body: map[string]interface{}{"userEmail": "some1@email.com"},
+			exportStudent: func(request datatypes.StudentExportRequest, actor string) ([]byte, datatypes.StudentExportReference, error) {
+				return nil, datatypes.StudentExportReference{}, test.InternalServerErr
+			},
+			expectedStatus:      http.StatusInternalServerError,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedResponse:    "{\"message\":\"internal server error\"}",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			privacyAPI := PrivacyAPI{config: config.Config{}, log: logger.ZapLogger{Logger: zap.NewExample()}, exportStudent: tc.exportStudent}
+			jsonData, err := json.Marshal(tc.body)
+			assert.NoError(t, err)
+			req, err := http.NewRequest("GET", "", bytes.NewBuffer(jsonData))
+			assert.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = req
+			c.Set(constants.TokenSubjectKey, "subject")
+
+			privacyAPI.ExportStudent(c)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedContentType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+}
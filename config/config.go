@@ -2,8 +2,10 @@ package config
 
 import (
 	_ "embed"
+	"encoding/json"
 	"log"
 	"os"
+	"strconv"
 	"www-api/internal/constants"
 	"www-api/internal/logger"
 	"www-api/utils"
@@ -12,13 +14,103 @@ import (
 )
 
 type Config struct {
-	Region     string
-	Deployment string
-	Server     server
-	Mysql      map[string]mysqlDatabase
-	Redis      map[string]redisDatabase
-	Elastic    elasticvariables
-	RedisPort  string
+	Region      string
+	Deployment  string
+	Server      server
+	Mysql       map[string]mysqlDatabase
+	Redis       map[string]redisDatabase
+	Elastic     elasticvariables
+	RedisPort   string
+	Scoring     scoring
+	Alerts      alerts
+	Summary     summary
+	Score       score
+	Idempotency idempotency
+	Archive     archive
+	Reconcile   reconcile
+	Privacy     privacy
+	LookupCache lookupCache
+}
+
+// lookupCache configures the read-through cache of the customer lookups, TTLSeconds maps an
+// entity (timezone, notification or filter-type) to how long its lookups are kept and
+// NegativeTTLSeconds is how long a lookup that was not found is kept
+type lookupCache struct {
+	TTLSeconds         map[string]int
+	NegativeTTLSeconds int
+}
+
+// privacy configures the erasure and export of a student, ReceiptSecret signs the erasure
+// receipts and the documents of ElasticIndices whose ElasticField is the email are erased.
+// Exports are only uploaded when ExportBucket is set
+type privacy struct {
+	ReceiptSecret  string
+	ElasticIndices []string
+	ElasticField   string
+	ExportBucket   string
+	ExportPrefix   string
+}
+
+// reconcile schedules the reconciliation of the redis totals with the AtRiskScore table,
+// it only runs inside the api server when IntervalSeconds is set. Repair is mysql, ledger
+// or blank to only report, schedule a repair on a single instance
+type reconcile struct {
+	IntervalSeconds int
+	Repair          string
+	Examples        int
+}
+
+// archive configures the archiver of expiring at-risk events, it only runs when Bucket
+// is set and archives the events expiring within WindowSeconds every IntervalSeconds
+type archive struct {
+	Bucket          string
+	Prefix          string
+	WindowSeconds   int
+	IntervalSeconds int
+}
+
+// idempotency configures how long the response of an Idempotency-Key is replayed
+type idempotency struct {
+	WindowSeconds int
+}
+
+// score configures the score lookup, MaxBatchSize is the number of emails of a request
+type score struct {
+	MaxBatchSize int
+}
+
+// summary configures the domain summary, Bands are the lower bounds of its score bands
+type summary struct {
+	Bands []int
+}
+
+// alerts configures the threshold alerts of at-risk totals, Thresholds are keyed
+// by email domain with "default" used for domains without their own thresholds
+type alerts struct {
+	Sink       string
+	Thresholds map[string][]int
+	Webhook    webhook
+	Stream     stream
+}
+
+type webhook struct {
+	URL                 string
+	Secret              string
+	Retries             int
+	TimeoutSeconds      int
+	BackoffMilliseconds int
+}
+
+type stream struct {
+	Name   string
+	MaxLen int64
+}
+
+// scoring is the default strategy of the total at-risk score, requests can override it
+type scoring struct {
+	Strategy     string
+	WindowDays   int
+	HalfLifeDays float64
 }
 
 type elasticvariables struct {
@@ -60,6 +152,24 @@ type server struct {
 func LoadConfig(filePath, region, deployment, secretName string, logger logger.ZapLogger) (Config, error) {
 	if region != "" && secretName != "" {
 		secrets := utils.FetchAWSSecrets(region, secretName, logger)
+		//missing or invalid scoring secrets fall back to the default strategy
+		windowDays, _ := strconv.Atoi(secrets["at-risk-scoring-window-days"])
+		halfLifeDays, _ := strconv.ParseFloat(secrets["at-risk-scoring-half-life-days"], 64)
+		thresholds := map[string][]int{}
+		_ = json.Unmarshal([]byte(secrets["at-risk-alert-thresholds"]), &thresholds)
+		bands := []int{}
+		maxBatchSize, _ := strconv.Atoi(secrets["at-risk-score-max-batch-size"])
+		idempotencyWindow, _ := strconv.Atoi(secrets["at-risk-idempotency-window-seconds"])
+		archiveWindow, _ := strconv.Atoi(secrets["at-risk-archive-window-seconds"])
+		archiveInterval, _ := strconv.Atoi(secrets["at-risk-archive-interval-seconds"])
+		reconcileInterval, _ := strconv.Atoi(secrets["at-risk-reconcile-interval-seconds"])
+		reconcileExamples, _ := strconv.Atoi(secrets["at-risk-reconcile-examples"])
+		_ = json.Unmarshal([]byte(secrets["at-risk-summary-bands"]), &bands)
+		lookupTTLs := map[string]int{}
+		_ = json.Unmarshal([]byte(secrets["lookup-cache-ttls"]), &lookupTTLs)
+		lookupNegativeTTL, _ := strconv.Atoi(secrets["lookup-cache-negative-ttl-seconds"])
+		privacyIndices := []string{}
+		_ = json.Unmarshal([]byte(secrets["privacy-elastic-indices"]), &privacyIndices)
 
 		return Config{
 			Region:     region,
@@ -120,6 +230,53 @@ func LoadConfig(filePath, region, deployment, secretName string, logger logger.Z
 				Host:     secrets["globals-elastic_cloud_host"],
 				Port:     secrets["globals-elastic_cloud_port"],
 			},
+			Scoring: scoring{
+				Strategy:     secrets["at-risk-scoring-strategy"],
+				WindowDays:   windowDays,
+				HalfLifeDays: halfLifeDays,
+			},
+			Alerts: alerts{
+				Sink:       secrets["at-risk-alert-sink"],
+				Thresholds: thresholds,
+				Webhook: webhook{
+					URL:    secrets["at-risk-alert-webhook-url"],
+					Secret: secrets["at-risk-alert-webhook-secret"],
+				},
+				Stream: stream{
+					Name: secrets["at-risk-alert-stream"],
+				},
+			},
+			Summary: summary{
+				Bands: bands,
+			},
+			Score: score{
+				MaxBatchSize: maxBatchSize,
+			},
+			Idempotency: idempotency{
+				WindowSeconds: idempotencyWindow,
+			},
+			Archive: archive{
+				Bucket:          secrets["at-risk-archive-bucket"],
+				Prefix:          secrets["at-risk-archive-prefix"],
+				WindowSeconds:   archiveWindow,
+				IntervalSeconds: archiveInterval,
+			},
+			Reconcile: reconcile{
+				IntervalSeconds: reconcileInterval,
+				Repair:          secrets["at-risk-reconcile-repair"],
+				Examples:        reconcileExamples,
+			},
+			Privacy: privacy{
+				ReceiptSecret:  secrets["privacy-receipt-secret"],
+				ElasticIndices: privacyIndices,
+				ElasticField:   secrets["privacy-elastic-field"],
+				ExportBucket:   secrets["privacy-export-bucket"],
+				ExportPrefix:   secrets["privacy-export-prefix"],
+			},
+			LookupCache: lookupCache{
+				TTLSeconds:         lookupTTLs,
+				NegativeTTLSeconds: lookupNegativeTTL,
+			},
 		}, nil
 	}
 
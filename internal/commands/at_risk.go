@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+	"www-api/config"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/internal/server"
+	"www-api/pkg/aws/s3"
+	service "www-api/service/at-risk"
+	"www-api/utils"
+)
+
+// BackfillAtRiskIndex builds the per email at-risk aggregates from the
+// email:timestamp keys already in cache, it is meant to run once before the
+// api starts relying on the aggregates
+func BackfillAtRiskIndex(conf config.Config, log logger.ZapLogger, args []string) error {
+	serv := newRiskService(server.NewConnections(conf, log), log)
+	emails, err := serv.RebuildIndex()
+	if err != nil {
+		log.Error("error occured while backfilling at-risk aggregates", map[string]interface{}{"error": err})
+		return err
+	}
+
+	log.Info("backfilled at-risk aggregates", map[string]interface{}{"emails": emails})
+	return nil
+}
+
+// RebuildAtRiskCache restores the cached at-risk events from the AtRiskEvent
+// ledger, for the emails passed as args or for every email of the ledger
+func RebuildAtRiskCache(conf config.Config, log logger.ZapLogger, args []string) error {
+	for _, email := range args {
+		err := utils.ValidateEmail(email, log)
+		if err != nil {
+			return err
+		}
+	}
+
+	serv := newRiskService(server.NewConnections(conf, log), log)
+	events, err := serv.RebuildCache(args...)
+	if err != nil {
+		log.Error("error occured while rebuilding at-risk cache", map[string]interface{}{"error": err, "emails": args, "restored": events})
+		return err
+	}
+
+	log.Info("rebuilt at-risk cache from ledger", map[string]interface{}{"emails": args, "events": events})
+	return nil
+}
+
+// ArchiveAtRiskEvents runs the archiver of expiring at-risk events once, for a
+// deployment that runs it on a schedule instead of inside the api server
+func ArchiveAtRiskEvents(conf config.Config, log logger.ZapLogger, args []string) error {
+	if conf.Archive.Bucket == "" {
+		log.Error("no archive bucket configured", map[string]interface{}{})
+		return constants.ArchiveNotConfigured
+	}
+
+	store, err := s3.NewS3Wrapper(conf.Region)
+	if err != nil {
+		log.Error("unable to create s3 client", map[string]interface{}{"region": conf.Region, "error": err})
+		return err
+	}
+
+	prefix := conf.Archive.Prefix
+	if prefix == "" {
+		prefix = constants.DefaultArchivePrefix
+	}
+	window := conf.Archive.WindowSeconds
+	if window <= 0 {
+		window = constants.DefaultArchiveWindowSeconds
+	}
+
+	serv := newRiskService(server.NewConnections(conf, log), log).WithArchive(store, conf.Archive.Bucket, prefix)
+	events, err := serv.ArchiveExpiring(time.Duration(window) * time.Second)
+	if err != nil {
+		log.Error("error occured while archiving expiring events", map[string]interface{}{"error": err, "archived": events})
+		return err
+	}
+
+	log.Info("archived expiring at-risk events", map[string]interface{}{"events": events})
+	return nil
+}
+
+// ReconcileAtRisk compares the redis totals with the AtRiskScore table once and writes the
+// drift report as json to stdout, -repair=mysql rewrites the AtRiskScore rows of the drifted
+// emails from redis, -repair=ledger restores their cached events from the AtRiskEvent ledger and -examples sets the number of drifted emails listed in the report
+func ReconcileAtRisk(conf config.Config, log logger.ZapLogger, args []string) error {
+	flags := flag.NewFlagSet("reconcile-at-risk", flag.ContinueOnError)
+	repair := flags.String("repair", "", "repair of the drifted emails, mysql or ledger")
+	examples := flags.Int("examples", constants.DefaultDriftExamples, "number of drifted emails listed in the report")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *repair != "" && *repair != constants.RepairMySQL && *repair != constants.RepairLedger {
+		log.Error("invalid repair", map[string]interface{}{"repair": *repair})
+		return constants.InvalidRepair
+	}
+
+	serv := newRiskService(server.NewConnections(conf, log), log)
+	report, err := serv.Reconcile(*repair, *examples)
+	if err != nil {
+		log.Error("error occured while reconciling at-risk totals", map[string]interface{}{"error": err, "repair": *repair})
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// newRiskService returns a RiskService pointed at the at-risk redis db
+func newRiskService(connections *datatypes.Connections, log logger.ZapLogger) service.RiskService {
+	connections.Redis[constants.AtRiskReadRedisKey].Options().DB = constants.RedisDB6
+	connections.Redis[constants.AtRiskWriteRedisKey].Options().DB = constants.RedisDB6
+	return service.NewRiskService(log, connections)
+}
@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"www-api/config"
+	"www-api/internal/constants"
+	"www-api/internal/logger"
+)
+
+// Command is an administrative task run from the cli instead of the api server
+type Command func(conf config.Config, log logger.ZapLogger, args []string) error
+
+var registry = map[string]Command{
+	"backfill-at-risk-index": BackfillAtRiskIndex,
+	"rebuild-at-risk-cache":  RebuildAtRiskCache,
+	"archive-at-risk-events": ArchiveAtRiskEvents,
+	"reconcile-at-risk":      ReconcileAtRisk,
+}
+
+// Run executes the command registered under name with the remaining cli args
+func Run(name string, args []string, conf config.Config, log logger.ZapLogger) error {
+	command, ok := registry[name]
+	if !ok {
+		log.Error("unknown command", map[string]interface{}{"command": name})
+		return constants.UnknownCommand
+	}
+
+	log.Info("running command", map[string]interface{}{"command": name, "args": args})
+	return command(conf, log, args)
+}
@@ -0,0 +1,136 @@
+package constants
+
+// AtRiskEventTTL is the expiry of an at-risk event in cache, 60 days in seconds
+const AtRiskEventTTL = 5184000
+const MaxBatchOperations = 1000
+
+const BatchActionCreate = "create"
+const BatchActionDelete = "delete"
+
+// actions recorded in the AtRiskAudit table
+const AuditActionCreate = "create"
+const AuditActionDelete = "delete"
+const AuditActionExtendTTL = "extend-ttl"
+const AuditActionErase = "erase"
+const AuditActionExport = "export"
+
+const BatchStatusCreated = "created"
+const BatchStatusDeleted = "deleted"
+const BatchStatusInvalid = "invalid"
+const BatchStatusNotFound = "notFound"
+const BatchStatusFailed = "failed"
+
+// per email aggregates kept next to the email:timestamp event keys, the prefix
+// keeps them out of email:* scans
+const AtRiskEventsKey = "atrisk:events:%s"
+const AtRiskExpiryKey = "atrisk:expiry:%s"
+const AtRiskTotalsKey = "atrisk:totals:%s"
+const AtRiskTimelineKey = "atrisk:timeline:%s"
+const AtRiskMidKey = "atrisk:mids:%s"
+const AtRiskVersionsKey = "atrisk:versions:%s"
+
+// AtRiskVersionSequence hands out event versions, it never expires so a version is
+// never reused for an event key, not even after the event was deleted and created again
+const AtRiskVersionSequence = "atrisk:version-sequence"
+
+// conditional writes, an event's version is sent as a strong ETag and checked against
+// the If-Match and If-None-Match headers of create and delete
+const ETagHeader = "ETag"
+const IfMatchHeader = "If-Match"
+const IfNoneMatchHeader = "If-None-Match"
+const AnyETag = "*"
+
+// archive of expiring events, events expiring within the window are written to s3 as
+// ndjson under prefix/email/ every interval, the archived hash keeps the version of each
+// event already written so that an event is archived again only after it changed
+const AtRiskArchivedKey = "atrisk:archived:%s"
+const DefaultArchivePrefix = "at-risk-archive"
+const DefaultArchiveWindowSeconds = 86400
+const DefaultArchiveIntervalSeconds = 3600
+
+// locks taken with cache.AcquireLock, the archiver and reconciler locks let a single
+// instance run at a time and the email lock keeps the archiver and an erasure of the
+// same email apart
+const AtRiskLockKey = "atrisk:lock:%s"
+const ArchiverLock = "archiver"
+const ReconcilerLock = "reconciler"
+const EmailLock = "email:%s"
+const EmailLockSeconds = 300
+const ReconcilerLockSeconds = 3600
+
+// reconciliation of the redis totals with the AtRiskScore table, a repair either rewrites
+// the AtRiskScore rows of a drifted email from redis or restores its cached events from the
+// AtRiskEvent ledger, AtRiskScore only keeps totals so redis can't be rebuilt from it
+const RepairMySQL = "mysql"
+const RepairLedger = "ledger"
+const DriftMismatch = "mismatch"
+const DriftMissingInRedis = "missingInRedis"
+const DriftMissingInMySQL = "missingInMySQL"
+const DefaultDriftExamples = 10
+const DefaultReconcileIntervalSeconds = 86400
+
+// idempotent writes, the response of the first request with an Idempotency-Key header is
+// kept under the key for the window and replayed for repeats. While the first request runs
+// the key is only claimed for IdempotencyPendingSeconds so a crashed request frees it soon
+const IdempotencyKey = "atrisk:idempotency:%s:%s"
+const IdempotencyKeyHeader = "Idempotency-Key"
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+const MaxIdempotencyKeyLength = 255
+const DefaultIdempotencyWindowSeconds = 86400
+const IdempotencyPendingSeconds = 60
+
+// DefaultScoreBatchSize is the number of emails a score lookup accepts when not configured
+const DefaultScoreBatchSize = 100
+
+const DefaultEventsLimit = 50
+const MaxEventsLimit = 500
+const OrderAsc = "asc"
+const OrderDesc = "desc"
+
+// scoring strategies of the total at-risk score of an email
+const ScoringFlat = "flat"
+const ScoringWindow = "window"
+const ScoringDecay = "decay"
+const DefaultScoringWindowDays = 7
+const DefaultScoringHalfLifeDays = 7
+
+// threshold alerts, the alert key keeps the highest threshold crossed by an email
+const AtRiskAlertKey = "atrisk:alert:%s"
+const AlertSinkWebhook = "webhook"
+const AlertSinkStream = "stream"
+const AlertDirectionUp = "up"
+const AlertDirectionDown = "down"
+const DefaultAlertDomain = "default"
+const DefaultAlertStream = "atrisk:alerts"
+const DefaultAlertStreamMaxLen = 10000
+const DefaultWebhookRetries = 3
+const DefaultWebhookTimeoutSeconds = 5
+const DefaultWebhookBackoffMilliseconds = 200
+const AlertSignatureHeader = "X-AtRisk-Signature"
+const AlertTimestampHeader = "X-AtRisk-Timestamp"
+
+// number of alerts sent at the same time, an alert crossing while all of them are busy is dropped
+const MaxAlertSenders = 16
+
+// change feed, every change of the events of an email is published on the pub/sub
+// channel of its domain so that streams on any instance receive it
+const AtRiskChangesKey = "atrisk:changes:%s"
+const StreamHeartbeatSeconds = 15
+
+// per domain aggregates kept up to date by the event write scripts
+//   - scores (zset): email -> total score of the email
+//   - events (zset): email -> number of live events of the email
+//   - expiry (zset): email -> unix time the last event of the email expires
+//   - totals (hash): "events" -> number of live events in the domain
+//   - due (zset): email -> unix time the next event of the email expires
+const AtRiskDomainScoresKey = "atrisk:domain:scores:%s"
+const AtRiskDomainEventsKey = "atrisk:domain:events:%s"
+const AtRiskDomainExpiryKey = "atrisk:domain:expiry:%s"
+const AtRiskDomainTotalsKey = "atrisk:domain:totals:%s"
+const AtRiskDomainDueKey = "atrisk:domain:due:%s"
+
+const DefaultSummaryLimit = 10
+const MaxSummaryLimit = 100
+
+// DefaultScoreBands are the lower bounds of the score bands of a domain summary
+var DefaultScoreBands = []int{0, 25, 50, 100}
@@ -3,10 +3,12 @@ package constants
 const ServerPort = "8080"
 const DBConnectionString = "%s:%s@tcp(%s:%s)/%s"
 const RedisConnectionString = "%s:%s"
+const ElasticConnectionString = "%s:%s"
 const ProdAuthUrl = "https://accounts.securly.com"
 const DevAuthUrl = "https://accounts.securly.io"
 const DevEnvironment = "dev"
 const ProdEnvironment = "prod"
+const TokenSubjectKey = "subject"
 const RedisDB15 = 15
 const RedisDB6 = 6
 const RedisDB21 = 21
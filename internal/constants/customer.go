@@ -0,0 +1,31 @@
+package constants
+
+// read-through cache of the customer lookups, a lookup is kept under LookupCacheKey with
+// its entity and fid. Entities without a configured ttl are kept for DefaultLookupTTLSeconds
+// and lookups that were not found for DefaultLookupNegativeTTLSeconds
+const LookupCacheKey = "lookup:%s:%s"
+const LookupTimezone = "timezone"
+const LookupNotification = "notification"
+const LookupFilterType = "filter-type"
+const DefaultLookupTTLSeconds = 3600
+const DefaultLookupNegativeTTLSeconds = 300
+
+// LookupRedisDB is the db the lookups are kept in, on redis clients of their own pinned to
+// it so that they never share a db with the privacy keys
+const LookupRedisDB = 0
+
+// status of a timezone lookup, the next transition of a timezone is searched for
+// TimezoneTransitionHorizonDays ahead and at most DefaultTimezoneBatchSize fids are
+// looked up in one request
+const TimezoneOK = "ok"
+const TimezoneUnknown = "unknown"
+const TimezoneInvalid = "invalid"
+const TimezoneTransitionHorizonDays = 366
+const DefaultTimezoneBatchSize = 100
+
+// recipients of an awareEmailNotification row are kept in notifEmail separated by
+// NotificationEmailSeparator, a new row starts at InitialNotificationBasegen and
+// every update bumps its basegen
+const NotificationEmailSeparator = ","
+const MaxNotificationEmails = 20
+const InitialNotificationBasegen = 1
@@ -8,8 +8,11 @@ var BlankAtRiskKey = errors.New("atRiskKey missing in request body")
 var BlankAtRiskValue = errors.New("atRiskValue missing in request body")
 var BlankEmail = errors.New("email missing in request body")
 var BlankFid = errors.New("fid missing in request body")
+var BlankMid = errors.New("mid missing in request body")
 var BlankTimestamp = errors.New("timestamp missing in request body")
+var BlankDomain = errors.New("domain missing in request body")
 var EmptyFid = errors.New("fid not received")
+var EmptyBatch = errors.New("operations missing in request body")
 
 var InvalidKeyValue = errors.New("invalid key value")
 var InvalidTimestampValue = errors.New("invalid timestamp value")
@@ -20,8 +23,49 @@ var InvalidKeyTimestamp = errors.New("invalid timestamp in atRiskKey, second par
 var InvalidValueScore = errors.New("invalid score value in atRiskValue, first part should be numeric")
 var InvalidValueLength = errors.New("invalid value format, atRiskValue should be seperated by two colon")
 var InvalidTimestampParam = errors.New("invalid timestamp, should be numeric")
+var InvalidOrderParam = errors.New("invalid order, should be asc or desc")
+var InvalidLimitParam = errors.New("invalid limit, should be between 1 and 500")
 var InvalidEmailParam = errors.New("invalid userEmail")
 var InvalidFidParam = errors.New("invalid fid")
+var InvalidDomainParam = errors.New("invalid domain")
+var InvalidCategoryParam = errors.New("invalid category, should not be blank or contain a colon")
+var InvalidSummaryLimit = errors.New("invalid limit, should be between 1 and 100")
+var InvalidScoringStrategy = errors.New("invalid scoring strategy, should be flat, window or decay")
+var InvalidScoringWindow = errors.New("invalid windowDays, should be between 1 and 60")
+var InvalidScoringHalfLife = errors.New("invalid halfLifeDays, should be greater than 0")
+var InvalidBatchAction = errors.New("invalid action, should be create or delete")
+var InvalidBatchSize = errors.New("too many operations in request body")
+var InvalidEmailBatchSize = errors.New("too many emails in request body")
+var InvalidIdempotencyKey = errors.New("invalid Idempotency-Key header, should be at most 255 characters")
+var IdempotencyKeyReused = errors.New("Idempotency-Key was already used with a different request")
+var IdempotencyKeyInProgress = errors.New("a request with the same Idempotency-Key is in progress")
+var InvalidETag = errors.New("invalid If-Match or If-None-Match header, should be * or a list of quoted versions")
+var PreconditionFailed = errors.New("precondition failed, atRiskKey was changed or does not match If-Match/If-None-Match")
+var ArchiveNotConfigured = errors.New("at-risk archive is not configured")
+var InvalidRepair = errors.New("invalid repair, should be mysql or ledger")
+var RepairInProgress = errors.New("at-risk totals are being repaired by another instance")
+var EmailLocked = errors.New("email is being archived or erased, try again")
+var ErasureIncomplete = errors.New("events of email were written while it was erased, try again")
+var ReceiptSecretNotConfigured = errors.New("erasure receipt secret is not configured")
+var ElasticRequestFailed = errors.New("elastic request failed")
+var InvalidPrivacyFlag = errors.New("unknown privacy flag")
+var ConflictingPrivacyFlags = errors.New("privacy flag is both set and cleared")
+var NoPrivacyFlags = errors.New("no privacy flag to set or clear")
+var InvalidPrivacyCombination = errors.New("invalid combination of privacy flags")
+var PrivacyFlagsChanged = errors.New("privacy flags changed while being written, try again")
+var InvalidLookupEntity = errors.New("unknown lookup entity, should be timezone, notification or filter-type")
+var InvalidFidBatchSize = errors.New("too many fids in request body")
+var BlankNotificationEmails = errors.New("emails missing in request body")
+var InvalidNotificationEmail = errors.New("invalid email address in emails")
+var BlankNotificationID = errors.New("id missing in request body")
+var NotificationChanged = errors.New("basegen does not match, notification config was changed")
+var InvalidExportFormat = errors.New("format must be json or zip")
+var ExportStoreNotConfigured = errors.New("export bucket is not configured")
+var InvalidStreamFilter = errors.New("userEmail or domain is required")
+var UnknownCommand = errors.New("unknown command")
+var InvalidScriptReply = errors.New("invalid reply received from cache script")
+var WebhookRejected = errors.New("alert rejected by webhook")
+var WebhookUnavailable = errors.New("alert webhook unavailable")
 var InvalidCoversionToInt = errors.New("invalid value, cannot be converted to int")
 
 var EmptyString = ""
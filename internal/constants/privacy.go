@@ -5,3 +5,34 @@ const AWARE_PRIVACY = 1
 const RESPONDER_PRIVACY = 2
 const TWENTY_FOUR_PRIVACY = 3
 const SUPPRESS_BULLY = 4
+
+// PrivacyModes names the privacy modes by their bit in ENHANCED_PRIVACY
+var PrivacyModes = []string{
+	FILTER_PRIVACY:      "Filter",
+	AWARE_PRIVACY:       "Aware",
+	RESPONDER_PRIVACY:   "Responder",
+	TWENTY_FOUR_PRIVACY: "24",
+	SUPPRESS_BULLY:      "suppBully",
+}
+
+// erasure of a student, documents are matched on DefaultPrivacyElasticField when no
+// field is configured
+const DefaultPrivacyElasticField = "user_email"
+const ReceiptSignaturePrefix = "sha256="
+
+// export of a student, bundles are uploaded under DefaultExportPrefix when no prefix is
+// configured
+const ExportFormatJSON = "json"
+const ExportFormatZip = "zip"
+const DefaultExportPrefix = "privacy-exports"
+
+// writes of the privacy flags of a domain and its fid, the keys live in redis DB 21 and
+// every change is recorded in PrivacyAuditStream. A write is tried PrivacyWriteAttempts
+// times when the keys change while it runs
+const PrivacyDomainKey = "%s:PF:ENHANCED_PRIVACY"
+const PrivacyPNKey = "%s:PN"
+const PrivacyPF3Key = "%s:PF:3"
+const PrivacyResponderKey = "%s:PN:RESPONDER"
+const PrivacyAuditStream = "privacy:audit"
+const PrivacyAuditMaxLen = 100000
+const PrivacyWriteAttempts = 3
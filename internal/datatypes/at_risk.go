@@ -1,28 +1,357 @@
 package datatypes
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"www-api/internal/constants"
+)
+
+// AtRiskEventRecord is a row of the AtRiskEvent ledger, the durable copy of the events cached in redis
+type AtRiskEventRecord struct {
+	UserEmail      string `db:"user_email"`
+	EventTimestamp string `db:"event_timestamp"`
+	AtRiskValue    string `db:"atrisk_value"`
+	Score          int    `db:"score"`
+	Category       string `db:"category"`
+	MessageID      string `db:"mid"`
+	ExpiresAt      int64  `db:"expires_at"`
+}
+
+// AtRiskAuditEntry is a row of the append-only AtRiskAudit table, one for every change to
+// the cached events. BeforeValue and AfterValue are the event values, nil when the event
+// did not exist, an extend-ttl entry has the new expiry in AfterValue
+type AtRiskAuditEntry struct {
+	ID               int64   `db:"id" json:"id"`
+	Actor            string  `db:"actor" json:"actor"`
+	Action           string  `db:"action" json:"action"`
+	UserEmail        string  `db:"user_email" json:"userEmail"`
+	AtRiskKey        string  `db:"atrisk_key" json:"atRiskKey"`
+	BeforeValue      *string `db:"before_value" json:"beforeValue"`
+	AfterValue       *string `db:"after_value" json:"afterValue"`
+	TotalAtRiskScore int     `db:"total_atrisk_score" json:"totalAtRiskScore"`
+	CreatedAt        int64   `db:"created_at" json:"createdAt"`
+}
+
 type RiskScore struct {
 	Email         string `db:"user_email"`
 	SelfHarmScore string `db:"self_harm_score"`
 }
 
+// EmailRiskScores groups the self harm scores of an email with the total of its cached
+// events and that total broken down by the category of the events
+type EmailRiskScores struct {
+	UserEmail        string          `json:"userEmail"`
+	SelfHarmScores   []string        `json:"selfHarmScores"`
+	TotalAtRiskScore int             `json:"totalAtRiskScore"`
+	Categories       []CategoryScore `json:"categories"`
+}
+
+// CategoryScore is the total score and number of the cached events of one category
+type CategoryScore struct {
+	Category string `json:"category"`
+	Score    int    `json:"score"`
+	Events   int    `json:"events"`
+}
+
+// RiskScoresResponse has the scores of each email found in the same order as requested
+// and the emails without any score, Category is set when the totals were filtered by it
+type RiskScoresResponse struct {
+	Category     string            `json:"category,omitempty"`
+	AtRiskScores []EmailRiskScores `json:"atRiskScores"`
+	NotFound     []string          `json:"notFound"`
+}
+
 type CacheRequest struct {
+	AtRiskKey   string           `json:"atRiskKey"`
+	AtRiskValue string           `json:"atRiskValue"`
+	Event       *AtRiskEvent     `json:"event"`
+	Scoring     *ScoringStrategy `json:"scoring"`
+}
+
+// ScoringStrategy selects how the total score of an email is computed from its events
+//   - flat: sum of the scores of all cached events
+//   - window: sum of the scores of the events of the last WindowDays days
+//   - decay: sum of the scores halved every HalfLifeDays days of event age
+type ScoringStrategy struct {
+	Strategy     string  `json:"strategy"`
+	WindowDays   int     `json:"windowDays,omitempty"`
+	HalfLifeDays float64 `json:"halfLifeDays,omitempty"`
+}
+
+// Value returns the cache value of the request, a structured event is used
+// when atRiskValue is not sent
+func (r CacheRequest) Value() string {
+	if r.AtRiskValue == "" && r.Event != nil {
+		return r.Event.Format()
+	}
+	return r.AtRiskValue
+}
+
+type AtRiskRequest struct {
+	UserEmail  string   `json:"userEmail"`
+	UserEmails []string `json:"userEmails"`
+	TTL        string   `json:"ttl"`
+	Timestamp  string   `json:"timestamp"`
+	Mid        string   `json:"mid"`
+	DryRun     bool     `json:"dryRun"`
+	Category   string   `json:"category"`
+}
+
+// AtRiskKeyTTL is the remaining ttl of an event key in seconds, -1 when the key has no expiry
+type AtRiskKeyTTL struct {
+	AtRiskKey string `json:"atRiskKey"`
+	TTL       int    `json:"ttl"`
+}
+
+type AtRiskTTLResponse struct {
+	UserEmail string         `json:"userEmail"`
+	Keys      []AtRiskKeyTTL `json:"keys"`
+}
+
+// ExtendedKeyTTL is the ttl of an event key before and after an extension, in seconds
+type ExtendedKeyTTL struct {
+	AtRiskKey string `json:"atRiskKey"`
+	OldTTL    int    `json:"oldTtl"`
+	NewTTL    int    `json:"newTtl"`
+}
+
+// ExtendTTLResponse lists the keys of an extension, nothing is changed when DryRun is set
+type ExtendTTLResponse struct {
+	UserEmail string           `json:"userEmail"`
+	Category  string           `json:"category,omitempty"`
+	DryRun    bool             `json:"dryRun"`
+	Keys      []ExtendedKeyTTL `json:"keys"`
+}
+
+// ArchivedAtRiskEvent is a line of an ndjson archive of expiring events
+type ArchivedAtRiskEvent struct {
 	AtRiskKey   string `json:"atRiskKey"`
 	AtRiskValue string `json:"atRiskValue"`
+	Version     int64  `json:"version"`
+	ExpiresAt   int64  `json:"expiresAt"`
+	ArchivedAt  int64  `json:"archivedAt"`
 }
 
-type AtRiskRequest struct {
-	UserEmail string `json:"userEmail"`
-	TTL       string `json:"ttl"`
-	Timestamp string `json:"timestamp"`
-	Mid       string `json:"mid"`
+type AtRiskRestoreResponse struct {
+	UserEmail string   `json:"userEmail"`
+	Restored  []string `json:"restored"`
+	Skipped   []string `json:"skipped"`
 }
 
 type AtRiskResponse struct {
 	AtRiskScore int
+	Scoring     ScoringStrategy `json:"scoring"`
+	Version     int64           `json:"version,omitempty"`
+}
+
+// CreateCacheResponse is the body of a create, the total is sent as totalAtRiskScore
+type CreateCacheResponse struct {
+	TotalAtRiskScore int             `json:"totalAtRiskScore"`
+	Scoring          ScoringStrategy `json:"scoring"`
+	Version          int64           `json:"version,omitempty"`
 }
 
 type EventScoreResponse struct {
 	AtRiskKey   string
 	AtRiskValue string
 	AtRiskScore int
+	Event       AtRiskEvent `json:"event"`
+	Version     int64       `json:"version"`
+}
+
+// Precondition holds the If-Match and If-None-Match headers of a conditional write, each
+// is "*" or a comma separated list of versions and empty when the header was not sent
+type Precondition struct {
+	IfMatch     string
+	IfNoneMatch string
+}
+
+// IsSet reports whether the write is conditional
+func (p Precondition) IsSet() bool {
+	return p.IfMatch != "" || p.IfNoneMatch != ""
+}
+
+// AtRiskEvent is the decoded form of an atRiskValue, kept in cache as score:category:mid
+type AtRiskEvent struct {
+	Score     int    `json:"score"`
+	Category  string `json:"category"`
+	MessageID string `json:"messageId"`
+}
+
+// ParseAtRiskEvent decodes a score:category:mid cache value
+func ParseAtRiskEvent(value string) (AtRiskEvent, error) {
+	if value == "" {
+		return AtRiskEvent{}, constants.BlankAtRiskValue
+	}
+
+	split := strings.Split(value, ":")
+	if len(split) != 3 {
+		return AtRiskEvent{}, constants.InvalidValueLength
+	}
+
+	score, err := strconv.Atoi(split[0])
+	if err != nil {
+		return AtRiskEvent{}, constants.InvalidValueScore
+	}
+
+	return AtRiskEvent{Score: score, Category: split[1], MessageID: split[2]}, nil
+}
+
+// Format encodes the event into the score:category:mid cache value
+func (e AtRiskEvent) Format() string {
+	return fmt.Sprintf("%d:%s:%s", e.Score, e.Category, e.MessageID)
+}
+
+type BatchCacheRequest struct {
+	Operations []BatchCacheOperation `json:"operations"`
+}
+
+type BatchCacheOperation struct {
+	Action      string       `json:"action"`
+	AtRiskKey   string       `json:"atRiskKey"`
+	AtRiskValue string       `json:"atRiskValue"`
+	Event       *AtRiskEvent `json:"event"`
+}
+
+// Value returns the cache value of the operation, a structured event is used
+// when atRiskValue is not sent
+func (o BatchCacheOperation) Value() string {
+	if o.AtRiskValue == "" && o.Event != nil {
+		return o.Event.Format()
+	}
+	return o.AtRiskValue
+}
+
+type BatchCacheResult struct {
+	Action    string `json:"action"`
+	AtRiskKey string `json:"atRiskKey"`
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+}
+
+type BatchCacheResponse struct {
+	Results           []BatchCacheResult `json:"results"`
+	TotalAtRiskScores map[string]int     `json:"totalAtRiskScores"`
+}
+
+type AtRiskEventsRequest struct {
+	UserEmail string `json:"userEmail"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Order     string `json:"order"`
+	Cursor    string `json:"cursor"`
+	Limit     int    `json:"limit"`
+}
+
+type AtRiskEventItem struct {
+	AtRiskKey string      `json:"atRiskKey"`
+	Timestamp string      `json:"timestamp"`
+	Event     AtRiskEvent `json:"event"`
+}
+
+type AtRiskEventsResponse struct {
+	Events     []AtRiskEventItem `json:"events"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+// ThresholdAlert is published when the total score of an email crosses a threshold
+// of its domain, Direction is up when the threshold was reached and down when the
+// total fell below it
+type ThresholdAlert struct {
+	UserEmail        string `json:"userEmail"`
+	Domain           string `json:"domain"`
+	AtRiskKey        string `json:"atRiskKey"`
+	Threshold        int    `json:"threshold"`
+	Direction        string `json:"direction"`
+	TotalAtRiskScore int    `json:"totalAtRiskScore"`
+	Scoring          string `json:"scoring"`
+	CreatedAt        int64  `json:"createdAt"`
+}
+
+// AtRiskChange is published on the change feed of the domain of an email whenever a
+// create, delete or ttl extension changed its events, Action is the audit action
+type AtRiskChange struct {
+	Action           string `json:"action"`
+	UserEmail        string `json:"userEmail"`
+	Domain           string `json:"domain"`
+	AtRiskKey        string `json:"atRiskKey"`
+	TotalAtRiskScore int    `json:"totalAtRiskScore"`
+	Version          int64  `json:"version,omitempty"`
+	CreatedAt        int64  `json:"createdAt"`
+}
+
+// AtRiskDrift is an email whose total of cached events differs from the sum of its
+// AtRiskScore rows, Kind tells whether the email is missing from one of the stores
+type AtRiskDrift struct {
+	UserEmail  string `json:"userEmail"`
+	Kind       string `json:"kind"`
+	RedisTotal int    `json:"redisTotal"`
+	MySQLTotal int    `json:"mysqlTotal"`
+}
+
+// AtRiskDriftReport counts the emails compared by a reconciliation and the drifted ones,
+// Examples holds the first drifted emails ordered by email. Unrepaired lists the emails
+// whose repair failed
+type AtRiskDriftReport struct {
+	CheckedAt      int64         `json:"checkedAt"`
+	Emails         int           `json:"emails"`
+	Matching       int           `json:"matching"`
+	Drifted        int           `json:"drifted"`
+	Mismatched     int           `json:"mismatched"`
+	MissingInRedis int           `json:"missingInRedis"`
+	MissingInMySQL int           `json:"missingInMySQL"`
+	Examples       []AtRiskDrift `json:"examples"`
+	Repair         string        `json:"repair,omitempty"`
+	Repaired       int           `json:"repaired"`
+	Unrepaired     []string      `json:"unrepaired,omitempty"`
+	Unparsable     []string      `json:"unparsable,omitempty"`
+}
+
+// AtRiskStreamRequest is read from the query string as browsers cannot send a body
+// with an event stream request, userEmail can be repeated
+type AtRiskStreamRequest struct {
+	UserEmails []string `form:"userEmail"`
+	Domain     string   `form:"domain"`
+}
+
+type AtRiskDomainSummaryRequest struct {
+	Domain string `json:"domain"`
+	Limit  int    `json:"limit"`
+}
+
+type AtRiskStudent struct {
+	UserEmail        string `json:"userEmail"`
+	TotalAtRiskScore int    `json:"totalAtRiskScore"`
+	ActiveEvents     int    `json:"activeEvents"`
+}
+
+// ScoreBand counts the students whose total is at least Min and below Max, the
+// last band has no Max
+type ScoreBand struct {
+	Min      int  `json:"min"`
+	Max      *int `json:"max,omitempty"`
+	Students int  `json:"students"`
+}
+
+type AtRiskDomainSummaryResponse struct {
+	Domain       string          `json:"domain"`
+	Students     int             `json:"students"`
+	ActiveEvents int             `json:"activeEvents"`
+	TopStudents  []AtRiskStudent `json:"topStudents"`
+	ScoreBands   []ScoreBand     `json:"scoreBands"`
+}
+
+type AtRiskAuditRequest struct {
+	UserEmail string `json:"userEmail"`
+	Actor     string `json:"actor"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Cursor    string `json:"cursor"`
+	Limit     int    `json:"limit"`
+}
+
+type AtRiskAuditResponse struct {
+	Entries    []AtRiskAuditEntry `json:"entries"`
+	NextCursor string             `json:"nextCursor,omitempty"`
 }
@@ -0,0 +1,57 @@
+package datatypes
+
+import (
+	"testing"
+	"www-api/internal/constants"
+)
+
+func TestParseAtRiskEvent(t *testing.T) {
+	type tests struct {
+		name      string
+		value     string
+		wantEvent AtRiskEvent
+		wantErr   error
+	}
+
+	testCases := []tests{
+		{
+			name:      "valid case",
+			value:     "45:scan:<1dc13ds5c1651@mail.com>",
+			wantEvent: AtRiskEvent{Score: 45, Category: "scan", MessageID: "<1dc13ds5c1651@mail.com>"},
+			wantErr:   nil,
+		},
+		{
+			name:      "fail case, blank value",
+			value:     "",
+			wantEvent: AtRiskEvent{},
+			wantErr:   constants.BlankAtRiskValue,
+		},
+		{
+			name:      "fail case, missing parts",
+			value:     "45:scan",
+			wantEvent: AtRiskEvent{},
+			wantErr:   constants.InvalidValueLength,
+		},
+		{
+			name:      "fail case, non numeric score",
+			value:     "dede:scan:1dc13ds5c1651",
+			wantEvent: AtRiskEvent{},
+			wantErr:   constants.InvalidValueScore,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			event, err := ParseAtRiskEvent(tc.value)
+			if tc.wantEvent != event {
+				t.Errorf("expected event %+v got %+v", tc.wantEvent, event)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+			if err == nil && event.Format() != tc.value {
+				t.Errorf("expected formatted value %s got %s", tc.value, event.Format())
+			}
+		})
+	}
+}
@@ -1,13 +1,19 @@
 package datatypes
 
+import (
+	"strings"
+	"www-api/internal/constants"
+)
+
 // type StudentInfo struct {
 // 	GivenName  string `db:"givenName"`
 // 	FamilyName string `db:"familyName"`
 // }
 
 type CustomerRequest struct {
-	Email string `json:"email"`
-	Fid   string `json:"fid"`
+	Email string   `json:"email"`
+	Fid   string   `json:"fid"`
+	Fids  []string `json:"fids"`
 }
 
 type Notification struct {
@@ -17,6 +23,40 @@ type Notification struct {
 	Basegen           int    `db:"basegen"`
 }
 
+// NotificationConfigRequest creates, updates or deletes an awareEmailNotification config of
+// Fid, ID and Basegen identify the config and the version of it being updated
+type NotificationConfigRequest struct {
+	ID      int      `json:"id"`
+	Fid     string   `json:"fid"`
+	Emails  []string `json:"emails"`
+	Basegen int      `json:"basegen"`
+}
+
+// NotificationConfig is an awareEmailNotification row with its recipients split into Emails
+type NotificationConfig struct {
+	ID      int      `json:"id"`
+	Fid     string   `json:"fid"`
+	Emails  []string `json:"emails"`
+	Basegen int      `json:"basegen"`
+}
+
+type NotificationConfigsResponse struct {
+	Fid     string               `json:"fid"`
+	Configs []NotificationConfig `json:"configs"`
+}
+
+// NewNotificationConfig splits the recipients of notification
+func NewNotificationConfig(notification Notification) NotificationConfig {
+	emails := []string{}
+	for _, email := range strings.Split(notification.NotificationEmail, constants.NotificationEmailSeparator) {
+		email = strings.TrimSpace(email)
+		if email != "" {
+			emails = append(emails, email)
+		}
+	}
+	return NotificationConfig{ID: notification.ID, Fid: notification.Fid, Emails: emails, Basegen: notification.Basegen}
+}
+
 type FilterType struct {
 	ID                 int    `db:"id"`
 	BlockPageMsg       []byte `db:"block_page_msg"`
@@ -31,7 +71,133 @@ type FilterType struct {
 	AzureGrpImportPref string `db:"azureGrpImportPref"`
 }
 
+// TimezoneResponse is the timezone of a fid at the time of the request, Status is
+// constants.TimezoneUnknown when the fid has no timezone and constants.TimezoneInvalid
+// when Tz is not an IANA zone, only Tz is set then
 type TimezoneResponse struct {
-	Tz     string
-	TzAbbr string
+	Tz             string
+	TzAbbr         string
+	Fid            string              `json:"fid,omitempty"`
+	Status         string              `json:"status"`
+	Offset         string              `json:"offset,omitempty"`
+	OffsetSeconds  int                 `json:"offsetSeconds"`
+	IsDST          bool                `json:"isDst"`
+	LocalTime      string              `json:"localTime,omitempty"`
+	NextTransition *TimezoneTransition `json:"nextTransition,omitempty"`
+}
+
+// TimezoneTransition is the next change of the offset or abbreviation of a timezone
+type TimezoneTransition struct {
+	At            string `json:"at"`
+	TzAbbr        string `json:"tzAbbr"`
+	Offset        string `json:"offset"`
+	OffsetSeconds int    `json:"offsetSeconds"`
+	IsDST         bool   `json:"isDst"`
+}
+
+type TimezonesResponse struct {
+	Timezones []TimezoneResponse `json:"timezones"`
+}
+
+// PrivacyFlags is the ENHANCED_PRIVACY bit vector of a domain, its bits
+// constants.FILTER_PRIVACY to constants.SUPPRESS_BULLY are the privacy modes
+type PrivacyFlags int
+
+// Has reports whether bit is set
+func (f PrivacyFlags) Has(bit int) bool {
+	return bit >= 0 && int(f)&(1<<bit) != 0
+}
+
+// Modes returns the name of every privacy mode that is set, ordered by bit
+func (f PrivacyFlags) Modes() []string {
+	modes := []string{}
+	for bit, mode := range constants.PrivacyModes {
+		if f.Has(bit) {
+			modes = append(modes, mode)
+		}
+	}
+	return modes
+}
+
+// PrivacyEligibility tells whether an Aware domain also gets 24 and Responder, Checked is
+// false when that was not needed. Reasons explain every decision
+type PrivacyEligibility struct {
+	Checked    bool     `json:"checked"`
+	TwentyFour bool     `json:"24"`
+	Responder  bool     `json:"Responder"`
+	Reasons    []string `json:"reasons"`
+}
+
+// PrivacyStatus is the privacy of a domain, a mode is 1 when its bit is set in BitVector
+// or, for 24 and Responder, when the domain is eligible for them
+type PrivacyStatus struct {
+	Filter        int                `json:"Filter"`
+	Aware         int                `json:"Aware"`
+	TwentyFour    int                `json:"24"`
+	Responder     int                `json:"Responder"`
+	SuppressBully int                `json:"suppBully"`
+	BitVector     int                `json:"bitVector"`
+	Modes         []string           `json:"modes"`
+	Eligibility   PrivacyEligibility `json:"eligibility"`
+}
+
+// NewPrivacyStatus decodes every privacy mode of flags
+func NewPrivacyStatus(flags PrivacyFlags) PrivacyStatus {
+	status := PrivacyStatus{BitVector: int(flags), Modes: flags.Modes(), Eligibility: PrivacyEligibility{Reasons: []string{}}}
+	for bit, mode := range map[int]*int{
+		constants.FILTER_PRIVACY:      &status.Filter,
+		constants.AWARE_PRIVACY:       &status.Aware,
+		constants.RESPONDER_PRIVACY:   &status.Responder,
+		constants.TWENTY_FOUR_PRIVACY: &status.TwentyFour,
+		constants.SUPPRESS_BULLY:      &status.SuppressBully,
+	} {
+		if flags.Has(bit) {
+			*mode = 1
+		}
+	}
+	return status
+}
+
+// PrivacyFlagsRequest writes the privacy flags of a fid and its domain, a flag is a privacy
+// mode of the domain or a bit of the fid named after its key, like PN:9 or PF:3:3. A PUT sets
+// Flags and clears every other flag, a PATCH sets Set and clears Clear
+type PrivacyFlagsRequest struct {
+	Fid   string   `json:"fid"`
+	Flags []string `json:"flags"`
+	Set   []string `json:"set"`
+	Clear []string `json:"clear"`
+}
+
+// PrivacyFlagsResponse lists the flags a write changed and the privacy status after it,
+// Violations are the combinations a rejected write would have broken
+type PrivacyFlagsResponse struct {
+	Fid        string        `json:"fid"`
+	Changed    []string      `json:"changed"`
+	AuditID    string        `json:"auditId,omitempty"`
+	Violations []string      `json:"violations,omitempty"`
+	Status     PrivacyStatus `json:"status"`
+}
+
+// LookupCacheRequest invalidates the cached lookups of a fid, every entity when Entities is empty
+type LookupCacheRequest struct {
+	Fid      string   `json:"fid"`
+	Entities []string `json:"entities"`
+}
+
+type LookupCacheInvalidation struct {
+	Fid         string   `json:"fid"`
+	Invalidated []string `json:"invalidated"`
+}
+
+// LookupStats counts the reads of the cached lookups of an entity since the process
+// started, NegativeHits are reads of lookups cached as not found and Errors failed reads
+// or writes of redis that fell back to the database
+type LookupStats struct {
+	Entity             string `json:"entity"`
+	TTLSeconds         int    `json:"ttlSeconds"`
+	NegativeTTLSeconds int    `json:"negativeTtlSeconds"`
+	Hits               int64  `json:"hits"`
+	NegativeHits       int64  `json:"negativeHits"`
+	Misses             int64  `json:"misses"`
+	Errors             int64  `json:"errors"`
 }
@@ -0,0 +1,81 @@
+package datatypes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewPrivacyStatus(t *testing.T) {
+	type tests struct {
+		name       string
+		flags      PrivacyFlags
+		wantStatus PrivacyStatus
+	}
+
+	testCases := []tests{
+		{
+			name:       "valid case, no privacy",
+			flags:      0,
+			wantStatus: PrivacyStatus{Modes: []string{}, Eligibility: PrivacyEligibility{Reasons: []string{}}},
+		},
+		{
+			name:       "valid case, filter and suppress bully",
+			flags:      17,
+			wantStatus: PrivacyStatus{Filter: 1, SuppressBully: 1, BitVector: 17, Modes: []string{"Filter", "suppBully"}, Eligibility: PrivacyEligibility{Reasons: []string{}}},
+		},
+		{
+			name:       "valid case, every mode",
+			flags:      31,
+			wantStatus: PrivacyStatus{Filter: 1, Aware: 1, TwentyFour: 1, Responder: 1, SuppressBully: 1, BitVector: 31, Modes: []string{"Filter", "Aware", "Responder", "24", "suppBully"}, Eligibility: PrivacyEligibility{Reasons: []string{}}},
+		},
+		{
+			name:       "valid case, unknown bits are only kept in the bit vector",
+			flags:      34,
+			wantStatus: PrivacyStatus{Aware: 1, BitVector: 34, Modes: []string{"Aware"}, Eligibility: PrivacyEligibility{Reasons: []string{}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := NewPrivacyStatus(tc.flags)
+			if !reflect.DeepEqual(tc.wantStatus, status) {
+				t.Errorf("expected status %+v got %+v", tc.wantStatus, status)
+			}
+		})
+	}
+}
+
+func TestNewNotificationConfig(t *testing.T) {
+	type tests struct {
+		name         string
+		notification Notification
+		want         NotificationConfig
+	}
+
+	testCases := []tests{
+		{
+			name:         "valid case, single recipient",
+			notification: Notification{ID: 1, Fid: "fid", NotificationEmail: "a@securly.com", Basegen: 345},
+			want:         NotificationConfig{ID: 1, Fid: "fid", Emails: []string{"a@securly.com"}, Basegen: 345},
+		},
+		{
+			name:         "valid case, many recipients",
+			notification: Notification{ID: 2, Fid: "fid", NotificationEmail: "a@securly.com, b@securly.com,,", Basegen: 1},
+			want:         NotificationConfig{ID: 2, Fid: "fid", Emails: []string{"a@securly.com", "b@securly.com"}, Basegen: 1},
+		},
+		{
+			name:         "valid case, no recipient",
+			notification: Notification{ID: 3, Fid: "fid"},
+			want:         NotificationConfig{ID: 3, Fid: "fid", Emails: []string{}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := NewNotificationConfig(tc.notification)
+			if !reflect.DeepEqual(tc.want, config) {
+				t.Errorf("expected config %v got %v", tc.want, config)
+			}
+		})
+	}
+}
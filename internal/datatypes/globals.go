@@ -15,6 +15,17 @@ type ConnectionString struct {
 	AtRiskWriteRedis string
 	WWWReadRedis     string
 	WWWWriteRedis    string
+	Elastic          string
+}
+
+// IdempotentResponse is the response kept for an Idempotency-Key, Status is 0 while the
+// first request with the key is running
+type IdempotentResponse struct {
+	RequestHash string `json:"requestHash"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	ETag        string `json:"etag,omitempty"`
+	Body        string `json:"body"`
 }
 
 type Connections struct {
@@ -0,0 +1,81 @@
+package datatypes
+
+type StudentErasureRequest struct {
+	UserEmail string `json:"userEmail"`
+	DryRun    bool   `json:"dryRun"`
+}
+
+// AtRiskErasure lists the at-risk data of a student that was erased, or that a dry run
+// would erase
+type AtRiskErasure struct {
+	RedisKeys       []string `json:"redisKeys"`
+	AtRiskScoreRows int64    `json:"atRiskScoreRows"`
+	LedgerRows      int64    `json:"ledgerRows"`
+	ArchiveObjects  []string `json:"archiveObjects"`
+	AuditRows       int64    `json:"auditRows"`
+	AlertEntries    int64    `json:"alertEntries"`
+}
+
+// ErasureReceipt is returned for an erasure of a student, Signature is "sha256=" followed
+// by the hex HMAC-SHA256 of "erasedAt.receipt" where receipt is the json of the receipt
+// without its signature
+type ErasureReceipt struct {
+	ReceiptID        string        `json:"receiptId"`
+	UserEmail        string        `json:"userEmail"`
+	Actor            string        `json:"actor"`
+	DryRun           bool          `json:"dryRun"`
+	ErasedAt         int64         `json:"erasedAt"`
+	AtRisk           AtRiskErasure `json:"atRisk"`
+	ElasticIndices   []string      `json:"elasticIndices"`
+	ElasticDocuments int64         `json:"elasticDocuments"`
+	ExportObjects    []string      `json:"exportObjects"`
+	Signature        string        `json:"signature,omitempty"`
+}
+
+type StudentExportRequest struct {
+	UserEmail string `json:"userEmail"`
+	Fid       string `json:"fid"`
+	Format    string `json:"format"`
+	Upload    bool   `json:"upload"`
+}
+
+// AtRiskExport is the at-risk data kept about a student, its cached events and the self
+// harm scores of its AtRiskScore rows
+type AtRiskExport struct {
+	Events []AtRiskEventItem `json:"events"`
+	Scores []string          `json:"scores"`
+}
+
+// CustomerSettings are the settings of the customer of a student, Timezone and
+// AwareNotification are missing when the customer has none
+type CustomerSettings struct {
+	Fid               string            `json:"fid"`
+	PrivacyStatus     PrivacyStatus     `json:"privacyStatus"`
+	Timezone          *TimezoneResponse `json:"timezone"`
+	AwareNotification *Notification     `json:"awareNotification"`
+	FilterType        string            `json:"filterType"`
+}
+
+// StudentExport bundles everything kept about a student, Student is missing when the
+// student is not found in usermap/azureUsers and Customer when no fid was requested
+type StudentExport struct {
+	ExportID   string            `json:"exportId"`
+	UserEmail  string            `json:"userEmail"`
+	ExportedAt int64             `json:"exportedAt"`
+	Student    *StudentInfo      `json:"student"`
+	AtRisk     AtRiskExport      `json:"atRisk"`
+	Customer   *CustomerSettings `json:"customer"`
+}
+
+// StudentExportReference describes an export bundle, Bucket, Key and Location are only
+// set when the bundle was uploaded and Location is where it can be downloaded from
+type StudentExportReference struct {
+	ExportID    string `json:"exportId"`
+	UserEmail   string `json:"userEmail"`
+	Format      string `json:"format"`
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+	Bucket      string `json:"bucket,omitempty"`
+	Key         string `json:"key,omitempty"`
+	Location    string `json:"location,omitempty"`
+}
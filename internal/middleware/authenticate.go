@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"www-api/internal/constants"
 	"www-api/internal/sso"
 
 	"github.com/gin-gonic/gin"
@@ -44,7 +45,7 @@ func Authenticate(c *gin.Context) {
 	}
 
 	//verify token based on deployment type
-	isValid, err := sso.VerifyToken(auth[1], environement.(string))
+	subject, isValid, err := sso.VerifyToken(auth[1], environement.(string))
 	if err != nil {
 		fmt.Println(err)
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
@@ -61,6 +62,8 @@ func Authenticate(c *gin.Context) {
 		return
 	}
 
+	//set token subject in gin context, handlers record it as the actor of a change
+	c.Set(constants.TokenSubjectKey, subject)
 	c.Next()
 
 }
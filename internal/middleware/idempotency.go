@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseRecorder keeps a copy of the response body written by the handlers
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w responseRecorder) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// Idempotency replays the response of the first request sent with an Idempotency-Key header
+// for every repeat with the same key and body within window, a repeat with a different body
+// gets 409. Keys are scoped to the token subject, requests without the header are not
+// affected and server errors or panics are not kept so that a retry runs again
+func Idempotency(redis cache.RedisOps, window time.Duration, log logger.ZapLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(constants.IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			c.Next()
+			return
+		}
+
+		if len(idempotencyKey) > constants.MaxIdempotencyKeyLength {
+			log.Error("invalid idempotency key received", map[string]interface{}{"idempotencyKey": idempotencyKey})
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": constants.InvalidIdempotencyKey.Error()})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			log.Error("error reading request body", map[string]interface{}{"error": err})
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := fmt.Sprintf(constants.IdempotencyKey, c.GetString(constants.TokenSubjectKey), idempotencyKey)
+		hash := requestHash(c.Request.Method, c.FullPath(), body)
+		pending, _ := json.Marshal(datatypes.IdempotentResponse{RequestHash: hash})
+		pendingSeconds := constants.IdempotencyPendingSeconds
+		if int(window.Seconds()) < pendingSeconds {
+			pendingSeconds = int(window.Seconds())
+		}
+		reply, err := redis.RunScript(cache.ClaimIdempotencyKey, []string{key}, string(pending), pendingSeconds)
+		if err != nil {
+			log.Error("unable to claim idempotency key", map[string]interface{}{"key": key, "error": err})
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+			return
+		}
+
+		if reply != nil {
+			replay(c, reply, hash, log)
+			return
+		}
+
+		//the claim is released unless the response was stored, also when a handler panics
+		kept := false
+		defer func() {
+			if kept {
+				return
+			}
+			err := redis.Delete(key)
+			if err != nil {
+				log.Error("unable to release idempotency key", map[string]interface{}{"key": key, "error": err})
+			}
+		}()
+
+		recorder := responseRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = recorder
+		c.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		stored, _ := json.Marshal(datatypes.IdempotentResponse{
+			RequestHash: hash,
+			Status:      recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			ETag:        recorder.Header().Get(constants.ETagHeader),
+			Body:        recorder.body.String(),
+		})
+		err = redis.SetWithTTL(key, string(stored), window)
+		if err != nil {
+			log.Error("unable to store response of idempotency key", map[string]interface{}{"key": key, "error": err})
+			return
+		}
+		kept = true
+	}
+}
+
+// replay writes the response stored for an idempotency key, or 409 when the key belongs
+// to a different request or its first request is still running
+func replay(c *gin.Context, reply interface{}, hash string, log logger.ZapLogger) {
+	var stored datatypes.IdempotentResponse
+	value, _ := reply.(string)
+	err := json.Unmarshal([]byte(value), &stored)
+	if err != nil {
+		log.Error("invalid response stored for idempotency key", map[string]interface{}{"reply": reply, "error": err})
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		return
+	}
+
+	if stored.RequestHash != hash {
+		log.Error("idempotency key reused with a different request", map[string]interface{}{"idempotencyKey": c.GetHeader(constants.IdempotencyKeyHeader)})
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": constants.IdempotencyKeyReused.Error()})
+		return
+	}
+
+	if stored.Status == 0 {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": constants.IdempotencyKeyInProgress.Error()})
+		return
+	}
+
+	log.Info("replaying response of idempotency key", map[string]interface{}{"idempotencyKey": c.GetHeader(constants.IdempotencyKeyHeader), "status": stored.Status})
+	c.Header(constants.IdempotentReplayedHeader, "true")
+	if stored.ETag != "" {
+		c.Header(constants.ETagHeader, stored.ETag)
+	}
+	c.Data(stored.Status, stored.ContentType, []byte(stored.Body))
+	c.Abort()
+}
+
+// requestHash identifies a request by its method, route and body
+func requestHash(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestIdempotency(t *testing.T) {
+	type tests struct {
+		name             string
+		idempotencyKey   string
+		body             string
+		status           int
+		panics           bool
+		redisClient      func() *mocks.RedisOps
+		wantCalls        int
+		expectedStatus   int
+		expectedResponse string
+		expectedReplayed string
+		expectedETag     string
+	}
+
+	body := `{"atRiskKey":"some_key@securly.com:16546548465","atRiskValue":"35:docs:4854184194"}`
+	hash := requestHash("POST", "/cache/create", []byte(body))
+	key := "atrisk:idempotency:subject:retry-1"
+	stored := func(response datatypes.IdempotentResponse) string {
+		value, _ := json.Marshal(response)
+		return string(value)
+	}
+	testCases := []tests{
+		{
+			name:           "valid case, no idempotency key",
+			idempotencyKey: "",
+			body:           body,
+			status:         http.StatusOK,
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			wantCalls:        1,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"totalAtRiskScore\":35}",
+			expectedETag:     "\"3\"",
+		},
+		{
+			name:           "valid case, first request is stored",
+			idempotencyKey: "retry-1",
+			body:           body,
+			status:         http.StatusOK,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ClaimIdempotencyKey, []string{key}, stored(datatypes.IdempotentResponse{RequestHash: hash}), 60).Return(nil, nil).Once()
+				moc.On("SetWithTTL", key, stored(datatypes.IdempotentResponse{RequestHash: hash, Status: 200, ContentType: "application/json; charset=utf-8", ETag: "\"3\"", Body: "{\"totalAtRiskScore\":35}"}), time.Hour).Return(nil).Once()
+				return moc
+			},
+			wantCalls:        1,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"totalAtRiskScore\":35}",
+			expectedETag:     "\"3\"",
+		},
+		{
+			name:           "valid case, claim is released when the response cannot be stored",
+			idempotencyKey: "retry-1",
+			body:           body,
+			status:         http.StatusOK,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ClaimIdempotencyKey, []string{key}, mock.Anything, 60).Return(nil, nil).Once()
+				moc.On("SetWithTTL", key, mock.Anything, time.Hour).Return(test.CacheSetErr).Once()
+				moc.On("Delete", key).Return(nil).Once()
+				return moc
+			},
+			wantCalls:        1,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"totalAtRiskScore\":35}",
+			expectedETag:     "\"3\"",
+		},
+		{
+			name:           "valid case, claim is released when the handler panics",
+			idempotencyKey: "retry-1",
+			body:           body,
+			panics:         true,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ClaimIdempotencyKey, []string{key}, mock.Anything, 60).Return(nil, nil).Once()
+				moc.On("Delete", key).Return(nil).Once()
+				return moc
+			},
+			wantCalls:        1,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "",
+		},
+		{
+			name:           "valid case, repeat is replayed",
+			idempotencyKey: "retry-1",
+			body:           body,
+			status:         http.StatusOK,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ClaimIdempotencyKey, []string{key}, mock.Anything, 60).Return(stored(datatypes.IdempotentResponse{RequestHash: hash, Status: 200, ContentType: "application/json; charset=utf-8", ETag: "\"3\"", Body: "{\"totalAtRiskScore\":35}"}), nil).Once()
+				return moc
+			},
+			wantCalls:        0,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "{\"totalAtRiskScore\":35}",
+			expectedReplayed: "true",
+			expectedETag:     "\"3\"",
+		},
+		{
+			name:           "fail case, repeat with a different body",
+			idempotencyKey: "retry-1",
+			body:           `{"atRiskKey":"some_key@securly.com:16546548465","atRiskValue":"90:docs:4854184194"}`,
+			status:         http.StatusOK,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ClaimIdempotencyKey, []string{key}, mock.Anything, 60).Return(stored(datatypes.IdempotentResponse{RequestHash: hash, Status: 200, Body: "{}"}), nil).Once()
+				return moc
+			},
+			wantCalls:        0,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "{\"message\":\"Idempotency-Key was already used with a different request\"}",
+		},
+		{
+			name:           "fail case, first request in progress",
+			idempotencyKey: "retry-1",
+			body:           body,
+			status:         http.StatusOK,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ClaimIdempotencyKey, []string{key}, mock.Anything, 60).Return(stored(datatypes.IdempotentResponse{RequestHash: hash}), nil).Once()
+				return moc
+			},
+			wantCalls:        0,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: "{\"message\":\"a request with the same Idempotency-Key is in progress\"}",
+		},
+		{
+			name:           "valid case, server error is not stored",
+			idempotencyKey: "retry-1",
+			body:           body,
+			status:         http.StatusInternalServerError,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ClaimIdempotencyKey, []string{key}, mock.Anything, 60).Return(nil, nil).Once()
+				moc.On("Delete", key).Return(nil).Once()
+				return moc
+			},
+			wantCalls:        1,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"totalAtRiskScore\":35}",
+		},
+		{
+			name:           "fail case, idempotency key too long",
+			idempotencyKey: strings.Repeat("k", 256),
+			body:           body,
+			status:         http.StatusOK,
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			wantCalls:        0,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: "{\"message\":\"invalid Idempotency-Key header, should be at most 255 characters\"}",
+		},
+		{
+			name:           "fail case, error claiming idempotency key",
+			idempotencyKey: "retry-1",
+			body:           body,
+			status:         http.StatusOK,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ClaimIdempotencyKey, []string{key}, mock.Anything, 60).Return(nil, test.CacheSetErr).Once()
+				return moc
+			},
+			wantCalls:        0,
+			expectedStatus:   http.StatusInternalServerError,
+			expectedResponse: "{\"message\":\"internal server error\"}",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			router := gin.New()
+			router.Use(gin.Recovery())
+			router.Use(func(c *gin.Context) { c.Set(constants.TokenSubjectKey, "subject") })
+			router.POST("/cache/create", Idempotency(tc.redisClient(), time.Hour, logger.ZapLogger{Logger: zap.NewExample()}), func(c *gin.Context) {
+				var request datatypes.CacheRequest
+				err := c.BindJSON(&request)
+				assert.NoError(t, err)
+				calls++
+				if tc.panics {
+					panic("handler failed")
+				}
+				if tc.status < http.StatusInternalServerError {
+					c.Header(constants.ETagHeader, "\"3\"")
+				}
+				c.JSON(tc.status, gin.H{"totalAtRiskScore": 35})
+			})
+
+			req, err := http.NewRequest("POST", "/cache/create", bytes.NewBufferString(tc.body))
+			assert.NoError(t, err)
+			if tc.idempotencyKey != "" {
+				req.Header.Set(constants.IdempotencyKeyHeader, tc.idempotencyKey)
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tc.wantCalls, calls)
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedResponse, recorder.Body.String())
+			assert.Equal(t, tc.expectedReplayed, recorder.Header().Get(constants.IdempotentReplayedHeader))
+			assert.Equal(t, tc.expectedETag, recorder.Header().Get(constants.ETagHeader))
+		})
+	}
+}
@@ -2,7 +2,9 @@ package server
 
 import (
 	lo "log"
+	"www-api/config"
 	"www-api/internal/constants"
+	"www-api/internal/datatypes"
 	"www-api/internal/logger"
 
 	"github.com/elastic/go-elasticsearch/v8"
@@ -10,6 +12,45 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// NewConnections opens every database, redis and elastic connection listed in config
+func NewConnections(config config.Config, log logger.ZapLogger) *datatypes.Connections {
+	connectionStrings := getConnectionString(config)
+
+	at_risk_read_redis := redisConnection(connectionStrings.AtRiskReadRedis, log)
+	at_risk_write_redis := redisConnection(connectionStrings.AtRiskWriteRedis, log)
+
+	www_read_redis := redisConnection(connectionStrings.WWWReadRedis, log)
+	www_write_redis := redisConnection(connectionStrings.WWWWriteRedis, log)
+
+	at_risk_read_db := databaseConnection(connectionStrings.AtRiskReadDB, log)
+	at_risk_write_db := databaseConnection(connectionStrings.AtRiskWriteDB, log)
+
+	schools_read_db := databaseConnection(connectionStrings.SchoolsReadDB, log)
+	schools_write_db := databaseConnection(connectionStrings.SchoolsWriteDB, log)
+
+	//elastic is optional, the features using it are disabled without a host
+	elastic := map[string]*elasticsearch.Client{}
+	if connectionStrings.Elastic != "" {
+		elastic[constants.ElasticKey] = elasticConnection(connectionStrings.Elastic, config.Elastic.Username, config.Elastic.Password, log)
+	}
+
+	return &datatypes.Connections{
+		DB: map[string]*sqlx.DB{
+			constants.AtRiskReadDBKey:   at_risk_read_db,
+			constants.AtRiskWriteDBKey:  at_risk_write_db,
+			constants.SchoolsReadDBKey:  schools_read_db,
+			constants.SchoolsWriteDBKey: schools_write_db,
+		},
+		Redis: map[string]*redis.Client{
+			constants.AtRiskReadRedisKey:  at_risk_read_redis,
+			constants.AtRiskWriteRedisKey: at_risk_write_redis,
+			constants.WWWReadRedisKey:     www_read_redis,
+			constants.WWWWriteRedisKey:    www_write_redis,
+		},
+		Elastic: elastic,
+	}
+}
+
 // databaseConnection takes a db connection string and returns a db instance
 func databaseConnection(connectionString string, log logger.ZapLogger) *sqlx.DB {
 	conn, err := sqlx.Connect("mysql", connectionString)
@@ -50,8 +91,8 @@ func elasticConnection(url, username, password string, log logger.ZapLogger) *el
 		Addresses: []string{
 			url,
 		},
-		Username: "foo",
-		Password: "bar",
+		Username: username,
+		Password: password,
 	}
 
 	es, err := elasticsearch.NewClient(cfg)
@@ -1,51 +1,27 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 	atRisk "www-api/api/at-risk"
 	"www-api/api/customer"
+	"www-api/api/privacy"
 	info "www-api/api/student"
 	"www-api/config"
 	"www-api/internal/constants"
 	"www-api/internal/datatypes"
 	"www-api/internal/logger"
+	"www-api/internal/middleware"
+	"www-api/pkg/cache"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jmoiron/sqlx"
-	"github.com/redis/go-redis/v9"
 )
 
 // implement different api routes
 func AddRoutes(router *gin.Engine, config config.Config, log logger.ZapLogger) {
-	connectionStrings := getConnectionString(config)
-
-	at_risk_read_redis := redisConnection(connectionStrings.AtRiskReadRedis, log)
-	at_risk_write_redis := redisConnection(connectionStrings.AtRiskWriteRedis, log)
-
-	www_read_redis := redisConnection(connectionStrings.WWWReadRedis, log)
-	www_write_redis := redisConnection(connectionStrings.WWWWriteRedis, log)
-
-	at_risk_read_db := databaseConnection(connectionStrings.AtRiskReadDB, log)
-	at_risk_write_db := databaseConnection(connectionStrings.AtRiskWriteDB, log)
-
-	schools_read_db := databaseConnection(connectionStrings.SchoolsReadDB, log)
-	schools_write_db := databaseConnection(connectionStrings.SchoolsWriteDB, log)
-
-	connections := &datatypes.Connections{
-		DB: map[string]*sqlx.DB{
-			constants.AtRiskReadDBKey:   at_risk_read_db,
-			constants.AtRiskWriteDBKey:  at_risk_write_db,
-			constants.SchoolsReadDBKey:  schools_read_db,
-			constants.SchoolsWriteDBKey: schools_write_db,
-		},
-		Redis: map[string]*redis.Client{
-			constants.AtRiskReadRedisKey:  at_risk_read_redis,
-			constants.AtRiskWriteRedisKey: at_risk_write_redis,
-			constants.WWWReadRedisKey:     www_read_redis,
-			constants.WWWWriteRedisKey:    www_write_redis,
-		},
-		Elastic: nil,
-	}
+	connections := NewConnections(config, log)
 
 	//create instance of NewRiskAPI
 	risk := atRisk.NewRiskAPI(config, log, connections)
@@ -53,6 +29,10 @@ func AddRoutes(router *gin.Engine, config config.Config, log logger.ZapLogger) {
 	student := info.NewInfoAPI(config, log, connections)
 	//create instance of CustomerAPI
 	cust := customer.NewCustomerAPI(config, log, connections)
+	//create instance of PrivacyAPI
+	priv := privacy.NewPrivacyAPI(config, log, connections)
+	//replays responses of at-risk writes repeated with the same Idempotency-Key
+	idempotency := newIdempotency(config, connections, log)
 
 	//create main router group
 	api := router.Group("/api")
@@ -60,20 +40,44 @@ func AddRoutes(router *gin.Engine, config config.Config, log logger.ZapLogger) {
 		//create router sub group & attach hanlder functions
 		atRisk := api.Group("/atRisk")
 		{
-			atRisk.POST("/cache/create", risk.CreateCache)
-			atRisk.DELETE("/cache/delete", risk.DeleteCache)
+			atRisk.POST("/cache/create", idempotency, risk.CreateCache)
+			atRisk.DELETE("/cache/delete", idempotency, risk.DeleteCache)
+			atRisk.POST("/cache/batch", risk.BatchCache)
 			atRisk.POST("/extend-ttl", risk.ExtendTTL)
+			atRisk.GET("/ttl", risk.TTL)
 			atRisk.GET("/score", risk.Score)
 			atRisk.GET("/event-score-details", risk.EventScore)
+			atRisk.GET("/events", risk.Events)
+			atRisk.GET("/event-by-mid", risk.EventByMid)
+			atRisk.GET("/domain/summary", risk.DomainSummary)
+			atRisk.GET("/audit", risk.Audit)
+			atRisk.POST("/restore", risk.Restore)
+			atRisk.GET("/stream", risk.Stream)
 		}
 
 		//create router sub group & attach hanlder functions
 		customer := api.Group("/customer")
 		{
 			customer.GET("/privacy/status", cust.PrivacyStatus)
+			customer.PUT("/privacy", cust.ReplacePrivacyFlags)
+			customer.PATCH("/privacy", cust.UpdatePrivacyFlags)
 			customer.GET("/timezone", cust.Timezone)
+			customer.GET("/timezones", cust.Timezones)
 			customer.GET("/notification/config/aware", cust.Notification)
+			customer.GET("/notification/configs/aware", cust.Notifications)
+			customer.POST("/notification/config/aware", cust.CreateNotification)
+			customer.PUT("/notification/config/aware", cust.UpdateNotification)
+			customer.DELETE("/notification/config/aware", cust.DeleteNotification)
 			customer.GET("/filter-type", cust.FilterType)
+			customer.DELETE("/cache", cust.InvalidateCache)
+			customer.GET("/cache/stats", cust.CacheStats)
+		}
+
+		//create router sub group & attach hanlder functions
+		privacy := api.Group("/privacy")
+		{
+			privacy.DELETE("/student", priv.EraseStudent)
+			privacy.GET("/student/export", priv.ExportStudent)
 		}
 
 		api.GET("/user", student.GetInfo)
@@ -81,6 +85,17 @@ func AddRoutes(router *gin.Engine, config config.Config, log logger.ZapLogger) {
 	}
 }
 
+// newIdempotency returns the idempotency middleware of the at-risk writes, responses are kept
+// in the at-risk redis for the configured window
+func newIdempotency(config config.Config, connections *datatypes.Connections, log logger.ZapLogger) gin.HandlerFunc {
+	window := config.Idempotency.WindowSeconds
+	if window <= 0 {
+		window = constants.DefaultIdempotencyWindowSeconds
+	}
+	redis := cache.NewRedis(connections.Redis[constants.AtRiskReadRedisKey], connections.Redis[constants.AtRiskWriteRedisKey], log, context.Background())
+	return middleware.Idempotency(redis, time.Duration(window)*time.Second, log)
+}
+
 func getConnectionString(config config.Config) datatypes.ConnectionString {
 	riskReadDB := config.Mysql[constants.AtRiskDBKey].Read
 	riskWriteDB := config.Mysql[constants.AtRiskDBKey].Write
@@ -90,6 +105,16 @@ func getConnectionString(config config.Config) datatypes.ConnectionString {
 	atRiskWriteRedis := config.Redis[constants.AtRiskRedisKey].Write
 	wwwReadRedis := config.Redis[constants.WWWRedisKey].Read
 	wwwWriteRedis := config.Redis[constants.WWWRedisKey].Write
+	elastic := ""
+	if config.Elastic.Host != "" {
+		elastic = config.Elastic.Host
+		if !strings.Contains(elastic, "://") {
+			elastic = "http://" + elastic
+		}
+		if config.Elastic.Port != "" {
+			elastic = fmt.Sprintf(constants.ElasticConnectionString, elastic, config.Elastic.Port)
+		}
+	}
 
 	return datatypes.ConnectionString{
 		AtRiskReadDB:     fmt.Sprintf(constants.DBConnectionString, riskReadDB.User, riskReadDB.Password, riskReadDB.Host, riskReadDB.Port, riskReadDB.DBName),
@@ -100,5 +125,6 @@ func getConnectionString(config config.Config) datatypes.ConnectionString {
 		AtRiskWriteRedis: fmt.Sprintf(constants.RedisConnectionString, atRiskWriteRedis.Host, atRiskWriteRedis.Port),
 		WWWReadRedis:     fmt.Sprintf(constants.RedisConnectionString, wwwReadRedis.Host, wwwReadRedis.Port),
 		WWWWriteRedis:    fmt.Sprintf(constants.RedisConnectionString, wwwWriteRedis.Host, wwwWriteRedis.Port),
+		Elastic:          elastic,
 	}
 }
@@ -12,14 +12,14 @@ var claims struct {
 	Role string
 }
 
-// VerifyToken validates and verify the raw token based on deployment
-func VerifyToken(rawToken string, environement string) (bool, error) {
+// VerifyToken validates and verify the raw token based on deployment and returns its subject
+func VerifyToken(rawToken string, environement string) (string, bool, error) {
 	var err error
 	// by default creates a provider of prod type
 	provider, err := oidc.NewProvider(context.Background(), constants.ProdAuthUrl)
 	if err != nil {
 		log.Fatalf("Could not setup oidc connect verification with prod: %v\n", err)
-		return false, err
+		return "", false, err
 	}
 
 	//from provider create a verifier
@@ -31,39 +31,40 @@ func VerifyToken(rawToken string, environement string) (bool, error) {
 		provider, err = oidc.NewProvider(context.Background(), constants.DevAuthUrl)
 		if err != nil {
 			log.Fatalf("Could not setup oidc connect verification with rtqa: %v\n", err)
-			return false, err
+			return "", false, err
 		}
 		//overwrite verifyer too
 		verifier = provider.Verifier(&oidc.Config{SkipClientIDCheck: true})
 	}
 
 	//call openid_connect_verification to verify token
-	if openid_connect_verification(verifier, rawToken) {
-		return true, nil
+	subject, ok := openid_connect_verification(verifier, rawToken)
+	if ok {
+		return subject, true, nil
 	}
 
-	return false, nil
+	return "", false, nil
 }
 
-// openid_connect_verification takes a verifyer and token to verify
-func openid_connect_verification(verifier *oidc.IDTokenVerifier, token string) bool {
+// openid_connect_verification takes a verifyer and token to verify, returns the subject of a verified token
+func openid_connect_verification(verifier *oidc.IDTokenVerifier, token string) (string, bool) {
 	//parse and verify Token payload.
 	idToken, err := verifier.Verify(context.Background(), token)
 	if err != nil {
 		log.Printf("Authentication Error: Token failed verification: %v '%s'", err, token)
-		return false
+		return "", false
 	}
 
 	//fetch claims and check if role is backend
 	if err := idToken.Claims(&claims); err != nil {
 		log.Printf("Authentication Error: Could not parse token claims")
-		return false
+		return "", false
 	}
 
 	if claims.Role == "Backend" {
-		return true
+		return idToken.Subject, true
 	}
 
 	log.Printf("Authentication Error: Invalid token, requires Role = 'Backend'")
-	return false
+	return "", false
 }
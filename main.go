@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"www-api/config"
+	"www-api/internal/commands"
 	"www-api/internal/logger"
 	"www-api/internal/server"
 )
@@ -52,6 +53,16 @@ func main() {
 			"error": err,
 		})
 	}
+
+	//run an administrative command instead of the server when one follows the flags
+	if flag.NArg() > 0 {
+		err = commands.Run(flag.Arg(0), flag.Args()[1:], conf, logger)
+		if err != nil {
+			logger.Fatal("command failed", map[string]interface{}{"command": flag.Arg(0), "error": err})
+		}
+		return
+	}
+
 	r := server.GetRouter(conf, logger)
 	server := &http.Server{Addr: ":" + conf.Server.Port, Handler: r}
 
@@ -0,0 +1,8 @@
+package alert
+
+import "www-api/internal/datatypes"
+
+// Sink delivers threshold alerts of at-risk totals to their consumers
+type Sink interface {
+	Publish(alert datatypes.ThresholdAlert) error
+}
@@ -0,0 +1,49 @@
+package alert
+
+import (
+	"encoding/json"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache"
+)
+
+// Stream appends alerts to a redis stream, each entry holds the alert json in
+// the "alert" field next to the email and domain for consumers filtering entries
+type Stream struct {
+	redis  cache.RedisOps
+	name   string
+	maxLen int64
+	log    logger.ZapLogger
+}
+
+// NewStream returns an instance of Stream, the stream is trimmed to about maxLen entries
+func NewStream(redis cache.RedisOps, name string, maxLen int64, log logger.ZapLogger) Stream {
+	return Stream{
+		redis:  redis,
+		name:   name,
+		maxLen: maxLen,
+		log:    log,
+	}
+}
+
+// Publish adds an alert to the stream
+func (s Stream) Publish(alert datatypes.ThresholdAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		s.log.Error("unable to marshal alert", map[string]interface{}{"alert": alert, "error": err})
+		return err
+	}
+
+	id, err := s.redis.StreamAdd(s.name, s.maxLen, map[string]interface{}{
+		"userEmail": alert.UserEmail,
+		"domain":    alert.Domain,
+		"alert":     string(body),
+	})
+	if err != nil {
+		s.log.Error("unable to add alert to stream", map[string]interface{}{"stream": s.name, "email": alert.UserEmail, "error": err})
+		return err
+	}
+
+	s.log.Info("alert added to stream", map[string]interface{}{"stream": s.name, "id": id, "email": alert.UserEmail})
+	return nil
+}
@@ -0,0 +1,59 @@
+package alert
+
+import (
+	"testing"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"go.uber.org/zap"
+)
+
+func TestStreamPublish(t *testing.T) {
+	type tests struct {
+		name        string
+		redisClient func() *mocks.RedisOps
+		wantErr     error
+	}
+
+	alert := datatypes.ThresholdAlert{UserEmail: "user@securly.com", Domain: "securly.com", Threshold: 50, Direction: "up", TotalAtRiskScore: 55}
+	testCases := []tests{
+		{
+			name: "valid case",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("StreamAdd", "atrisk:alerts", int64(100), map[string]interface{}{
+					"userEmail": "user@securly.com",
+					"domain":    "securly.com",
+					"alert":     `{"userEmail":"user@securly.com","domain":"securly.com","atRiskKey":"","threshold":50,"direction":"up","totalAtRiskScore":55,"scoring":"","createdAt":0}`,
+				}).Return("1684231487000-0", nil).Once()
+				return moc
+			},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, error adding to stream",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("StreamAdd", "atrisk:alerts", int64(100), map[string]interface{}{
+					"userEmail": "user@securly.com",
+					"domain":    "securly.com",
+					"alert":     `{"userEmail":"user@securly.com","domain":"securly.com","atRiskKey":"","threshold":50,"direction":"up","totalAtRiskScore":55,"scoring":"","createdAt":0}`,
+				}).Return("", test.CacheSetErr).Once()
+				return moc
+			},
+			wantErr: test.CacheSetErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stream := NewStream(tc.redisClient(), "atrisk:alerts", 100, logger.ZapLogger{Logger: zap.NewExample()})
+			err := stream.Publish(alert)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
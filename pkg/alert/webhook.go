@@ -0,0 +1,103 @@
+package alert
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+)
+
+// Webhook posts alerts as json to an http endpoint. Every request is signed with
+// HMAC-SHA256 of "timestamp.body" using the shared secret, the signature and the
+// timestamp are sent in the X-AtRisk-Signature and X-AtRisk-Timestamp headers
+type Webhook struct {
+	url     string
+	secret  string
+	retries int
+	backoff time.Duration
+	client  *http.Client
+	log     logger.ZapLogger
+}
+
+// NewWebhook returns an instance of Webhook, a failed delivery is retried up to
+// retries times waiting backoff, doubled on every attempt, in between
+func NewWebhook(url, secret string, retries int, timeout, backoff time.Duration, log logger.ZapLogger) Webhook {
+	return Webhook{
+		url:     url,
+		secret:  secret,
+		retries: retries,
+		backoff: backoff,
+		client:  &http.Client{Timeout: timeout},
+		log:     log,
+	}
+}
+
+// Publish sends an alert to the webhook, network errors, 429 and 5xx responses
+// are retried while other responses are final
+func (w Webhook) Publish(alert datatypes.ThresholdAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		w.log.Error("unable to marshal alert", map[string]interface{}{"alert": alert, "error": err})
+		return err
+	}
+
+	wait := w.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.send(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.retries {
+			w.log.Error("unable to deliver alert to webhook", map[string]interface{}{"email": alert.UserEmail, "attempts": attempt + 1, "error": err})
+			return err
+		}
+
+		w.log.Info("retrying alert delivery", map[string]interface{}{"email": alert.UserEmail, "attempt": attempt + 1, "error": err})
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// send makes a single delivery attempt and reports whether a failure can be retried
+func (w Webhook) send(body []byte) (bool, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(constants.AlertTimestampHeader, timestamp)
+	req.Header.Set(constants.AlertSignatureHeader, "sha256="+Sign(w.secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, constants.WebhookUnavailable
+	}
+	return false, constants.WebhookRejected
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "timestamp.body", receivers verify
+// an alert by computing it with the shared secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,116 @@
+package alert
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestWebhookPublish(t *testing.T) {
+	type tests struct {
+		name         string
+		statuses     []int
+		retries      int
+		wantAttempts int
+		wantErr      error
+	}
+
+	testCases := []tests{
+		{
+			name:         "valid case",
+			statuses:     []int{http.StatusOK},
+			retries:      2,
+			wantAttempts: 1,
+			wantErr:      nil,
+		},
+		{
+			name:         "valid case, delivered after retries",
+			statuses:     []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusAccepted},
+			retries:      2,
+			wantAttempts: 3,
+			wantErr:      nil,
+		},
+		{
+			name:         "fail case, retries exhausted",
+			statuses:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			retries:      2,
+			wantAttempts: 3,
+			wantErr:      constants.WebhookUnavailable,
+		},
+		{
+			name:         "fail case, rejected alert is not retried",
+			statuses:     []int{http.StatusBadRequest},
+			retries:      2,
+			wantAttempts: 1,
+			wantErr:      constants.WebhookRejected,
+		},
+	}
+
+	alert := datatypes.ThresholdAlert{UserEmail: "user@securly.com", Domain: "securly.com", AtRiskKey: "user@securly.com:1684231487", Threshold: 50, Direction: "up", TotalAtRiskScore: 55, Scoring: "flat", CreatedAt: 1684231487}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.Equal(t, "sha256="+Sign("secret", r.Header.Get(constants.AlertTimestampHeader), body), r.Header.Get(constants.AlertSignatureHeader))
+
+				var received datatypes.ThresholdAlert
+				assert.NoError(t, json.Unmarshal(body, &received))
+				assert.Equal(t, alert, received)
+
+				w.WriteHeader(tc.statuses[attempts])
+				attempts++
+			}))
+			defer receiver.Close()
+
+			webhook := NewWebhook(receiver.URL, "secret", tc.retries, time.Second, time.Millisecond, logger.ZapLogger{Logger: zap.NewExample()})
+			err := webhook.Publish(alert)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+			if tc.wantAttempts != attempts {
+				t.Errorf("expected attempts %d got %d", tc.wantAttempts, attempts)
+			}
+		})
+	}
+}
+
+func TestWebhookPublishUnreachable(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := receiver.URL
+	receiver.Close()
+
+	webhook := NewWebhook(url, "secret", 1, time.Second, time.Millisecond, logger.ZapLogger{Logger: zap.NewExample()})
+	err := webhook.Publish(datatypes.ThresholdAlert{UserEmail: "user@securly.com"})
+	if err == nil {
+		t.Errorf("expected error for unreachable webhook got nil")
+	}
+}
+
+func TestSign(t *testing.T) {
+	signature := Sign("secret", "1684231487", []byte(`{"userEmail":"user@securly.com"}`))
+	if signature != Sign("secret", "1684231487", []byte(`{"userEmail":"user@securly.com"}`)) {
+		t.Errorf("expected signature to be stable")
+	}
+	if signature == Sign("other", "1684231487", []byte(`{"userEmail":"user@securly.com"}`)) {
+		t.Errorf("expected signature to depend on the secret")
+	}
+	if signature == Sign("secret", "1684231488", []byte(`{"userEmail":"user@securly.com"}`)) {
+		t.Errorf("expected signature to depend on the timestamp")
+	}
+	if len(signature) != 64 {
+		t.Errorf("expected hex encoded sha256 got %s", signature)
+	}
+}
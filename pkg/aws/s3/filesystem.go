@@ -0,0 +1,95 @@
+package s3
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileSystem is an S3Action that keeps every bucket as a directory under root and
+// every object as a file at its key. It is meant for tests and local runs without aws.
+type FileSystem struct {
+	root string
+}
+
+// NewFileSystem creates a new instance of the FileSystem storing buckets under root.
+func NewFileSystem(root string) *FileSystem {
+	return &FileSystem{root: root}
+}
+
+// PutObject writes an object, creating its bucket and parent directories when missing.
+func (f *FileSystem) PutObject(bucket, key string, data []byte) error {
+	path := f.path(bucket, key)
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// GetObject reads an object from the specified bucket and key.
+func (f *FileSystem) GetObject(bucket, key string) ([]byte, error) {
+	return os.ReadFile(f.path(bucket, key))
+}
+
+// ListObjects lists the keys of a bucket starting with the prefix in lexical order.
+func (f *FileSystem) ListObjects(bucket, prefix string) ([]string, error) {
+	objects := []string{}
+	dir := f.path(bucket, "")
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, key)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return objects, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(objects)
+	return objects, nil
+}
+
+// DeleteObject removes an object, a missing object is not an error just like on s3.
+func (f *FileSystem) DeleteObject(bucket, key string) error {
+	err := os.Remove(f.path(bucket, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// BucketExists checks if the directory of a bucket exists.
+func (f *FileSystem) BucketExists(bucket string) (bool, error) {
+	info, err := os.Stat(f.path(bucket, ""))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+func (f *FileSystem) path(bucket, key string) string {
+	return filepath.Join(f.root, bucket, filepath.FromSlash(key))
+}
@@ -0,0 +1,58 @@
+package s3
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFileSystem(t *testing.T) {
+	store := NewFileSystem(t.TempDir())
+
+	exists, err := store.BucketExists("bucket")
+	if err != nil || exists {
+		t.Fatalf("expected missing bucket got exists %v, error %v", exists, err)
+	}
+	objects, err := store.ListObjects("bucket", "")
+	if err != nil || len(objects) != 0 {
+		t.Fatalf("expected no objects in missing bucket got %v, error %v", objects, err)
+	}
+
+	for _, key := range []string{"archive/b@securly.com/2.ndjson", "archive/a@securly.com/1.ndjson", "archive/a@securly.community/1.ndjson"} {
+		err = store.PutObject("bucket", key, []byte(key))
+		if err != nil {
+			t.Fatalf("unable to put object %s: %v", key, err)
+		}
+	}
+
+	exists, err = store.BucketExists("bucket")
+	if err != nil || !exists {
+		t.Errorf("expected bucket to exist got %v, error %v", exists, err)
+	}
+
+	objects, err = store.ListObjects("bucket", "archive/a@securly.com/")
+	if err != nil || !reflect.DeepEqual(objects, []string{"archive/a@securly.com/1.ndjson"}) {
+		t.Errorf("unexpected objects %v, error %v", objects, err)
+	}
+	objects, err = store.ListObjects("bucket", "archive/")
+	if err != nil || !reflect.DeepEqual(objects, []string{"archive/a@securly.com/1.ndjson", "archive/a@securly.community/1.ndjson", "archive/b@securly.com/2.ndjson"}) {
+		t.Errorf("unexpected objects %v, error %v", objects, err)
+	}
+
+	data, err := store.GetObject("bucket", "archive/b@securly.com/2.ndjson")
+	if err != nil || string(data) != "archive/b@securly.com/2.ndjson" {
+		t.Errorf("unexpected object content %q, error %v", data, err)
+	}
+
+	err = store.DeleteObject("bucket", "archive/b@securly.com/2.ndjson")
+	if err != nil {
+		t.Errorf("unable to delete object: %v", err)
+	}
+	err = store.DeleteObject("bucket", "archive/b@securly.com/2.ndjson")
+	if err != nil {
+		t.Errorf("expected no error deleting a missing object got %v", err)
+	}
+	_, err = store.GetObject("bucket", "archive/b@securly.com/2.ndjson")
+	if err == nil {
+		t.Errorf("expected error reading a deleted object")
+	}
+}
@@ -3,9 +3,15 @@
 package mocks
 
 import (
-	time "time"
+	context "context"
+
+	cache "www-api/pkg/cache"
 
 	mock "github.com/stretchr/testify/mock"
+
+	redis "github.com/redis/go-redis/v9"
+
+	time "time"
 )
 
 // RedisOps is an autogenerated mock type for the RedisOps type
@@ -77,6 +83,30 @@ func (_m *RedisOps) GetKeys(key string) ([]string, error) {
 	return r0, r1
 }
 
+// GetTTL provides a mock function with given fields: key
+func (_m *RedisOps) GetTTL(key string) (int, error) {
+	ret := _m.Called(key)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(key)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetValue provides a mock function with given fields: key
 func (_m *RedisOps) GetValue(key string) (string, error) {
 	ret := _m.Called(key)
@@ -101,6 +131,75 @@ func (_m *RedisOps) GetValue(key string) (string, error) {
 	return r0, r1
 }
 
+// Pipeline provides a mock function with given fields: ops
+func (_m *RedisOps) Pipeline(ops []cache.Operation) ([]cache.OperationResult, error) {
+	ret := _m.Called(ops)
+
+	var r0 []cache.OperationResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]cache.Operation) ([]cache.OperationResult, error)); ok {
+		return rf(ops)
+	}
+	if rf, ok := ret.Get(0).(func([]cache.Operation) []cache.OperationResult); ok {
+		r0 = rf(ops)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cache.OperationResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]cache.Operation) error); ok {
+		r1 = rf(ops)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Publish provides a mock function with given fields: channel, message
+func (_m *RedisOps) Publish(channel string, message interface{}) error {
+	ret := _m.Called(channel, message)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, interface{}) error); ok {
+		r0 = rf(channel, message)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RunScript provides a mock function with given fields: script, keys, args
+func (_m *RedisOps) RunScript(script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	var _ca []interface{}
+	_ca = append(_ca, script, keys)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	var r0 interface{}
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*redis.Script, []string, ...interface{}) (interface{}, error)); ok {
+		return rf(script, keys, args...)
+	}
+	if rf, ok := ret.Get(0).(func(*redis.Script, []string, ...interface{}) interface{}); ok {
+		r0 = rf(script, keys, args...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(interface{})
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*redis.Script, []string, ...interface{}) error); ok {
+		r1 = rf(script, keys, args...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SetDB provides a mock function with given fields: db
 func (_m *RedisOps) SetDB(db int) {
 	_m.Called(db)
@@ -134,6 +233,63 @@ func (_m *RedisOps) SetWithTTL(key string, value interface{}, ttl time.Duration)
 	return r0
 }
 
+// StreamAdd provides a mock function with given fields: stream, maxLen, values
+func (_m *RedisOps) StreamAdd(stream string, maxLen int64, values map[string]interface{}) (string, error) {
+	ret := _m.Called(stream, maxLen, values)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int64, map[string]interface{}) (string, error)); ok {
+		return rf(stream, maxLen, values)
+	}
+	if rf, ok := ret.Get(0).(func(string, int64, map[string]interface{}) string); ok {
+		r0 = rf(stream, maxLen, values)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int64, map[string]interface{}) error); ok {
+		r1 = rf(stream, maxLen, values)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Subscribe provides a mock function with given fields: ctx, channels
+func (_m *RedisOps) Subscribe(ctx context.Context, channels ...string) (<-chan string, error) {
+	_va := make([]interface{}, len(channels))
+	for _i := range channels {
+		_va[_i] = channels[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 <-chan string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, ...string) (<-chan string, error)); ok {
+		return rf(ctx, channels...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, ...string) <-chan string); ok {
+		r0 = rf(ctx, channels...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, ...string) error); ok {
+		r1 = rf(ctx, channels...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewRedisOps interface {
 	mock.TestingT
 	Cleanup(func())
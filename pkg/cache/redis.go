@@ -17,7 +17,41 @@ type RedisOps interface {
 	Exists(key string) (bool, error)
 	Delete(key string) error
 	SetTTL(key string, expiry int) error
+	GetTTL(key string) (int, error)
 	SetDB(db int)
+	Pipeline(ops []Operation) ([]OperationResult, error)
+	RunScript(script *redis.Script, keys []string, args ...interface{}) (interface{}, error)
+	StreamAdd(stream string, maxLen int64, values map[string]interface{}) (string, error)
+	Publish(channel string, message interface{}) error
+	Subscribe(ctx context.Context, channels ...string) (<-chan string, error)
+}
+
+const (
+	SetAction    = "set"
+	DeleteAction = "delete"
+	ScriptAction = "script"
+)
+
+// Operation is a single command queued in a redis pipeline, Script, Keys
+// and Args are only used by ScriptAction
+type Operation struct {
+	Action string
+	Key    string
+	Value  interface{}
+	TTL    time.Duration
+	Script *redis.Script
+	Keys   []string
+	Args   []interface{}
+}
+
+// OperationResult holds the outcome of a pipelined operation, Affected is
+// the number of keys written or removed by the command and Reply is the raw
+// reply of a script
+type OperationResult struct {
+	Key      string
+	Affected int64
+	Reply    interface{}
+	Err      error
 }
 
 type Redis struct {
@@ -32,6 +66,19 @@ func NewRedis(read *redis.Client, write *redis.Client, log logger.ZapLogger, ctx
 	return Redis{read, write, log, ctx}
 }
 
+// NewPinnedRedis returns a Redis on new clients with the options of read and write that
+// always use db, a SetDB on read and write does not move it
+func NewPinnedRedis(read *redis.Client, write *redis.Client, db int, log logger.ZapLogger, ctx context.Context) Redis {
+	return Redis{pinnedClient(read, db), pinnedClient(write, db), log, ctx}
+}
+
+// pinnedClient returns a new client with the options of client that uses db
+func pinnedClient(client *redis.Client, db int) *redis.Client {
+	options := *client.Options()
+	options.DB = db
+	return redis.NewClient(&options)
+}
+
 // GetValue fetches a value mapped to a key
 func (r Redis) GetValue(key string) (string, error) {
 	value, err := r.read.Get(r.ctx, key).Result()
@@ -112,7 +159,137 @@ func (r Redis) SetTTL(key string, expiry int) error {
 	return nil
 }
 
+// GetTTL returns the remaining ttl of a key in seconds, -1 when the key has no expiry
+func (r Redis) GetTTL(key string) (int, error) {
+	ttl, err := r.read.TTL(r.ctx, key).Result()
+	if err != nil {
+		r.log.Error("error while fetching ttl", map[string]interface{}{"key": key, "err": err})
+		return 0, err
+	}
+
+	//redis replies -2 for a missing key and -1 for a key without expiry
+	switch ttl {
+	case -2:
+		return 0, constants.ResourceNotFound
+	case -1:
+		return -1, nil
+	}
+	return int(ttl / time.Second), nil
+}
+
 func (r Redis) SetDB(db int) {
 	r.write.Options().DB = db
 	r.read.Options().DB = db
 }
+
+// Pipeline applies a list of set/delete operations in a single round trip
+// and returns the outcome of each operation in the same order
+func (r Redis) Pipeline(ops []Operation) ([]OperationResult, error) {
+	cmds, err := r.write.Pipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		for _, op := range ops {
+			switch op.Action {
+			case SetAction:
+				pipe.Set(r.ctx, op.Key, op.Value, op.TTL)
+			case DeleteAction:
+				pipe.Del(r.ctx, op.Key)
+			case ScriptAction:
+				op.Script.Eval(r.ctx, pipe, op.Keys, op.Args...)
+			}
+		}
+		return nil
+	})
+	if err != nil && len(cmds) == 0 {
+		r.log.Error("unable to execute redis pipeline", map[string]interface{}{"operations": len(ops), "err": err})
+		return nil, err
+	}
+
+	results := make([]OperationResult, len(cmds))
+	for i, cmd := range cmds {
+		results[i] = OperationResult{Key: ops[i].Key, Err: cmd.Err()}
+		if del, ok := cmd.(*redis.IntCmd); ok && cmd.Err() == nil {
+			results[i].Affected = del.Val()
+		}
+		if set, ok := cmd.(*redis.StatusCmd); ok && cmd.Err() == nil && set.Val() == "OK" {
+			results[i].Affected = 1
+		}
+		if script, ok := cmd.(*redis.Cmd); ok && cmd.Err() == nil {
+			results[i].Reply = script.Val()
+		}
+		if results[i].Err != nil {
+			r.log.Error("pipelined redis command failed", map[string]interface{}{"key": ops[i].Key, "err": results[i].Err})
+		}
+	}
+
+	return results, nil
+}
+
+// RunScript executes a lua script atomically on the write client, the script
+// is sent by its sha and loaded on the server when missing
+func (r Redis) RunScript(script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
+	result, err := script.Run(r.ctx, r.write, keys, args...).Result()
+	if err != nil && err != redis.Nil {
+		r.log.Error("unable to run script in redis", map[string]interface{}{"keys": keys, "err": err})
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// StreamAdd appends an entry to a stream, trimmed to about maxLen entries, and returns the entry id
+func (r Redis) StreamAdd(stream string, maxLen int64, values map[string]interface{}) (string, error) {
+	id, err := r.write.XAdd(r.ctx, &redis.XAddArgs{Stream: stream, MaxLen: maxLen, Approx: true, Values: values}).Result()
+	if err != nil {
+		r.log.Error("unable to add entry to redis stream", map[string]interface{}{"stream": stream, "err": err})
+		return "", err
+	}
+
+	return id, nil
+}
+
+// Publish sends a message to every subscriber of a channel, on any instance connected to the server
+func (r Redis) Publish(channel string, message interface{}) error {
+	err := r.write.Publish(r.ctx, channel, message).Err()
+	if err != nil {
+		r.log.Error("unable to publish message to redis channel", map[string]interface{}{"channel": channel, "err": err})
+		return err
+	}
+
+	return nil
+}
+
+// Subscribe subscribes to the channels and returns their message payloads, the subscription
+// is closed and the returned channel with it once ctx is done
+func (r Redis) Subscribe(ctx context.Context, channels ...string) (<-chan string, error) {
+	pubsub := r.write.Subscribe(ctx, channels...)
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		r.log.Error("unable to subscribe to redis channels", map[string]interface{}{"channels": channels, "err": err})
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	messages := make(chan string)
+	go func() {
+		defer close(messages)
+		defer pubsub.Close()
+
+		incoming := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-incoming:
+				if !ok {
+					return
+				}
+				select {
+				case messages <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return messages, nil
+}
@@ -0,0 +1,681 @@
+package cache
+
+import "github.com/redis/go-redis/v9"
+
+// atRiskIndexHelpers is shared by every at-risk script. Each user has six
+// aggregate keys next to the email:timestamp event keys, always passed to a
+// script as consecutive KEYS and read with index_of
+//   - events (hash): timestamp -> event value
+//   - expiry (zset): timestamp scored by the unix time the event expires
+//   - totals (hash): "total" -> sum of scores of all live events, "score:<category>" and
+//     "events:<category>" -> sum of scores and number of the live events of a category
+//   - timeline (zset): timestamp scored by itself, used for range reads
+//   - mids (hash): message id -> timestamp of the event carrying it
+//   - versions (hash): timestamp -> version of the event
+//
+// prune drops events whose expiry has passed so the aggregates never count
+// an event that redis already evicted, touch keeps the aggregate keys alive
+// exactly as long as the last event of the user
+//
+// The category totals are kept once the totals hash carries the "categorized"
+// field, which is set when the hash is created. categorize fills them in from
+// the events hash for users indexed before the category totals existed
+//
+// The write scripts also keep the aggregates of the user's email domain, read
+// with domain_of, see constants.AtRiskDomainScoresKey. track copies the user's
+// total and event count into them, prune_domain drops users whose last event
+// expired so a domain read never lists a user without live events. The due zset
+// holds the expiry of the next event of each user, a user whose next event
+// expired is tracked again from its own aggregates before the domain is read
+//
+// Every write of an event takes a new version from the version sequence,
+// precondition_holds compares it with the If-Match and If-None-Match versions
+// of a conditional write, see datatypes.Precondition
+const atRiskIndexHelpers = `
+local function index_of(first)
+	return {
+		events = KEYS[first],
+		expiry = KEYS[first + 1],
+		totals = KEYS[first + 2],
+		timeline = KEYS[first + 3],
+		mids = KEYS[first + 4],
+		versions = KEYS[first + 5],
+	}
+end
+
+local function score_of(value)
+	return tonumber(string.match(value, '^(-?%d+):')) or 0
+end
+
+local function mid_of(value)
+	return string.match(value, '^[^:]*:[^:]*:(.+)$')
+end
+
+local function category_of(value)
+	return string.match(value, '^[^:]*:([^:]*):')
+end
+
+local function count_category(idx, value, sign)
+	local category = category_of(value)
+	if not category then
+		return
+	end
+	redis.call('HINCRBY', idx.totals, 'score:' .. category, sign * score_of(value))
+	if redis.call('HINCRBY', idx.totals, 'events:' .. category, sign) <= 0 then
+		redis.call('HDEL', idx.totals, 'score:' .. category, 'events:' .. category)
+	end
+end
+
+local function categorized(idx)
+	return redis.call('HEXISTS', idx.totals, 'categorized') == 1
+end
+
+local function categorize(idx)
+	if categorized(idx) then
+		return
+	end
+	for _, value in ipairs(redis.call('HVALS', idx.events)) do
+		count_category(idx, value, 1)
+	end
+	redis.call('HSET', idx.totals, 'categorized', 1)
+end
+
+local function add(idx, member, value, expires)
+	if redis.call('EXISTS', idx.totals) == 0 then
+		redis.call('HSET', idx.totals, 'categorized', 1)
+	end
+	redis.call('HSET', idx.events, member, value)
+	redis.call('ZADD', idx.expiry, expires, member)
+	redis.call('ZADD', idx.timeline, tonumber(member) or 0, member)
+	redis.call('HINCRBY', idx.totals, 'total', score_of(value))
+	if categorized(idx) then
+		count_category(idx, value, 1)
+	end
+	local mid = mid_of(value)
+	if mid then
+		redis.call('HSET', idx.mids, mid, member)
+	end
+end
+
+local function remove(idx, member)
+	local value = redis.call('HGET', idx.events, member)
+	if value then
+		redis.call('HINCRBY', idx.totals, 'total', -score_of(value))
+		if categorized(idx) then
+			count_category(idx, value, -1)
+		end
+		redis.call('HDEL', idx.events, member)
+		local mid = mid_of(value)
+		if mid and redis.call('HGET', idx.mids, mid) == member then
+			redis.call('HDEL', idx.mids, mid)
+		end
+	end
+	redis.call('ZREM', idx.expiry, member)
+	redis.call('ZREM', idx.timeline, member)
+	redis.call('HDEL', idx.versions, member)
+	return value
+end
+
+local function prune(idx, now)
+	local expired = redis.call('ZRANGEBYSCORE', idx.expiry, '-inf', now)
+	for _, member in ipairs(expired) do
+		remove(idx, member)
+	end
+end
+
+local function touch(idx)
+	local keys = {idx.events, idx.expiry, idx.totals, idx.timeline, idx.mids, idx.versions}
+	local last = redis.call('ZRANGE', idx.expiry, -1, -1, 'WITHSCORES')
+	for _, key in ipairs(keys) do
+		if #last == 0 then
+			redis.call('DEL', key)
+		elseif last[2] == 'inf' then
+			redis.call('PERSIST', key)
+		else
+			redis.call('EXPIREAT', key, last[2])
+		end
+	end
+end
+
+local function total_of(idx)
+	return tonumber(redis.call('HGET', idx.totals, 'total')) or 0
+end
+
+local function version_of(idx, member)
+	return tonumber(redis.call('HGET', idx.versions, member)) or 0
+end
+
+local function listed(versions, version)
+	for listed_version in string.gmatch(versions, '[^,]+') do
+		if tonumber(listed_version) == version then
+			return true
+		end
+	end
+	return false
+end
+
+local function precondition_holds(key, idx, member, if_match, if_none_match)
+	local exists = redis.call('EXISTS', key) == 1
+	local version = version_of(idx, member)
+	if if_match and if_match ~= '' then
+		if not exists or (if_match ~= '*' and not listed(if_match, version)) then
+			return false
+		end
+	end
+	if if_none_match and if_none_match ~= '' then
+		if exists and (if_none_match == '*' or listed(if_none_match, version)) then
+			return false
+		end
+	end
+	return true
+end
+
+local function domain_of(first)
+	return {
+		scores = KEYS[first],
+		events = KEYS[first + 1],
+		expiry = KEYS[first + 2],
+		totals = KEYS[first + 3],
+		due = KEYS[first + 4],
+	}
+end
+
+local function untrack(domain, email)
+	local events = tonumber(redis.call('ZSCORE', domain.events, email)) or 0
+	redis.call('HINCRBY', domain.totals, 'events', -events)
+	redis.call('ZREM', domain.scores, email)
+	redis.call('ZREM', domain.events, email)
+	redis.call('ZREM', domain.expiry, email)
+	redis.call('ZREM', domain.due, email)
+end
+
+local function prune_domain(domain, now)
+	local expired = redis.call('ZRANGEBYSCORE', domain.expiry, '-inf', now)
+	for _, email in ipairs(expired) do
+		untrack(domain, email)
+	end
+end
+
+local function track(domain, idx, now)
+	local email = string.match(idx.events, '^atrisk:events:(.+)$')
+	prune_domain(domain, now)
+	untrack(domain, email)
+
+	local last = redis.call('ZRANGE', idx.expiry, -1, -1, 'WITHSCORES')
+	if #last > 0 then
+		local events = redis.call('HLEN', idx.events)
+		local first = redis.call('ZRANGE', idx.expiry, 0, 0, 'WITHSCORES')
+		redis.call('ZADD', domain.scores, total_of(idx), email)
+		redis.call('ZADD', domain.events, events, email)
+		redis.call('ZADD', domain.expiry, last[2], email)
+		redis.call('ZADD', domain.due, first[2], email)
+		redis.call('HINCRBY', domain.totals, 'events', events)
+	end
+
+	local keys = {domain.scores, domain.events, domain.expiry, domain.totals, domain.due}
+	local latest = redis.call('ZRANGE', domain.expiry, -1, -1, 'WITHSCORES')
+	for _, key in ipairs(keys) do
+		if #latest == 0 then
+			redis.call('DEL', key)
+		elseif latest[2] == 'inf' then
+			redis.call('PERSIST', key)
+		else
+			redis.call('EXPIREAT', key, latest[2])
+		end
+	end
+end
+`
+
+// SetAtRiskEvent stores an event and adds its score to the user's total
+// KEYS: event key, events, expiry, totals, timeline, mids, versions, domain scores, domain events, domain expiry, domain totals, domain due, version sequence
+// ARGV: timestamp, value, ttl in seconds, current unix time, optional If-Match and If-None-Match versions
+// returns {1, total, previous value or nil, version}, {-1, total, nil, current version} when the precondition fails
+var SetAtRiskEvent = redis.NewScript(atRiskIndexHelpers + `
+local idx = index_of(2)
+local now = tonumber(ARGV[4])
+prune(idx, now)
+
+if not precondition_holds(KEYS[1], idx, ARGV[1], ARGV[5], ARGV[6]) then
+	touch(idx)
+	track(domain_of(8), idx, now)
+	return {-1, total_of(idx), false, version_of(idx, ARGV[1])}
+end
+
+local previous = remove(idx, ARGV[1])
+redis.call('SET', KEYS[1], ARGV[2], 'EX', ARGV[3])
+add(idx, ARGV[1], ARGV[2], now + tonumber(ARGV[3]))
+local version = redis.call('INCR', KEYS[13])
+redis.call('HSET', idx.versions, ARGV[1], version)
+
+touch(idx)
+track(domain_of(8), idx, now)
+return {1, total_of(idx), previous or false, version}
+`)
+
+// DeleteAtRiskEvent removes an event and subtracts its score from the user's total
+// KEYS: event key, events, expiry, totals, timeline, mids, versions, domain scores, domain events, domain expiry, domain totals, domain due, version sequence
+// ARGV: timestamp, current unix time, optional If-Match and If-None-Match versions
+// returns {number of event keys removed, total, removed value or nil}, {-1, total, nil, current version}
+// when the precondition fails
+var DeleteAtRiskEvent = redis.NewScript(atRiskIndexHelpers + `
+local idx = index_of(2)
+local now = tonumber(ARGV[2])
+prune(idx, now)
+
+if not precondition_holds(KEYS[1], idx, ARGV[1], ARGV[3], ARGV[4]) then
+	touch(idx)
+	track(domain_of(8), idx, now)
+	return {-1, total_of(idx), false, version_of(idx, ARGV[1])}
+end
+
+local deleted = redis.call('DEL', KEYS[1])
+local previous = remove(idx, ARGV[1])
+
+touch(idx)
+track(domain_of(8), idx, now)
+return {deleted, total_of(idx), previous or false}
+`)
+
+// AtRiskTotal returns the user's total score after dropping expired events
+// KEYS: events, expiry, totals, timeline, mids, versions
+// ARGV: current unix time
+var AtRiskTotal = redis.NewScript(atRiskIndexHelpers + `
+local idx = index_of(1)
+prune(idx, tonumber(ARGV[1]))
+touch(idx)
+return total_of(idx)
+`)
+
+// AtRiskCategoryTotals returns the total score and number of the user's live events of each
+// category from the totals hash after dropping expired events
+// KEYS: events, expiry, totals, timeline, mids, versions
+// ARGV: current unix time
+// returns {category, score, events, category, score, events...}
+var AtRiskCategoryTotals = redis.NewScript(atRiskIndexHelpers + `
+local idx = index_of(1)
+prune(idx, tonumber(ARGV[1]))
+categorize(idx)
+
+local totals = redis.call('HGETALL', idx.totals)
+local categories = {}
+for i = 1, #totals, 2 do
+	local category = string.match(totals[i], '^events:(.*)$')
+	if category then
+		table.insert(categories, category)
+		table.insert(categories, tonumber(redis.call('HGET', idx.totals, 'score:' .. category)) or 0)
+		table.insert(categories, tonumber(totals[i + 1]))
+	end
+end
+
+touch(idx)
+return categories
+`)
+
+// ListAtRiskEvents returns a page of the user's events ordered by timestamp
+// KEYS: events, expiry, totals, timeline, mids, versions
+// ARGV: current unix time, min timestamp, max timestamp, "asc" or "desc", limit
+// min and max accept the ZRANGEBYSCORE syntax ("-inf", "+inf", "(123")
+// returns {timestamp, value, timestamp, value...}
+var ListAtRiskEvents = redis.NewScript(atRiskIndexHelpers + `
+local idx = index_of(1)
+prune(idx, tonumber(ARGV[1]))
+touch(idx)
+
+local members
+if ARGV[4] == 'asc' then
+	members = redis.call('ZRANGEBYSCORE', idx.timeline, ARGV[2], ARGV[3], 'LIMIT', 0, ARGV[5])
+else
+	members = redis.call('ZREVRANGEBYSCORE', idx.timeline, ARGV[3], ARGV[2], 'LIMIT', 0, ARGV[5])
+end
+
+local page = {}
+for _, member in ipairs(members) do
+	local value = redis.call('HGET', idx.events, member)
+	if value then
+		table.insert(page, member)
+		table.insert(page, value)
+	end
+end
+return page
+`)
+
+// ListAtRiskExpiry returns the remaining ttl of each of the user's events from the expiry zset
+// KEYS: events, expiry, totals, timeline, mids, versions
+// ARGV: current unix time
+// returns {timestamp, ttl in seconds, timestamp, ttl in seconds...} ordered by expiry
+var ListAtRiskExpiry = redis.NewScript(atRiskIndexHelpers + `
+local idx = index_of(1)
+local now = tonumber(ARGV[1])
+prune(idx, now)
+touch(idx)
+
+local members = redis.call('ZRANGE', idx.expiry, 0, -1, 'WITHSCORES')
+local ttls = {}
+for i = 1, #members, 2 do
+	table.insert(ttls, members[i])
+	table.insert(ttls, tonumber(members[i + 1]) - now)
+end
+return ttls
+`)
+
+// ExpireAtRiskEvents moves the expiry of the given event keys and of their events in the index
+// to a new unix time and tracks the user again in the aggregates of its domain
+// KEYS: events, expiry, totals, timeline, mids, versions, domain scores, domain events, domain expiry, domain totals, domain due, event keys...
+// ARGV: current unix time, unix time of expiry, timestamp of each event key in the same order
+// returns number of event keys moved
+var ExpireAtRiskEvents = redis.NewScript(atRiskIndexHelpers + `
+local idx = index_of(1)
+local now = tonumber(ARGV[1])
+local moved = 0
+for i = 3, #ARGV do
+	moved = moved + redis.call('EXPIREAT', KEYS[i + 9], ARGV[2])
+	redis.call('ZADD', idx.expiry, 'XX', ARGV[2], ARGV[i])
+end
+prune(idx, now)
+touch(idx)
+track(domain_of(7), idx, now)
+return moved
+`)
+
+// RebuildAtRiskIndex rebuilds the aggregates of a user from the event keys
+// KEYS: events, expiry, totals, timeline, mids, versions, domain scores, domain events, domain expiry, domain totals, domain due, event keys...
+// ARGV: current unix time, timestamp of each event key in the same order
+// the versions of the events are kept, an event without one reads as version 0
+// returns number of events indexed
+var RebuildAtRiskIndex = redis.NewScript(atRiskIndexHelpers + `
+local idx = index_of(1)
+local now = tonumber(ARGV[1])
+redis.call('DEL', idx.events, idx.expiry, idx.totals, idx.timeline, idx.mids)
+
+local indexed = 0
+for i = 12, #KEYS do
+	local value = redis.call('GET', KEYS[i])
+	local ttl = redis.call('TTL', KEYS[i])
+	if value and ttl ~= -2 then
+		local expires = '+inf'
+		if ttl > 0 then
+			expires = now + ttl
+		end
+		add(idx, ARGV[i - 10], value, expires)
+		indexed = indexed + 1
+	end
+end
+
+touch(idx)
+track(domain_of(7), idx, now)
+return indexed
+`)
+
+// FindAtRiskEvent looks up a live event of the user by its message id
+// KEYS: events, expiry, totals, timeline, mids, versions
+// ARGV: current unix time, message id
+// returns {timestamp, value, version} or nil when no live event carries the message id
+var FindAtRiskEvent = redis.NewScript(atRiskIndexHelpers + `
+local idx = index_of(1)
+prune(idx, tonumber(ARGV[1]))
+touch(idx)
+
+local member = redis.call('HGET', idx.mids, ARGV[2])
+if not member then
+	return nil
+end
+local value = redis.call('HGET', idx.events, member)
+if not value then
+	return nil
+end
+return {member, value, version_of(idx, member)}
+`)
+
+// GetAtRiskEvent returns an event with its version
+// KEYS: event key, events, expiry, totals, timeline, mids, versions
+// ARGV: timestamp
+// returns {value, version} or nil when the event key does not exist
+var GetAtRiskEvent = redis.NewScript(atRiskIndexHelpers + `
+local value = redis.call('GET', KEYS[1])
+if not value then
+	return nil
+end
+return {value, version_of(index_of(2), ARGV[1])}
+`)
+
+// ExpiringAtRiskEvents returns the events of a user expiring until a unix time that were
+// not archived yet in their current version, expired events are read before prune drops them
+// KEYS: events, expiry, totals, timeline, mids, versions, archived
+// ARGV: unix time
+// returns {timestamp, value, expiry, version, timestamp, value, expiry, version...}
+var ExpiringAtRiskEvents = redis.NewScript(atRiskIndexHelpers + `
+local idx = index_of(1)
+local expiring = redis.call('ZRANGEBYSCORE', idx.expiry, '-inf', ARGV[1], 'WITHSCORES')
+
+local events = {}
+for i = 1, #expiring, 2 do
+	local member = expiring[i]
+	local value = redis.call('HGET', idx.events, member)
+	local version = version_of(idx, member)
+	if value and tonumber(redis.call('HGET', KEYS[7], member)) ~= version then
+		table.insert(events, member)
+		table.insert(events, value)
+		table.insert(events, expiring[i + 1])
+		table.insert(events, version)
+	end
+end
+return events
+`)
+
+// MarkAtRiskEventsArchived records the versions of a user's events written to the archive
+// and drops the archived events that already expired
+// KEYS: events, expiry, totals, timeline, mids, versions, archived
+// ARGV: current unix time, unix time the record expires, timestamp and version of each event
+// returns number of events recorded
+var MarkAtRiskEventsArchived = redis.NewScript(atRiskIndexHelpers + `
+local idx = index_of(1)
+for i = 3, #ARGV, 2 do
+	redis.call('HSET', KEYS[7], ARGV[i], ARGV[i + 1])
+end
+redis.call('EXPIREAT', KEYS[7], ARGV[2])
+prune(idx, tonumber(ARGV[1]))
+touch(idx)
+return (#ARGV - 2) / 2
+`)
+
+// EraseAtRiskEmail deletes every key of a user and drops the user from the aggregates of its domain
+// KEYS: events, expiry, totals, timeline, mids, versions, domain scores, domain events, domain expiry, domain totals, domain due, other keys of the user...
+// ARGV: current unix time
+// returns number of keys deleted
+var EraseAtRiskEmail = redis.NewScript(atRiskIndexHelpers + `
+local idx = index_of(1)
+local domain = domain_of(7)
+local email = string.match(idx.events, '^atrisk:events:(.+)$')
+prune_domain(domain, tonumber(ARGV[1]))
+untrack(domain, email)
+if redis.call('ZCARD', domain.expiry) == 0 then
+	redis.call('DEL', domain.scores, domain.events, domain.expiry, domain.totals, domain.due)
+end
+
+local deleted = 0
+for i = 1, #KEYS do
+	if i <= 6 or i > 11 then
+		deleted = deleted + redis.call('DEL', KEYS[i])
+	end
+end
+return deleted
+`)
+
+// ClaimIdempotencyKey stores a pending response for an Idempotency-Key unless the key exists
+// KEYS: idempotency key
+// ARGV: pending response, ttl in seconds
+// returns the stored response, nil when the key was claimed
+var ClaimIdempotencyKey = redis.NewScript(`
+local stored = redis.call('GET', KEYS[1])
+if stored then
+	return stored
+end
+redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[2])
+return nil
+`)
+
+// SwapAtRiskAlertLevel stores the highest threshold reached by the total of an email
+// KEYS: alert key
+// ARGV: threshold, ttl in seconds
+// returns the previously stored threshold, 0 when none was reached
+var SwapAtRiskAlertLevel = redis.NewScript(`
+local previous = tonumber(redis.call('GET', KEYS[1])) or 0
+if tonumber(ARGV[1]) == 0 then
+	redis.call('DEL', KEYS[1])
+else
+	redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[2])
+end
+return previous
+`)
+
+// RestoreAtRiskAlertLevel puts back the threshold an email had before a swap whose alert was
+// not sent, the key is left alone when a later swap already stored another threshold
+// KEYS: alert key
+// ARGV: threshold stored by the swap, threshold to restore, ttl in seconds
+// returns 1 when the threshold was restored, 0 when it changed since the swap
+var RestoreAtRiskAlertLevel = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1])) or 0
+if current ~= tonumber(ARGV[1]) then
+	return 0
+end
+if tonumber(ARGV[2]) == 0 then
+	redis.call('DEL', KEYS[1])
+else
+	redis.call('SET', KEYS[1], ARGV[2], 'EX', ARGV[3])
+end
+return 1
+`)
+
+// DueAtRiskDomainUsers returns the users of a domain whose next event expired, their totals
+// in the domain aggregates still count that event
+// KEYS: domain scores, domain events, domain expiry, domain totals, domain due
+// ARGV: current unix time
+// returns {email, email...}
+var DueAtRiskDomainUsers = redis.NewScript(atRiskIndexHelpers + `
+local domain = domain_of(1)
+prune_domain(domain, tonumber(ARGV[1]))
+return redis.call('ZRANGEBYSCORE', domain.due, '-inf', ARGV[1])
+`)
+
+// EraseStreamEntries removes the entries of a stream whose field has a value, the stream
+// is read in pages so that a long stream does not have to fit into a single reply
+// KEYS: stream
+// ARGV: field, value, "1" to remove the entries or "0" to only count them
+// returns number of entries matched
+var EraseStreamEntries = redis.NewScript(`
+local matched = 0
+local start = '-'
+while true do
+	local entries = redis.call('XRANGE', KEYS[1], start, '+', 'COUNT', 1000)
+	for _, entry in ipairs(entries) do
+		local fields = entry[2]
+		for i = 1, #fields, 2 do
+			if fields[i] == ARGV[1] and fields[i + 1] == ARGV[2] then
+				matched = matched + 1
+				if ARGV[3] == '1' then
+					redis.call('XDEL', KEYS[1], entry[1])
+				end
+				break
+			end
+		end
+	end
+	if #entries < 1000 then
+		return matched
+	end
+	start = '(' .. entries[#entries][1]
+end
+`)
+
+// AtRiskDomainSummary returns the top users and score bands of a domain after tracking the
+// given users again from their own aggregates, see DueAtRiskDomainUsers
+// KEYS: domain scores, domain events, domain expiry, domain totals, domain due, events, expiry,
+// totals, timeline, mids and versions of each user to track...
+// ARGV: current unix time, number of top users, lower bound of each score band in ascending order
+// returns {live events, users, {email, total, events, email, total, events...}, {users in each band}}
+var AtRiskDomainSummary = redis.NewScript(atRiskIndexHelpers + `
+local domain = domain_of(1)
+local now = tonumber(ARGV[1])
+for first = 6, #KEYS, 6 do
+	local idx = index_of(first)
+	prune(idx, now)
+	touch(idx)
+	track(domain, idx, now)
+end
+prune_domain(domain, now)
+
+local top = redis.call('ZREVRANGE', domain.scores, 0, tonumber(ARGV[2]) - 1, 'WITHSCORES')
+local users = {}
+for i = 1, #top, 2 do
+	table.insert(users, top[i])
+	table.insert(users, top[i + 1])
+	table.insert(users, redis.call('ZSCORE', domain.events, top[i]))
+end
+
+local bands = {}
+for i = 3, #ARGV do
+	local max = '+inf'
+	if ARGV[i + 1] then
+		max = '(' .. ARGV[i + 1]
+	end
+	table.insert(bands, redis.call('ZCOUNT', domain.scores, ARGV[i], max))
+end
+
+local events = tonumber(redis.call('HGET', domain.totals, 'events')) or 0
+return {events, redis.call('ZCARD', domain.scores), users, bands}
+`)
+
+// SetPrivacyFlags writes the privacy keys of a domain and its fid when none changed since
+// they were read, and records the change in the privacy audit stream
+// KEYS: privacy keys, audit stream
+// ARGV: number of privacy keys, value read of each key, new value of each key, max length of
+// the audit stream, audit field and value pairs...
+// a value is "" for a missing key, a key whose value is unchanged is not written
+// returns {1, audit entry id} or {0} when a key changed since it was read
+var SetPrivacyFlags = redis.NewScript(`
+local count = tonumber(ARGV[1])
+for i = 1, count do
+	if (redis.call('GET', KEYS[i]) or '') ~= ARGV[i + 1] then
+		return {0}
+	end
+end
+
+for i = 1, count do
+	local value = ARGV[count + i + 1]
+	if value ~= ARGV[i + 1] then
+		if value == '' then
+			redis.call('DEL', KEYS[i])
+		else
+			redis.call('SET', KEYS[i], value)
+		end
+	end
+end
+
+local entry = {KEYS[count + 1], 'MAXLEN', '~', ARGV[2 * count + 2], '*'}
+for i = 2 * count + 3, #ARGV do
+	table.insert(entry, ARGV[i])
+end
+return {1, redis.call('XADD', unpack(entry))}
+`)
+
+// AcquireLock takes a lock unless another holder has it
+// KEYS: lock key
+// ARGV: token of the holder, ttl in seconds
+// returns 1 when the lock was taken, 0 when it is held
+var AcquireLock = redis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'EX', ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+// ReleaseLock drops a lock if it is still held with the token, a lock that expired and
+// was taken by another holder is left alone
+// KEYS: lock key
+// ARGV: token of the holder
+// returns 1 when the lock was released
+var ReleaseLock = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
@@ -1,11 +1,26 @@
 package database
 
-import "github.com/jmoiron/sqlx"
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
 
 type DatabaseOps interface {
 	Select(query string, data interface{}, args ...interface{}) error
 	Insert(query string, args ...interface{}) error
+	InsertID(query string, args ...interface{}) (int64, error)
+	Exec(query string, args ...interface{}) (int64, error)
 	Get(query string, data interface{}, args ...interface{}) error
+	Transaction(statements ...Statement) error
+}
+
+// Statement is a query with its arguments run as part of a transaction, a statement that
+// MustAffect rows rolls the transaction back with sql.ErrNoRows when it changes none
+type Statement struct {
+	Query      string
+	Args       []interface{}
+	MustAffect bool
 }
 
 type Database struct {
@@ -24,11 +39,55 @@ func (m Database) Select(query string, data interface{}, args ...interface{}) er
 
 // Insert is used for adding data to db
 func (m Database) Insert(query string, args ...interface{}) error {
-	_, err := m.DB.Exec(query, args)
+	_, err := m.DB.Exec(query, args...)
 	return err
 }
 
+// InsertID is used for adding a row to db and returns its auto increment id
+func (m Database) InsertID(query string, args ...interface{}) (int64, error) {
+	result, err := m.DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// Exec is used for updating or deleting data in db and returns the number of affected rows
+func (m Database) Exec(query string, args ...interface{}) (int64, error) {
+	result, err := m.DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // Get is used for fetching specific data from db
 func (m Database) Get(query string, data interface{}, args ...interface{}) error {
 	return m.DB.Get(data, query, args...)
 }
+
+// Transaction runs the statements in order in a single transaction, it is rolled back when
+// any of them fails
+func (m Database) Transaction(statements ...Statement) error {
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		var result sql.Result
+		result, err = tx.Exec(statement.Query, statement.Args...)
+		if err == nil && statement.MustAffect {
+			var affected int64
+			affected, err = result.RowsAffected()
+			if err == nil && affected == 0 {
+				err = sql.ErrNoRows
+			}
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -2,13 +2,44 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	database "www-api/pkg/database"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // DatabaseOps is an autogenerated mock type for the DatabaseOps type
 type DatabaseOps struct {
 	mock.Mock
 }
 
+// Exec provides a mock function with given fields: query, args
+func (_m *DatabaseOps) Exec(query string, args ...interface{}) (int64, error) {
+	var _ca []interface{}
+	_ca = append(_ca, query)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, ...interface{}) (int64, error)); ok {
+		return rf(query, args...)
+	}
+	if rf, ok := ret.Get(0).(func(string, ...interface{}) int64); ok {
+		r0 = rf(query, args...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, ...interface{}) error); ok {
+		r1 = rf(query, args...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Get provides a mock function with given fields: query, data, args
 func (_m *DatabaseOps) Get(query string, data interface{}, args ...interface{}) error {
 	var _ca []interface{}
@@ -43,6 +74,33 @@ func (_m *DatabaseOps) Insert(query string, args ...interface{}) error {
 	return r0
 }
 
+// InsertID provides a mock function with given fields: query, args
+func (_m *DatabaseOps) InsertID(query string, args ...interface{}) (int64, error) {
+	var _ca []interface{}
+	_ca = append(_ca, query)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, ...interface{}) (int64, error)); ok {
+		return rf(query, args...)
+	}
+	if rf, ok := ret.Get(0).(func(string, ...interface{}) int64); ok {
+		r0 = rf(query, args...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, ...interface{}) error); ok {
+		r1 = rf(query, args...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Select provides a mock function with given fields: query, data, args
 func (_m *DatabaseOps) Select(query string, data interface{}, args ...interface{}) error {
 	var _ca []interface{}
@@ -60,6 +118,26 @@ func (_m *DatabaseOps) Select(query string, data interface{}, args ...interface{
 	return r0
 }
 
+// Transaction provides a mock function with given fields: statements
+func (_m *DatabaseOps) Transaction(statements ...database.Statement) error {
+	_va := make([]interface{}, len(statements))
+	for _i := range statements {
+		_va[_i] = statements[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(...database.Statement) error); ok {
+		r0 = rf(statements...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewDatabaseOps interface {
 	mock.TestingT
 	Cleanup(func())
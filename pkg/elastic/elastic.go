@@ -1,6 +1,8 @@
 package elastic
 
 import (
+	"io"
+
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 )
@@ -18,6 +20,8 @@ func NewElasticClient(e *elasticsearch.Client) ElasticClient {
 type ElasticActions interface {
 	Info() (*esapi.Response, error)
 	Search() (*esapi.Response, error)
+	Count(indices []string, query io.Reader) (*esapi.Response, error)
+	DeleteByQuery(indices []string, query io.Reader) (*esapi.Response, error)
 }
 
 func (c ElasticClient) Info() (*esapi.Response, error) {
@@ -27,3 +31,15 @@ func (c ElasticClient) Info() (*esapi.Response, error) {
 func (c ElasticClient) Search() (*esapi.Response, error) {
 	return c.con.Search()
 }
+
+// Count counts the documents of the indices matching the query
+func (c ElasticClient) Count(indices []string, query io.Reader) (*esapi.Response, error) {
+	return c.con.Count(c.con.Count.WithIndex(indices...), c.con.Count.WithBody(query))
+}
+
+// DeleteByQuery deletes the documents of the indices matching the query, documents changed
+// while deleting are deleted as well and the indices are refreshed so that a count or search
+// right after does not find them any more
+func (c ElasticClient) DeleteByQuery(indices []string, query io.Reader) (*esapi.Response, error) {
+	return c.con.DeleteByQuery(indices, query, c.con.DeleteByQuery.WithConflicts("proceed"), c.con.DeleteByQuery.WithRefresh(true))
+}
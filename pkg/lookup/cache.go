@@ -0,0 +1,133 @@
+package lookup
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache"
+)
+
+// Cached is a read-through cache of an entity whose lookups can be invalidated
+type Cached interface {
+	Entity() string
+	Invalidate(key string) error
+	Stats() datatypes.LookupStats
+}
+
+// entry is kept in redis for every lookup, Found is false for a lookup that returned
+// constants.ResourceNotFound
+type entry[T any] struct {
+	Found bool `json:"found"`
+	Value *T   `json:"value,omitempty"`
+}
+
+// counters are shared by every copy of a Cache
+type counters struct {
+	hits         int64
+	negativeHits int64
+	misses       int64
+	errors       int64
+}
+
+// Cache reads the lookups of an entity through redis, a value is kept for ttl and a lookup
+// that was not found for negativeTTL. When redis fails the lookup is fetched from the database
+type Cache[T any] struct {
+	redis       cache.RedisOps
+	entity      string
+	ttl         time.Duration
+	negativeTTL time.Duration
+	fetch       func(key string) (T, error)
+	counters    *counters
+	log         logger.ZapLogger
+}
+
+// NewCache returns an instance of Cache keeping the lookups of fetch under entity
+func NewCache[T any](redis cache.RedisOps, entity string, ttl, negativeTTL time.Duration, fetch func(key string) (T, error), log logger.ZapLogger) Cache[T] {
+	return Cache[T]{
+		redis:       redis,
+		entity:      entity,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		fetch:       fetch,
+		counters:    &counters{},
+		log:         log,
+	}
+}
+
+// Entity returns the entity whose lookups are cached
+func (c Cache[T]) Entity() string {
+	return c.entity
+}
+
+// Get returns the cached lookup of key, fetching and caching it on a miss
+func (c Cache[T]) Get(key string) (T, error) {
+	cacheKey := fmt.Sprintf(constants.LookupCacheKey, c.entity, key)
+	value, err := c.redis.GetValue(cacheKey)
+	if err == nil {
+		var cached entry[T]
+		err = json.Unmarshal([]byte(value), &cached)
+		if err == nil && cached.Found && cached.Value != nil {
+			atomic.AddInt64(&c.counters.hits, 1)
+			return *cached.Value, nil
+		}
+		if err == nil && !cached.Found {
+			atomic.AddInt64(&c.counters.negativeHits, 1)
+			var none T
+			return none, constants.ResourceNotFound
+		}
+		c.log.Error("invalid lookup in cache, fetching it again", map[string]interface{}{"key": cacheKey, "value": value, "error": err})
+	} else if err != constants.ResourceNotFound {
+		atomic.AddInt64(&c.counters.errors, 1)
+		c.log.Error("unable to read lookup from cache, fetching it from database", map[string]interface{}{"key": cacheKey, "error": err})
+	}
+
+	atomic.AddInt64(&c.counters.misses, 1)
+	result, err := c.fetch(key)
+	if err != nil && err != constants.ResourceNotFound {
+		return result, err
+	}
+
+	cached, ttl := entry[T]{Found: true, Value: &result}, c.ttl
+	if err == constants.ResourceNotFound {
+		cached, ttl = entry[T]{Found: false}, c.negativeTTL
+	}
+	body, marshalErr := json.Marshal(cached)
+	if marshalErr != nil {
+		c.log.Error("unable to encode lookup for cache", map[string]interface{}{"key": cacheKey, "error": marshalErr})
+		return result, err
+	}
+	if setErr := c.redis.SetWithTTL(cacheKey, string(body), ttl); setErr != nil {
+		atomic.AddInt64(&c.counters.errors, 1)
+		c.log.Error("unable to cache lookup", map[string]interface{}{"key": cacheKey, "error": setErr})
+	}
+
+	return result, err
+}
+
+// Invalidate drops the cached lookup of key so the next Get fetches it again
+func (c Cache[T]) Invalidate(key string) error {
+	cacheKey := fmt.Sprintf(constants.LookupCacheKey, c.entity, key)
+	err := c.redis.Delete(cacheKey)
+	if err != nil {
+		c.log.Error("unable to invalidate lookup", map[string]interface{}{"key": cacheKey, "error": err})
+		return err
+	}
+	return nil
+}
+
+// Stats returns the counters of the cache since the process started
+func (c Cache[T]) Stats() datatypes.LookupStats {
+	return datatypes.LookupStats{
+		Entity:             c.entity,
+		TTLSeconds:         int(c.ttl / time.Second),
+		NegativeTTLSeconds: int(c.negativeTTL / time.Second),
+		Hits:               atomic.LoadInt64(&c.counters.hits),
+		NegativeHits:       atomic.LoadInt64(&c.counters.negativeHits),
+		Misses:             atomic.LoadInt64(&c.counters.misses),
+		Errors:             atomic.LoadInt64(&c.counters.errors),
+	}
+}
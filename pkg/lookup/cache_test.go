@@ -0,0 +1,155 @@
+package lookup
+
+import (
+	"testing"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestGet(t *testing.T) {
+
+	type tests struct {
+		name        string
+		redisClient func() *mocks.RedisOps
+		fetch       func(key string) (datatypes.Notification, error)
+		want        datatypes.Notification
+		wantErr     error
+		wantStats   datatypes.LookupStats
+	}
+
+	key := "lookup:notification:admin@securly.com"
+	notification := datatypes.Notification{ID: 1, Fid: "admin@securly.com", NotificationEmail: "alerts@securly.com", Basegen: 2}
+	cached := `{"found":true,"value":{"ID":1,"Fid":"admin@securly.com","NotificationEmail":"alerts@securly.com","Basegen":2}}`
+	fetched := func(key string) (datatypes.Notification, error) { return notification, nil }
+	stats := func(hits, negativeHits, misses, errors int64) datatypes.LookupStats {
+		return datatypes.LookupStats{Entity: "notification", TTLSeconds: 3600, NegativeTTLSeconds: 60, Hits: hits, NegativeHits: negativeHits, Misses: misses, Errors: errors}
+	}
+	testCases := []tests{
+		{
+			name: "valid case, hit",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetValue", key).Return(cached, nil).Once()
+				return moc
+			},
+			want:      notification,
+			wantErr:   nil,
+			wantStats: stats(1, 0, 0, 0),
+		},
+		{
+			name: "valid case, miss is fetched and cached",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetValue", key).Return("", constants.ResourceNotFound).Once()
+				moc.On("SetWithTTL", key, cached, time.Hour).Return(nil).Once()
+				return moc
+			},
+			fetch:     fetched,
+			want:      notification,
+			wantErr:   nil,
+			wantStats: stats(0, 0, 1, 0),
+		},
+		{
+			name: "valid case, lookup not found is cached",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetValue", key).Return("", constants.ResourceNotFound).Once()
+				moc.On("SetWithTTL", key, `{"found":false}`, time.Minute).Return(nil).Once()
+				return moc
+			},
+			fetch: func(key string) (datatypes.Notification, error) {
+				return datatypes.Notification{}, constants.ResourceNotFound
+			},
+			want:      datatypes.Notification{},
+			wantErr:   constants.ResourceNotFound,
+			wantStats: stats(0, 0, 1, 0),
+		},
+		{
+			name: "valid case, negative hit",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetValue", key).Return(`{"found":false}`, nil).Once()
+				return moc
+			},
+			want:      datatypes.Notification{},
+			wantErr:   constants.ResourceNotFound,
+			wantStats: stats(0, 1, 0, 0),
+		},
+		{
+			name: "valid case, redis unavailable falls back to database",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetValue", key).Return("", test.CacheGetValueErr).Once()
+				moc.On("SetWithTTL", key, cached, time.Hour).Return(test.CacheSetErr).Once()
+				return moc
+			},
+			fetch:     fetched,
+			want:      notification,
+			wantErr:   nil,
+			wantStats: stats(0, 0, 1, 2),
+		},
+		{
+			name: "valid case, invalid cached value is fetched again",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetValue", key).Return("invalid", nil).Once()
+				moc.On("SetWithTTL", key, cached, time.Hour).Return(nil).Once()
+				return moc
+			},
+			fetch:     fetched,
+			want:      notification,
+			wantErr:   nil,
+			wantStats: stats(0, 0, 1, 0),
+		},
+		{
+			name: "fail case, database error is not cached",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetValue", key).Return("", constants.ResourceNotFound).Once()
+				return moc
+			},
+			fetch: func(key string) (datatypes.Notification, error) {
+				return datatypes.Notification{}, test.DBSomethingWentWrongErr
+			},
+			want:      datatypes.Notification{},
+			wantErr:   test.DBSomethingWentWrongErr,
+			wantStats: stats(0, 0, 1, 0),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lookup := NewCache(tc.redisClient(), "notification", time.Hour, time.Minute, tc.fetch, logger.ZapLogger{Logger: zap.NewExample()})
+
+			value, err := lookup.Get("admin@securly.com")
+			assert.Equal(t, tc.want, value)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+			assert.Equal(t, tc.wantStats, lookup.Stats())
+		})
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	moc := mocks.NewRedisOps(t)
+	moc.On("Delete", "lookup:timezone:admin@securly.com").Return(nil).Once()
+	moc.On("Delete", "lookup:timezone:other@securly.com").Return(test.CacheDeleteKeyErr).Once()
+	lookup := NewCache(moc, "timezone", time.Hour, time.Minute, func(key string) (string, error) { return "", nil }, logger.ZapLogger{Logger: zap.NewExample()})
+
+	err := lookup.Invalidate("admin@securly.com")
+	if err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+	err = lookup.Invalidate("other@securly.com")
+	if err != test.CacheDeleteKeyErr {
+		t.Errorf("expected error %v got %v", test.CacheDeleteKeyErr, err)
+	}
+}
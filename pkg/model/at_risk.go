@@ -1,16 +1,256 @@
 package model
 
 import (
+	"database/sql"
+	"strings"
+	"www-api/internal/constants"
 	"www-api/internal/datatypes"
+	"www-api/pkg/database"
+
+	"github.com/jmoiron/sqlx"
 )
 
-// GetAtRiskScore fetches user_email, self_harm_score from AtRiskScore table based on user_email
-func (m *ReadModel) GetAtRiskScore(email string) ([]datatypes.RiskScore, error) {
+// GetAtRiskScore fetches user_email, self_harm_score from AtRiskScore table for a list of user_email
+func (m *ReadModel) GetAtRiskScore(emails []string) ([]datatypes.RiskScore, error) {
+	scores := []datatypes.RiskScore{}
+	if len(emails) == 0 {
+		return scores, nil
+	}
+
+	//sqlx.In expands the IN (?) of the query into one bind variable per email
+	query, args, err := sqlx.In(GetAtRiskQuery, emails)
+	if err != nil {
+		m.log.Error("error expanding emails into atRiskScore query", map[string]interface{}{"error": err, "emails": emails, "query": GetAtRiskQuery})
+		return nil, err
+	}
+
+	err = m.db.Select(query, &scores, args...)
+	if err != nil {
+		m.log.Error("error fetching self_harm_scores from atRiskScore table", map[string]interface{}{"error": err, "emails": emails, "query": GetAtRiskQuery})
+		return nil, err
+	}
+	return scores, nil
+}
+
+// GetAllAtRiskScores fetches user_email, self_harm_score of every row of the AtRiskScore table
+func (m *ReadModel) GetAllAtRiskScores() ([]datatypes.RiskScore, error) {
 	scores := []datatypes.RiskScore{}
-	err := m.db.Select(GetAtRiskQuery, &scores, email)
+	err := m.db.Select(GetAllAtRiskScoresQuery, &scores)
 	if err != nil {
-		m.log.Error("error fetching self_harm_scores from atRiskScore table", map[string]interface{}{"error": err, "email": email, "query": GetAtRiskQuery})
+		m.log.Error("error fetching self_harm_scores from atRiskScore table", map[string]interface{}{"error": err, "query": GetAllAtRiskScoresQuery})
 		return nil, err
 	}
 	return scores, nil
 }
+
+// GetAtRiskEvents fetches the live events of an email from the AtRiskEvent ledger
+func (m ReadModel) GetAtRiskEvents(email string, now int64) ([]datatypes.AtRiskEventRecord, error) {
+	records := []datatypes.AtRiskEventRecord{}
+	err := m.db.Select(GetAtRiskEventsQuery, &records, email, now)
+	if err != nil {
+		m.log.Error("error fetching events from atRiskEvent table", map[string]interface{}{"error": err, "email": email, "query": GetAtRiskEventsQuery})
+		return nil, err
+	}
+	return records, nil
+}
+
+// GetAtRiskEventEmails fetches every email with at least one live event in the AtRiskEvent ledger
+func (m ReadModel) GetAtRiskEventEmails(now int64) ([]string, error) {
+	emails := []string{}
+	err := m.db.Select(GetAtRiskEventEmailsQuery, &emails, now)
+	if err != nil {
+		m.log.Error("error fetching emails from atRiskEvent table", map[string]interface{}{"error": err, "query": GetAtRiskEventEmailsQuery})
+		return nil, err
+	}
+	return emails, nil
+}
+
+// CountAtRiskEvents counts every row of an email in the AtRiskEvent ledger, deleted and expired ones included
+func (m ReadModel) CountAtRiskEvents(email string) (int64, error) {
+	var count int64
+	err := m.db.Get(CountAtRiskEventsQuery, &count, email)
+	if err != nil {
+		m.log.Error("error counting events in atRiskEvent table", map[string]interface{}{"error": err, "email": email, "query": CountAtRiskEventsQuery})
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetAtRiskAudit fetches entries of the AtRiskAudit table matching the filters of the request,
+// newest first, the cursor is the id of the last entry of the previous page
+func (m ReadModel) GetAtRiskAudit(request datatypes.AtRiskAuditRequest) ([]datatypes.AtRiskAuditEntry, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	if request.UserEmail != "" {
+		conditions = append(conditions, "user_email = ?")
+		args = append(args, request.UserEmail)
+	}
+	if request.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, request.Actor)
+	}
+	if request.From != "" {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, request.From)
+	}
+	if request.To != "" {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, request.To)
+	}
+	if request.Cursor != "" {
+		conditions = append(conditions, "id < ?")
+		args = append(args, request.Cursor)
+	}
+
+	query := GetAtRiskAuditQuery
+	if len(conditions) != 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, request.Limit)
+
+	entries := []datatypes.AtRiskAuditEntry{}
+	err := m.db.Select(query, &entries, args...)
+	if err != nil {
+		m.log.Error("error fetching entries from atRiskAudit table", map[string]interface{}{"error": err, "request": request, "query": query})
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CountAtRiskAuditValues counts the create, delete and extend-ttl entries of an email in the
+// AtRiskAudit table that still carry at-risk values, see RedactAtRiskAudit
+func (m ReadModel) CountAtRiskAuditValues(email string) (int64, error) {
+	var count int64
+	err := m.db.Get(CountAtRiskAuditValuesQuery, &count, email, constants.AuditActionCreate, constants.AuditActionDelete, constants.AuditActionExtendTTL)
+	if err != nil {
+		m.log.Error("error counting entries in atRiskAudit table", map[string]interface{}{"error": err, "email": email, "query": CountAtRiskAuditValuesQuery})
+		return 0, err
+	}
+	return count, nil
+}
+
+// RedactAtRiskAudit clears the before and after values and the total of the create, delete and
+// extend-ttl entries of an email in the AtRiskAudit table and returns the number of rows affected,
+// the entries stay so the trail of who changed what is kept. Erase and export entries only carry
+// receipt and export ids and are left alone
+func (m WriteModel) RedactAtRiskAudit(email string) (int64, error) {
+	affected, err := m.db.Exec(RedactAtRiskAuditQuery, email, constants.AuditActionCreate, constants.AuditActionDelete, constants.AuditActionExtendTTL)
+	if err != nil {
+		m.log.Error("error redacting entries of atRiskAudit table", map[string]interface{}{"error": err, "email": email})
+		return 0, err
+	}
+	return affected, nil
+}
+
+// SaveAtRiskAudit appends an entry to the AtRiskAudit table
+func (m WriteModel) SaveAtRiskAudit(entry datatypes.AtRiskAuditEntry) error {
+	statement := atRiskAuditStatement(entry)
+	err := m.db.Insert(statement.Query, statement.Args...)
+	if err != nil {
+		m.log.Error("error saving entry into atRiskAudit table", map[string]interface{}{"error": err, "entry": entry})
+		return err
+	}
+	return nil
+}
+
+// SaveAtRiskEvent inserts an event into the AtRiskEvent ledger, replacing a previous event with
+// the same key, and appends its audit entry to the AtRiskAudit table in one transaction
+func (m WriteModel) SaveAtRiskEvent(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+	err := m.db.Transaction(
+		database.Statement{Query: UpsertAtRiskEventQuery, Args: []interface{}{record.UserEmail, record.EventTimestamp, record.AtRiskValue, record.Score, record.Category, record.MessageID, record.ExpiresAt}},
+		atRiskAuditStatement(entry),
+	)
+	if err != nil {
+		m.log.Error("error saving event into atRiskEvent table", map[string]interface{}{"error": err, "email": record.UserEmail, "timestamp": record.EventTimestamp})
+		return err
+	}
+	return nil
+}
+
+// DeleteAtRiskEvent marks an event of the AtRiskEvent ledger as deleted and appends its audit entry
+// to the AtRiskAudit table in one transaction, with mustExist nothing is written and ResourceNotFound
+// is returned when the ledger has no such event
+func (m WriteModel) DeleteAtRiskEvent(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error {
+	err := m.db.Transaction(
+		database.Statement{Query: DeleteAtRiskEventQuery, Args: []interface{}{email, timestamp}, MustAffect: mustExist},
+		atRiskAuditStatement(entry),
+	)
+	if err == sql.ErrNoRows {
+		return constants.ResourceNotFound
+	}
+	if err != nil {
+		m.log.Error("error deleting event from atRiskEvent table", map[string]interface{}{"error": err, "email": email, "timestamp": timestamp})
+		return err
+	}
+	return nil
+}
+
+// ExtendAtRiskEvents moves the expiry of the live events of an email, only of the events of a
+// category when category is not blank, and appends its audit entry to the AtRiskAudit table in
+// one transaction
+func (m WriteModel) ExtendAtRiskEvents(email, category string, expiresAt, now int64, entry datatypes.AtRiskAuditEntry) error {
+	query, args := ExtendAtRiskEventsQuery, []interface{}{expiresAt, email, now}
+	if category != "" {
+		query, args = ExtendAtRiskCategoryEventsQuery, append(args, category)
+	}
+
+	err := m.db.Transaction(database.Statement{Query: query, Args: args}, atRiskAuditStatement(entry))
+	if err != nil {
+		m.log.Error("error extending events in atRiskEvent table", map[string]interface{}{"error": err, "email": email, "category": category})
+		return err
+	}
+	return nil
+}
+
+// atRiskAuditStatement returns the insert of an entry into the AtRiskAudit table
+func atRiskAuditStatement(entry datatypes.AtRiskAuditEntry) database.Statement {
+	return database.Statement{Query: InsertAtRiskAuditQuery, Args: []interface{}{entry.Actor, entry.Action, entry.UserEmail, entry.AtRiskKey, entry.BeforeValue, entry.AfterValue, entry.TotalAtRiskScore, entry.CreatedAt}}
+}
+
+// SaveAtRiskScore inserts a self_harm_score row for an email into the AtRiskScore table
+func (m WriteModel) SaveAtRiskScore(email, score string) error {
+	err := m.db.Insert(InsertAtRiskScoreQuery, email, score)
+	if err != nil {
+		m.log.Error("error saving score into atRiskScore table", map[string]interface{}{"error": err, "email": email})
+		return err
+	}
+	return nil
+}
+
+// ReplaceAtRiskScore replaces every row of previousEmail in the AtRiskScore table by a single
+// self_harm_score row for email in one transaction, no row is inserted when score is blank
+func (m WriteModel) ReplaceAtRiskScore(previousEmail, email, score string) error {
+	statements := []database.Statement{{Query: DeleteAtRiskScoreQuery, Args: []interface{}{previousEmail}}}
+	if score != "" {
+		statements = append(statements, database.Statement{Query: InsertAtRiskScoreQuery, Args: []interface{}{email, score}})
+	}
+
+	err := m.db.Transaction(statements...)
+	if err != nil {
+		m.log.Error("error replacing scores in atRiskScore table", map[string]interface{}{"error": err, "email": email, "previousEmail": previousEmail})
+		return err
+	}
+	return nil
+}
+
+// DeleteAtRiskScore deletes every row of an email from the AtRiskScore table and returns the number of rows affected
+func (m WriteModel) DeleteAtRiskScore(email string) (int64, error) {
+	affected, err := m.db.Exec(DeleteAtRiskScoreQuery, email)
+	if err != nil {
+		m.log.Error("error deleting scores from atRiskScore table", map[string]interface{}{"error": err, "email": email})
+		return 0, err
+	}
+	return affected, nil
+}
+
+// EraseAtRiskEvents removes every row of an email from the AtRiskEvent ledger, unlike DeleteAtRiskEvent
+// the rows are not kept as deleted, and returns the number of rows affected
+func (m WriteModel) EraseAtRiskEvents(email string) (int64, error) {
+	affected, err := m.db.Exec(EraseAtRiskEventsQuery, email)
+	if err != nil {
+		m.log.Error("error erasing events from atRiskEvent table", map[string]interface{}{"error": err, "email": email})
+		return 0, err
+	}
+	return affected, nil
+}
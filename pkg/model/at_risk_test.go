@@ -1,7 +1,9 @@
 package model
 
 import (
+	"database/sql"
 	"testing"
+	"www-api/internal/constants"
 	"www-api/internal/datatypes"
 	"www-api/internal/logger"
 	"www-api/pkg/database"
@@ -41,6 +43,425 @@ func TestNewRiskModel(t *testing.T) {
 }
 
 func TestGetAtRiskScore(t *testing.T) {
+	type tests struct {
+		name      string
+		emails    []string
+		db        func() *mocks.DatabaseOps
+		wantScore []datatypes.RiskScore
+		wantErr   error
+	}
+	scores := []datatypes.RiskScore{}
+	testCases := []tests{
+		{
+			name:   "valid case",
+			emails: []string{"email1@securly.com", "email2@securly.com"},
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Select", "SELECT user_email, self_harm_score FROM AtRiskScore WHERE user_email IN (?, ?)", &scores, "email1@securly.com", "email2@securly.com").Run(func(args mock.Arguments) {
+					arg := args.Get(1).(*[]datatypes.RiskScore)
+					*arg = append(*arg, datatypes.RiskScore{Email: "email1@securly.com", SelfHarmScore: "51"}, datatypes.RiskScore{Email: "email2@securly.com", SelfHarmScore: "56"})
+				}).Return(nil).Once()
+				return moc
+			},
+			wantScore: []datatypes.RiskScore{
+				{Email: "email1@securly.com", SelfHarmScore: "51"},
+				{Email: "email2@securly.com", SelfHarmScore: "56"},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "fail case, error select func",
+			emails: []string{"email1@securly.com", "email2@securly.com"},
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Select", mock.Anything, &scores, mock.Anything, mock.Anything).Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantScore: nil,
+			wantErr:   test.DBSomethingWentWrongErr,
+		},
+		{
+			name:   "valid case, no emails",
+			emails: []string{},
+			db: func() *mocks.DatabaseOps {
+				return mocks.NewDatabaseOps(t)
+			},
+			wantScore: []datatypes.RiskScore{},
+			wantErr:   nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := ReadModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			score, err := risk.GetAtRiskScore(tc.emails)
+			if !assert.Equal(t, tc.wantScore, score) {
+				t.Errorf("expected score %v got %v", tc.wantScore, score)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+}
+
+func TestGetAtRiskEvents(t *testing.T) {
+	type tests struct {
+		name        string
+		db          func() *mocks.DatabaseOps
+		wantRecords []datatypes.AtRiskEventRecord
+		wantErr     error
+	}
+	records := []datatypes.AtRiskEventRecord{}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Select", GetAtRiskEventsQuery, &records, "email1@securly.com", int64(1684231487)).Run(func(args mock.Arguments) {
+					arg := args.Get(1).(*[]datatypes.AtRiskEventRecord)
+					*arg = append(*arg, datatypes.AtRiskEventRecord{UserEmail: "email1@securly.com", EventTimestamp: "1684231400", AtRiskValue: "45:scan:1dc13ds5c1651", Score: 45, Category: "scan", MessageID: "1dc13ds5c1651", ExpiresAt: 1689415400})
+				}).Return(nil).Once()
+				return moc
+			},
+			wantRecords: []datatypes.AtRiskEventRecord{
+				{UserEmail: "email1@securly.com", EventTimestamp: "1684231400", AtRiskValue: "45:scan:1dc13ds5c1651", Score: 45, Category: "scan", MessageID: "1dc13ds5c1651", ExpiresAt: 1689415400},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, error select func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Select", GetAtRiskEventsQuery, &records, "email1@securly.com", int64(1684231487)).Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantRecords: nil,
+			wantErr:     test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := ReadModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			records, err := risk.GetAtRiskEvents("email1@securly.com", 1684231487)
+			if !assert.Equal(t, tc.wantRecords, records) {
+				t.Errorf("expected records %v got %v", tc.wantRecords, records)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGetAtRiskEventEmails(t *testing.T) {
+	type tests struct {
+		name       string
+		db         func() *mocks.DatabaseOps
+		wantEmails []string
+		wantErr    error
+	}
+	emails := []string{}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Select", GetAtRiskEventEmailsQuery, &emails, int64(1684231487)).Run(func(args mock.Arguments) {
+					arg := args.Get(1).(*[]string)
+					*arg = append(*arg, "email1@securly.com", "email2@securly.com")
+				}).Return(nil).Once()
+				return moc
+			},
+			wantEmails: []string{"email1@securly.com", "email2@securly.com"},
+			wantErr:    nil,
+		},
+		{
+			name: "fail case, error select func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Select", GetAtRiskEventEmailsQuery, &emails, int64(1684231487)).Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantEmails: nil,
+			wantErr:    test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := ReadModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			emails, err := risk.GetAtRiskEventEmails(1684231487)
+			if !assert.Equal(t, tc.wantEmails, emails) {
+				t.Errorf("expected emails %v got %v", tc.wantEmails, emails)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSaveAtRiskEvent(t *testing.T) {
+	type tests struct {
+		name    string
+		db      func() *mocks.DatabaseOps
+		wantErr error
+	}
+	record := datatypes.AtRiskEventRecord{UserEmail: "email1@securly.com", EventTimestamp: "1684231400", AtRiskValue: "45:scan:1dc13ds5c1651", Score: 45, Category: "scan", MessageID: "1dc13ds5c1651", ExpiresAt: 1689415400}
+	after := record.AtRiskValue
+	entry := datatypes.AtRiskAuditEntry{Actor: "subject", Action: "create", UserEmail: "email1@securly.com", AtRiskKey: "email1@securly.com:1684231400", AfterValue: &after, TotalAtRiskScore: 45, CreatedAt: 1684231487}
+	audited := database.Statement{Query: InsertAtRiskAuditQuery, Args: []interface{}{"subject", "create", "email1@securly.com", "email1@securly.com:1684231400", (*string)(nil), &after, 45, int64(1684231487)}}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Transaction", database.Statement{Query: UpsertAtRiskEventQuery, Args: []interface{}{"email1@securly.com", "1684231400", "45:scan:1dc13ds5c1651", 45, "scan", "1dc13ds5c1651", int64(1689415400)}}, audited).Return(nil).Once()
+				return moc
+			},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, error transaction func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Transaction", mock.Anything, audited).Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantErr: test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := WriteModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			err := risk.SaveAtRiskEvent(record, entry)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDeleteAtRiskEvent(t *testing.T) {
+	type tests struct {
+		name      string
+		mustExist bool
+		db        func() *mocks.DatabaseOps
+		wantErr   error
+	}
+	before := "45:scan:1dc13ds5c1651"
+	entry := datatypes.AtRiskAuditEntry{Actor: "subject", Action: "delete", UserEmail: "email1@securly.com", AtRiskKey: "email1@securly.com:1684231400", BeforeValue: &before, CreatedAt: 1684231487}
+	audited := database.Statement{Query: InsertAtRiskAuditQuery, Args: []interface{}{"subject", "delete", "email1@securly.com", "email1@securly.com:1684231400", &before, (*string)(nil), 0, int64(1684231487)}}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Transaction", database.Statement{Query: DeleteAtRiskEventQuery, Args: []interface{}{"email1@securly.com", "1684231400"}}, audited).Return(nil).Once()
+				return moc
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "valid case, event must exist",
+			mustExist: true,
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Transaction", database.Statement{Query: DeleteAtRiskEventQuery, Args: []interface{}{"email1@securly.com", "1684231400"}, MustAffect: true}, audited).Return(nil).Once()
+				return moc
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "fail case, event doesn't exist",
+			mustExist: true,
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Transaction", mock.Anything, audited).Return(sql.ErrNoRows).Once()
+				return moc
+			},
+			wantErr: constants.ResourceNotFound,
+		},
+		{
+			name: "fail case, error transaction func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Transaction", mock.Anything, audited).Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantErr: test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := WriteModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			err := risk.DeleteAtRiskEvent("email1@securly.com", "1684231400", entry, tc.mustExist)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExtendAtRiskEvents(t *testing.T) {
+	type tests struct {
+		name     string
+		category string
+		db       func() *mocks.DatabaseOps
+		wantErr  error
+	}
+	expiresAt := "1689415400"
+	entry := datatypes.AtRiskAuditEntry{Actor: "subject", Action: "extend-ttl", UserEmail: "email1@securly.com", AtRiskKey: "email1@securly.com:*", AfterValue: &expiresAt, TotalAtRiskScore: 72, CreatedAt: 1684231487}
+	audited := database.Statement{Query: InsertAtRiskAuditQuery, Args: []interface{}{"subject", "extend-ttl", "email1@securly.com", "email1@securly.com:*", (*string)(nil), &expiresAt, 72, int64(1684231487)}}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Transaction", database.Statement{Query: ExtendAtRiskEventsQuery, Args: []interface{}{int64(1689415400), "email1@securly.com", int64(1684231487)}}, audited).Return(nil).Once()
+				return moc
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "valid case, category",
+			category: "scan",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Transaction", database.Statement{Query: ExtendAtRiskCategoryEventsQuery, Args: []interface{}{int64(1689415400), "email1@securly.com", int64(1684231487), "scan"}}, audited).Return(nil).Once()
+				return moc
+			},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, error transaction func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Transaction", mock.Anything, audited).Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantErr: test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := WriteModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			err := risk.ExtendAtRiskEvents("email1@securly.com", tc.category, 1689415400, 1684231487, entry)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGetAtRiskAudit(t *testing.T) {
+	type tests struct {
+		name        string
+		request     datatypes.AtRiskAuditRequest
+		db          func() *mocks.DatabaseOps
+		wantEntries []datatypes.AtRiskAuditEntry
+		wantErr     error
+	}
+	entries := []datatypes.AtRiskAuditEntry{}
+	testCases := []tests{
+		{
+			name:    "valid case, all filters",
+			request: datatypes.AtRiskAuditRequest{UserEmail: "email1@securly.com", Actor: "subject", From: "1684231400", To: "1684231487", Cursor: "12", Limit: 3},
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				query := GetAtRiskAuditQuery + " WHERE user_email = ? AND actor = ? AND created_at >= ? AND created_at <= ? AND id < ? ORDER BY id DESC LIMIT ?"
+				moc.On("Select", query, &entries, "email1@securly.com", "subject", "1684231400", "1684231487", "12", 3).Run(func(args mock.Arguments) {
+					arg := args.Get(1).(*[]datatypes.AtRiskAuditEntry)
+					*arg = append(*arg, datatypes.AtRiskAuditEntry{ID: 11, Actor: "subject", Action: "create", UserEmail: "email1@securly.com"})
+				}).Return(nil).Once()
+				return moc
+			},
+			wantEntries: []datatypes.AtRiskAuditEntry{{ID: 11, Actor: "subject", Action: "create", UserEmail: "email1@securly.com"}},
+			wantErr:     nil,
+		},
+		{
+			name:    "valid case, no filters",
+			request: datatypes.AtRiskAuditRequest{Limit: 3},
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Select", GetAtRiskAuditQuery+" ORDER BY id DESC LIMIT ?", &entries, 3).Return(nil).Once()
+				return moc
+			},
+			wantEntries: []datatypes.AtRiskAuditEntry{},
+			wantErr:     nil,
+		},
+		{
+			name:    "fail case, error select func",
+			request: datatypes.AtRiskAuditRequest{Limit: 3},
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Select", mock.Anything, &entries, mock.Anything).Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantEntries: nil,
+			wantErr:     test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := ReadModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			got, err := risk.GetAtRiskAudit(tc.request)
+			assert.Equal(t, tc.wantEntries, got)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSaveAtRiskAudit(t *testing.T) {
+	type tests struct {
+		name    string
+		db      func() *mocks.DatabaseOps
+		wantErr error
+	}
+	before := "40:chat:1dc13ds5c1651"
+	entry := datatypes.AtRiskAuditEntry{Actor: "subject", Action: "delete", UserEmail: "email1@securly.com", AtRiskKey: "email1@securly.com:1684231400", BeforeValue: &before, TotalAtRiskScore: 12, CreatedAt: 1684231487}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Insert", InsertAtRiskAuditQuery, "subject", "delete", "email1@securly.com", "email1@securly.com:1684231400", &before, (*string)(nil), 12, int64(1684231487)).Return(nil).Once()
+				return moc
+			},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, error insert func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Insert", InsertAtRiskAuditQuery, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantErr: test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := WriteModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			err := risk.SaveAtRiskAudit(entry)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGetAllAtRiskScores(t *testing.T) {
 	type tests struct {
 		name      string
 		db        func() *mocks.DatabaseOps
@@ -53,7 +474,7 @@ func TestGetAtRiskScore(t *testing.T) {
 			name: "valid case",
 			db: func() *mocks.DatabaseOps {
 				moc := mocks.NewDatabaseOps(t)
-				moc.On("Select", mock.Anything, &scores, mock.Anything).Run(func(args mock.Arguments) {
+				moc.On("Select", GetAllAtRiskScoresQuery, &scores).Run(func(args mock.Arguments) {
 					arg := args.Get(1).(*[]datatypes.RiskScore)
 					*arg = append(*arg, datatypes.RiskScore{Email: "email1@securly.com", SelfHarmScore: "51"}, datatypes.RiskScore{Email: "email2@securly.com", SelfHarmScore: "56"})
 				}).Return(nil).Once()
@@ -69,7 +490,7 @@ func TestGetAtRiskScore(t *testing.T) {
 			name: "fail case, error select func",
 			db: func() *mocks.DatabaseOps {
 				moc := mocks.NewDatabaseOps(t)
-				moc.On("Select", mock.Anything, &scores, mock.Anything).Return(test.DBSomethingWentWrongErr).Once()
+				moc.On("Select", GetAllAtRiskScoresQuery, &scores).Return(test.DBSomethingWentWrongErr).Once()
 				return moc
 			},
 			wantScore: nil,
@@ -80,7 +501,7 @@ func TestGetAtRiskScore(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			risk := ReadModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
-			score, err := risk.GetAtRiskScore("email")
+			score, err := risk.GetAllAtRiskScores()
 			if !assert.Equal(t, tc.wantScore, score) {
 				t.Errorf("expected score %v got %v", tc.wantScore, score)
 			}
@@ -89,5 +510,320 @@ func TestGetAtRiskScore(t *testing.T) {
 			}
 		})
 	}
+}
+
+func TestSaveAtRiskScore(t *testing.T) {
+	type tests struct {
+		name    string
+		db      func() *mocks.DatabaseOps
+		wantErr error
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Insert", InsertAtRiskScoreQuery, "email1@securly.com", "51").Return(nil).Once()
+				return moc
+			},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, error insert func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Insert", InsertAtRiskScoreQuery, "email1@securly.com", "51").Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantErr: test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := WriteModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			err := risk.SaveAtRiskScore("email1@securly.com", "51")
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDeleteAtRiskScore(t *testing.T) {
+	type tests struct {
+		name         string
+		db           func() *mocks.DatabaseOps
+		wantAffected int64
+		wantErr      error
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Exec", DeleteAtRiskScoreQuery, "email1@securly.com").Return(int64(2), nil).Once()
+				return moc
+			},
+			wantAffected: 2,
+			wantErr:      nil,
+		},
+		{
+			name: "fail case, error exec func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Exec", DeleteAtRiskScoreQuery, "email1@securly.com").Return(int64(0), test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantAffected: 0,
+			wantErr:      test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := WriteModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			affected, err := risk.DeleteAtRiskScore("email1@securly.com")
+			if tc.wantAffected != affected {
+				t.Errorf("expected affected %d got %d", tc.wantAffected, affected)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReplaceAtRiskScore(t *testing.T) {
+	type tests struct {
+		name    string
+		score   string
+		db      func() *mocks.DatabaseOps
+		wantErr error
+	}
+	deleted := database.Statement{Query: DeleteAtRiskScoreQuery, Args: []interface{}{"Email1@securly.com"}}
+	testCases := []tests{
+		{
+			name:  "valid case",
+			score: "45",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Transaction", deleted, database.Statement{Query: InsertAtRiskScoreQuery, Args: []interface{}{"email1@securly.com", "45"}}).Return(nil).Once()
+				return moc
+			},
+			wantErr: nil,
+		},
+		{
+			name:  "valid case, blank score only deletes",
+			score: "",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Transaction", deleted).Return(nil).Once()
+				return moc
+			},
+			wantErr: nil,
+		},
+		{
+			name:  "fail case, error in transaction",
+			score: "45",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Transaction", deleted, mock.Anything).Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantErr: test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := WriteModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			err := risk.ReplaceAtRiskScore("Email1@securly.com", "email1@securly.com", tc.score)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCountAtRiskEvents(t *testing.T) {
+	type tests struct {
+		name      string
+		db        func() *mocks.DatabaseOps
+		wantCount int64
+		wantErr   error
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Get", CountAtRiskEventsQuery, mock.AnythingOfType("*int64"), "email1@securly.com").Run(func(args mock.Arguments) {
+					arg := args.Get(1).(*int64)
+					*arg = 3
+				}).Return(nil).Once()
+				return moc
+			},
+			wantCount: 3,
+			wantErr:   nil,
+		},
+		{
+			name: "fail case, error get func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Get", CountAtRiskEventsQuery, mock.AnythingOfType("*int64"), "email1@securly.com").Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantCount: 0,
+			wantErr:   test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := ReadModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			count, err := risk.CountAtRiskEvents("email1@securly.com")
+			if tc.wantCount != count {
+				t.Errorf("expected count %d got %d", tc.wantCount, count)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEraseAtRiskEvents(t *testing.T) {
+	type tests struct {
+		name         string
+		db           func() *mocks.DatabaseOps
+		wantAffected int64
+		wantErr      error
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Exec", EraseAtRiskEventsQuery, "email1@securly.com").Return(int64(3), nil).Once()
+				return moc
+			},
+			wantAffected: 3,
+			wantErr:      nil,
+		},
+		{
+			name: "fail case, error exec func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Exec", EraseAtRiskEventsQuery, "email1@securly.com").Return(int64(0), test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantAffected: 0,
+			wantErr:      test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := WriteModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			affected, err := risk.EraseAtRiskEvents("email1@securly.com")
+			if tc.wantAffected != affected {
+				t.Errorf("expected affected %d got %d", tc.wantAffected, affected)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCountAtRiskAuditValues(t *testing.T) {
+	type tests struct {
+		name      string
+		db        func() *mocks.DatabaseOps
+		wantCount int64
+		wantErr   error
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Get", CountAtRiskAuditValuesQuery, mock.AnythingOfType("*int64"), "email1@securly.com", "create", "delete", "extend-ttl").Run(func(args mock.Arguments) {
+					arg := args.Get(1).(*int64)
+					*arg = 4
+				}).Return(nil).Once()
+				return moc
+			},
+			wantCount: 4,
+			wantErr:   nil,
+		},
+		{
+			name: "fail case, error get func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Get", CountAtRiskAuditValuesQuery, mock.AnythingOfType("*int64"), "email1@securly.com", "create", "delete", "extend-ttl").Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantCount: 0,
+			wantErr:   test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := ReadModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			count, err := risk.CountAtRiskAuditValues("email1@securly.com")
+			if tc.wantCount != count {
+				t.Errorf("expected count %d got %d", tc.wantCount, count)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
 
+func TestRedactAtRiskAudit(t *testing.T) {
+	type tests struct {
+		name         string
+		db           func() *mocks.DatabaseOps
+		wantAffected int64
+		wantErr      error
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Exec", RedactAtRiskAuditQuery, "email1@securly.com", "create", "delete", "extend-ttl").Return(int64(4), nil).Once()
+				return moc
+			},
+			wantAffected: 4,
+			wantErr:      nil,
+		},
+		{
+			name: "fail case, error exec func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Exec", RedactAtRiskAuditQuery, "email1@securly.com", "create", "delete", "extend-ttl").Return(int64(0), test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantAffected: 0,
+			wantErr:      test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := WriteModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			affected, err := risk.RedactAtRiskAudit("email1@securly.com")
+			if tc.wantAffected != affected {
+				t.Errorf("expected affected %d got %d", tc.wantAffected, affected)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
 }
@@ -33,6 +33,63 @@ func (m ReadModel) GetAwareNotification(fid string) (datatypes.Notification, err
 	return notification, nil
 }
 
+// GetAwareNotifications fetches every awareEmailNotification row of fid
+func (m ReadModel) GetAwareNotifications(fid string) ([]datatypes.Notification, error) {
+	notifications := []datatypes.Notification{}
+	err := m.db.Select(GetAwareNotificationsQuery, &notifications, fid)
+	if err != nil {
+		m.log.Error("error fetching rows from awareEmailNotification table", map[string]interface{}{"error": err, "fid": fid})
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// GetAwareNotificationByID fetches the awareEmailNotification row id of fid
+func (m ReadModel) GetAwareNotificationByID(id int, fid string) (datatypes.Notification, error) {
+	var notifications []datatypes.Notification
+	err := m.db.Select(GetAwareNotificationByIDQuery, &notifications, id, fid)
+	if err != nil {
+		m.log.Error("error fetching row from awareEmailNotification table", map[string]interface{}{"error": err, "id": id, "fid": fid})
+		return datatypes.Notification{}, err
+	}
+	if len(notifications) == 0 {
+		return datatypes.Notification{}, constants.ResourceNotFound
+	}
+	return notifications[0], nil
+}
+
+// SaveAwareNotification inserts a row into the awareEmailNotification table and returns its id
+func (m WriteModel) SaveAwareNotification(notification datatypes.Notification) (int64, error) {
+	id, err := m.db.InsertID(InsertAwareNotificationQuery, notification.Fid, notification.NotificationEmail, notification.Basegen)
+	if err != nil {
+		m.log.Error("error saving row into awareEmailNotification table", map[string]interface{}{"error": err, "fid": notification.Fid})
+		return 0, err
+	}
+	return id, nil
+}
+
+// UpdateAwareNotification replaces the recipients of the awareEmailNotification row with the id, fid and
+// basegen of notification and bumps its basegen, it returns the number of rows affected
+func (m WriteModel) UpdateAwareNotification(notification datatypes.Notification) (int64, error) {
+	affected, err := m.db.Exec(UpdateAwareNotificationQuery, notification.NotificationEmail, notification.ID, notification.Fid, notification.Basegen)
+	if err != nil {
+		m.log.Error("error updating row in awareEmailNotification table", map[string]interface{}{"error": err, "id": notification.ID, "fid": notification.Fid})
+		return 0, err
+	}
+	return affected, nil
+}
+
+// DeleteAwareNotification deletes the awareEmailNotification row id of fid when its basegen still matches
+// and returns the number of rows affected
+func (m WriteModel) DeleteAwareNotification(id int, fid string, basegen int) (int64, error) {
+	affected, err := m.db.Exec(DeleteAwareNotificationQuery, id, fid, basegen)
+	if err != nil {
+		m.log.Error("error deleting row from awareEmailNotification table", map[string]interface{}{"error": err, "id": id, "fid": fid, "basegen": basegen})
+		return 0, err
+	}
+	return affected, nil
+}
+
 // GetUserTimezone fetches givenName, familyName from usermap and azureUsers table based on userEmail
 func (m ReadModel) GetFilterType(fid string) (datatypes.FilterType, error) {
 	var filter datatypes.FilterType
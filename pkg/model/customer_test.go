@@ -203,3 +203,190 @@ func TestGetFilterType(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAwareNotificationByID(t *testing.T) {
+	type tests struct {
+		name    string
+		db      func() *mocks.DatabaseOps
+		want    datatypes.Notification
+		wantErr error
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Select", GetAwareNotificationByIDQuery, mock.Anything, 7, "fid").Run(func(args mock.Arguments) {
+					arg := args.Get(1).(*[]datatypes.Notification)
+					*arg = []datatypes.Notification{{ID: 7, Fid: "fid", NotificationEmail: "a@securly.com,b@securly.com", Basegen: 2}}
+				}).Return(nil).Once()
+				return moc
+			},
+			want:    datatypes.Notification{ID: 7, Fid: "fid", NotificationEmail: "a@securly.com,b@securly.com", Basegen: 2},
+			wantErr: nil,
+		},
+		{
+			name: "invalid case, resource not found",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Select", GetAwareNotificationByIDQuery, mock.Anything, 7, "fid").Return(nil).Once()
+				return moc
+			},
+			want:    datatypes.Notification{},
+			wantErr: constants.ResourceNotFound,
+		},
+		{
+			name: "fail case, error select func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Select", GetAwareNotificationByIDQuery, mock.Anything, 7, "fid").Return(test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			want:    datatypes.Notification{},
+			wantErr: test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			customer := ReadModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			notification, err := customer.GetAwareNotificationByID(7, "fid")
+			assert.Equal(t, tc.want, notification)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSaveAwareNotification(t *testing.T) {
+	type tests struct {
+		name    string
+		db      func() *mocks.DatabaseOps
+		wantID  int64
+		wantErr error
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("InsertID", InsertAwareNotificationQuery, "fid", "a@securly.com,b@securly.com", 1).Return(int64(7), nil).Once()
+				return moc
+			},
+			wantID:  7,
+			wantErr: nil,
+		},
+		{
+			name: "fail case, error insert func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("InsertID", InsertAwareNotificationQuery, "fid", "a@securly.com,b@securly.com", 1).Return(int64(0), test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantID:  0,
+			wantErr: test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			customer := WriteModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			id, err := customer.SaveAwareNotification(datatypes.Notification{Fid: "fid", NotificationEmail: "a@securly.com,b@securly.com", Basegen: 1})
+			if tc.wantID != id {
+				t.Errorf("expected id %d got %d", tc.wantID, id)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUpdateAwareNotification(t *testing.T) {
+	type tests struct {
+		name         string
+		db           func() *mocks.DatabaseOps
+		wantAffected int64
+		wantErr      error
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Exec", UpdateAwareNotificationQuery, "a@securly.com", 7, "fid", 2).Return(int64(1), nil).Once()
+				return moc
+			},
+			wantAffected: 1,
+			wantErr:      nil,
+		},
+		{
+			name: "fail case, error exec func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Exec", UpdateAwareNotificationQuery, "a@securly.com", 7, "fid", 2).Return(int64(0), test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantAffected: 0,
+			wantErr:      test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			customer := WriteModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			affected, err := customer.UpdateAwareNotification(datatypes.Notification{ID: 7, Fid: "fid", NotificationEmail: "a@securly.com", Basegen: 2})
+			if tc.wantAffected != affected {
+				t.Errorf("expected affected %d got %d", tc.wantAffected, affected)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDeleteAwareNotification(t *testing.T) {
+	type tests struct {
+		name         string
+		db           func() *mocks.DatabaseOps
+		wantAffected int64
+		wantErr      error
+	}
+	testCases := []tests{
+		{
+			name: "valid case",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Exec", DeleteAwareNotificationQuery, 7, "fid", 2).Return(int64(1), nil).Once()
+				return moc
+			},
+			wantAffected: 1,
+			wantErr:      nil,
+		},
+		{
+			name: "fail case, error exec func",
+			db: func() *mocks.DatabaseOps {
+				moc := mocks.NewDatabaseOps(t)
+				moc.On("Exec", DeleteAwareNotificationQuery, 7, "fid", 2).Return(int64(0), test.DBSomethingWentWrongErr).Once()
+				return moc
+			},
+			wantAffected: 0,
+			wantErr:      test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			customer := WriteModel{logger.ZapLogger{Logger: zap.NewExample()}, tc.db()}
+			affected, err := customer.DeleteAwareNotification(7, "fid", 2)
+			if tc.wantAffected != affected {
+				t.Errorf("expected affected %d got %d", tc.wantAffected, affected)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -17,12 +17,35 @@ type WriteModel struct {
 }
 
 type DatabaseReadAction interface {
-	GetAtRiskScore(email string) ([]datatypes.RiskScore, error)
+	GetAtRiskScore(emails []string) ([]datatypes.RiskScore, error)
+	GetAllAtRiskScores() ([]datatypes.RiskScore, error)
 	GetStudentInfo(email string) (datatypes.StudentInfo, error)
 	GetStudentInfoWithFid(fid, email string) (datatypes.StudentInfo, error)
 	GetAwareNotification(fid string) (datatypes.Notification, error)
+	GetAwareNotifications(fid string) ([]datatypes.Notification, error)
+	GetAwareNotificationByID(id int, fid string) (datatypes.Notification, error)
 	GetUserTimezone(email string) (string, error)
 	GetFilterType(fid string) (datatypes.FilterType, error)
+	GetAtRiskEvents(email string, now int64) ([]datatypes.AtRiskEventRecord, error)
+	GetAtRiskEventEmails(now int64) ([]string, error)
+	CountAtRiskEvents(email string) (int64, error)
+	GetAtRiskAudit(request datatypes.AtRiskAuditRequest) ([]datatypes.AtRiskAuditEntry, error)
+	CountAtRiskAuditValues(email string) (int64, error)
+}
+
+type DatabaseWriteAction interface {
+	SaveAtRiskEvent(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error
+	DeleteAtRiskEvent(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error
+	ExtendAtRiskEvents(email, category string, expiresAt, now int64, entry datatypes.AtRiskAuditEntry) error
+	SaveAtRiskAudit(entry datatypes.AtRiskAuditEntry) error
+	RedactAtRiskAudit(email string) (int64, error)
+	SaveAtRiskScore(email, score string) error
+	ReplaceAtRiskScore(previousEmail, email, score string) error
+	DeleteAtRiskScore(email string) (int64, error)
+	EraseAtRiskEvents(email string) (int64, error)
+	SaveAwareNotification(notification datatypes.Notification) (int64, error)
+	UpdateAwareNotification(notification datatypes.Notification) (int64, error)
+	DeleteAwareNotification(id int, fid string, basegen int) (int64, error)
 }
 
 // NewReadModel returns an instance of ReadModel struct
@@ -33,7 +56,7 @@ func NewReadModel(log logger.ZapLogger, db database.Database) ReadModel {
 	}
 }
 
-// NewWriteModel returns an instance of ReadModel struct
+// NewWriteModel returns an instance of WriteModel struct
 func NewWriteModel(log logger.ZapLogger, db database.Database) WriteModel {
 	return WriteModel{
 		log: log,
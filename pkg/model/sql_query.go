@@ -1,8 +1,30 @@
 package model
 
 var GetAtRiskQuery = "SELECT user_email, self_harm_score FROM AtRiskScore WHERE user_email IN (?)"
+var GetAllAtRiskScoresQuery = "SELECT user_email, self_harm_score FROM AtRiskScore"
+var InsertAtRiskScoreQuery = "INSERT INTO AtRiskScore (user_email, self_harm_score) VALUES (?, ?)"
+var DeleteAtRiskScoreQuery = "DELETE FROM AtRiskScore WHERE user_email = ?"
 var GetStudentInfoQuery = "SELECT givenName, familyName FROM usermap WHERE userEmail = ? UNION SELECT givenName, familyName FROM azureUsers WHERE userEmail = ?"
 var GetStudentInfoWithFidQuery = "SELECT givenName, familyName FROM usermap WHERE email = ? AND userEmail = ? UNION SELECT givenName, familyName FROM azureUsers WHERE fid = ? AND userEmail = ?"
 var GetTimeZone = "SELECT timezone FROM user WHERE email = ?"
 var GetAwareNotification = "SELECT * FROM awareEmailNotification WHERE fid = ?"
+var GetAwareNotificationsQuery = "SELECT * FROM awareEmailNotification WHERE fid = ? ORDER BY id"
+var GetAwareNotificationByIDQuery = "SELECT * FROM awareEmailNotification WHERE id = ? AND fid = ?"
+var InsertAwareNotificationQuery = "INSERT INTO awareEmailNotification (fid, notifEmail, basegen) VALUES (?, ?, ?)"
+var UpdateAwareNotificationQuery = "UPDATE awareEmailNotification SET notifEmail = ?, basegen = basegen + 1 WHERE id = ? AND fid = ? AND basegen = ?"
+var DeleteAwareNotificationQuery = "DELETE FROM awareEmailNotification WHERE id = ? AND fid = ? AND basegen = ?"
 var GetFilterType = "select s.* from setting as s left join user as u on s.user_id = u.userId where u.email = ? limit 1"
+
+var UpsertAtRiskEventQuery = "INSERT INTO AtRiskEvent (user_email, event_timestamp, atrisk_value, score, category, mid, expires_at, deleted_at) VALUES (?, ?, ?, ?, ?, ?, ?, NULL) ON DUPLICATE KEY UPDATE atrisk_value = VALUES(atrisk_value), score = VALUES(score), category = VALUES(category), mid = VALUES(mid), expires_at = VALUES(expires_at), deleted_at = NULL"
+var DeleteAtRiskEventQuery = "UPDATE AtRiskEvent SET deleted_at = NOW() WHERE user_email = ? AND event_timestamp = ? AND deleted_at IS NULL"
+var ExtendAtRiskEventsQuery = "UPDATE AtRiskEvent SET expires_at = ? WHERE user_email = ? AND deleted_at IS NULL AND expires_at > ?"
+var ExtendAtRiskCategoryEventsQuery = "UPDATE AtRiskEvent SET expires_at = ? WHERE user_email = ? AND deleted_at IS NULL AND expires_at > ? AND category = ?"
+var GetAtRiskEventsQuery = "SELECT user_email, event_timestamp, atrisk_value, score, category, mid, expires_at FROM AtRiskEvent WHERE user_email = ? AND deleted_at IS NULL AND expires_at > ?"
+var CountAtRiskEventsQuery = "SELECT COUNT(*) FROM AtRiskEvent WHERE user_email = ?"
+var EraseAtRiskEventsQuery = "DELETE FROM AtRiskEvent WHERE user_email = ?"
+var GetAtRiskEventEmailsQuery = "SELECT DISTINCT user_email FROM AtRiskEvent WHERE deleted_at IS NULL AND expires_at > ?"
+
+var InsertAtRiskAuditQuery = "INSERT INTO AtRiskAudit (actor, action, user_email, atrisk_key, before_value, after_value, total_atrisk_score, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+var GetAtRiskAuditQuery = "SELECT id, actor, action, user_email, atrisk_key, before_value, after_value, total_atrisk_score, created_at FROM AtRiskAudit"
+var CountAtRiskAuditValuesQuery = "SELECT COUNT(*) FROM AtRiskAudit WHERE user_email = ? AND action IN (?, ?, ?) AND (before_value IS NOT NULL OR after_value IS NOT NULL OR total_atrisk_score <> 0)"
+var RedactAtRiskAuditQuery = "UPDATE AtRiskAudit SET before_value = NULL, after_value = NULL, total_atrisk_score = 0 WHERE user_email = ? AND action IN (?, ?, ?) AND (before_value IS NOT NULL OR after_value IS NOT NULL OR total_atrisk_score <> 0)"
@@ -1,3 +1,31 @@
 CREATE TABLE IF NOT EXISTS AtRiskScore (user_email text, self_harm_score int);  
 INSERT INTO AtRiskScore (user_email,self_harm_score) VALUES ('admin@rtqa1securly.com',45);
-INSERT INTO AtRiskScore (user_email,self_harm_score) VALUES ('admin@rtqa1securly.com',136);
\ No newline at end of file
+INSERT INTO AtRiskScore (user_email,self_harm_score) VALUES ('admin@rtqa1securly.com',136);
+CREATE TABLE IF NOT EXISTS AtRiskEvent (
+  user_email varchar(255) NOT NULL,
+  event_timestamp bigint NOT NULL,
+  atrisk_value varchar(1024) NOT NULL,
+  score int NOT NULL,
+  category varchar(64) NOT NULL,
+  mid varchar(512) NOT NULL,
+  expires_at bigint NOT NULL,
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  deleted_at datetime NULL,
+  PRIMARY KEY (user_email, event_timestamp),
+  KEY idx_atrisk_event_live (deleted_at, expires_at)
+);
+CREATE TABLE IF NOT EXISTS AtRiskAudit (
+  id bigint NOT NULL AUTO_INCREMENT,
+  actor varchar(255) NOT NULL,
+  action varchar(32) NOT NULL,
+  user_email varchar(255) NOT NULL,
+  atrisk_key varchar(255) NOT NULL,
+  before_value varchar(1024) NULL,
+  after_value varchar(1024) NULL,
+  total_atrisk_score int NOT NULL,
+  created_at bigint NOT NULL,
+  PRIMARY KEY (id),
+  KEY idx_atrisk_audit_email (user_email, created_at),
+  KEY idx_atrisk_audit_actor (actor, created_at)
+);
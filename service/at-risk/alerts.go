@@ -0,0 +1,134 @@
+package atrisk
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/pkg/cache"
+)
+
+// checkThresholds publishes an alert when the flat total of an email reached a threshold of
+// its domain or fell below the threshold it had reached. Thresholds are always checked against
+// the flat total kept by the write scripts, whatever scoring the caller asked for, so callers
+// with different strategies share one alert state. The threshold reached is kept in redis so
+// the same crossing is alerted once, the alert is sent by dispatch and never fails the request.
+// An alert that could not be sent gives the threshold back so the next change alerts again
+func (s RiskService) checkThresholds(key string, total int) {
+	if s.alerts == nil {
+		return
+	}
+
+	email, _ := splitAtRiskKey(key)
+	domain := emailDomain(email)
+	thresholds := s.domainThresholds(domain)
+	if len(thresholds) == 0 {
+		return
+	}
+
+	reached := reachedThreshold(thresholds, total)
+	alertKeys := []string{fmt.Sprintf(constants.AtRiskAlertKey, email)}
+	reply, err := s.redis.RunScript(cache.SwapAtRiskAlertLevel, alertKeys, reached, constants.AtRiskEventTTL)
+	if err != nil {
+		s.log.Error("unable to update alert threshold of email", map[string]interface{}{"email": email, "error": err})
+		return
+	}
+
+	previous, ok := reply.(int64)
+	if !ok {
+		s.log.Error("invalid alert threshold received from redis", map[string]interface{}{"email": email, "reply": reply})
+		return
+	}
+	if int(previous) == reached {
+		return
+	}
+
+	alert := datatypes.ThresholdAlert{
+		UserEmail:        email,
+		Domain:           domain,
+		AtRiskKey:        key,
+		Threshold:        reached,
+		Direction:        constants.AlertDirectionUp,
+		TotalAtRiskScore: total,
+		Scoring:          constants.ScoringFlat,
+		CreatedAt:        time.Now().Unix(),
+	}
+	if reached < int(previous) {
+		alert.Threshold = int(previous)
+		alert.Direction = constants.AlertDirectionDown
+	}
+
+	sent := s.dispatch(func() {
+		s.publishAlert(alertKeys, reached, previous, alert)
+	})
+	if !sent {
+		s.log.Error("all alert senders are busy, threshold alert dropped", map[string]interface{}{"alert": alert})
+		s.restoreAlertLevel(alertKeys, reached, previous, email)
+	}
+}
+
+// publishAlert sends an alert to the sink, the webhook sink retries with backoff so it
+// runs outside of the request that crossed the threshold
+func (s RiskService) publishAlert(alertKeys []string, reached int, previous int64, alert datatypes.ThresholdAlert) {
+	err := s.alerts.Publish(alert)
+	if err != nil {
+		s.log.Error("unable to publish threshold alert", map[string]interface{}{"alert": alert, "error": err})
+		s.restoreAlertLevel(alertKeys, reached, previous, alert.UserEmail)
+		return
+	}
+
+	s.log.Info("threshold alert published", map[string]interface{}{"email": alert.UserEmail, "threshold": alert.Threshold, "direction": alert.Direction})
+}
+
+// restoreAlertLevel puts back the threshold an email had before reached was stored so the next
+// score change alerts again, a threshold stored by a later change is kept
+func (s RiskService) restoreAlertLevel(alertKeys []string, reached int, previous int64, email string) {
+	reply, err := s.redis.RunScript(cache.RestoreAtRiskAlertLevel, alertKeys, reached, previous, constants.AtRiskEventTTL)
+	if err != nil {
+		s.log.Error("unable to restore alert threshold of email", map[string]interface{}{"email": email, "error": err})
+		return
+	}
+	if restored, _ := reply.(int64); restored == 0 {
+		s.log.Info("alert threshold of email changed since, not restored", map[string]interface{}{"email": email, "threshold": reached})
+	}
+}
+
+// domainThresholds returns the positive thresholds of a domain in ascending order, the
+// default thresholds are used for domains without their own
+func (s RiskService) domainThresholds(domain string) []int {
+	configured, ok := s.thresholds[domain]
+	if !ok {
+		configured = s.thresholds[constants.DefaultAlertDomain]
+	}
+
+	thresholds := []int{}
+	for _, threshold := range configured {
+		if threshold > 0 {
+			thresholds = append(thresholds, threshold)
+		}
+	}
+	sort.Ints(thresholds)
+	return thresholds
+}
+
+// reachedThreshold returns the highest threshold not above total, 0 when total is below all of them
+func reachedThreshold(thresholds []int, total int) int {
+	reached := 0
+	for _, threshold := range thresholds {
+		if total >= threshold {
+			reached = threshold
+		}
+	}
+	return reached
+}
+
+// emailDomain returns the lower cased domain of an email
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at == -1 {
+		return ""
+	}
+	return strings.ToLower(email[at+1:])
+}
@@ -0,0 +1,244 @@
+package atrisk
+
+import (
+	"testing"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	alertmocks "www-api/pkg/alert/mocks"
+	"www-api/pkg/cache"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestCheckThresholds(t *testing.T) {
+
+	type tests struct {
+		name        string
+		key         string
+		total       int
+		redisClient func() *mocks.RedisOps
+		sink        func() *alertmocks.Sink
+	}
+
+	thresholds := map[string][]int{
+		"default":     {100, 50},
+		"securly.com": {30},
+	}
+	alertKeys := []string{"atrisk:alert:user@other.com"}
+	testCases := []tests{
+		{
+			name:  "valid case, threshold reached",
+			key:   "user@other.com:1684231487",
+			total: 75,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SwapAtRiskAlertLevel, alertKeys, 50, constants.AtRiskEventTTL).Return(int64(0), nil).Once()
+				return moc
+			},
+			sink: func() *alertmocks.Sink {
+				moc := alertmocks.NewSink(t)
+				moc.On("Publish", mock.MatchedBy(func(alert datatypes.ThresholdAlert) bool {
+					return alert.UserEmail == "user@other.com" && alert.Domain == "other.com" && alert.AtRiskKey == "user@other.com:1684231487" &&
+						alert.Threshold == 50 && alert.Direction == "up" && alert.TotalAtRiskScore == 75 && alert.Scoring == "flat"
+				})).Return(nil).Once()
+				return moc
+			},
+		},
+		{
+			name:  "valid case, fell below reached threshold",
+			key:   "user@other.com:1684231487",
+			total: 60,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SwapAtRiskAlertLevel, alertKeys, 50, constants.AtRiskEventTTL).Return(int64(100), nil).Once()
+				return moc
+			},
+			sink: func() *alertmocks.Sink {
+				moc := alertmocks.NewSink(t)
+				moc.On("Publish", mock.MatchedBy(func(alert datatypes.ThresholdAlert) bool {
+					return alert.Threshold == 100 && alert.Direction == "down" && alert.TotalAtRiskScore == 60
+				})).Return(nil).Once()
+				return moc
+			},
+		},
+		{
+			name:  "valid case, same threshold is not alerted again",
+			key:   "user@other.com:1684231487",
+			total: 80,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SwapAtRiskAlertLevel, alertKeys, 50, constants.AtRiskEventTTL).Return(int64(50), nil).Once()
+				return moc
+			},
+			sink: func() *alertmocks.Sink {
+				return alertmocks.NewSink(t)
+			},
+		},
+		{
+			name:  "valid case, domain thresholds",
+			key:   "user@securly.com:1684231487",
+			total: 35,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SwapAtRiskAlertLevel, []string{"atrisk:alert:user@securly.com"}, 30, constants.AtRiskEventTTL).Return(int64(0), nil).Once()
+				return moc
+			},
+			sink: func() *alertmocks.Sink {
+				moc := alertmocks.NewSink(t)
+				moc.On("Publish", mock.MatchedBy(func(alert datatypes.ThresholdAlert) bool {
+					return alert.Domain == "securly.com" && alert.Threshold == 30 && alert.Direction == "up"
+				})).Return(nil).Once()
+				return moc
+			},
+		},
+		{
+			name:  "fail case, publish failure restores the previous threshold",
+			key:   "user@other.com:1684231487",
+			total: 120,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SwapAtRiskAlertLevel, alertKeys, 100, constants.AtRiskEventTTL).Return(int64(50), nil).Once()
+				moc.On("RunScript", cache.RestoreAtRiskAlertLevel, alertKeys, 100, int64(50), constants.AtRiskEventTTL).Return(int64(1), nil).Once()
+				return moc
+			},
+			sink: func() *alertmocks.Sink {
+				moc := alertmocks.NewSink(t)
+				moc.On("Publish", mock.Anything).Return(constants.WebhookUnavailable).Once()
+				return moc
+			},
+		},
+		{
+			name:  "fail case, publish failure keeps a threshold stored since",
+			key:   "user@other.com:1684231487",
+			total: 120,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SwapAtRiskAlertLevel, alertKeys, 100, constants.AtRiskEventTTL).Return(int64(50), nil).Once()
+				moc.On("RunScript", cache.RestoreAtRiskAlertLevel, alertKeys, 100, int64(50), constants.AtRiskEventTTL).Return(int64(0), nil).Once()
+				return moc
+			},
+			sink: func() *alertmocks.Sink {
+				moc := alertmocks.NewSink(t)
+				moc.On("Publish", mock.Anything).Return(constants.WebhookUnavailable).Once()
+				return moc
+			},
+		},
+		{
+			name:  "fail case, error updating threshold is not alerted",
+			key:   "user@other.com:1684231487",
+			total: 120,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SwapAtRiskAlertLevel, alertKeys, 100, constants.AtRiskEventTTL).Return(nil, test.CacheSetErr).Once()
+				return moc
+			},
+			sink: func() *alertmocks.Sink {
+				return alertmocks.NewSink(t)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient()}.WithAlerts(thresholds, tc.sink())
+			risk.dispatch = func(send func()) bool { send(); return true }
+			risk.checkThresholds(tc.key, tc.total)
+		})
+	}
+}
+
+func TestCheckThresholdsDisabled(t *testing.T) {
+	risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: mocks.NewRedisOps(t)}
+	risk.checkThresholds("user@other.com:1684231487", 120)
+
+	risk = risk.WithAlerts(map[string][]int{"securly.com": {30}}, alertmocks.NewSink(t))
+	risk.checkThresholds("user@other.com:1684231487", 120)
+}
+
+func TestCheckThresholdsDispatch(t *testing.T) {
+	release := make(chan struct{})
+	published := make(chan struct{})
+	moc := mocks.NewRedisOps(t)
+	moc.On("RunScript", cache.SwapAtRiskAlertLevel, []string{"atrisk:alert:user@other.com"}, 50, constants.AtRiskEventTTL).Return(int64(0), nil).Once()
+	sink := alertmocks.NewSink(t)
+	sink.On("Publish", mock.Anything).Run(func(args mock.Arguments) {
+		<-release
+		close(published)
+	}).Return(nil).Once()
+
+	risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: moc}.WithAlerts(map[string][]int{"default": {50}}, sink)
+	//returns while the sink is still sending the alert
+	risk.checkThresholds("user@other.com:1684231487", 75)
+	close(release)
+
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Errorf("expected alert to be published")
+	}
+}
+
+func TestCheckThresholdsSendersBusy(t *testing.T) {
+	risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}}.WithAlerts(map[string][]int{"default": {50}}, alertmocks.NewSink(t))
+	release := make(chan struct{})
+	for i := 0; i < constants.MaxAlertSenders; i++ {
+		if !risk.dispatch(func() { <-release }) {
+			t.Fatalf("expected sender %d to be free", i)
+		}
+	}
+
+	//the alert is dropped and its threshold given back while every sender is busy
+	moc := mocks.NewRedisOps(t)
+	moc.On("RunScript", cache.SwapAtRiskAlertLevel, []string{"atrisk:alert:user@other.com"}, 50, constants.AtRiskEventTTL).Return(int64(0), nil).Once()
+	moc.On("RunScript", cache.RestoreAtRiskAlertLevel, []string{"atrisk:alert:user@other.com"}, 50, int64(0), constants.AtRiskEventTTL).Return(int64(1), nil).Once()
+	risk.redis = moc
+	risk.checkThresholds("user@other.com:1684231487", 75)
+	close(release)
+
+	sent := make(chan struct{})
+	deadline := time.Now().Add(time.Second)
+	for !risk.dispatch(func() { close(sent) }) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a sender to be freed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	<-sent
+}
+
+func TestBatchCacheAlerts(t *testing.T) {
+	moc := mocks.NewRedisOps(t)
+	moc.On("Pipeline", mock.AnythingOfType("[]cache.Operation")).Return([]cache.OperationResult{
+		{Key: "user@other.com:1684231487", Reply: []interface{}{int64(1), int64(40)}},
+		{Key: "user@other.com:1684231488", Reply: []interface{}{int64(1), int64(75)}},
+	}, nil).Once()
+	moc.On("RunScript", cache.SwapAtRiskAlertLevel, []string{"atrisk:alert:user@other.com"}, 0, constants.AtRiskEventTTL).Return(int64(0), nil).Once()
+	moc.On("RunScript", cache.SwapAtRiskAlertLevel, []string{"atrisk:alert:user@other.com"}, 50, constants.AtRiskEventTTL).Return(int64(0), nil).Once()
+	sink := alertmocks.NewSink(t)
+	sink.On("Publish", mock.MatchedBy(func(alert datatypes.ThresholdAlert) bool {
+		return alert.AtRiskKey == "user@other.com:1684231488" && alert.Threshold == 50 && alert.Direction == "up" && alert.TotalAtRiskScore == 75 && alert.Scoring == "flat"
+	})).Return(nil).Once()
+
+	risk := RiskService{
+		log:   logger.ZapLogger{Logger: zap.NewExample()},
+		redis: moc,
+		saveAtRiskEvent: func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+			audited(t, "subject", entry)
+			return nil
+		},
+	}.WithAlerts(map[string][]int{"default": {50}}, sink)
+	risk.dispatch = func(send func()) bool { send(); return true }
+
+	_, err := risk.BatchCache([]datatypes.BatchCacheOperation{
+		{Action: "create", AtRiskKey: "user@other.com:1684231487", AtRiskValue: "40:scan:1dc13ds5c1651"},
+		{Action: "create", AtRiskKey: "user@other.com:1684231488", AtRiskValue: "35:scan:1dc13ds5c1652"},
+	}, "subject")
+	if err != nil {
+		t.Errorf("expected error %v got %v", nil, err)
+	}
+}
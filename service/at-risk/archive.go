@@ -0,0 +1,261 @@
+package atrisk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/pkg/aws/s3"
+	"www-api/pkg/cache"
+)
+
+// WithArchive returns a copy of the service that archives expiring events as ndjson
+// objects under prefix in bucket of store and restores them from there
+func (s RiskService) WithArchive(store s3.S3Action, bucket, prefix string) RiskService {
+	s.archive = store
+	s.archiveBucket = bucket
+	s.archivePrefix = strings.TrimSuffix(prefix, "/")
+	return s
+}
+
+// RunArchiver archives the events expiring within window every interval until ctx is done,
+// the window should be longer than the interval so that no event expires between two runs.
+// Every instance runs the archiver, a run is skipped while another instance holds the lock
+func (s RiskService) RunArchiver(ctx context.Context, interval, window time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.archiveRun(interval, window)
+		}
+	}
+}
+
+// archiveRun archives the expiring events once if no other instance is archiving
+func (s RiskService) archiveRun(interval, window time.Duration) {
+	release, held, err := s.lock(constants.ArchiverLock, interval)
+	if err != nil {
+		return
+	}
+	if !held {
+		s.log.Info("archiver is running on another instance", map[string]interface{}{})
+		return
+	}
+	defer release()
+
+	events, err := s.ArchiveExpiring(window)
+	if err != nil {
+		s.log.Error("error occured while archiving expiring events", map[string]interface{}{"archived": events, "error": err})
+		return
+	}
+	s.log.Info("archived expiring events", map[string]interface{}{"archived": events})
+}
+
+// ArchiveExpiring writes the events expiring within window to the archive, one ndjson
+// object per email and run. An event is written again only once its version changed,
+// and an expired event is dropped from the email aggregates after it was written.
+// An email that fails is retried on the next run, the errors of all failed emails are
+// returned together
+func (s RiskService) ArchiveExpiring(window time.Duration) (int, error) {
+	if s.archive == nil {
+		return 0, constants.ArchiveNotConfigured
+	}
+
+	keys, err := s.redis.GetKeys(fmt.Sprintf(constants.AtRiskExpiryKey, "*"))
+	if err != nil {
+		s.log.Error("unable to fetch expiry keys from redis", map[string]interface{}{"error": err})
+		return 0, err
+	}
+	sort.Strings(keys)
+
+	archived := 0
+	var failed errorList
+	for _, key := range keys {
+		email := strings.TrimPrefix(key, fmt.Sprintf(constants.AtRiskExpiryKey, ""))
+		events, err := s.archiveEmail(email, window)
+		archived += events
+		if err != nil {
+			s.log.Error("unable to archive expiring events of email", map[string]interface{}{"email": email, "error": err})
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) > 0 {
+		return archived, failed
+	}
+	return archived, nil
+}
+
+// archiveEmail writes the expiring events of an email to the archive and records their
+// versions, an email being erased is left for the next run
+func (s RiskService) archiveEmail(email string, window time.Duration) (int, error) {
+	release, held, err := s.lock(fmt.Sprintf(constants.EmailLock, email), constants.EmailLockSeconds*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	if !held {
+		s.log.Info("email is locked, archiving it on the next run", map[string]interface{}{"email": email})
+		return 0, nil
+	}
+	defer release()
+
+	now := time.Now().Unix()
+	until := now + int64(window.Seconds())
+	keys := append(atRiskIndexKeys(email), fmt.Sprintf(constants.AtRiskArchivedKey, email))
+
+	reply, err := s.redis.RunScript(cache.ExpiringAtRiskEvents, keys, until)
+	if err != nil {
+		s.log.Error("unable to fetch expiring events from redis", map[string]interface{}{"email": email, "error": err})
+		return 0, err
+	}
+
+	events, err := parseExpiringEvents(email, reply, now)
+	if err != nil {
+		s.log.Error("invalid expiring events received from redis", map[string]interface{}{"email": email, "reply": reply, "error": err})
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	var archive bytes.Buffer
+	encoder := json.NewEncoder(&archive)
+	args := []interface{}{now, until}
+	for _, event := range events {
+		_ = encoder.Encode(event)
+		_, timestamp := splitAtRiskKey(event.AtRiskKey)
+		args = append(args, timestamp, event.Version)
+	}
+
+	object := fmt.Sprintf("%s/%s/%d.ndjson", s.archivePrefix, email, now)
+	err = s.archive.PutObject(s.archiveBucket, object, archive.Bytes())
+	if err != nil {
+		s.log.Error("unable to write archive of expiring events", map[string]interface{}{"email": email, "object": object, "error": err})
+		return 0, err
+	}
+
+	_, err = s.redis.RunScript(cache.MarkAtRiskEventsArchived, keys, args...)
+	if err != nil {
+		s.log.Error("unable to mark events as archived in redis", map[string]interface{}{"email": email, "error": err})
+		return 0, err
+	}
+
+	s.log.Info("archived expiring events of email", map[string]interface{}{"email": email, "object": object, "events": len(events)})
+	return len(events), nil
+}
+
+// RestoreArchive creates the archived events of an email again, the latest archived copy
+// of each event is used. Events that are in cache already are skipped and left untouched
+func (s RiskService) RestoreArchive(email, actor string) (datatypes.AtRiskRestoreResponse, error) {
+	if s.archive == nil {
+		return datatypes.AtRiskRestoreResponse{}, constants.ArchiveNotConfigured
+	}
+
+	events, err := s.readArchive(email)
+	if err != nil {
+		return datatypes.AtRiskRestoreResponse{}, err
+	}
+
+	response := datatypes.AtRiskRestoreResponse{UserEmail: email, Restored: []string{}, Skipped: []string{}}
+	for _, event := range events {
+		_, err = s.CreateCache(event.AtRiskKey, event.AtRiskValue, datatypes.ScoringStrategy{}, actor, datatypes.Precondition{IfNoneMatch: constants.AnyETag})
+		if err == constants.PreconditionFailed {
+			response.Skipped = append(response.Skipped, event.AtRiskKey)
+			continue
+		}
+		if err != nil {
+			s.log.Error("error occured while restoring archived event", map[string]interface{}{"key": event.AtRiskKey, "error": err})
+			return datatypes.AtRiskRestoreResponse{}, err
+		}
+		response.Restored = append(response.Restored, event.AtRiskKey)
+	}
+
+	s.log.Info("restored archived events", map[string]interface{}{"email": email, "restored": len(response.Restored), "skipped": len(response.Skipped)})
+	return response, nil
+}
+
+// readArchive reads every archived event of an email ordered by key, keeping the
+// latest archived copy of an event archived more than once
+func (s RiskService) readArchive(email string) ([]datatypes.ArchivedAtRiskEvent, error) {
+	prefix := s.archivePrefix + "/" + email + "/"
+	objects, err := s.archive.ListObjects(s.archiveBucket, prefix)
+	if err != nil {
+		s.log.Error("unable to list archives of email", map[string]interface{}{"email": email, "error": err})
+		return nil, err
+	}
+
+	latest := map[string]datatypes.ArchivedAtRiskEvent{}
+	for _, object := range objects {
+		content, err := s.archive.GetObject(s.archiveBucket, object)
+		if err != nil {
+			s.log.Error("unable to read archive of email", map[string]interface{}{"object": object, "error": err})
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			var event datatypes.ArchivedAtRiskEvent
+			err = json.Unmarshal(scanner.Bytes(), &event)
+			if err != nil {
+				s.log.Error("invalid event in archive", map[string]interface{}{"object": object, "error": err})
+				return nil, err
+			}
+			if previous, ok := latest[event.AtRiskKey]; !ok || event.ArchivedAt >= previous.ArchivedAt {
+				latest[event.AtRiskKey] = event
+			}
+		}
+		if scanner.Err() != nil {
+			s.log.Error("unable to read archive of email", map[string]interface{}{"object": object, "error": scanner.Err()})
+			return nil, scanner.Err()
+		}
+	}
+
+	events := []datatypes.ArchivedAtRiskEvent{}
+	for _, event := range latest {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].AtRiskKey < events[j].AtRiskKey })
+	return events, nil
+}
+
+// parseExpiringEvents reads the {timestamp, value, expiry, version...} reply of the expiring events script
+func parseExpiringEvents(email string, reply interface{}, now int64) ([]datatypes.ArchivedAtRiskEvent, error) {
+	values, ok := reply.([]interface{})
+	if !ok || len(values)%4 != 0 {
+		return nil, constants.InvalidScriptReply
+	}
+
+	events := []datatypes.ArchivedAtRiskEvent{}
+	for i := 0; i < len(values); i += 4 {
+		timestamp, _ := values[i].(string)
+		value, _ := values[i+1].(string)
+		expiresAt, err := replyInt(values[i+2])
+		if err != nil {
+			return nil, err
+		}
+		version, ok := values[i+3].(int64)
+		if !ok {
+			return nil, constants.InvalidScriptReply
+		}
+		events = append(events, datatypes.ArchivedAtRiskEvent{
+			AtRiskKey:   email + ":" + timestamp,
+			AtRiskValue: value,
+			Version:     version,
+			ExpiresAt:   int64(expiresAt),
+			ArchivedAt:  now,
+		})
+	}
+	return events, nil
+}
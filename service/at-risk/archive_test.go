@@ -0,0 +1,334 @@
+package atrisk
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/aws/s3"
+	"www-api/pkg/cache"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestArchiveExpiring(t *testing.T) {
+
+	type tests struct {
+		name        string
+		redisClient func() *mocks.RedisOps
+		noArchive   bool
+		want        int
+		wantObjects int
+		wantLines   []string
+		wantErr     error
+	}
+
+	archiveKeys := []string{"atrisk:events:email@securly.com", "atrisk:expiry:email@securly.com", "atrisk:totals:email@securly.com", "atrisk:timeline:email@securly.com", "atrisk:mids:email@securly.com", "atrisk:versions:email@securly.com", "atrisk:archived:email@securly.com"}
+	testCases := []tests{
+		{
+			name: "valid case",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "atrisk:expiry:*").Return([]string{"atrisk:expiry:email@securly.com", "atrisk:expiry:other@securly.com"}, nil).Once()
+				expectLocks(moc, "email:email@securly.com", "email:other@securly.com")
+				moc.On("RunScript", cache.ExpiringAtRiskEvents, archiveKeys, mock.AnythingOfType("int64")).Return([]interface{}{
+					"1684231400", "27:chat:5gf8d54ss45s8", "1684231500", int64(3),
+					"1684231487", "45:scan:1dc13ds5c1651", "1684231600", int64(5),
+				}, nil).Once()
+				moc.On("RunScript", cache.MarkAtRiskEventsArchived, archiveKeys, mock.AnythingOfType("int64"), mock.AnythingOfType("int64"), "1684231400", int64(3), "1684231487", int64(5)).Return(int64(2), nil).Once()
+				moc.On("RunScript", cache.ExpiringAtRiskEvents, mock.Anything, mock.AnythingOfType("int64")).Return([]interface{}{}, nil).Once()
+				return moc
+			},
+			want:        2,
+			wantObjects: 1,
+			wantLines: []string{
+				`{"atRiskKey":"email@securly.com:1684231400","atRiskValue":"27:chat:5gf8d54ss45s8","version":3,"expiresAt":1684231500,`,
+				`{"atRiskKey":"email@securly.com:1684231487","atRiskValue":"45:scan:1dc13ds5c1651","version":5,"expiresAt":1684231600,`,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "valid case, email being erased is skipped",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "atrisk:expiry:*").Return([]string{"atrisk:expiry:email@securly.com"}, nil).Once()
+				moc.On("RunScript", cache.AcquireLock, []string{"atrisk:lock:email:email@securly.com"}, mock.AnythingOfType("string"), int64(300)).Return(int64(0), nil).Once()
+				return moc
+			},
+			want:        0,
+			wantObjects: 0,
+			wantErr:     nil,
+		},
+		{
+			name: "fail case, failed email does not stop the others",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "atrisk:expiry:*").Return([]string{"atrisk:expiry:broken@securly.com", "atrisk:expiry:email@securly.com"}, nil).Once()
+				expectLocks(moc, "email:broken@securly.com", "email:email@securly.com")
+				moc.On("RunScript", cache.ExpiringAtRiskEvents, mock.Anything, mock.AnythingOfType("int64")).Return(nil, test.CacheGetValueErr).Once()
+				moc.On("RunScript", cache.ExpiringAtRiskEvents, archiveKeys, mock.AnythingOfType("int64")).Return([]interface{}{"1684231400", "27:chat:5gf8d54ss45s8", "1684231500", int64(3)}, nil).Once()
+				moc.On("RunScript", cache.MarkAtRiskEventsArchived, archiveKeys, mock.Anything, mock.Anything, "1684231400", int64(3)).Return(int64(1), nil).Once()
+				return moc
+			},
+			want:        1,
+			wantObjects: 1,
+			wantLines: []string{
+				`{"atRiskKey":"email@securly.com:1684231400","atRiskValue":"27:chat:5gf8d54ss45s8","version":3,"expiresAt":1684231500,`,
+			},
+			wantErr: test.CacheGetValueErr,
+		},
+		{
+			name: "valid case, nothing expiring",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "atrisk:expiry:*").Return([]string{"atrisk:expiry:email@securly.com"}, nil).Once()
+				expectLocks(moc, "email:email@securly.com")
+				moc.On("RunScript", cache.ExpiringAtRiskEvents, archiveKeys, mock.AnythingOfType("int64")).Return([]interface{}{}, nil).Once()
+				return moc
+			},
+			want:        0,
+			wantObjects: 0,
+			wantErr:     nil,
+		},
+		{
+			name: "fail case, archive not configured",
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			noArchive: true,
+			want:      0,
+			wantErr:   constants.ArchiveNotConfigured,
+		},
+		{
+			name: "fail case, error fetching expiry keys",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "atrisk:expiry:*").Return(nil, test.CacheGetKeysErr).Once()
+				return moc
+			},
+			want:    0,
+			wantErr: test.CacheGetKeysErr,
+		},
+		{
+			name: "fail case, invalid script reply",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "atrisk:expiry:*").Return([]string{"atrisk:expiry:email@securly.com"}, nil).Once()
+				expectLocks(moc, "email:email@securly.com")
+				moc.On("RunScript", cache.ExpiringAtRiskEvents, archiveKeys, mock.AnythingOfType("int64")).Return([]interface{}{"1684231400", "27:chat:5gf8d54ss45s8"}, nil).Once()
+				return moc
+			},
+			want:    0,
+			wantErr: constants.InvalidScriptReply,
+		},
+		{
+			name: "fail case, error marking events as archived",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "atrisk:expiry:*").Return([]string{"atrisk:expiry:email@securly.com"}, nil).Once()
+				expectLocks(moc, "email:email@securly.com")
+				moc.On("RunScript", cache.ExpiringAtRiskEvents, archiveKeys, mock.AnythingOfType("int64")).Return([]interface{}{"1684231400", "27:chat:5gf8d54ss45s8", "1684231500", int64(3)}, nil).Once()
+				moc.On("RunScript", cache.MarkAtRiskEventsArchived, archiveKeys, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheSetErr).Once()
+				return moc
+			},
+			want:        0,
+			wantObjects: 1,
+			wantErr:     test.CacheSetErr,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := s3.NewFileSystem(t.TempDir())
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient()}
+			if !tc.noArchive {
+				risk = risk.WithArchive(store, "archive-bucket", "at-risk-archive/")
+			}
+
+			archived, err := risk.ArchiveExpiring(time.Hour)
+			if tc.want != archived {
+				t.Errorf("expected %d archived events got %d", tc.want, archived)
+			}
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+
+			objects, err := store.ListObjects("archive-bucket", "at-risk-archive/email@securly.com/")
+			if err != nil || len(objects) != tc.wantObjects {
+				t.Fatalf("expected %d archive objects got %v, error %v", tc.wantObjects, objects, err)
+			}
+			if len(tc.wantLines) == 0 {
+				return
+			}
+			content, _ := store.GetObject("archive-bucket", objects[0])
+			lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+			if len(lines) != len(tc.wantLines) {
+				t.Fatalf("expected %d archived lines got %q", len(tc.wantLines), lines)
+			}
+			for i, line := range lines {
+				if !strings.HasPrefix(line, tc.wantLines[i]) {
+					t.Errorf("expected archived line %s got %s", tc.wantLines[i], line)
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreArchive(t *testing.T) {
+
+	type tests struct {
+		name        string
+		objects     map[string]string
+		redisClient func() *mocks.RedisOps
+		noArchive   bool
+		want        datatypes.AtRiskRestoreResponse
+		wantErr     error
+	}
+
+	saved := func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+		audited(t, "subject", entry)
+		return nil
+	}
+	testCases := []tests{
+		{
+			name: "valid case, latest copy restored and live event skipped",
+			objects: map[string]string{
+				"at-risk-archive/email@securly.com/1684231000.ndjson": `{"atRiskKey":"email@securly.com:1684231400","atRiskValue":"27:chat:5gf8d54ss45s8","version":3,"expiresAt":1684231500,"archivedAt":1684231000}
+{"atRiskKey":"email@securly.com:1684231487","atRiskValue":"45:scan:1dc13ds5c1651","version":5,"expiresAt":1684231600,"archivedAt":1684231000}
+`,
+				"at-risk-archive/email@securly.com/1684232000.ndjson": `{"atRiskKey":"email@securly.com:1684231400","atRiskValue":"30:chat:5gf8d54ss45s8","version":4,"expiresAt":1684231900,"archivedAt":1684232000}
+`,
+				"at-risk-archive/email@securly.community/1684231000.ndjson": `{"atRiskKey":"email@securly.community:1684231400","atRiskValue":"90:chat:5gf8d54ss45s8","version":1,"expiresAt":1684231500,"archivedAt":1684231000}
+`,
+			},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, "1684231400", "30:chat:5gf8d54ss45s8", constants.AtRiskEventTTL, mock.AnythingOfType("int64"), "", "*").Return([]interface{}{int64(1), int64(30), nil, int64(9)}, nil).Once()
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, "1684231487", "45:scan:1dc13ds5c1651", constants.AtRiskEventTTL, mock.AnythingOfType("int64"), "", "*").Return([]interface{}{int64(-1), int64(30), nil, int64(6)}, nil).Once()
+				return moc
+			},
+			want: datatypes.AtRiskRestoreResponse{
+				UserEmail: "email@securly.com",
+				Restored:  []string{"email@securly.com:1684231400"},
+				Skipped:   []string{"email@securly.com:1684231487"},
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "valid case, nothing archived",
+			objects: map[string]string{},
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			want:    datatypes.AtRiskRestoreResponse{UserEmail: "email@securly.com", Restored: []string{}, Skipped: []string{}},
+			wantErr: nil,
+		},
+		{
+			name:    "fail case, archive not configured",
+			objects: map[string]string{},
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			noArchive: true,
+			want:      datatypes.AtRiskRestoreResponse{},
+			wantErr:   constants.ArchiveNotConfigured,
+		},
+		{
+			name: "fail case, error restoring event",
+			objects: map[string]string{
+				"at-risk-archive/email@securly.com/1684231000.ndjson": `{"atRiskKey":"email@securly.com:1684231400","atRiskValue":"27:chat:5gf8d54ss45s8","version":3,"expiresAt":1684231500,"archivedAt":1684231000}
+`,
+			},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheSetErr).Once()
+				return moc
+			},
+			want:    datatypes.AtRiskRestoreResponse{},
+			wantErr: test.CacheSetErr,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := s3.NewFileSystem(t.TempDir())
+			for key, content := range tc.objects {
+				err := store.PutObject("archive-bucket", key, []byte(content))
+				if err != nil {
+					t.Fatalf("unable to write archive object %s: %v", key, err)
+				}
+			}
+
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient(), saveAtRiskEvent: saved}
+			if !tc.noArchive {
+				risk = risk.WithArchive(store, "archive-bucket", "at-risk-archive")
+			}
+
+			response, err := risk.RestoreArchive("email@securly.com", "subject")
+			if !reflect.DeepEqual(tc.want, response) {
+				t.Errorf("expected response %+v got %+v", tc.want, response)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestArchiveRun(t *testing.T) {
+
+	type tests struct {
+		name        string
+		redisClient func() *mocks.RedisOps
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case, lock taken",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.AcquireLock, []string{"atrisk:lock:archiver"}, mock.AnythingOfType("string"), int64(3600)).Return(int64(1), nil).Once()
+				moc.On("GetKeys", "atrisk:expiry:*").Return([]string{}, nil).Once()
+				moc.On("RunScript", cache.ReleaseLock, []string{"atrisk:lock:archiver"}, mock.AnythingOfType("string")).Return(int64(1), nil).Once()
+				return moc
+			},
+		},
+		{
+			name: "valid case, archiver running on another instance",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.AcquireLock, []string{"atrisk:lock:archiver"}, mock.AnythingOfType("string"), int64(3600)).Return(int64(0), nil).Once()
+				return moc
+			},
+		},
+		{
+			name: "fail case, error taking lock",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.AcquireLock, []string{"atrisk:lock:archiver"}, mock.AnythingOfType("string"), int64(3600)).Return(nil, test.CacheSetErr).Once()
+				return moc
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient()}
+			risk = risk.WithArchive(s3.NewFileSystem(t.TempDir()), "archive-bucket", "at-risk-archive/")
+			risk.archiveRun(time.Hour, 2*time.Hour)
+		})
+	}
+}
+
+// expectLocks expects each named lock to be taken and released once
+func expectLocks(moc *mocks.RedisOps, names ...string) {
+	for _, name := range names {
+		key := []string{"atrisk:lock:" + name}
+		moc.On("RunScript", cache.AcquireLock, key, mock.AnythingOfType("string"), mock.AnythingOfType("int64")).Return(int64(1), nil).Once()
+		moc.On("RunScript", cache.ReleaseLock, key, mock.AnythingOfType("string")).Return(int64(1), nil).Once()
+	}
+}
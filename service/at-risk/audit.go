@@ -0,0 +1,25 @@
+package atrisk
+
+import (
+	"strconv"
+	"www-api/internal/datatypes"
+)
+
+// GetAudit returns a page of the audit log matching the filters of the request, newest first
+func (s RiskService) GetAudit(request datatypes.AtRiskAuditRequest) (datatypes.AtRiskAuditResponse, error) {
+	//fetching one more entry tells whether there is a next page
+	limit := request.Limit
+	request.Limit++
+	entries, err := s.getAtRiskAudit(request)
+	if err != nil {
+		s.log.Error("unable to fetch audit entries from database", map[string]interface{}{"request": request, "error": err})
+		return datatypes.AtRiskAuditResponse{}, err
+	}
+
+	response := datatypes.AtRiskAuditResponse{Entries: entries}
+	if len(entries) > limit {
+		response.Entries = entries[:limit]
+		response.NextCursor = strconv.FormatInt(entries[limit-1].ID, 10)
+	}
+	return response, nil
+}
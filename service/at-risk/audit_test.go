@@ -0,0 +1,175 @@
+package atrisk
+
+import (
+	"testing"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// audited checks an audit entry written along with a ledger change is recorded under actor
+func audited(t *testing.T, actor string, entry datatypes.AtRiskAuditEntry) {
+	if entry.Actor != actor || entry.CreatedAt == 0 {
+		t.Errorf("unexpected audit entry %+v", entry)
+	}
+}
+
+func TestAuditEntries(t *testing.T) {
+
+	type tests struct {
+		name        string
+		redisClient func() *mocks.RedisOps
+		change      func(risk RiskService) error
+		wantEntry   datatypes.AtRiskAuditEntry
+	}
+
+	previous := "40:chat:1dc13ds5c1651"
+	value := "45:scan:1dc13ds5c1651"
+	testCases := []tests{
+		{
+			name: "create replacing an event",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{int64(1), int64(72), previous}, nil).Once()
+				return moc
+			},
+			change: func(risk RiskService) error {
+				_, err := risk.CreateCache("email@securly.com:1684231487", value, datatypes.ScoringStrategy{}, "subject", datatypes.Precondition{})
+				return err
+			},
+			wantEntry: datatypes.AtRiskAuditEntry{Actor: "subject", Action: "create", UserEmail: "email@securly.com", AtRiskKey: "email@securly.com:1684231487", BeforeValue: &previous, AfterValue: &value, TotalAtRiskScore: 72},
+		},
+		{
+			name: "create of a new event",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{int64(1), int64(45), nil}, nil).Once()
+				return moc
+			},
+			change: func(risk RiskService) error {
+				_, err := risk.CreateCache("email@securly.com:1684231487", value, datatypes.ScoringStrategy{}, "subject", datatypes.Precondition{})
+				return err
+			},
+			wantEntry: datatypes.AtRiskAuditEntry{Actor: "subject", Action: "create", UserEmail: "email@securly.com", AtRiskKey: "email@securly.com:1684231487", AfterValue: &value, TotalAtRiskScore: 45},
+		},
+		{
+			name: "delete",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.DeleteAtRiskEvent, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{int64(1), int64(0), previous}, nil).Once()
+				return moc
+			},
+			change: func(risk RiskService) error {
+				_, err := risk.DeleteCache("email@securly.com:1684231487", datatypes.ScoringStrategy{}, "subject", datatypes.Precondition{})
+				return err
+			},
+			wantEntry: datatypes.AtRiskAuditEntry{Actor: "subject", Action: "delete", UserEmail: "email@securly.com", AtRiskKey: "email@securly.com:1684231487", BeforeValue: &previous, TotalAtRiskScore: 0},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries := []datatypes.AtRiskAuditEntry{}
+			risk := RiskService{
+				log:   logger.ZapLogger{Logger: zap.NewExample()},
+				redis: tc.redisClient(),
+				saveAtRiskEvent: func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+					entries = append(entries, entry)
+					return nil
+				},
+				deleteAtRiskEvent: func(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error {
+					entries = append(entries, entry)
+					return nil
+				},
+			}
+			err := tc.change(risk)
+			assert.NoError(t, err)
+			if assert.Len(t, entries, 1) {
+				assert.NotZero(t, entries[0].CreatedAt)
+				entries[0].CreatedAt = 0
+				assert.Equal(t, tc.wantEntry, entries[0])
+			}
+		})
+	}
+}
+
+func TestAuditFailure(t *testing.T) {
+	moc := mocks.NewRedisOps(t)
+	moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, "1684231487", "45:scan:1dc13ds5c1651", constants.AtRiskEventTTL, mock.AnythingOfType("int64")).Return([]interface{}{int64(1), int64(45), nil, int64(3)}, nil).Once()
+	moc.On("RunScript", cache.DeleteAtRiskEvent, mock.Anything, "1684231487", mock.AnythingOfType("int64"), "3", "").Return([]interface{}{int64(1), int64(0), "45:scan:1dc13ds5c1651"}, nil).Once()
+	risk := RiskService{
+		log:   logger.ZapLogger{Logger: zap.NewExample()},
+		redis: moc,
+		saveAtRiskEvent: func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+			return test.DBSomethingWentWrongErr
+		},
+	}
+
+	_, err := risk.CreateCache("email@securly.com:1684231487", "45:scan:1dc13ds5c1651", datatypes.ScoringStrategy{}, "subject", datatypes.Precondition{})
+	if err != test.DBSomethingWentWrongErr {
+		t.Errorf("expected error %v got %v", test.DBSomethingWentWrongErr, err)
+	}
+}
+
+func TestGetAudit(t *testing.T) {
+
+	type tests struct {
+		name           string
+		request        datatypes.AtRiskAuditRequest
+		getAtRiskAudit func(request datatypes.AtRiskAuditRequest) ([]datatypes.AtRiskAuditEntry, error)
+		wantResp       datatypes.AtRiskAuditResponse
+		wantErr        error
+	}
+
+	entries := []datatypes.AtRiskAuditEntry{{ID: 9, Actor: "subject"}, {ID: 7, Actor: "subject"}, {ID: 4, Actor: "subject"}}
+	testCases := []tests{
+		{
+			name:    "valid case, next page available",
+			request: datatypes.AtRiskAuditRequest{Actor: "subject", Limit: 2},
+			getAtRiskAudit: func(request datatypes.AtRiskAuditRequest) ([]datatypes.AtRiskAuditEntry, error) {
+				if request.Limit != 3 || request.Actor != "subject" {
+					return nil, test.DBSomethingWentWrongErr
+				}
+				return entries, nil
+			},
+			wantResp: datatypes.AtRiskAuditResponse{Entries: entries[:2], NextCursor: "7"},
+			wantErr:  nil,
+		},
+		{
+			name:    "valid case, last page",
+			request: datatypes.AtRiskAuditRequest{Limit: 5},
+			getAtRiskAudit: func(request datatypes.AtRiskAuditRequest) ([]datatypes.AtRiskAuditEntry, error) {
+				return entries, nil
+			},
+			wantResp: datatypes.AtRiskAuditResponse{Entries: entries},
+			wantErr:  nil,
+		},
+		{
+			name:    "fail case, error fetching entries",
+			request: datatypes.AtRiskAuditRequest{Limit: 5},
+			getAtRiskAudit: func(request datatypes.AtRiskAuditRequest) ([]datatypes.AtRiskAuditEntry, error) {
+				return nil, test.DBSomethingWentWrongErr
+			},
+			wantResp: datatypes.AtRiskAuditResponse{},
+			wantErr:  test.DBSomethingWentWrongErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, getAtRiskAudit: tc.getAtRiskAudit}
+			resp, err := risk.GetAudit(tc.request)
+			assert.Equal(t, tc.wantResp, resp)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package atrisk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+)
+
+// WithChangeFeed returns a copy of the service that publishes every change of the events
+// of an email on the pub/sub channel of its domain, see SubscribeChanges
+func (s RiskService) WithChangeFeed() RiskService {
+	s.changeFeed = true
+	return s
+}
+
+// publishChange publishes a change on the channel of the domain of its email, failures
+// are only logged as a missed change never fails the request that made it
+func (s RiskService) publishChange(change datatypes.AtRiskChange) {
+	if !s.changeFeed {
+		return
+	}
+
+	change.Domain = emailDomain(change.UserEmail)
+	change.CreatedAt = time.Now().Unix()
+	message, err := json.Marshal(change)
+	if err != nil {
+		s.log.Error("unable to encode at-risk change", map[string]interface{}{"change": change, "error": err})
+		return
+	}
+
+	err = s.redis.Publish(fmt.Sprintf(constants.AtRiskChangesKey, change.Domain), string(message))
+	if err != nil {
+		s.log.Error("unable to publish at-risk change", map[string]interface{}{"change": change, "error": err})
+	}
+}
+
+// SubscribeChanges returns the changes of the events of the emails and of every email of
+// domain until ctx is done. The channels of their domains are subscribed to and changes
+// of other emails are dropped here, invalid messages are logged and skipped
+func (s RiskService) SubscribeChanges(ctx context.Context, emails []string, domain string) (<-chan datatypes.AtRiskChange, error) {
+	domain = strings.ToLower(domain)
+	wanted := map[string]bool{}
+	domains := map[string]bool{}
+	if domain != "" {
+		domains[domain] = true
+	}
+	for _, email := range emails {
+		wanted[strings.ToLower(email)] = true
+		domains[emailDomain(email)] = true
+	}
+
+	channels := []string{}
+	for name := range domains {
+		channels = append(channels, fmt.Sprintf(constants.AtRiskChangesKey, name))
+	}
+	sort.Strings(channels)
+
+	messages, err := s.redis.Subscribe(ctx, channels...)
+	if err != nil {
+		s.log.Error("unable to subscribe to at-risk changes", map[string]interface{}{"channels": channels, "error": err})
+		return nil, err
+	}
+
+	changes := make(chan datatypes.AtRiskChange)
+	go func() {
+		defer close(changes)
+		for message := range messages {
+			var change datatypes.AtRiskChange
+			err := json.Unmarshal([]byte(message), &change)
+			if err != nil {
+				s.log.Error("invalid at-risk change received from redis", map[string]interface{}{"message": message, "error": err})
+				continue
+			}
+			if change.Domain != domain && !wanted[strings.ToLower(change.UserEmail)] {
+				continue
+			}
+
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes, nil
+}
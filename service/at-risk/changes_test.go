@@ -0,0 +1,192 @@
+package atrisk
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestPublishChange(t *testing.T) {
+
+	type tests struct {
+		name        string
+		change      datatypes.AtRiskChange
+		redisClient func() *mocks.RedisOps
+		noFeed      bool
+	}
+
+	published := func(prefix string) interface{} {
+		return mock.MatchedBy(func(message string) bool { return strings.HasPrefix(message, prefix) })
+	}
+	testCases := []tests{
+		{
+			name:   "valid case",
+			change: datatypes.AtRiskChange{Action: "create", UserEmail: "email@Securly.com", AtRiskKey: "email@Securly.com:1684231487", TotalAtRiskScore: 45, Version: 3},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Publish", "atrisk:changes:securly.com", published(`{"action":"create","userEmail":"email@Securly.com","domain":"securly.com","atRiskKey":"email@Securly.com:1684231487","totalAtRiskScore":45,"version":3,"createdAt":`)).Return(nil).Once()
+				return moc
+			},
+		},
+		{
+			name:   "valid case, change feed disabled",
+			change: datatypes.AtRiskChange{Action: "create", UserEmail: "email@securly.com"},
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			noFeed: true,
+		},
+		{
+			name:   "fail case, error publishing is only logged",
+			change: datatypes.AtRiskChange{Action: "delete", UserEmail: "email@securly.com", AtRiskKey: "email@securly.com:1684231487"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Publish", "atrisk:changes:securly.com", mock.Anything).Return(test.CacheSetErr).Once()
+				return moc
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient()}
+			if !tc.noFeed {
+				risk = risk.WithChangeFeed()
+			}
+
+			risk.publishChange(tc.change)
+		})
+	}
+}
+
+func TestBatchCacheChanges(t *testing.T) {
+	published := func(prefix string) interface{} {
+		return mock.MatchedBy(func(message string) bool { return strings.HasPrefix(message, prefix) })
+	}
+	moc := mocks.NewRedisOps(t)
+	moc.On("Pipeline", mock.AnythingOfType("[]cache.Operation")).Return([]cache.OperationResult{
+		{Key: "email@securly.com:1684231487", Reply: []interface{}{int64(1), int64(45), false, int64(7)}},
+		{Key: "email@securly.com:1684231400", Reply: []interface{}{int64(1), int64(18), "27:chat:5gf8d54ss45s8"}},
+		{Key: "email@securly.com:1684231500", Err: test.CacheSetErr},
+	}, nil).Once()
+	moc.On("Publish", "atrisk:changes:securly.com", published(`{"action":"create","userEmail":"email@securly.com","domain":"securly.com","atRiskKey":"email@securly.com:1684231487","totalAtRiskScore":45,"version":7,"createdAt":`)).Return(nil).Once()
+	moc.On("Publish", "atrisk:changes:securly.com", published(`{"action":"delete","userEmail":"email@securly.com","domain":"securly.com","atRiskKey":"email@securly.com:1684231400","totalAtRiskScore":18,"createdAt":`)).Return(nil).Once()
+
+	risk := RiskService{
+		log:   logger.ZapLogger{Logger: zap.NewExample()},
+		redis: moc,
+		saveAtRiskEvent: func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+			audited(t, "subject", entry)
+			return nil
+		},
+		deleteAtRiskEvent: func(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error {
+			audited(t, "subject", entry)
+			return nil
+		},
+	}.WithChangeFeed()
+
+	_, err := risk.BatchCache([]datatypes.BatchCacheOperation{
+		{Action: "create", AtRiskKey: "email@securly.com:1684231487", AtRiskValue: "45:scan:1dc13ds5c1651"},
+		{Action: "delete", AtRiskKey: "email@securly.com:1684231400"},
+		{Action: "create", AtRiskKey: "email@securly.com:1684231500", AtRiskValue: "5:scan:6dc13ds5c1651"},
+	}, "subject")
+	if err != nil {
+		t.Errorf("expected error %v got %v", nil, err)
+	}
+}
+
+func TestSubscribeChanges(t *testing.T) {
+
+	type tests struct {
+		name        string
+		emails      []string
+		domain      string
+		messages    []string
+		redisClient func(messages <-chan string) *mocks.RedisOps
+		want        []datatypes.AtRiskChange
+		wantErr     error
+	}
+
+	testCases := []tests{
+		{
+			name:   "valid case, only the requested emails",
+			emails: []string{"Email@securly.com", "other@securly.community"},
+			messages: []string{
+				`{"action":"create","userEmail":"email@securly.com","domain":"securly.com","atRiskKey":"email@securly.com:1684231487","totalAtRiskScore":45,"version":3,"createdAt":1684231500}`,
+				`{"action":"create","userEmail":"someone@securly.com","domain":"securly.com","atRiskKey":"someone@securly.com:1684231487","totalAtRiskScore":10,"version":4,"createdAt":1684231500}`,
+				`not a change`,
+				`{"action":"delete","userEmail":"other@securly.community","domain":"securly.community","atRiskKey":"other@securly.community:1684231400","totalAtRiskScore":0,"createdAt":1684231600}`,
+			},
+			redisClient: func(messages <-chan string) *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Subscribe", mock.Anything, "atrisk:changes:securly.com", "atrisk:changes:securly.community").Return(messages, nil).Once()
+				return moc
+			},
+			want: []datatypes.AtRiskChange{
+				{Action: "create", UserEmail: "email@securly.com", Domain: "securly.com", AtRiskKey: "email@securly.com:1684231487", TotalAtRiskScore: 45, Version: 3, CreatedAt: 1684231500},
+				{Action: "delete", UserEmail: "other@securly.community", Domain: "securly.community", AtRiskKey: "other@securly.community:1684231400", CreatedAt: 1684231600},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "valid case, every email of the domain",
+			domain: "Securly.com",
+			messages: []string{
+				`{"action":"extend-ttl","userEmail":"someone@securly.com","domain":"securly.com","atRiskKey":"someone@securly.com:*","totalAtRiskScore":10,"createdAt":1684231500}`,
+			},
+			redisClient: func(messages <-chan string) *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Subscribe", mock.Anything, "atrisk:changes:securly.com").Return(messages, nil).Once()
+				return moc
+			},
+			want: []datatypes.AtRiskChange{
+				{Action: "extend-ttl", UserEmail: "someone@securly.com", Domain: "securly.com", AtRiskKey: "someone@securly.com:*", TotalAtRiskScore: 10, CreatedAt: 1684231500},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "fail case, error subscribing",
+			domain: "securly.com",
+			redisClient: func(messages <-chan string) *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Subscribe", mock.Anything, "atrisk:changes:securly.com").Return(nil, test.CacheGetValueErr).Once()
+				return moc
+			},
+			want:    nil,
+			wantErr: test.CacheGetValueErr,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			messages := make(chan string, len(tc.messages))
+			for _, message := range tc.messages {
+				messages <- message
+			}
+			close(messages)
+
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient(messages)}
+			changes, err := risk.SubscribeChanges(context.Background(), tc.emails, tc.domain)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+
+			var got []datatypes.AtRiskChange
+			if changes != nil {
+				for change := range changes {
+					got = append(got, change)
+				}
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("expected changes %+v got %+v", tc.want, got)
+			}
+		})
+	}
+}
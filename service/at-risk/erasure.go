@@ -0,0 +1,213 @@
+package atrisk
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/pkg/cache"
+)
+
+// WithAlertStream returns a copy of the service that also erases the entries of an email
+// from the alert stream name, see alert.Stream
+func (s RiskService) WithAlertStream(name string) RiskService {
+	s.alertStream = name
+	return s
+}
+
+// EraseEmail deletes everything kept about an email: its rows of the AtRiskEvent ledger,
+// its AtRiskScore rows, its archived objects, the at-risk values of its audit entries, its
+// keys in redis and its alert stream entries, in that order so that no rebuild or restore
+// running meanwhile brings the events back. An erasure that failed half way can be run
+// again. A dry run only lists what would be deleted.
+// The email lock keeps the archiver from writing archives of the email meanwhile, a write
+// that lands during the erasure is found afterwards and fails it with ErasureIncomplete
+func (s RiskService) EraseEmail(email string, dryRun bool) (datatypes.AtRiskErasure, error) {
+	if !dryRun {
+		release, held, err := s.lock(fmt.Sprintf(constants.EmailLock, email), constants.EmailLockSeconds*time.Second)
+		if err != nil {
+			return datatypes.AtRiskErasure{}, err
+		}
+		if !held {
+			s.log.Error("email is locked, not erasing it", map[string]interface{}{"email": email})
+			return datatypes.AtRiskErasure{}, constants.EmailLocked
+		}
+		defer release()
+	}
+
+	keys, err := s.emailKeys(email)
+	if err != nil {
+		return datatypes.AtRiskErasure{}, err
+	}
+
+	objects, err := s.archivedObjects(email)
+	if err != nil {
+		return datatypes.AtRiskErasure{}, err
+	}
+
+	erasure := datatypes.AtRiskErasure{RedisKeys: keys, ArchiveObjects: objects}
+	if dryRun {
+		erasure.LedgerRows, err = s.countAtRiskEvents(email)
+		if err != nil {
+			s.log.Error("unable to count events of email in ledger", map[string]interface{}{"email": email, "error": err})
+			return datatypes.AtRiskErasure{}, err
+		}
+
+		scores, err := s.getAtRiskScore([]string{email})
+		if err != nil {
+			s.log.Error("unable to fetch scores from database", map[string]interface{}{"email": email, "error": err})
+			return datatypes.AtRiskErasure{}, err
+		}
+		erasure.AtRiskScoreRows = int64(len(scores))
+
+		erasure.AuditRows, err = s.countAuditValues(email)
+		if err != nil {
+			s.log.Error("unable to count audit entries of email", map[string]interface{}{"email": email, "error": err})
+			return datatypes.AtRiskErasure{}, err
+		}
+
+		erasure.AlertEntries, err = s.alertEntries(email, false)
+		if err != nil {
+			return datatypes.AtRiskErasure{}, err
+		}
+
+		s.log.Info("dry run, not erasing email", map[string]interface{}{"email": email, "erasure": erasure})
+		return erasure, nil
+	}
+
+	erasure.LedgerRows, err = s.eraseAtRiskEvents(email)
+	if err != nil {
+		s.log.Error("unable to erase events of email from ledger", map[string]interface{}{"email": email, "error": err})
+		return datatypes.AtRiskErasure{}, err
+	}
+
+	erasure.AtRiskScoreRows, err = s.deleteAtRiskScore(email)
+	if err != nil {
+		s.log.Error("unable to delete scores of email from database", map[string]interface{}{"email": email, "error": err})
+		return datatypes.AtRiskErasure{}, err
+	}
+
+	for _, object := range objects {
+		err = s.archive.DeleteObject(s.archiveBucket, object)
+		if err != nil {
+			s.log.Error("unable to delete archive of email", map[string]interface{}{"email": email, "object": object, "error": err})
+			return datatypes.AtRiskErasure{}, err
+		}
+	}
+
+	erasure.AuditRows, err = s.redactAudit(email)
+	if err != nil {
+		s.log.Error("unable to redact audit entries of email", map[string]interface{}{"email": email, "error": err})
+		return datatypes.AtRiskErasure{}, err
+	}
+
+	//the domain keys let the script drop the email from the domain aggregates
+	scriptKeys := append(atRiskIndexKeys(email), atRiskDomainKeys(emailDomain(email))...)
+	scriptKeys = append(scriptKeys, fmt.Sprintf(constants.AtRiskAlertKey, email), fmt.Sprintf(constants.AtRiskArchivedKey, email))
+	for _, key := range keys {
+		if isAtRiskKey(key) {
+			scriptKeys = append(scriptKeys, key)
+		}
+	}
+	_, err = s.redis.RunScript(cache.EraseAtRiskEmail, scriptKeys, time.Now().Unix())
+	if err != nil {
+		s.log.Error("unable to erase keys of email from redis", map[string]interface{}{"email": email, "error": err})
+		return datatypes.AtRiskErasure{}, err
+	}
+
+	erasure.AlertEntries, err = s.alertEntries(email, true)
+	if err != nil {
+		return datatypes.AtRiskErasure{}, err
+	}
+
+	remainingKeys, err := s.emailKeys(email)
+	if err != nil {
+		return datatypes.AtRiskErasure{}, err
+	}
+	remainingObjects, err := s.archivedObjects(email)
+	if err != nil {
+		return datatypes.AtRiskErasure{}, err
+	}
+	remainingAlerts, err := s.alertEntries(email, false)
+	if err != nil {
+		return datatypes.AtRiskErasure{}, err
+	}
+	if len(remainingKeys) != 0 || len(remainingObjects) != 0 || remainingAlerts != 0 {
+		s.log.Error("email was written while it was erased", map[string]interface{}{"email": email, "keys": remainingKeys, "objects": remainingObjects, "alerts": remainingAlerts})
+		return datatypes.AtRiskErasure{}, constants.ErasureIncomplete
+	}
+
+	s.log.Info("erased email", map[string]interface{}{"email": email, "erasure": erasure})
+	return erasure, nil
+}
+
+// emailKeys returns the keys of an email in redis, its event keys followed by the
+// aggregate, alert and archive keys that exist
+func (s RiskService) emailKeys(email string) ([]string, error) {
+	eventKeys, err := s.redis.GetKeys(email + ":*")
+	if err != nil {
+		s.log.Error("unable to fetch all keys from redis", map[string]interface{}{"email": email, "error": err})
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, key := range eventKeys {
+		if isAtRiskKey(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	emailKeys := append(atRiskIndexKeys(email), fmt.Sprintf(constants.AtRiskAlertKey, email), fmt.Sprintf(constants.AtRiskArchivedKey, email))
+	for _, key := range emailKeys {
+		exists, err := s.redis.Exists(key)
+		if err != nil {
+			s.log.Error("unable to check key in redis", map[string]interface{}{"key": key, "error": err})
+			return nil, err
+		}
+		if exists {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// archivedObjects lists the archive objects of an email, none when the archive is not configured
+func (s RiskService) archivedObjects(email string) ([]string, error) {
+	if s.archive == nil {
+		return []string{}, nil
+	}
+
+	objects, err := s.archive.ListObjects(s.archiveBucket, s.archivePrefix+"/"+email+"/")
+	if err != nil {
+		s.log.Error("unable to list archives of email", map[string]interface{}{"email": email, "error": err})
+		return nil, err
+	}
+	return objects, nil
+}
+
+// alertEntries removes the entries of an email from the alert stream and returns their number,
+// they are only counted unless erase is set. There are none without an alert stream
+func (s RiskService) alertEntries(email string, erase bool) (int64, error) {
+	if s.alertStream == "" {
+		return 0, nil
+	}
+
+	mode := "0"
+	if erase {
+		mode = "1"
+	}
+	reply, err := s.redis.RunScript(cache.EraseStreamEntries, []string{s.alertStream}, "userEmail", email, mode)
+	if err != nil {
+		s.log.Error("unable to erase alerts of email from stream", map[string]interface{}{"email": email, "stream": s.alertStream, "error": err})
+		return 0, err
+	}
+
+	entries, ok := reply.(int64)
+	if !ok {
+		s.log.Error("invalid number of alerts received from redis", map[string]interface{}{"email": email, "reply": reply})
+		return 0, constants.InvalidScriptReply
+	}
+	return entries, nil
+}
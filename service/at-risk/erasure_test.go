@@ -0,0 +1,245 @@
+package atrisk
+
+import (
+	"reflect"
+	"testing"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/aws/s3"
+	"www-api/pkg/cache"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestEraseEmail(t *testing.T) {
+
+	type tests struct {
+		name              string
+		dryRun            bool
+		redisClient       func() *mocks.RedisOps
+		eraseAtRiskEvents func(email string) (int64, error)
+		deleteAtRiskScore func(email string) (int64, error)
+		redactAudit       func(email string) (int64, error)
+		want              datatypes.AtRiskErasure
+		wantObjects       int
+		wantErr           error
+	}
+
+	existing := func(moc *mocks.RedisOps) {
+		moc.On("GetKeys", "email@securly.com:*").Return([]string{"email@securly.com:1684231487", "email@securly.com:1684231400", "email@securly.com:notanevent"}, nil).Once()
+		for _, key := range atRiskIndexKeys("email@securly.com") {
+			moc.On("Exists", key).Return(true, nil).Once()
+		}
+		moc.On("Exists", "atrisk:alert:email@securly.com").Return(false, nil).Once()
+		moc.On("Exists", "atrisk:archived:email@securly.com").Return(true, nil).Once()
+	}
+	erased := func(moc *mocks.RedisOps, eventKeys ...string) {
+		moc.On("GetKeys", "email@securly.com:*").Return(eventKeys, nil).Once()
+		for _, key := range atRiskIndexKeys("email@securly.com") {
+			moc.On("Exists", key).Return(len(eventKeys) != 0, nil).Once()
+		}
+		moc.On("Exists", "atrisk:alert:email@securly.com").Return(false, nil).Once()
+		moc.On("Exists", "atrisk:archived:email@securly.com").Return(false, nil).Once()
+	}
+	redisKeys := []string{
+		"email@securly.com:1684231400", "email@securly.com:1684231487",
+		"atrisk:events:email@securly.com", "atrisk:expiry:email@securly.com", "atrisk:totals:email@securly.com", "atrisk:timeline:email@securly.com", "atrisk:mids:email@securly.com", "atrisk:versions:email@securly.com",
+		"atrisk:archived:email@securly.com",
+	}
+	scriptKeys := append(atRiskIndexKeys("email@securly.com"), atRiskDomainKeys("securly.com")...)
+	scriptKeys = append(scriptKeys, "atrisk:alert:email@securly.com", "atrisk:archived:email@securly.com", "email@securly.com:1684231400", "email@securly.com:1684231487")
+	alerts := func(moc *mocks.RedisOps, mode string, entries int64) {
+		moc.On("RunScript", cache.EraseStreamEntries, []string{"atrisk:alerts"}, "userEmail", "email@securly.com", mode).Return(entries, nil).Once()
+	}
+	redacted := func(email string) (int64, error) { return 5, nil }
+	testCases := []tests{
+		{
+			name: "valid case",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				expectLocks(moc, "email:email@securly.com")
+				existing(moc)
+				moc.On("RunScript", cache.EraseAtRiskEmail, scriptKeys, mock.AnythingOfType("int64")).Return(int64(9), nil).Once()
+				alerts(moc, "1", 2)
+				erased(moc)
+				alerts(moc, "0", 0)
+				return moc
+			},
+			eraseAtRiskEvents: func(email string) (int64, error) { return 3, nil },
+			deleteAtRiskScore: func(email string) (int64, error) { return 2, nil },
+			redactAudit:       redacted,
+			want: datatypes.AtRiskErasure{
+				RedisKeys:       redisKeys,
+				AtRiskScoreRows: 2,
+				LedgerRows:      3,
+				ArchiveObjects:  []string{"at-risk-archive/email@securly.com/1684231000.ndjson"},
+				AuditRows:       5,
+				AlertEntries:    2,
+			},
+			wantObjects: 0,
+			wantErr:     nil,
+		},
+		{
+			name:   "valid case, dry run",
+			dryRun: true,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				existing(moc)
+				alerts(moc, "0", 2)
+				return moc
+			},
+			want: datatypes.AtRiskErasure{
+				RedisKeys:       redisKeys,
+				AtRiskScoreRows: 1,
+				LedgerRows:      4,
+				ArchiveObjects:  []string{"at-risk-archive/email@securly.com/1684231000.ndjson"},
+				AuditRows:       6,
+				AlertEntries:    2,
+			},
+			wantObjects: 1,
+			wantErr:     nil,
+		},
+		{
+			name: "fail case, email locked by the archiver",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.AcquireLock, []string{"atrisk:lock:email:email@securly.com"}, mock.AnythingOfType("string"), int64(300)).Return(int64(0), nil).Once()
+				return moc
+			},
+			want:        datatypes.AtRiskErasure{},
+			wantObjects: 1,
+			wantErr:     constants.EmailLocked,
+		},
+		{
+			name: "fail case, event written while erasing",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				expectLocks(moc, "email:email@securly.com")
+				existing(moc)
+				moc.On("RunScript", cache.EraseAtRiskEmail, scriptKeys, mock.AnythingOfType("int64")).Return(int64(9), nil).Once()
+				alerts(moc, "1", 2)
+				erased(moc, "email@securly.com:1684231500")
+				alerts(moc, "0", 0)
+				return moc
+			},
+			eraseAtRiskEvents: func(email string) (int64, error) { return 3, nil },
+			deleteAtRiskScore: func(email string) (int64, error) { return 2, nil },
+			redactAudit:       redacted,
+			want:              datatypes.AtRiskErasure{},
+			wantObjects:       0,
+			wantErr:           constants.ErasureIncomplete,
+		},
+		{
+			name: "fail case, alert published while erasing",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				expectLocks(moc, "email:email@securly.com")
+				existing(moc)
+				moc.On("RunScript", cache.EraseAtRiskEmail, scriptKeys, mock.AnythingOfType("int64")).Return(int64(9), nil).Once()
+				alerts(moc, "1", 2)
+				erased(moc)
+				alerts(moc, "0", 1)
+				return moc
+			},
+			eraseAtRiskEvents: func(email string) (int64, error) { return 3, nil },
+			deleteAtRiskScore: func(email string) (int64, error) { return 2, nil },
+			redactAudit:       redacted,
+			want:              datatypes.AtRiskErasure{},
+			wantObjects:       0,
+			wantErr:           constants.ErasureIncomplete,
+		},
+		{
+			name: "fail case, error redacting audit entries keeps redis",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				expectLocks(moc, "email:email@securly.com")
+				existing(moc)
+				return moc
+			},
+			eraseAtRiskEvents: func(email string) (int64, error) { return 3, nil },
+			deleteAtRiskScore: func(email string) (int64, error) { return 2, nil },
+			redactAudit:       func(email string) (int64, error) { return 0, test.DBSomethingWentWrongErr },
+			want:              datatypes.AtRiskErasure{},
+			wantObjects:       0,
+			wantErr:           test.DBSomethingWentWrongErr,
+		},
+		{
+			name: "fail case, error fetching keys",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				expectLocks(moc, "email:email@securly.com")
+				moc.On("GetKeys", "email@securly.com:*").Return(nil, test.CacheGetKeysErr).Once()
+				return moc
+			},
+			want:        datatypes.AtRiskErasure{},
+			wantObjects: 1,
+			wantErr:     test.CacheGetKeysErr,
+		},
+		{
+			name: "fail case, error erasing ledger keeps everything else",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				expectLocks(moc, "email:email@securly.com")
+				existing(moc)
+				return moc
+			},
+			eraseAtRiskEvents: func(email string) (int64, error) { return 0, test.DBSomethingWentWrongErr },
+			want:              datatypes.AtRiskErasure{},
+			wantObjects:       1,
+			wantErr:           test.DBSomethingWentWrongErr,
+		},
+		{
+			name: "fail case, error erasing redis keys",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				expectLocks(moc, "email:email@securly.com")
+				existing(moc)
+				moc.On("RunScript", cache.EraseAtRiskEmail, scriptKeys, mock.AnythingOfType("int64")).Return(nil, test.CacheDeleteKeyErr).Once()
+				return moc
+			},
+			eraseAtRiskEvents: func(email string) (int64, error) { return 3, nil },
+			deleteAtRiskScore: func(email string) (int64, error) { return 2, nil },
+			redactAudit:       redacted,
+			want:              datatypes.AtRiskErasure{},
+			wantObjects:       0,
+			wantErr:           test.CacheDeleteKeyErr,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := s3.NewFileSystem(t.TempDir())
+			_ = store.PutObject("archive-bucket", "at-risk-archive/email@securly.com/1684231000.ndjson", []byte("{}\n"))
+			_ = store.PutObject("archive-bucket", "at-risk-archive/email@securly.community/1684231000.ndjson", []byte("{}\n"))
+
+			risk := RiskService{
+				log:   logger.ZapLogger{Logger: zap.NewExample()},
+				redis: tc.redisClient(),
+				getAtRiskScore: func(emails []string) ([]datatypes.RiskScore, error) {
+					return []datatypes.RiskScore{{Email: "email@securly.com", SelfHarmScore: "51"}}, nil
+				},
+				countAtRiskEvents: func(email string) (int64, error) { return 4, nil },
+				countAuditValues:  func(email string) (int64, error) { return 6, nil },
+				eraseAtRiskEvents: tc.eraseAtRiskEvents,
+				deleteAtRiskScore: tc.deleteAtRiskScore,
+				redactAudit:       tc.redactAudit,
+			}.WithArchive(store, "archive-bucket", "at-risk-archive").WithAlertStream("atrisk:alerts")
+
+			erasure, err := risk.EraseEmail("email@securly.com", tc.dryRun)
+			if !reflect.DeepEqual(tc.want, erasure) {
+				t.Errorf("expected erasure %+v got %+v", tc.want, erasure)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+
+			objects, _ := store.ListObjects("archive-bucket", "at-risk-archive/email@securly.com/")
+			if len(objects) != tc.wantObjects {
+				t.Errorf("expected %d archive objects left got %v", tc.wantObjects, objects)
+			}
+		})
+	}
+}
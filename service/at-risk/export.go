@@ -0,0 +1,27 @@
+package atrisk
+
+import (
+	"time"
+	"www-api/internal/datatypes"
+)
+
+// ExportEmail returns what is kept about an email for a subject access export: every
+// cached event ordered by timestamp and the self harm scores of its AtRiskScore rows
+func (s RiskService) ExportEmail(email string) (datatypes.AtRiskExport, error) {
+	events, err := s.cachedEvents(email, time.Now())
+	if err != nil {
+		return datatypes.AtRiskExport{}, err
+	}
+
+	rows, err := s.getAtRiskScore([]string{email})
+	if err != nil {
+		s.log.Error("unable to fetch scores from database", map[string]interface{}{"email": email, "error": err})
+		return datatypes.AtRiskExport{}, err
+	}
+
+	scores := make([]string, 0, len(rows))
+	for _, row := range rows {
+		scores = append(scores, row.SelfHarmScore)
+	}
+	return datatypes.AtRiskExport{Events: events, Scores: scores}, nil
+}
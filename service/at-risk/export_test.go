@@ -0,0 +1,96 @@
+package atrisk
+
+import (
+	"reflect"
+	"testing"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestExportEmail(t *testing.T) {
+
+	type tests struct {
+		name           string
+		redisClient    func() *mocks.RedisOps
+		getAtRiskScore func(emails []string) ([]datatypes.RiskScore, error)
+		want           datatypes.AtRiskExport
+		wantErr        error
+	}
+
+	listed := func() *mocks.RedisOps {
+		moc := mocks.NewRedisOps(t)
+		moc.On("RunScript", cache.ListAtRiskEvents, atRiskIndexKeys("email@securly.com"), mock.AnythingOfType("int64"), "-inf", "+inf", "asc", -1).Return([]interface{}{
+			"1684231400", "27:chat:5gf8d54ss45s8",
+			"1684231487", "45:scan:1dc13ds5c1651",
+		}, nil).Once()
+		return moc
+	}
+	testCases := []tests{
+		{
+			name:        "valid case",
+			redisClient: listed,
+			getAtRiskScore: func(emails []string) ([]datatypes.RiskScore, error) {
+				return []datatypes.RiskScore{{Email: "email@securly.com", SelfHarmScore: "27"}, {Email: "email@securly.com", SelfHarmScore: "45"}}, nil
+			},
+			want: datatypes.AtRiskExport{
+				Events: []datatypes.AtRiskEventItem{
+					{AtRiskKey: "email@securly.com:1684231400", Timestamp: "1684231400", Event: datatypes.AtRiskEvent{Score: 27, Category: "chat", MessageID: "5gf8d54ss45s8"}},
+					{AtRiskKey: "email@securly.com:1684231487", Timestamp: "1684231487", Event: datatypes.AtRiskEvent{Score: 45, Category: "scan", MessageID: "1dc13ds5c1651"}},
+				},
+				Scores: []string{"27", "45"},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "valid case, nothing kept",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskEvents, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{}, nil).Once()
+				return moc
+			},
+			getAtRiskScore: func(emails []string) ([]datatypes.RiskScore, error) {
+				return nil, nil
+			},
+			want:    datatypes.AtRiskExport{Events: []datatypes.AtRiskEventItem{}, Scores: []string{}},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, error fetching events",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskEvents, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheGetValueErr).Once()
+				return moc
+			},
+			want:    datatypes.AtRiskExport{},
+			wantErr: test.CacheGetValueErr,
+		},
+		{
+			name:        "fail case, error fetching scores",
+			redisClient: listed,
+			getAtRiskScore: func(emails []string) ([]datatypes.RiskScore, error) {
+				return nil, test.DBSomethingWentWrongErr
+			},
+			want:    datatypes.AtRiskExport{},
+			wantErr: test.DBSomethingWentWrongErr,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient(), getAtRiskScore: tc.getAtRiskScore}
+
+			export, err := risk.ExportEmail("email@securly.com")
+			if !reflect.DeepEqual(tc.want, export) {
+				t.Errorf("expected export %+v got %+v", tc.want, export)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package atrisk
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"www-api/internal/constants"
+	"www-api/pkg/cache"
+)
+
+// lock takes the named lock for ttl, held is false when another instance has it. The
+// returned release drops the lock and must be called once the work is done
+func (s RiskService) lock(name string, ttl time.Duration) (release func(), held bool, err error) {
+	token := make([]byte, 16)
+	_, err = rand.Read(token)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := []string{fmt.Sprintf(constants.AtRiskLockKey, name)}
+	holder := hex.EncodeToString(token)
+	reply, err := s.redis.RunScript(cache.AcquireLock, key, holder, int64(ttl.Seconds()))
+	if err != nil {
+		s.log.Error("unable to take lock in redis", map[string]interface{}{"lock": name, "error": err})
+		return nil, false, err
+	}
+	if taken, _ := reply.(int64); taken != 1 {
+		return nil, false, nil
+	}
+
+	release = func() {
+		_, err := s.redis.RunScript(cache.ReleaseLock, key, holder)
+		if err != nil {
+			s.log.Error("unable to release lock in redis", map[string]interface{}{"lock": name, "error": err})
+		}
+	}
+	return release, true, nil
+}
+
+// errorList combines the errors of a run that carried on past failures
+type errorList []error
+
+func (e errorList) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Is reports whether any of the combined errors is target
+func (e errorList) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
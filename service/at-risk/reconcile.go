@@ -0,0 +1,214 @@
+package atrisk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+)
+
+// storedTotals is an email as found in redis and in the AtRiskScore table, the stores
+// may keep it in a different case
+type storedTotals struct {
+	redisEmail string
+	mysqlEmail string
+	mysqlTotal int
+	unparsable bool
+}
+
+// RunReconciler reconciles the redis totals with the AtRiskScore table every interval until
+// ctx is done and logs the drift report of each run
+func (s RiskService) RunReconciler(ctx context.Context, interval time.Duration, repair string, examples int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.Reconcile(repair, examples)
+			if err == constants.RepairInProgress {
+				s.log.Info("at-risk totals are being repaired on another instance", map[string]interface{}{"repair": repair})
+				continue
+			}
+			if err != nil {
+				s.log.Error("error occured while reconciling at-risk totals", map[string]interface{}{"error": err})
+				continue
+			}
+			s.log.Info("reconciled at-risk totals", map[string]interface{}{"report": report})
+		}
+	}
+}
+
+// Reconcile compares the total of the cached events of every email in redis with the sum of
+// its rows in the AtRiskScore table and reports the emails that differ, at most examples of
+// them are listed. With repair mysql the rows of a drifted email are replaced by its redis
+// total, with repair ledger its cached events are restored from the AtRiskEvent ledger since a
+// redis total is always the sum of the cached events and AtRiskScore only keeps totals, the
+// restored total may still differ from AtRiskScore when the ledger does. A failed repair of an email is
+// reported and does not stop the others. Only one instance repairs at a time, a repair
+// while another one runs fails with RepairInProgress. Emails with a self_harm_score that
+// is not a number are reported as unparsable and neither compared nor repaired
+func (s RiskService) Reconcile(repair string, examples int) (datatypes.AtRiskDriftReport, error) {
+	if repair != "" && repair != constants.RepairMySQL && repair != constants.RepairLedger {
+		return datatypes.AtRiskDriftReport{}, constants.InvalidRepair
+	}
+	if examples <= 0 {
+		examples = constants.DefaultDriftExamples
+	}
+
+	if repair != "" {
+		release, held, err := s.lock(constants.ReconcilerLock, constants.ReconcilerLockSeconds*time.Second)
+		if err != nil {
+			return datatypes.AtRiskDriftReport{}, err
+		}
+		if !held {
+			return datatypes.AtRiskDriftReport{}, constants.RepairInProgress
+		}
+		defer release()
+	}
+
+	stored, err := s.storedTotals()
+	if err != nil {
+		return datatypes.AtRiskDriftReport{}, err
+	}
+
+	emails := []string{}
+	for email := range stored {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+
+	report := datatypes.AtRiskDriftReport{CheckedAt: time.Now().Unix(), Emails: len(emails), Examples: []datatypes.AtRiskDrift{}, Repair: repair}
+	for _, email := range emails {
+		totals := stored[email]
+		if totals.unparsable {
+			report.Unparsable = append(report.Unparsable, totals.mysqlEmail)
+			continue
+		}
+
+		drift := datatypes.AtRiskDrift{UserEmail: email, MySQLTotal: totals.mysqlTotal}
+		if totals.redisEmail != "" {
+			drift.UserEmail = totals.redisEmail
+			drift.RedisTotal, err = s.getTotalAtRiskScore(totals.redisEmail)
+			if err != nil {
+				return datatypes.AtRiskDriftReport{}, err
+			}
+		}
+		if drift.RedisTotal == drift.MySQLTotal {
+			report.Matching++
+			continue
+		}
+
+		switch {
+		case totals.mysqlEmail == "":
+			drift.Kind = constants.DriftMissingInMySQL
+			report.MissingInMySQL++
+		case totals.redisEmail == "":
+			drift.Kind = constants.DriftMissingInRedis
+			report.MissingInRedis++
+		default:
+			drift.Kind = constants.DriftMismatch
+			report.Mismatched++
+		}
+		report.Drifted++
+		if len(report.Examples) < examples {
+			report.Examples = append(report.Examples, drift)
+		}
+
+		if repair == "" {
+			continue
+		}
+		if s.repairDrift(repair, totals, drift) {
+			report.Repaired++
+			continue
+		}
+		report.Unrepaired = append(report.Unrepaired, drift.UserEmail)
+	}
+
+	s.log.Info("reconciled redis totals with AtRiskScore", map[string]interface{}{"emails": report.Emails, "drifted": report.Drifted, "unparsable": len(report.Unparsable), "repair": repair, "repaired": report.Repaired})
+	return report, nil
+}
+
+// storedTotals returns every email of redis and of the AtRiskScore table keyed by its
+// lower cased email, with the sum of its AtRiskScore rows. An email with a row that is
+// not a number is marked unparsable as its sum is unknown
+func (s RiskService) storedTotals() (map[string]storedTotals, error) {
+	scores, err := s.getAllAtRiskScores()
+	if err != nil {
+		s.log.Error("unable to fetch scores from database", map[string]interface{}{"error": err})
+		return nil, err
+	}
+
+	stored := map[string]storedTotals{}
+	for _, score := range scores {
+		email := strings.ToLower(score.Email)
+		totals := stored[email]
+		totals.mysqlEmail = score.Email
+		value, err := strconv.Atoi(strings.TrimSpace(score.SelfHarmScore))
+		if err != nil {
+			s.log.Error("invalid self_harm_score in AtRiskScore", map[string]interface{}{"email": score.Email, "score": score.SelfHarmScore})
+			totals.unparsable = true
+		}
+		totals.mysqlTotal += value
+		stored[email] = totals
+	}
+
+	keys, err := s.redis.GetKeys(fmt.Sprintf(constants.AtRiskExpiryKey, "*"))
+	if err != nil {
+		s.log.Error("unable to fetch expiry keys from redis", map[string]interface{}{"error": err})
+		return nil, err
+	}
+	for _, key := range keys {
+		email := strings.TrimPrefix(key, fmt.Sprintf(constants.AtRiskExpiryKey, ""))
+		totals := stored[strings.ToLower(email)]
+		totals.redisEmail = email
+		stored[strings.ToLower(email)] = totals
+	}
+	return stored, nil
+}
+
+// repairDrift repairs a drifted email, the AtRiskScore rows from redis or the cached events
+// from the ledger, and reports whether the repair succeeded
+func (s RiskService) repairDrift(repair string, totals storedTotals, drift datatypes.AtRiskDrift) bool {
+	if repair == constants.RepairLedger {
+		_, err := s.RebuildCache(drift.UserEmail)
+		if err != nil {
+			return false
+		}
+		total, err := s.getTotalAtRiskScore(drift.UserEmail)
+		if err != nil {
+			return false
+		}
+		if total != drift.MySQLTotal {
+			s.log.Info("restored events from ledger, the ledger differs from AtRiskScore", map[string]interface{}{"email": drift.UserEmail, "redisTotal": total, "mysqlTotal": drift.MySQLTotal})
+		}
+		return true
+	}
+
+	if totals.mysqlEmail == "" {
+		err := s.saveAtRiskScore(drift.UserEmail, strconv.Itoa(drift.RedisTotal))
+		if err != nil {
+			s.log.Error("unable to save redis total into database", map[string]interface{}{"email": drift.UserEmail, "error": err})
+			return false
+		}
+		return true
+	}
+
+	//the rows are replaced in one transaction so that a failed repair keeps them
+	score := ""
+	if drift.RedisTotal != 0 {
+		score = strconv.Itoa(drift.RedisTotal)
+	}
+	err := s.replaceAtRiskScore(totals.mysqlEmail, drift.UserEmail, score)
+	if err != nil {
+		s.log.Error("unable to replace drifted scores in database", map[string]interface{}{"email": totals.mysqlEmail, "error": err})
+		return false
+	}
+	return true
+}
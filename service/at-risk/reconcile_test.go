@@ -0,0 +1,276 @@
+package atrisk
+
+import (
+	"reflect"
+	"testing"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestReconcile(t *testing.T) {
+
+	type tests struct {
+		name               string
+		repair             string
+		examples           int
+		scores             []datatypes.RiskScore
+		scoresErr          error
+		redisClient        func() *mocks.RedisOps
+		getAtRiskEvents    func(email string, now int64) ([]datatypes.AtRiskEventRecord, error)
+		saveAtRiskScore    func(email, score string) error
+		replaceAtRiskScore func(previousEmail, email, score string) error
+		want               datatypes.AtRiskDriftReport
+		wantErr            error
+	}
+
+	scores := []datatypes.RiskScore{
+		{Email: "a@securly.com", SelfHarmScore: "10"},
+		{Email: "a@securly.com", SelfHarmScore: "5"},
+		{Email: "B@securly.com", SelfHarmScore: "7"},
+		{Email: "c@securly.com", SelfHarmScore: "not a score"},
+		{Email: "e@securly.com", SelfHarmScore: "8"},
+	}
+	totals := func(moc *mocks.RedisOps) {
+		moc.On("GetKeys", "atrisk:expiry:*").Return([]string{"atrisk:expiry:a@securly.com", "atrisk:expiry:b@securly.com", "atrisk:expiry:d@securly.com"}, nil).Once()
+		moc.On("RunScript", cache.AtRiskTotal, atRiskIndexKeys("a@securly.com"), mock.AnythingOfType("int64")).Return(int64(15), nil).Once()
+		moc.On("RunScript", cache.AtRiskTotal, atRiskIndexKeys("b@securly.com"), mock.AnythingOfType("int64")).Return(int64(3), nil).Once()
+		moc.On("RunScript", cache.AtRiskTotal, atRiskIndexKeys("d@securly.com"), mock.AnythingOfType("int64")).Return(int64(4), nil).Once()
+	}
+	testCases := []tests{
+		{
+			name:     "valid case, report only",
+			examples: 2,
+			scores:   scores,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				totals(moc)
+				return moc
+			},
+			want: datatypes.AtRiskDriftReport{
+				Emails:         5,
+				Matching:       1,
+				Drifted:        3,
+				Mismatched:     1,
+				MissingInRedis: 1,
+				MissingInMySQL: 1,
+				Examples: []datatypes.AtRiskDrift{
+					{UserEmail: "b@securly.com", Kind: constants.DriftMismatch, RedisTotal: 3, MySQLTotal: 7},
+					{UserEmail: "d@securly.com", Kind: constants.DriftMissingInMySQL, RedisTotal: 4, MySQLTotal: 0},
+				},
+				Unparsable: []string{"c@securly.com"},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "valid case, repair mysql",
+			repair: constants.RepairMySQL,
+			scores: scores,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				expectLocks(moc, "reconciler")
+				totals(moc)
+				return moc
+			},
+			saveAtRiskScore: func(email, score string) error {
+				if email != "d@securly.com" || score != "4" {
+					t.Errorf("unexpected score %s saved for %s", score, email)
+				}
+				return test.DBSomethingWentWrongErr
+			},
+			replaceAtRiskScore: func(previousEmail, email, score string) error {
+				replaced := previousEmail + " " + email + " " + score
+				if replaced != "B@securly.com b@securly.com 3" && replaced != "e@securly.com e@securly.com " {
+					t.Errorf("unexpected scores of %s replaced by %s for %s", previousEmail, score, email)
+				}
+				return nil
+			},
+			want: datatypes.AtRiskDriftReport{
+				Emails:         5,
+				Matching:       1,
+				Drifted:        3,
+				Mismatched:     1,
+				MissingInRedis: 1,
+				MissingInMySQL: 1,
+				Examples: []datatypes.AtRiskDrift{
+					{UserEmail: "b@securly.com", Kind: constants.DriftMismatch, RedisTotal: 3, MySQLTotal: 7},
+					{UserEmail: "d@securly.com", Kind: constants.DriftMissingInMySQL, RedisTotal: 4, MySQLTotal: 0},
+					{UserEmail: "e@securly.com", Kind: constants.DriftMissingInRedis, RedisTotal: 0, MySQLTotal: 8},
+				},
+				Repair:     constants.RepairMySQL,
+				Repaired:   2,
+				Unrepaired: []string{"d@securly.com"},
+				Unparsable: []string{"c@securly.com"},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "valid case, failed replace keeps the rows",
+			repair: constants.RepairMySQL,
+			scores: []datatypes.RiskScore{{Email: "b@securly.com", SelfHarmScore: "7"}},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				expectLocks(moc, "reconciler")
+				moc.On("GetKeys", "atrisk:expiry:*").Return([]string{"atrisk:expiry:b@securly.com"}, nil).Once()
+				moc.On("RunScript", cache.AtRiskTotal, atRiskIndexKeys("b@securly.com"), mock.AnythingOfType("int64")).Return(int64(3), nil).Once()
+				return moc
+			},
+			replaceAtRiskScore: func(previousEmail, email, score string) error {
+				return test.DBSomethingWentWrongErr
+			},
+			want: datatypes.AtRiskDriftReport{
+				Emails:     1,
+				Drifted:    1,
+				Mismatched: 1,
+				Examples: []datatypes.AtRiskDrift{
+					{UserEmail: "b@securly.com", Kind: constants.DriftMismatch, RedisTotal: 3, MySQLTotal: 7},
+				},
+				Repair:     constants.RepairMySQL,
+				Unrepaired: []string{"b@securly.com"},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "fail case, repair running on another instance",
+			repair: constants.RepairMySQL,
+			scores: scores,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.AcquireLock, []string{"atrisk:lock:reconciler"}, mock.AnythingOfType("string"), int64(3600)).Return(int64(0), nil).Once()
+				return moc
+			},
+			want:    datatypes.AtRiskDriftReport{},
+			wantErr: constants.RepairInProgress,
+		},
+		{
+			name:   "valid case, restore events from ledger",
+			repair: constants.RepairLedger,
+			scores: []datatypes.RiskScore{{Email: "b@securly.com", SelfHarmScore: "7"}},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				expectLocks(moc, "reconciler")
+				moc.On("GetKeys", "atrisk:expiry:*").Return([]string{"atrisk:expiry:b@securly.com"}, nil).Once()
+				moc.On("RunScript", cache.AtRiskTotal, atRiskIndexKeys("b@securly.com"), mock.AnythingOfType("int64")).Return(int64(3), nil).Once()
+				moc.On("Pipeline", mock.Anything).Return([]cache.OperationResult{{Key: "b@securly.com:1684231487"}}, nil).Once()
+				moc.On("GetKeys", "b@securly.com:*").Return([]string{"b@securly.com:1684231400", "b@securly.com:1684231487"}, nil).Once()
+				moc.On("RunScript", cache.RebuildAtRiskIndex, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(int64(2), nil).Once()
+				moc.On("RunScript", cache.AtRiskTotal, atRiskIndexKeys("b@securly.com"), mock.AnythingOfType("int64")).Return(int64(7), nil).Once()
+				return moc
+			},
+			getAtRiskEvents: func(email string, now int64) ([]datatypes.AtRiskEventRecord, error) {
+				return []datatypes.AtRiskEventRecord{{UserEmail: email, EventTimestamp: "1684231487", AtRiskValue: "4:chat:5gf8d54ss45s8", ExpiresAt: now + 60}}, nil
+			},
+			want: datatypes.AtRiskDriftReport{
+				Emails:     1,
+				Drifted:    1,
+				Mismatched: 1,
+				Examples: []datatypes.AtRiskDrift{
+					{UserEmail: "b@securly.com", Kind: constants.DriftMismatch, RedisTotal: 3, MySQLTotal: 7},
+				},
+				Repair:   constants.RepairLedger,
+				Repaired: 1,
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "valid case, restore events from ledger that differs from AtRiskScore",
+			repair: constants.RepairLedger,
+			scores: []datatypes.RiskScore{{Email: "b@securly.com", SelfHarmScore: "7"}},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				expectLocks(moc, "reconciler")
+				moc.On("GetKeys", "atrisk:expiry:*").Return([]string{"atrisk:expiry:b@securly.com"}, nil).Once()
+				moc.On("RunScript", cache.AtRiskTotal, atRiskIndexKeys("b@securly.com"), mock.AnythingOfType("int64")).Return(int64(3), nil).Once()
+				moc.On("Pipeline", mock.Anything).Return([]cache.OperationResult{{Key: "b@securly.com:1684231487"}}, nil).Once()
+				moc.On("GetKeys", "b@securly.com:*").Return([]string{"b@securly.com:1684231400", "b@securly.com:1684231487"}, nil).Once()
+				moc.On("RunScript", cache.RebuildAtRiskIndex, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(int64(2), nil).Once()
+				moc.On("RunScript", cache.AtRiskTotal, atRiskIndexKeys("b@securly.com"), mock.AnythingOfType("int64")).Return(int64(4), nil).Once()
+				return moc
+			},
+			getAtRiskEvents: func(email string, now int64) ([]datatypes.AtRiskEventRecord, error) {
+				return []datatypes.AtRiskEventRecord{{UserEmail: email, EventTimestamp: "1684231487", AtRiskValue: "4:chat:5gf8d54ss45s8", ExpiresAt: now + 60}}, nil
+			},
+			want: datatypes.AtRiskDriftReport{
+				Emails:     1,
+				Drifted:    1,
+				Mismatched: 1,
+				Examples: []datatypes.AtRiskDrift{
+					{UserEmail: "b@securly.com", Kind: constants.DriftMismatch, RedisTotal: 3, MySQLTotal: 7},
+				},
+				Repair:   constants.RepairLedger,
+				Repaired: 1,
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "fail case, invalid repair",
+			repair: "elastic",
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			want:    datatypes.AtRiskDriftReport{},
+			wantErr: constants.InvalidRepair,
+		},
+		{
+			name:      "fail case, error fetching scores",
+			scoresErr: test.DBSomethingWentWrongErr,
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			want:    datatypes.AtRiskDriftReport{},
+			wantErr: test.DBSomethingWentWrongErr,
+		},
+		{
+			name:   "fail case, error fetching expiry keys",
+			scores: scores,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "atrisk:expiry:*").Return(nil, test.CacheGetKeysErr).Once()
+				return moc
+			},
+			want:    datatypes.AtRiskDriftReport{},
+			wantErr: test.CacheGetKeysErr,
+		},
+		{
+			name:   "fail case, error fetching redis total",
+			scores: scores,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "atrisk:expiry:*").Return([]string{"atrisk:expiry:a@securly.com"}, nil).Once()
+				moc.On("RunScript", cache.AtRiskTotal, atRiskIndexKeys("a@securly.com"), mock.AnythingOfType("int64")).Return(nil, test.CacheGetValueErr).Once()
+				return moc
+			},
+			want:    datatypes.AtRiskDriftReport{},
+			wantErr: test.CacheGetValueErr,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{
+				log:   logger.ZapLogger{Logger: zap.NewExample()},
+				redis: tc.redisClient(),
+				getAllAtRiskScores: func() ([]datatypes.RiskScore, error) {
+					return tc.scores, tc.scoresErr
+				},
+				getAtRiskEvents:    tc.getAtRiskEvents,
+				saveAtRiskScore:    tc.saveAtRiskScore,
+				replaceAtRiskScore: tc.replaceAtRiskScore,
+			}
+
+			report, err := risk.Reconcile(tc.repair, tc.examples)
+			report.CheckedAt = 0
+			if !reflect.DeepEqual(tc.want, report) {
+				t.Errorf("expected report %+v got %+v", tc.want, report)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package atrisk
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+)
+
+// millisecondTimestamp is the smallest timestamp read as milliseconds, event
+// keys are created with both second and millisecond timestamps
+const millisecondTimestamp = 100000000000
+
+// scoreEvents computes the total score of the events of an email with a scoring strategy
+func scoreEvents(scoring datatypes.ScoringStrategy, events []datatypes.AtRiskEventItem, now time.Time) int {
+	total := 0.0
+	for _, item := range events {
+		age := now.Sub(eventTime(item.Timestamp))
+		if age < 0 {
+			age = 0
+		}
+		days := age.Hours() / 24
+
+		switch scoring.Strategy {
+		case constants.ScoringWindow:
+			if days <= float64(scoring.WindowDays) {
+				total += float64(item.Event.Score)
+			}
+		case constants.ScoringDecay:
+			total += float64(item.Event.Score) * math.Pow(0.5, days/scoring.HalfLifeDays)
+		default:
+			total += float64(item.Event.Score)
+		}
+	}
+	return int(math.Round(total))
+}
+
+// categoryScores keeps the category totals of category, or all of them when category is
+// blank, and returns them with the grand total of the kept scores
+func categoryScores(totals []datatypes.CategoryScore, category string) (int, []datatypes.CategoryScore) {
+	total := 0
+	categories := []datatypes.CategoryScore{}
+	for _, score := range totals {
+		if category != "" && !strings.EqualFold(score.Category, category) {
+			continue
+		}
+		categories = append(categories, score)
+		total += score.Score
+	}
+	return total, categories
+}
+
+// inCategory reports whether an event belongs to category, every event does when category
+// is blank. Categories are matched case insensitively like the category column of the ledger
+func inCategory(event datatypes.AtRiskEvent, category string) bool {
+	return category == "" || strings.EqualFold(event.Category, category)
+}
+
+// eventTime converts the timestamp of an event key into time
+func eventTime(timestamp string) time.Time {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	if ts >= millisecondTimestamp {
+		return time.UnixMilli(ts)
+	}
+	return time.Unix(ts, 0)
+}
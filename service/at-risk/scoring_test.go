@@ -0,0 +1,61 @@
+package atrisk
+
+import (
+	"testing"
+	"time"
+	"www-api/internal/datatypes"
+)
+
+func TestScoreEvents(t *testing.T) {
+
+	type tests struct {
+		name      string
+		scoring   datatypes.ScoringStrategy
+		wantScore int
+	}
+
+	now := time.Unix(1684231487, 0)
+	events := []datatypes.AtRiskEventItem{
+		{Timestamp: "1684231487", Event: datatypes.AtRiskEvent{Score: 40}},
+		{Timestamp: "1683972287000", Event: datatypes.AtRiskEvent{Score: 20}},
+		{Timestamp: "1683367487", Event: datatypes.AtRiskEvent{Score: 80}},
+		{Timestamp: "1684231500", Event: datatypes.AtRiskEvent{Score: 8}},
+	}
+
+	testCases := []tests{
+		{
+			name:      "flat",
+			scoring:   datatypes.ScoringStrategy{Strategy: "flat"},
+			wantScore: 148,
+		},
+		{
+			name:      "empty strategy is flat",
+			scoring:   datatypes.ScoringStrategy{},
+			wantScore: 148,
+		},
+		{
+			name:      "window of 3 days, millisecond timestamps are read as milliseconds",
+			scoring:   datatypes.ScoringStrategy{Strategy: "window", WindowDays: 3},
+			wantScore: 68,
+		},
+		{
+			name:      "window of 10 days",
+			scoring:   datatypes.ScoringStrategy{Strategy: "window", WindowDays: 10},
+			wantScore: 148,
+		},
+		{
+			name:      "decay with half life of 5 days",
+			scoring:   datatypes.ScoringStrategy{Strategy: "decay", HalfLifeDays: 5},
+			wantScore: 81,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			score := scoreEvents(tc.scoring, events, now)
+			if tc.wantScore != score {
+				t.Errorf("expected score %d got %d", tc.wantScore, score)
+			}
+		})
+	}
+}
@@ -2,14 +2,19 @@ package atrisk
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 	"www-api/internal/constants"
 	"www-api/internal/datatypes"
 	"www-api/internal/logger"
+	"www-api/pkg/alert"
+	"www-api/pkg/aws/s3"
 	"www-api/pkg/cache"
 	"www-api/pkg/model"
+	"www-api/utils"
 
 	"www-api/pkg/database"
 
@@ -17,104 +22,572 @@ import (
 )
 
 type RiskService struct {
-	log            logger.ZapLogger
-	redis          cache.RedisOps
-	getAtRiskScore func(email string) ([]datatypes.RiskScore, error)
+	log                  logger.ZapLogger
+	redis                cache.RedisOps
+	getAtRiskScore       func(emails []string) ([]datatypes.RiskScore, error)
+	getAllAtRiskScores   func() ([]datatypes.RiskScore, error)
+	saveAtRiskScore      func(email, score string) error
+	replaceAtRiskScore   func(previousEmail, email, score string) error
+	deleteAtRiskScore    func(email string) (int64, error)
+	countAtRiskEvents    func(email string) (int64, error)
+	countAuditValues     func(email string) (int64, error)
+	redactAudit          func(email string) (int64, error)
+	eraseAtRiskEvents    func(email string) (int64, error)
+	getAtRiskEvents      func(email string, now int64) ([]datatypes.AtRiskEventRecord, error)
+	getAtRiskEventEmails func(now int64) ([]string, error)
+	saveAtRiskEvent      func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error
+	deleteAtRiskEvent    func(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error
+	extendAtRiskEvents   func(email, category string, expiresAt, now int64, entry datatypes.AtRiskAuditEntry) error
+	getAtRiskAudit       func(request datatypes.AtRiskAuditRequest) ([]datatypes.AtRiskAuditEntry, error)
+	thresholds           map[string][]int
+	alerts               alert.Sink
+	dispatch             func(send func()) bool
+	alertStream          string
+	archive              s3.S3Action
+	archiveBucket        string
+	archivePrefix        string
+	changeFeed           bool
 }
 
 // NewRiskService returns an instance of RiskService struct
 func NewRiskService(log logger.ZapLogger, connections *datatypes.Connections) RiskService {
 	readinterface := model.NewReadModel(log, database.NewDatabase(connections.DB[constants.AtRiskReadDBKey]))
-	// writeinterface := model.NewWriteModel(log, database.NewDatabase(writeconn))
+	writeinterface := model.NewWriteModel(log, database.NewDatabase(connections.DB[constants.AtRiskWriteDBKey]))
 
 	return RiskService{
-		log:            log,
-		redis:          cache.NewRedis(connections.Redis[constants.AtRiskReadRedisKey], connections.Redis[constants.AtRiskWriteRedisKey], log, context.Background()),
-		getAtRiskScore: readinterface.GetAtRiskScore,
+		log:                  log,
+		redis:                cache.NewRedis(connections.Redis[constants.AtRiskReadRedisKey], connections.Redis[constants.AtRiskWriteRedisKey], log, context.Background()),
+		getAtRiskScore:       readinterface.GetAtRiskScore,
+		getAllAtRiskScores:   readinterface.GetAllAtRiskScores,
+		saveAtRiskScore:      writeinterface.SaveAtRiskScore,
+		replaceAtRiskScore:   writeinterface.ReplaceAtRiskScore,
+		deleteAtRiskScore:    writeinterface.DeleteAtRiskScore,
+		countAtRiskEvents:    readinterface.CountAtRiskEvents,
+		countAuditValues:     readinterface.CountAtRiskAuditValues,
+		redactAudit:          writeinterface.RedactAtRiskAudit,
+		eraseAtRiskEvents:    writeinterface.EraseAtRiskEvents,
+		getAtRiskEvents:      readinterface.GetAtRiskEvents,
+		getAtRiskEventEmails: readinterface.GetAtRiskEventEmails,
+		saveAtRiskEvent:      writeinterface.SaveAtRiskEvent,
+		deleteAtRiskEvent:    writeinterface.DeleteAtRiskEvent,
+		extendAtRiskEvents:   writeinterface.ExtendAtRiskEvents,
+		getAtRiskAudit:       readinterface.GetAtRiskAudit,
 	}
 }
 
-// CreateCache sets a key value pair in redis and returns total score for that email
-func (s RiskService) CreateCache(key, value string) (datatypes.AtRiskResponse, error) {
+// WithAlerts returns a copy of the service that publishes an alert to sink whenever
+// the total of an email crosses one of the thresholds of its domain, alerts are sent
+// in their own goroutine so a slow sink never holds up a write, at most MaxAlertSenders
+// at a time
+func (s RiskService) WithAlerts(thresholds map[string][]int, sink alert.Sink) RiskService {
+	s.thresholds = thresholds
+	s.alerts = sink
+	senders := make(chan struct{}, constants.MaxAlertSenders)
+	s.dispatch = func(send func()) bool {
+		select {
+		case senders <- struct{}{}:
+		default:
+			return false
+		}
+		go func() {
+			defer func() { <-senders }()
+			send()
+		}()
+		return true
+	}
+	return s
+}
+
+// CreateCache sets a key value pair in redis and returns total score for that email computed
+// with the scoring strategy, the event is written to the AtRiskEvent ledger along with its audit
+// entry under actor once redis accepted it so that cache can be rebuilt from it, and taken back
+// out of redis when the ledger refuses it
+func (s RiskService) CreateCache(key, value string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
 	s.log.Info("setting cache", map[string]interface{}{"key": key, "value": value})
-	//setting ttl as 60 days i.e. 5184000 secs
-	err := s.redis.SetWithTTL(key, value, 5184000*time.Second)
+	email, timestamp := splitAtRiskKey(key)
+	now := time.Now().Unix()
+	record, err := s.ledgerRecord(email, timestamp, value, now)
+	if err != nil {
+		return datatypes.AtRiskResponse{}, err
+	}
+
+	//setting ttl as 60 days i.e. 5184000 secs, the script updates the email aggregates in the same step
+	args := preconditionArgs([]interface{}{timestamp, value, constants.AtRiskEventTTL, now}, precondition)
+	reply, err := s.redis.RunScript(cache.SetAtRiskEvent, atRiskEventKeys(key), args...)
 	if err != nil {
 		s.log.Error("error occured while setting cache value", map[string]interface{}{"error": err})
 		return datatypes.AtRiskResponse{}, err
 	}
 
-	email := strings.Split(key, ":")[0]
-	score, err := s.getTotalAtRiskScore(email)
+	affected, score, err := scriptResult(reply)
 	if err != nil {
-		s.log.Error("error occured while getting total score", map[string]interface{}{"error": err})
+		s.log.Error("error occured while getting total score", map[string]interface{}{"reply": reply, "error": err})
 		return datatypes.AtRiskResponse{}, err
+	}
 
+	if affected < 0 {
+		s.log.Error("AT_RISK_PRECONDITION_FAILED. Not setting cache value", map[string]interface{}{"key": key, "precondition": precondition, "version": scriptVersion(reply)})
+		return datatypes.AtRiskResponse{}, constants.PreconditionFailed
 	}
 
-	return datatypes.AtRiskResponse{AtRiskScore: score}, nil
+	err = s.saveToLedger(record, datatypes.AtRiskAuditEntry{Actor: actor, Action: constants.AuditActionCreate, UserEmail: email, AtRiskKey: key, BeforeValue: scriptValue(reply), AfterValue: &value, TotalAtRiskScore: score, CreatedAt: now})
+	if err != nil {
+		s.restoreEvent(key, scriptValue(reply), datatypes.Precondition{IfMatch: strconv.FormatInt(scriptVersion(reply), 10)})
+		return datatypes.AtRiskResponse{}, err
+	}
+
+	response, err := s.totalScore(email, score, scoring)
+	if err != nil {
+		return datatypes.AtRiskResponse{}, err
+	}
+	response.Version = scriptVersion(reply)
+
+	s.checkThresholds(key, score)
+	s.publishChange(datatypes.AtRiskChange{Action: constants.AuditActionCreate, UserEmail: email, AtRiskKey: key, TotalAtRiskScore: score, Version: response.Version})
+	return response, nil
 }
 
-// DeleteCache returns the total score after removing the key value from redis
-func (s RiskService) DeleteCache(key string) (datatypes.AtRiskResponse, error) {
-	exists, err := s.redis.Exists(key)
+// DeleteCache returns the total score computed with the scoring strategy after removing
+// the key value from redis and then from the ledger along with its audit entry under actor,
+// the event is put back in redis when the ledger refuses the delete
+func (s RiskService) DeleteCache(key string, scoring datatypes.ScoringStrategy, actor string, precondition datatypes.Precondition) (datatypes.AtRiskResponse, error) {
+	email, timestamp := splitAtRiskKey(key)
+	now := time.Now().Unix()
+	args := preconditionArgs([]interface{}{timestamp, now}, precondition)
+	reply, err := s.redis.RunScript(cache.DeleteAtRiskEvent, atRiskEventKeys(key), args...)
 	if err != nil {
-		s.log.Error("error occured while checking if key exists in redis", map[string]interface{}{"key": key, "error": err})
+		s.log.Error("error occured while deleting key", map[string]interface{}{"key": key, "error": err})
 		return datatypes.AtRiskResponse{}, err
 	}
 
-	if !exists {
+	deleted, score, err := scriptResult(reply)
+	if err != nil {
+		s.log.Error("error occured while getting total score", map[string]interface{}{"key": key, "reply": reply, "error": err})
+		return datatypes.AtRiskResponse{}, err
+	}
+
+	if deleted < 0 {
+		s.log.Error("AT_RISK_PRECONDITION_FAILED. Not deleting key", map[string]interface{}{"key": key, "precondition": precondition, "version": scriptVersion(reply)})
+		return datatypes.AtRiskResponse{}, constants.PreconditionFailed
+	}
+
+	//an event only present in the ledger, e.g. after redis was flushed, still counts as deleted
+	err = s.deleteFromLedger(key, datatypes.AtRiskAuditEntry{Actor: actor, Action: constants.AuditActionDelete, UserEmail: email, AtRiskKey: key, BeforeValue: scriptValue(reply), TotalAtRiskScore: score, CreatedAt: now}, deleted == 0)
+	if err == constants.ResourceNotFound {
 		s.log.Error("AT_RISK_SCORE_NOT_FOUND. Cannnot unassign as score is not assigned to user at all. unassignAtRiskKey", map[string]interface{}{"key": key})
 		return datatypes.AtRiskResponse{}, constants.ResourceNotFound
 	}
+	if err != nil {
+		if deleted > 0 {
+			s.restoreEvent(key, scriptValue(reply), datatypes.Precondition{IfNoneMatch: "*"})
+		}
+		return datatypes.AtRiskResponse{}, err
+	}
 
-	err = s.redis.Delete(key)
+	response, err := s.totalScore(email, score, scoring)
 	if err != nil {
-		s.log.Error("error occured while deleting key", map[string]interface{}{"key": key, "error": err})
 		return datatypes.AtRiskResponse{}, err
 	}
 
-	email := strings.Split(key, ":")[0]
-	score, err := s.getTotalAtRiskScore(email)
+	s.checkThresholds(key, score)
+	s.publishChange(datatypes.AtRiskChange{Action: constants.AuditActionDelete, UserEmail: email, AtRiskKey: key, TotalAtRiskScore: score})
+	return response, nil
+}
+
+// totalScore returns the total score of an email for a scoring strategy, the flat
+// total is kept by the write scripts, the other strategies need the events
+func (s RiskService) totalScore(email string, flatTotal int, scoring datatypes.ScoringStrategy) (datatypes.AtRiskResponse, error) {
+	if scoring.Strategy == "" || scoring.Strategy == constants.ScoringFlat {
+		return datatypes.AtRiskResponse{AtRiskScore: flatTotal, Scoring: datatypes.ScoringStrategy{Strategy: constants.ScoringFlat}}, nil
+	}
+
+	now := time.Now()
+	events, err := s.cachedEvents(email, now)
 	if err != nil {
-		s.log.Error("error occured while getting total score", map[string]interface{}{"key": key, "error": err})
 		return datatypes.AtRiskResponse{}, err
 	}
 
-	return datatypes.AtRiskResponse{AtRiskScore: score}, err
+	return datatypes.AtRiskResponse{AtRiskScore: scoreEvents(scoring, events, now), Scoring: scoring}, nil
 }
 
-// GetScore fetches risk score from the database based on email
-func (s RiskService) GetScore(email string) ([]datatypes.RiskScore, error) {
-	scores, err := s.getAtRiskScore(email)
+// cachedEvents returns every live event of an email ordered by timestamp
+func (s RiskService) cachedEvents(email string, now time.Time) ([]datatypes.AtRiskEventItem, error) {
+	reply, err := s.redis.RunScript(cache.ListAtRiskEvents, atRiskIndexKeys(email), now.Unix(), "-inf", "+inf", constants.OrderAsc, -1)
 	if err != nil {
-		s.log.Error("unable to fetch scores from database", map[string]interface{}{"error": err})
+		s.log.Error("unable to fetch events from redis", map[string]interface{}{"email": email, "error": err})
 		return nil, err
 	}
-	return scores, nil
+
+	return s.parseEventPage(email, reply)
 }
 
-// ExtendTTL updates the ttl value for all keys with email pattern
-func (s RiskService) ExtendTTL(email string, ttl int) error {
-	keys, err := s.redis.GetKeys(email + ":*")
+// cachedCategories returns the score and number of the live events of each category of
+// an email from its totals hash, ordered by category
+func (s RiskService) cachedCategories(email string, now time.Time) ([]datatypes.CategoryScore, error) {
+	reply, err := s.redis.RunScript(cache.AtRiskCategoryTotals, atRiskIndexKeys(email), now.Unix())
 	if err != nil {
-		s.log.Error("unable to fetch all keys from redis: error", map[string]interface{}{"email": email, "error": err})
-		return err
+		s.log.Error("unable to fetch category totals from redis", map[string]interface{}{"email": email, "error": err})
+		return nil, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values)%3 != 0 {
+		s.log.Error("invalid category totals received from redis", map[string]interface{}{"email": email, "reply": reply})
+		return nil, constants.InvalidScriptReply
+	}
+
+	categories := []datatypes.CategoryScore{}
+	for i := 0; i < len(values); i += 3 {
+		category, isString := values[i].(string)
+		score, isScore := values[i+1].(int64)
+		events, isEvents := values[i+2].(int64)
+		if !isString || !isScore || !isEvents {
+			s.log.Error("invalid category total received from redis", map[string]interface{}{"email": email, "category": values[i], "score": values[i+1], "events": values[i+2]})
+			return nil, constants.InvalidScriptReply
+		}
+		categories = append(categories, datatypes.CategoryScore{Category: category, Score: int(score), Events: int(events)})
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Category < categories[j].Category
+	})
+	return categories, nil
+}
+
+// BatchCache applies create/delete operations in a single redis pipeline and returns
+// the status of each operation along with the total score of every affected email. Like a
+// single write an operation reaches the ledger along with its audit entry under actor once
+// redis applied it and is taken back out of redis when the ledger refuses it, every applied
+// operation is checked against the alert thresholds and published on the change feed
+func (s RiskService) BatchCache(operations []datatypes.BatchCacheOperation, actor string) (datatypes.BatchCacheResponse, error) {
+	results := make([]datatypes.BatchCacheResult, len(operations))
+	ops := []cache.Operation{}
+	positions := []int{}
+	records := map[int]datatypes.AtRiskEventRecord{}
+	now := time.Now().Unix()
+	for i, operation := range operations {
+		results[i] = datatypes.BatchCacheResult{Action: operation.Action, AtRiskKey: operation.AtRiskKey}
+		op, err := s.toCacheOperation(operation)
+		if err == nil && op.Script != cache.DeleteAtRiskEvent {
+			email, timestamp := splitAtRiskKey(operation.AtRiskKey)
+			records[i], err = s.ledgerRecord(email, timestamp, operation.Value(), now)
+		}
+		if err != nil {
+			results[i].Status = constants.BatchStatusInvalid
+			results[i].Message = err.Error()
+			continue
+		}
+		ops = append(ops, op)
+		positions = append(positions, i)
+	}
+
+	totals := map[string]int{}
+	if len(ops) != 0 {
+		pipelined, err := s.redis.Pipeline(ops)
+		if err != nil {
+			s.log.Error("error occured while applying batch to cache", map[string]interface{}{"operations": len(ops), "error": err})
+			return datatypes.BatchCacheResponse{}, err
+		}
+
+		for j, result := range pipelined {
+			i := positions[j]
+			if result.Err != nil {
+				results[i].Status = constants.BatchStatusFailed
+				results[i].Message = result.Err.Error()
+				continue
+			}
+
+			affected, score, err := scriptResult(result.Reply)
+			if err != nil {
+				results[i].Status = constants.BatchStatusFailed
+				results[i].Message = err.Error()
+				continue
+			}
+
+			email, _ := splitAtRiskKey(result.Key)
+			entry := datatypes.AtRiskAuditEntry{Actor: actor, Action: constants.AuditActionDelete, UserEmail: email, AtRiskKey: result.Key, BeforeValue: scriptValue(result.Reply), TotalAtRiskScore: score, CreatedAt: now}
+			if ops[j].Script == cache.DeleteAtRiskEvent {
+				err = s.deleteFromLedger(result.Key, entry, affected == 0)
+				if err != nil && affected > 0 {
+					s.restoreEvent(result.Key, scriptValue(result.Reply), datatypes.Precondition{IfNoneMatch: "*"})
+				}
+			} else {
+				value := operations[i].Value()
+				entry.Action = constants.AuditActionCreate
+				entry.AfterValue = &value
+				err = s.saveToLedger(records[i], entry)
+				if err != nil {
+					s.restoreEvent(result.Key, scriptValue(result.Reply), datatypes.Precondition{IfMatch: strconv.FormatInt(scriptVersion(result.Reply), 10)})
+				}
+			}
+			switch {
+			case err == constants.ResourceNotFound:
+				results[i].Status = constants.BatchStatusNotFound
+				results[i].Message = "key doesn't exists"
+				continue
+			case err != nil:
+				results[i].Status = constants.BatchStatusFailed
+				results[i].Message = err.Error()
+				continue
+			case ops[j].Script == cache.DeleteAtRiskEvent:
+				results[i].Status = constants.BatchStatusDeleted
+			default:
+				results[i].Status = constants.BatchStatusCreated
+			}
+			//scripts run in order, so the last reply of an email carries its final total
+			totals[email] = score
+
+			s.checkThresholds(result.Key, score)
+			s.publishChange(datatypes.AtRiskChange{Action: entry.Action, UserEmail: email, AtRiskKey: result.Key, TotalAtRiskScore: score, Version: scriptVersion(result.Reply)})
+		}
+	}
+
+	return datatypes.BatchCacheResponse{Results: results, TotalAtRiskScores: totals}, nil
+}
+
+// toCacheOperation validates a batch operation and converts it into a pipeline operation
+func (s RiskService) toCacheOperation(operation datatypes.BatchCacheOperation) (cache.Operation, error) {
+	err := utils.ValidateAtRiskKey(operation.AtRiskKey, s.log)
+	if err != nil {
+		return cache.Operation{}, err
+	}
+
+	_, timestamp := splitAtRiskKey(operation.AtRiskKey)
+	keys := atRiskEventKeys(operation.AtRiskKey)
+	switch operation.Action {
+	case constants.BatchActionCreate:
+		err = utils.ValidateAtRiskValue(operation.Value(), s.log)
+		if err != nil {
+			return cache.Operation{}, err
+		}
+		return cache.Operation{
+			Action: cache.ScriptAction,
+			Key:    operation.AtRiskKey,
+			Script: cache.SetAtRiskEvent,
+			Keys:   keys,
+			Args:   []interface{}{timestamp, operation.Value(), constants.AtRiskEventTTL, time.Now().Unix()},
+		}, nil
+	case constants.BatchActionDelete:
+		return cache.Operation{
+			Action: cache.ScriptAction,
+			Key:    operation.AtRiskKey,
+			Script: cache.DeleteAtRiskEvent,
+			Keys:   keys,
+			Args:   []interface{}{timestamp, time.Now().Unix()},
+		}, nil
+	}
+
+	s.log.Error("invalid batch action", map[string]interface{}{"action": operation.Action, "atRiskKey": operation.AtRiskKey})
+	return cache.Operation{}, constants.InvalidBatchAction
+}
+
+// GetScore fetches risk scores from the database for a list of emails and groups them per
+// email, emails are matched case insensitively like the user_email column. Every email also
+// gets the total of its cached events broken down by category from its totals hash, only of
+// the events of category when it is set. An email is not found when it has neither
+func (s RiskService) GetScore(emails []string, category string) (datatypes.RiskScoresResponse, error) {
+	scores, err := s.getAtRiskScore(emails)
+	if err != nil {
+		s.log.Error("unable to fetch scores from database", map[string]interface{}{"emails": emails, "error": err})
+		return datatypes.RiskScoresResponse{}, err
+	}
+
+	grouped := map[string][]string{}
+	for _, score := range scores {
+		email := strings.ToLower(score.Email)
+		grouped[email] = append(grouped[email], score.SelfHarmScore)
+	}
+
+	now := time.Now()
+	response := datatypes.RiskScoresResponse{Category: category, AtRiskScores: []datatypes.EmailRiskScores{}, NotFound: []string{}}
+	for _, email := range emails {
+		totals, err := s.cachedCategories(email, now)
+		if err != nil {
+			return datatypes.RiskScoresResponse{}, err
+		}
+
+		selfHarmScores, ok := grouped[strings.ToLower(email)]
+		if !ok && len(totals) == 0 {
+			response.NotFound = append(response.NotFound, email)
+			continue
+		}
+		if !ok {
+			selfHarmScores = []string{}
+		}
+
+		total, categories := categoryScores(totals, category)
+		response.AtRiskScores = append(response.AtRiskScores, datatypes.EmailRiskScores{UserEmail: email, SelfHarmScores: selfHarmScores, TotalAtRiskScore: total, Categories: categories})
+	}
+	return response, nil
+}
+
+// ExtendTTL updates the ttl value for all keys with email pattern, or only for the keys of
+// the events of category when it is set, and returns the ttl of each key before and after.
+// The ledger is extended along with the audit entry of the change under actor before redis.
+// A dry run only reports the keys and changes nothing
+func (s RiskService) ExtendTTL(email string, ttl int, category, actor string, dryRun bool) (datatypes.ExtendTTLResponse, error) {
+	now := time.Now().Unix()
+	keys, err := s.eventTTLs(email)
+	if err != nil {
+		return datatypes.ExtendTTLResponse{}, err
+	}
+
+	if category != "" {
+		keys, err = s.categoryKeyTTLs(email, category, keys)
+		if err != nil {
+			return datatypes.ExtendTTLResponse{}, err
+		}
 	}
 
+	response := datatypes.ExtendTTLResponse{UserEmail: email, Category: category, DryRun: dryRun, Keys: []datatypes.ExtendedKeyTTL{}}
 	for _, key := range keys {
-		err = s.redis.SetTTL(key, ttl)
+		response.Keys = append(response.Keys, datatypes.ExtendedKeyTTL{AtRiskKey: key.AtRiskKey, OldTTL: key.TTL, NewTTL: ttl})
+	}
+
+	if dryRun {
+		s.log.Info("dry run, not extending ttl", map[string]interface{}{"email": email, "ttl": ttl, "category": category, "keys": len(keys)})
+		return response, nil
+	}
+
+	//moving expiries leaves the total as it is, so the audit entry can carry it before the change
+	expiresAt := strconv.FormatInt(now+int64(ttl), 10)
+	entry := datatypes.AtRiskAuditEntry{Actor: actor, Action: constants.AuditActionExtendTTL, UserEmail: email, AtRiskKey: email + ":*", AfterValue: &expiresAt, CreatedAt: now}
+	entry.TotalAtRiskScore, err = s.getTotalAtRiskScore(email)
+	if err != nil {
+		return datatypes.ExtendTTLResponse{}, err
+	}
+
+	err = s.extendAtRiskEvents(email, category, now+int64(ttl), now, entry)
+	if err != nil {
+		s.log.Error("unable to extend expiry of events in ledger", map[string]interface{}{"email": email, "category": category, "error": err})
+		return datatypes.ExtendTTLResponse{}, err
+	}
+	if len(keys) == 0 {
+		return response, nil
+	}
+
+	//the keys, the index and the domain aggregates are moved in one step so the summary keeps
+	//the email while its events live
+	scriptKeys := append(atRiskIndexKeys(email), atRiskDomainKeys(emailDomain(email))...)
+	args := []interface{}{now, now + int64(ttl)}
+	for _, key := range keys {
+		_, timestamp := splitAtRiskKey(key.AtRiskKey)
+		scriptKeys = append(scriptKeys, key.AtRiskKey)
+		args = append(args, timestamp)
+	}
+	_, err = s.redis.RunScript(cache.ExpireAtRiskEvents, scriptKeys, args...)
+	if err != nil {
+		s.log.Error("unable to update expiry of events in redis", map[string]interface{}{"email": email, "error": err})
+		return datatypes.ExtendTTLResponse{}, err
+	}
+
+	s.publishChange(datatypes.AtRiskChange{Action: constants.AuditActionExtendTTL, UserEmail: email, AtRiskKey: entry.AtRiskKey, TotalAtRiskScore: entry.TotalAtRiskScore})
+	return response, nil
+}
+
+// categoryKeyTTLs keeps the keys of the events of an email that belong to category
+func (s RiskService) categoryKeyTTLs(email, category string, keys []datatypes.AtRiskKeyTTL) ([]datatypes.AtRiskKeyTTL, error) {
+	events, err := s.cachedEvents(email, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	inCategoryKeys := map[string]bool{}
+	for _, item := range events {
+		if inCategory(item.Event, category) {
+			inCategoryKeys[item.AtRiskKey] = true
+		}
+	}
+
+	filtered := []datatypes.AtRiskKeyTTL{}
+	for _, key := range keys {
+		if inCategoryKeys[key.AtRiskKey] {
+			filtered = append(filtered, key)
+		}
+	}
+	return filtered, nil
+}
+
+// GetTTL returns the remaining ttl of every cached event of an email
+func (s RiskService) GetTTL(email string) (datatypes.AtRiskTTLResponse, error) {
+	keys, err := s.eventTTLs(email)
+	if err != nil {
+		return datatypes.AtRiskTTLResponse{}, err
+	}
+
+	return datatypes.AtRiskTTLResponse{UserEmail: email, Keys: keys}, nil
+}
+
+// eventTTLs reads the remaining ttl of the event keys of an email ordered by key, -1 for a key
+// without expiry. The keys are taken from the expiry index, pruned first so an event that already
+// expired is not listed, and from the keys of the email for events cached before the index existed
+func (s RiskService) eventTTLs(email string) ([]datatypes.AtRiskKeyTTL, error) {
+	reply, err := s.redis.RunScript(cache.ListAtRiskExpiry, atRiskIndexKeys(email), time.Now().Unix())
+	if err != nil {
+		s.log.Error("unable to fetch expiry of events from redis", map[string]interface{}{"email": email, "error": err})
+		return nil, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values)%2 != 0 {
+		s.log.Error("invalid expiry of events received from redis", map[string]interface{}{"email": email, "reply": reply})
+		return nil, constants.InvalidScriptReply
+	}
+
+	keys := map[string]bool{}
+	for i := 0; i < len(values); i += 2 {
+		timestamp, ok := values[i].(string)
+		if !ok {
+			s.log.Error("invalid expiry of event received from redis", map[string]interface{}{"email": email, "timestamp": values[i]})
+			return nil, constants.InvalidScriptReply
+		}
+		keys[email+":"+timestamp] = true
+	}
+
+	eventKeys, err := s.redis.GetKeys(email + ":*")
+	if err != nil {
+		s.log.Error("unable to fetch all keys from redis", map[string]interface{}{"email": email, "error": err})
+		return nil, err
+	}
+	for _, key := range eventKeys {
+		if isAtRiskKey(key) {
+			keys[key] = true
+		}
+	}
+
+	ttls := []datatypes.AtRiskKeyTTL{}
+	for key := range keys {
+		ttl, err := s.redis.GetTTL(key)
+		if err == constants.ResourceNotFound {
+			continue
+		}
 		if err != nil {
-			s.log.Error("unable to set expiry in redis", map[string]interface{}{"key": key, "error": err})
-			return err
+			return nil, err
 		}
+		ttls = append(ttls, datatypes.AtRiskKeyTTL{AtRiskKey: key, TTL: ttl})
 	}
+	sort.Slice(ttls, func(i, j int) bool {
+		return ttls[i].AtRiskKey < ttls[j].AtRiskKey
+	})
+	return ttls, nil
+}
 
-	return nil
+// GetEventScore returns key, value & score for a specific event based on timestamp, an event
+// outside of category is reported as not found when category is set
+func (s RiskService) GetEventScore(email, timestamp, mid, category string) (datatypes.EventScoreResponse, error) {
+	response, err := s.eventScore(email, timestamp, mid)
+	if err != nil {
+		return datatypes.EventScoreResponse{}, err
+	}
+
+	if !inCategory(response.Event, category) {
+		s.log.Error("at risk event not in category", map[string]interface{}{"atRiskKey": response.AtRiskKey, "category": category})
+		return datatypes.EventScoreResponse{}, constants.ResourceNotFound
+	}
+	return response, nil
 }
 
-// GetEventScore returns key, value & score for a specific event based on timestamp
-func (s RiskService) GetEventScore(email, timestamp, mid string) (datatypes.EventScoreResponse, error) {
+// eventScore looks up an event by its timestamp in seconds or milliseconds, falling back to the message id
+func (s RiskService) eventScore(email, timestamp, mid string) (datatypes.EventScoreResponse, error) {
 	atRiskKey := email + ":" + timestamp
 	exists, err := s.redis.Exists(atRiskKey)
 	if err != nil {
@@ -132,72 +605,442 @@ func (s RiskService) GetEventScore(email, timestamp, mid string) (datatypes.Even
 		atRiskKey = email + ":" + newTime
 	}
 
-	atRiskValue, err := s.redis.GetValue(atRiskKey)
+	//the value and its version are read together so the version always belongs to the value
+	_, eventTimestamp := splitAtRiskKey(atRiskKey)
+	eventKeys := append([]string{atRiskKey}, atRiskIndexKeys(email)...)
+	reply, err := s.redis.RunScript(cache.GetAtRiskEvent, eventKeys, eventTimestamp)
 	if err != nil {
 		s.log.Error("error fetching value in redis", map[string]interface{}{"error": err})
 		return datatypes.EventScoreResponse{}, err
 	}
 
-	if atRiskValue == "" && mid != "" && mid[0] == '<' {
-		allKeys, err := s.redis.GetKeys(email + ":*")
-		if err != nil {
-			s.log.Error("unable to fetch all keys from redis", map[string]interface{}{"email": email, "error": err})
-			return datatypes.EventScoreResponse{}, err
-		}
+	//events stored under a different timestamp are found through the message id index
+	if reply == nil && mid != "" && mid[0] == '<' {
+		s.log.Info("looking up at risk event by mid", map[string]interface{}{"atRiskKey": atRiskKey, "mid": mid})
+		return s.GetEventByMid(email, mid)
+	}
 
-		for _, key := range allKeys {
-			redisValue, err := s.redis.GetValue(key)
-			if err != nil {
-				s.log.Error("unable to fetch score from redis", map[string]interface{}{"key": key, "error": err})
-				return datatypes.EventScoreResponse{}, err
-			}
-			if strings.Contains(redisValue, mid) {
-				atRiskValue = redisValue
-				atRiskKey = key
-				s.log.Info("at risk score found by looking into value", map[string]interface{}{"atRiskKey": atRiskKey})
-				break
-			}
-		}
+	if reply == nil {
+		s.log.Error("at risk key not found in redis", map[string]interface{}{"atRiskKey": atRiskKey})
+		return datatypes.EventScoreResponse{}, constants.ResourceNotFound
+	}
+
+	found, ok := reply.([]interface{})
+	if !ok || len(found) != 2 {
+		s.log.Error("invalid event received from redis", map[string]interface{}{"atRiskKey": atRiskKey, "reply": reply})
+		return datatypes.EventScoreResponse{}, constants.InvalidScriptReply
+	}
+	atRiskValue, _ := found[0].(string)
+	version, _ := found[1].(int64)
+
+	return s.eventScoreResponse(atRiskKey, atRiskValue, version)
+}
+
+// GetEventByMid returns key, value & score of the event of an email carrying the message id
+func (s RiskService) GetEventByMid(email, mid string) (datatypes.EventScoreResponse, error) {
+	reply, err := s.redis.RunScript(cache.FindAtRiskEvent, atRiskIndexKeys(email), time.Now().Unix(), mid)
+	if err != nil {
+		s.log.Error("unable to look up mid in redis", map[string]interface{}{"email": email, "mid": mid, "error": err})
+		return datatypes.EventScoreResponse{}, err
 	}
 
-	atRiskScoreString := strings.Split(atRiskValue, ":")[0]
-	atRiskScore, err := strconv.Atoi(atRiskScoreString)
+	if reply == nil {
+		s.log.Error("no event found for mid", map[string]interface{}{"email": email, "mid": mid})
+		return datatypes.EventScoreResponse{}, constants.ResourceNotFound
+	}
+
+	found, ok := reply.([]interface{})
+	if !ok || len(found) != 3 {
+		s.log.Error("invalid event received from redis", map[string]interface{}{"email": email, "reply": reply})
+		return datatypes.EventScoreResponse{}, constants.InvalidScriptReply
+	}
+	timestamp, _ := found[0].(string)
+	atRiskValue, _ := found[1].(string)
+	version, _ := found[2].(int64)
+
+	return s.eventScoreResponse(email+":"+timestamp, atRiskValue, version)
+}
+
+// eventScoreResponse parses a cached value into the response of an event
+func (s RiskService) eventScoreResponse(atRiskKey, atRiskValue string, version int64) (datatypes.EventScoreResponse, error) {
+	event, err := datatypes.ParseAtRiskEvent(atRiskValue)
 	if err != nil {
-		s.log.Error("unable to convert redis score value to int", map[string]interface{}{"error": err})
+		s.log.Error("unable to parse redis value into event", map[string]interface{}{"value": atRiskValue, "error": err})
 		return datatypes.EventScoreResponse{}, constants.InvalidScoreValue
 	}
 
 	return datatypes.EventScoreResponse{
 		AtRiskKey:   atRiskKey,
 		AtRiskValue: atRiskValue,
-		AtRiskScore: atRiskScore,
+		AtRiskScore: event.Score,
+		Event:       event,
+		Version:     version,
 	}, nil
+}
 
+// GetEvents returns a page of cached events of an email ordered by timestamp, the
+// cursor is the timestamp of the last event of the previous page
+func (s RiskService) GetEvents(request datatypes.AtRiskEventsRequest) (datatypes.AtRiskEventsResponse, error) {
+	lower, upper := "-inf", "+inf"
+	if request.From != "" {
+		lower = request.From
+	}
+	if request.To != "" {
+		upper = request.To
+	}
+	if request.Cursor != "" && request.Order == constants.OrderAsc {
+		lower = "(" + request.Cursor
+	}
+	if request.Cursor != "" && request.Order != constants.OrderAsc {
+		upper = "(" + request.Cursor
+	}
+
+	//fetching one extra event tells whether there is a next page
+	reply, err := s.redis.RunScript(cache.ListAtRiskEvents, atRiskIndexKeys(request.UserEmail), time.Now().Unix(), lower, upper, request.Order, request.Limit+1)
+	if err != nil {
+		s.log.Error("unable to fetch events from redis", map[string]interface{}{"email": request.UserEmail, "error": err})
+		return datatypes.AtRiskEventsResponse{}, err
+	}
+
+	//the page and its cursor are cut from the events fetched, not the ones parsed, so that a
+	//skipped value never ends the pages early
+	response := datatypes.AtRiskEventsResponse{}
+	if page, ok := reply.([]interface{}); ok && len(page)%2 == 0 && len(page) > 2*request.Limit {
+		reply = page[:2*request.Limit]
+		response.NextCursor, _ = page[2*request.Limit-2].(string)
+	}
+
+	response.Events, err = s.parseEventPage(request.UserEmail, reply)
+	if err != nil {
+		return datatypes.AtRiskEventsResponse{}, err
+	}
+
+	return response, nil
 }
 
-// getTotalAtRiskScore return the total score of all events based on email
-func (s RiskService) getTotalAtRiskScore(email string) (int, error) {
-	keys, err := s.redis.GetKeys(email + ":*")
+// parseEventPage reads the {timestamp, value...} reply of ListAtRiskEvents, events
+// with a value that cannot be parsed are skipped
+func (s RiskService) parseEventPage(email string, reply interface{}) ([]datatypes.AtRiskEventItem, error) {
+	page, ok := reply.([]interface{})
+	if !ok || len(page)%2 != 0 {
+		s.log.Error("invalid events page received from redis", map[string]interface{}{"email": email, "reply": reply})
+		return nil, constants.InvalidScriptReply
+	}
+
+	events := []datatypes.AtRiskEventItem{}
+	for i := 0; i < len(page); i += 2 {
+		timestamp, _ := page[i].(string)
+		value, _ := page[i+1].(string)
+		event, err := datatypes.ParseAtRiskEvent(value)
+		if err != nil {
+			s.log.Error("skipping event with invalid value", map[string]interface{}{"email": email, "timestamp": timestamp, "value": value, "error": err})
+			continue
+		}
+		events = append(events, datatypes.AtRiskEventItem{
+			AtRiskKey: email + ":" + timestamp,
+			Timestamp: timestamp,
+			Event:     event,
+		})
+	}
+
+	return events, nil
+}
+
+// RebuildIndex rebuilds the per email aggregates from the email:timestamp keys
+// present in cache and returns the number of emails indexed
+func (s RiskService) RebuildIndex() (int, error) {
+	keys, err := s.redis.GetKeys("*:*")
 	if err != nil {
-		s.log.Error("unable to fetch all keys from redis", map[string]interface{}{"email": email, "error": err})
+		s.log.Error("unable to fetch all keys from redis", map[string]interface{}{"error": err})
 		return 0, err
 	}
-	var totalScore int
+
+	grouped := map[string][]string{}
 	for _, key := range keys {
-		redisValue, err := s.redis.GetValue(key)
+		if !isAtRiskKey(key) {
+			continue
+		}
+		email, _ := splitAtRiskKey(key)
+		grouped[email] = append(grouped[email], key)
+	}
+
+	for email, eventKeys := range grouped {
+		err = s.rebuildEmailIndex(email, eventKeys)
 		if err != nil {
-			s.log.Error("unable to fetch score from redis ", map[string]interface{}{"key": key, "error": err})
 			return 0, err
 		}
+	}
+
+	return len(grouped), nil
+}
 
-		scoreString := strings.Split(redisValue, ":")
-		score, err := strconv.Atoi(scoreString[0])
+// RebuildCache restores the cached events of the given emails from the AtRiskEvent
+// ledger, every email of the ledger is restored when none is given. It returns the
+// number of events written to cache
+func (s RiskService) RebuildCache(emails ...string) (int, error) {
+	now := time.Now().Unix()
+	if len(emails) == 0 {
+		var err error
+		emails, err = s.getAtRiskEventEmails(now)
+		if err != nil {
+			s.log.Error("unable to fetch emails from ledger", map[string]interface{}{"error": err})
+			return 0, err
+		}
+	}
+
+	restored := 0
+	for _, email := range emails {
+		records, err := s.getAtRiskEvents(email, now)
+		if err != nil {
+			s.log.Error("unable to fetch events from ledger", map[string]interface{}{"email": email, "error": err})
+			return restored, err
+		}
+
+		ops := []cache.Operation{}
+		for _, record := range records {
+			ops = append(ops, cache.Operation{
+				Action: cache.SetAction,
+				Key:    email + ":" + record.EventTimestamp,
+				Value:  record.AtRiskValue,
+				TTL:    time.Duration(record.ExpiresAt-now) * time.Second,
+			})
+		}
+
+		if len(ops) != 0 {
+			results, err := s.redis.Pipeline(ops)
+			if err != nil {
+				s.log.Error("unable to restore events into cache", map[string]interface{}{"email": email, "error": err})
+				return restored, err
+			}
+			for _, result := range results {
+				if result.Err != nil {
+					s.log.Error("unable to restore event into cache", map[string]interface{}{"key": result.Key, "error": result.Err})
+					return restored, result.Err
+				}
+			}
+		}
+
+		//the aggregates also pick up events cached before the ledger existed
+		keys, err := s.redis.GetKeys(email + ":*")
+		if err != nil {
+			s.log.Error("unable to fetch all keys from redis", map[string]interface{}{"email": email, "error": err})
+			return restored, err
+		}
+		err = s.rebuildEmailIndex(email, keys)
 		if err != nil {
-			s.log.Error("unable to convert redis score value to int", map[string]interface{}{"error": err})
-			return 0, constants.InvalidKeyValue
+			return restored, err
 		}
-		totalScore += score
+
+		restored += len(ops)
+		s.log.Info("restored events from ledger", map[string]interface{}{"email": email, "events": len(ops)})
+	}
+
+	return restored, nil
+}
+
+// rebuildEmailIndex rebuilds the aggregates of an email from its event keys
+func (s RiskService) rebuildEmailIndex(email string, eventKeys []string) error {
+	args := []interface{}{time.Now().Unix()}
+	for _, key := range eventKeys {
+		_, timestamp := splitAtRiskKey(key)
+		args = append(args, timestamp)
+	}
+
+	keys := append(atRiskIndexKeys(email), atRiskDomainKeys(emailDomain(email))...)
+	indexed, err := s.redis.RunScript(cache.RebuildAtRiskIndex, append(keys, eventKeys...), args...)
+	if err != nil {
+		s.log.Error("unable to rebuild email aggregates", map[string]interface{}{"email": email, "error": err})
+		return err
+	}
+	s.log.Info("rebuilt email aggregates", map[string]interface{}{"email": email, "events": indexed})
+	return nil
+}
+
+// ledgerRecord returns the AtRiskEvent ledger record of a cached event with the expiry it gets in cache
+func (s RiskService) ledgerRecord(email, timestamp, value string, now int64) (datatypes.AtRiskEventRecord, error) {
+	event, err := datatypes.ParseAtRiskEvent(value)
+	if err != nil {
+		s.log.Error("unable to parse value into event", map[string]interface{}{"value": value, "error": err})
+		return datatypes.AtRiskEventRecord{}, err
+	}
+
+	return datatypes.AtRiskEventRecord{
+		UserEmail:      email,
+		EventTimestamp: timestamp,
+		AtRiskValue:    value,
+		Score:          event.Score,
+		Category:       event.Category,
+		MessageID:      event.MessageID,
+		ExpiresAt:      now + constants.AtRiskEventTTL,
+	}, nil
+}
+
+// saveToLedger writes a cached event into the AtRiskEvent ledger along with its audit entry
+func (s RiskService) saveToLedger(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+	err := s.saveAtRiskEvent(record, entry)
+	if err != nil {
+		s.log.Error("error occured while saving event into ledger", map[string]interface{}{"email": record.UserEmail, "timestamp": record.EventTimestamp, "error": err})
+		return err
+	}
+	return nil
+}
+
+// restoreEvent puts the event a write replaced or removed back in cache after the ledger
+// refused the write, previous is nil when there was no event. The restore only applies while
+// precondition holds so that it never undoes a later write, a restored event gets a fresh ttl
+func (s RiskService) restoreEvent(key string, previous *string, precondition datatypes.Precondition) {
+	_, timestamp := splitAtRiskKey(key)
+	now := time.Now().Unix()
+	var reply interface{}
+	var err error
+	if previous == nil {
+		reply, err = s.redis.RunScript(cache.DeleteAtRiskEvent, atRiskEventKeys(key), preconditionArgs([]interface{}{timestamp, now}, precondition)...)
+	} else {
+		reply, err = s.redis.RunScript(cache.SetAtRiskEvent, atRiskEventKeys(key), preconditionArgs([]interface{}{timestamp, *previous, constants.AtRiskEventTTL, now}, precondition)...)
+	}
+	if err != nil {
+		s.log.Error("AT_RISK_RESTORE_FAILED. cache keeps a write the ledger refused", map[string]interface{}{"key": key, "previous": previous, "error": err})
+		return
+	}
+
+	affected, _, err := scriptResult(reply)
+	if err != nil || affected < 0 {
+		s.log.Error("AT_RISK_RESTORE_SKIPPED. key was written again before it could be restored", map[string]interface{}{"key": key, "previous": previous, "reply": reply})
+		return
+	}
+	s.log.Info("restored cache after the ledger refused a write", map[string]interface{}{"key": key, "previous": previous})
+}
+
+// deleteFromLedger removes the event of an email:timestamp key from the ledger along with its
+// audit entry, with mustExist it fails with ResourceNotFound when the ledger has no such event
+func (s RiskService) deleteFromLedger(key string, entry datatypes.AtRiskAuditEntry, mustExist bool) error {
+	email, timestamp := splitAtRiskKey(key)
+	err := s.deleteAtRiskEvent(email, timestamp, entry, mustExist)
+	if err != nil && err != constants.ResourceNotFound {
+		s.log.Error("error occured while deleting event from ledger", map[string]interface{}{"key": key, "error": err})
+	}
+	return err
+}
+
+// getTotalAtRiskScore return the total score of all events based on email
+func (s RiskService) getTotalAtRiskScore(email string) (int, error) {
+	reply, err := s.redis.RunScript(cache.AtRiskTotal, atRiskIndexKeys(email), time.Now().Unix())
+	if err != nil {
+		s.log.Error("unable to fetch total score from redis", map[string]interface{}{"email": email, "error": err})
+		return 0, err
+	}
+
+	total, ok := reply.(int64)
+	if !ok {
+		s.log.Error("unable to convert redis score value to int", map[string]interface{}{"reply": reply})
+		return 0, constants.InvalidScriptReply
+	}
+
+	return int(total), nil
+}
+
+// atRiskIndexKeys returns the events, expiry, totals, timeline, mids and versions aggregate keys of an email
+func atRiskIndexKeys(email string) []string {
+	return []string{
+		fmt.Sprintf(constants.AtRiskEventsKey, email),
+		fmt.Sprintf(constants.AtRiskExpiryKey, email),
+		fmt.Sprintf(constants.AtRiskTotalsKey, email),
+		fmt.Sprintf(constants.AtRiskTimelineKey, email),
+		fmt.Sprintf(constants.AtRiskMidKey, email),
+		fmt.Sprintf(constants.AtRiskVersionsKey, email),
+	}
+}
+
+// atRiskDomainKeys returns the scores, events, expiry, totals and due aggregate keys of a domain
+func atRiskDomainKeys(domain string) []string {
+	return []string{
+		fmt.Sprintf(constants.AtRiskDomainScoresKey, domain),
+		fmt.Sprintf(constants.AtRiskDomainEventsKey, domain),
+		fmt.Sprintf(constants.AtRiskDomainExpiryKey, domain),
+		fmt.Sprintf(constants.AtRiskDomainTotalsKey, domain),
+		fmt.Sprintf(constants.AtRiskDomainDueKey, domain),
+	}
+}
+
+// atRiskEventKeys returns the keys of the event write scripts, the event key followed
+// by the aggregate keys of its email and of the email domain and the version sequence
+func atRiskEventKeys(key string) []string {
+	email, _ := splitAtRiskKey(key)
+	keys := append([]string{key}, atRiskIndexKeys(email)...)
+	keys = append(keys, atRiskDomainKeys(emailDomain(email))...)
+	return append(keys, constants.AtRiskVersionSequence)
+}
+
+// preconditionArgs appends the If-Match and If-None-Match versions of a conditional
+// write to the arguments of the event write scripts
+func preconditionArgs(args []interface{}, precondition datatypes.Precondition) []interface{} {
+	if !precondition.IsSet() {
+		return args
+	}
+	return append(args, precondition.IfMatch, precondition.IfNoneMatch)
+}
+
+// splitAtRiskKey splits an email:timestamp key into email and timestamp
+func splitAtRiskKey(key string) (string, string) {
+	split := strings.SplitN(key, ":", 2)
+	if len(split) != 2 {
+		return split[0], ""
+	}
+	return split[0], split[1]
+}
+
+// isAtRiskKey reports whether a cache key has the email:timestamp format
+func isAtRiskKey(key string) bool {
+	email, timestamp := splitAtRiskKey(key)
+	if !strings.Contains(email, "@") || strings.Contains(timestamp, ":") {
+		return false
+	}
+	_, err := strconv.Atoi(timestamp)
+	return err == nil
+}
+
+// scriptResult reads the {affected, total, value} reply of the at-risk write scripts
+func scriptResult(reply interface{}) (int64, int, error) {
+	values, ok := reply.([]interface{})
+	if !ok || len(values) < 2 {
+		return 0, 0, constants.InvalidScriptReply
+	}
+
+	affected, ok := values[0].(int64)
+	if !ok {
+		return 0, 0, constants.InvalidScriptReply
+	}
+
+	total, ok := values[1].(int64)
+	if !ok {
+		return 0, 0, constants.InvalidScriptReply
+	}
+
+	return affected, int(total), nil
+}
+
+// scriptValue returns the event value replaced or removed by an at-risk write script, nil
+// when there was no event
+func scriptValue(reply interface{}) *string {
+	values, ok := reply.([]interface{})
+	if !ok || len(values) < 3 {
+		return nil
+	}
+
+	value, ok := values[2].(string)
+	if !ok {
+		return nil
+	}
+	return &value
+}
+
+// scriptVersion returns the version in the reply of an event write script, 0 when the reply has none
+func scriptVersion(reply interface{}) int64 {
+	values, ok := reply.([]interface{})
+	if !ok || len(values) < 4 {
+		return 0
 	}
 
-	return totalScore, nil
+	version, _ := values[3].(int64)
+	return version
 }
@@ -1,10 +1,13 @@
 package atrisk
 
 import (
+	"strconv"
 	"testing"
+	"time"
 	"www-api/internal/constants"
 	"www-api/internal/datatypes"
 	"www-api/internal/logger"
+	"www-api/pkg/cache"
 	"www-api/pkg/cache/mocks"
 	"www-api/test"
 
@@ -44,6 +47,15 @@ func TestNewRiskService(t *testing.T) {
 			if riskService.getAtRiskScore == nil {
 				t.Errorf("expected getAtRiskScore but got nil")
 			}
+			if riskService.saveAtRiskEvent == nil || riskService.deleteAtRiskEvent == nil || riskService.extendAtRiskEvents == nil {
+				t.Errorf("expected ledger write funcs but got nil")
+			}
+			if riskService.getAtRiskEvents == nil || riskService.getAtRiskEventEmails == nil {
+				t.Errorf("expected ledger read funcs but got nil")
+			}
+			if riskService.getAtRiskAudit == nil {
+				t.Errorf("expected audit funcs but got nil")
+			}
 		})
 	}
 }
@@ -51,79 +63,153 @@ func TestNewRiskService(t *testing.T) {
 func TestCreateCache(t *testing.T) {
 
 	type tests struct {
-		name        string
-		redisClient func() *mocks.RedisOps
-		wantScore   datatypes.AtRiskResponse
-		wantErr     error
+		name            string
+		redisClient     func() *mocks.RedisOps
+		saveAtRiskEvent func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error
+		scoring         datatypes.ScoringStrategy
+		precondition    datatypes.Precondition
+		wantScore       datatypes.AtRiskResponse
+		wantErr         error
 	}
 
+	saved := func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+		audited(t, "subject", entry)
+		return nil
+	}
+	notSaved := func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+		t.Errorf("unexpected ledger record %+v", record)
+		return nil
+	}
 	testCases := []tests{
 		{
 			name: "valid case",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("SetWithTTL", mock.Anything, mock.Anything, mock.AnythingOfType("time.Duration")).Return(nil).Once()
-				moc.On("GetKeys", mock.Anything).Return([]string{"key1", "key2"}, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("45:scan:1dc13ds5c1651", nil).Once()
-				moc.On("GetValue", mock.Anything).Return("27:docs:1c5sd16c51dd8", nil).Once()
+				moc.On("RunScript", cache.SetAtRiskEvent, []string{"email@securly.com:1684231487", "atrisk:events:email@securly.com", "atrisk:expiry:email@securly.com", "atrisk:totals:email@securly.com", "atrisk:timeline:email@securly.com", "atrisk:mids:email@securly.com", "atrisk:versions:email@securly.com", "atrisk:domain:scores:securly.com", "atrisk:domain:events:securly.com", "atrisk:domain:expiry:securly.com", "atrisk:domain:totals:securly.com", "atrisk:domain:due:securly.com", "atrisk:version-sequence"}, "1684231487", "45:scan:1dc13ds5c1651", constants.AtRiskEventTTL, mock.AnythingOfType("int64")).Return([]interface{}{int64(1), int64(72), nil, int64(7)}, nil).Once()
 				return moc
 			},
-			wantScore: datatypes.AtRiskResponse{},
+			saveAtRiskEvent: func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+				if record.UserEmail != "email@securly.com" || record.EventTimestamp != "1684231487" || record.AtRiskValue != "45:scan:1dc13ds5c1651" ||
+					record.Score != 45 || record.Category != "scan" || record.MessageID != "1dc13ds5c1651" || record.ExpiresAt <= constants.AtRiskEventTTL {
+					t.Errorf("unexpected ledger record %+v", record)
+				}
+				return nil
+			},
+			wantScore: datatypes.AtRiskResponse{AtRiskScore: 72, Scoring: datatypes.ScoringStrategy{Strategy: "flat"}, Version: 7},
 			wantErr:   nil,
 		},
 		{
-			name: "fail case, error setting cache",
+			name: "valid case, conditional create",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("SetWithTTL", mock.Anything, mock.Anything, mock.AnythingOfType("time.Duration")).Return(test.CacheSetErr).Once()
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, "1684231487", "45:scan:1dc13ds5c1651", constants.AtRiskEventTTL, mock.AnythingOfType("int64"), "3,4", "").Return([]interface{}{int64(1), int64(72), "10:scan:1dc13ds5c1651", int64(8)}, nil).Once()
 				return moc
 			},
-			wantScore: datatypes.AtRiskResponse{},
-			wantErr:   test.CacheSetErr,
+			saveAtRiskEvent: saved,
+			precondition:    datatypes.Precondition{IfMatch: "3,4"},
+			wantScore:       datatypes.AtRiskResponse{AtRiskScore: 72, Scoring: datatypes.ScoringStrategy{Strategy: "flat"}, Version: 8},
+			wantErr:         nil,
 		},
 		{
-			name: "fail case, error getting cache keys",
+			name: "fail case, precondition failed",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("SetWithTTL", mock.Anything, mock.Anything, mock.AnythingOfType("time.Duration")).Return(nil).Once()
-				moc.On("GetKeys", mock.Anything).Return([]string{}, test.CacheGetKeysErr).Once()
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, "1684231487", "45:scan:1dc13ds5c1651", constants.AtRiskEventTTL, mock.AnythingOfType("int64"), "", "*").Return([]interface{}{int64(-1), int64(72), nil, int64(5)}, nil).Once()
 				return moc
 			},
-			wantScore: datatypes.AtRiskResponse{},
-			wantErr:   test.CacheGetKeysErr,
+			saveAtRiskEvent: notSaved,
+			precondition:    datatypes.Precondition{IfNoneMatch: "*"},
+			wantScore:       datatypes.AtRiskResponse{},
+			wantErr:         constants.PreconditionFailed,
 		},
 		{
-			name: "fail case, error getting cache value",
+			name: "fail case, error saving conditional event into ledger, new event is taken back out of cache",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("SetWithTTL", mock.Anything, mock.Anything, mock.AnythingOfType("time.Duration")).Return(nil).Once()
-				moc.On("GetKeys", mock.Anything).Return([]string{"key1"}, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("", test.CacheGetValueErr).Once()
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{int64(1), int64(72), nil, int64(8)}, nil).Once()
+				moc.On("RunScript", cache.DeleteAtRiskEvent, mock.Anything, "1684231487", mock.AnythingOfType("int64"), "8", "").Return([]interface{}{int64(1), int64(27), "45:scan:1dc13ds5c1651"}, nil).Once()
 				return moc
 			},
-			wantScore: datatypes.AtRiskResponse{},
-			wantErr:   test.CacheGetValueErr,
+			saveAtRiskEvent: func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+				return test.DBSomethingWentWrongErr
+			},
+			precondition: datatypes.Precondition{IfMatch: "*"},
+			wantScore:    datatypes.AtRiskResponse{},
+			wantErr:      test.DBSomethingWentWrongErr,
+		},
+		{
+			name: "valid case, window scoring",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{int64(1), int64(72)}, nil).Once()
+				moc.On("RunScript", cache.ListAtRiskEvents, []string{"atrisk:events:email@securly.com", "atrisk:expiry:email@securly.com", "atrisk:totals:email@securly.com", "atrisk:timeline:email@securly.com", "atrisk:mids:email@securly.com", "atrisk:versions:email@securly.com"}, mock.AnythingOfType("int64"), "-inf", "+inf", "asc", -1).Return([]interface{}{
+					strconv.FormatInt(time.Now().Add(-10*24*time.Hour).Unix(), 10), "27:chat:5gf8d54ss45s8",
+					strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10), "45:scan:1dc13ds5c1651",
+				}, nil).Once()
+				return moc
+			},
+			saveAtRiskEvent: saved,
+			scoring:         datatypes.ScoringStrategy{Strategy: "window", WindowDays: 7},
+			wantScore:       datatypes.AtRiskResponse{AtRiskScore: 45, Scoring: datatypes.ScoringStrategy{Strategy: "window", WindowDays: 7}},
+			wantErr:         nil,
 		},
 		{
-			name: "fail case, error invalid cache value",
+			name: "fail case, error fetching events for scoring",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("SetWithTTL", mock.Anything, mock.Anything, mock.AnythingOfType("time.Duration")).Return(nil).Once()
-				moc.On("GetKeys", mock.Anything).Return([]string{"key1"}, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("dede:scan:1dc13ds5c1651", nil).Once()
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{int64(1), int64(72)}, nil).Once()
+				moc.On("RunScript", cache.ListAtRiskEvents, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheGetValueErr).Once()
 				return moc
 			},
+			saveAtRiskEvent: saved,
+			scoring:         datatypes.ScoringStrategy{Strategy: "decay", HalfLifeDays: 7},
+			wantScore:       datatypes.AtRiskResponse{},
+			wantErr:         test.CacheGetValueErr,
+		},
+		{
+			name: "fail case, error saving event into ledger, replaced event is restored",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, "1684231487", "45:scan:1dc13ds5c1651", constants.AtRiskEventTTL, mock.AnythingOfType("int64")).Return([]interface{}{int64(1), int64(72), "10:scan:1dc13ds5c1651", int64(9)}, nil).Once()
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, "1684231487", "10:scan:1dc13ds5c1651", constants.AtRiskEventTTL, mock.AnythingOfType("int64"), "9", "").Return([]interface{}{int64(1), int64(37), "45:scan:1dc13ds5c1651", int64(10)}, nil).Once()
+				return moc
+			},
+			saveAtRiskEvent: func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+				return test.DBSomethingWentWrongErr
+			},
 			wantScore: datatypes.AtRiskResponse{},
-			wantErr:   constants.InvalidKeyValue,
+			wantErr:   test.DBSomethingWentWrongErr,
+		},
+		{
+			name: "fail case, error setting cache",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheSetErr).Once()
+				return moc
+			},
+			saveAtRiskEvent: notSaved,
+			wantScore:       datatypes.AtRiskResponse{},
+			wantErr:         test.CacheSetErr,
+		},
+		{
+			name: "fail case, error invalid script reply",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("OK", nil).Once()
+				return moc
+			},
+			saveAtRiskEvent: notSaved,
+			wantScore:       datatypes.AtRiskResponse{},
+			wantErr:         constants.InvalidScriptReply,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			risk := RiskService{logger.ZapLogger{Logger: zap.NewExample()}, tc.redisClient(), nil}
-			score, err := risk.CreateCache("key", "value")
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient(), saveAtRiskEvent: tc.saveAtRiskEvent}
+			score, err := risk.CreateCache("email@securly.com:1684231487", "45:scan:1dc13ds5c1651", tc.scoring, "subject", tc.precondition)
 			if tc.wantScore != score {
-				t.Errorf("expected score %d got %d", tc.wantScore, score)
+				t.Errorf("expected score %+v got %+v", tc.wantScore, score)
 			}
 			if tc.wantErr != err {
 				t.Errorf("expected error %v got %v", tc.wantErr, err)
@@ -135,105 +221,153 @@ func TestCreateCache(t *testing.T) {
 func TestDeleteCache(t *testing.T) {
 
 	type tests struct {
-		name        string
-		log         logger.ZapLogger
-		redisClient func() *mocks.RedisOps
-		wantScore   datatypes.AtRiskResponse
-		wantErr     error
+		name              string
+		log               logger.ZapLogger
+		redisClient       func() *mocks.RedisOps
+		deleteAtRiskEvent func(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error
+		scoring           datatypes.ScoringStrategy
+		precondition      datatypes.Precondition
+		wantScore         datatypes.AtRiskResponse
+		wantErr           error
 	}
 
+	deleted := func(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error {
+		audited(t, "subject", entry)
+		return nil
+	}
+	notInLedger := func(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error {
+		if mustExist {
+			return constants.ResourceNotFound
+		}
+		return nil
+	}
+	notDeleted := func(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error {
+		t.Errorf("unexpected ledger delete %s %s", email, timestamp)
+		return nil
+	}
 	testCases := []tests{
 		{
 			name: "valid case",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(true, nil).Once()
-				moc.On("Delete", mock.Anything).Return(nil).Once()
-				moc.On("GetKeys", mock.Anything).Return([]string{"key1", "key2"}, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("60:scan:1dc13ds5c1651", nil).Once()
-				moc.On("GetValue", mock.Anything).Return("32:docs:1c5sd16c51dd8", nil).Once()
+				moc.On("RunScript", cache.DeleteAtRiskEvent, []string{"email@securly.com:1684231487", "atrisk:events:email@securly.com", "atrisk:expiry:email@securly.com", "atrisk:totals:email@securly.com", "atrisk:timeline:email@securly.com", "atrisk:mids:email@securly.com", "atrisk:versions:email@securly.com", "atrisk:domain:scores:securly.com", "atrisk:domain:events:securly.com", "atrisk:domain:expiry:securly.com", "atrisk:domain:totals:securly.com", "atrisk:domain:due:securly.com", "atrisk:version-sequence"}, "1684231487", mock.AnythingOfType("int64")).Return([]interface{}{int64(1), int64(92)}, nil).Once()
 				return moc
 			},
-			wantScore: datatypes.AtRiskResponse{},
+			deleteAtRiskEvent: func(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error {
+				if email != "email@securly.com" || timestamp != "1684231487" || mustExist {
+					t.Errorf("unexpected ledger delete %s %s", email, timestamp)
+				}
+				return nil
+			},
+			wantScore: datatypes.AtRiskResponse{AtRiskScore: 92, Scoring: datatypes.ScoringStrategy{Strategy: "flat"}},
 			wantErr:   nil,
 		},
 		{
-			name: "fail case, error checking if key exists in cache",
+			name: "valid case, decay scoring",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(false, test.CacheKeyExistsErr).Once()
+				moc.On("RunScript", cache.DeleteAtRiskEvent, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{int64(1), int64(60)}, nil).Once()
+				moc.On("RunScript", cache.ListAtRiskEvents, mock.Anything, mock.Anything, "-inf", "+inf", "asc", -1).Return([]interface{}{
+					strconv.FormatInt(time.Now().Add(-4*24*time.Hour).UnixMilli(), 10), "40:chat:5gf8d54ss45s8",
+					strconv.FormatInt(time.Now().Unix(), 10), "20:scan:1dc13ds5c1651",
+				}, nil).Once()
 				return moc
 			},
-			wantScore: datatypes.AtRiskResponse{},
-			wantErr:   test.CacheKeyExistsErr,
+			deleteAtRiskEvent: deleted,
+			scoring:           datatypes.ScoringStrategy{Strategy: "decay", HalfLifeDays: 2},
+			wantScore:         datatypes.AtRiskResponse{AtRiskScore: 30, Scoring: datatypes.ScoringStrategy{Strategy: "decay", HalfLifeDays: 2}},
+			wantErr:           nil,
 		},
 		{
-			name: "fail case, key doesn't exists in cache",
+			name: "valid case, key only present in ledger",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(false, nil).Once()
+				moc.On("RunScript", cache.DeleteAtRiskEvent, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{int64(0), int64(0)}, nil).Once()
 				return moc
 			},
-			wantScore: datatypes.AtRiskResponse{},
-			wantErr:   constants.ResourceNotFound,
+			deleteAtRiskEvent: deleted,
+			wantScore:         datatypes.AtRiskResponse{AtRiskScore: 0, Scoring: datatypes.ScoringStrategy{Strategy: "flat"}},
+			wantErr:           nil,
 		},
 		{
-			name: "fail case, error deleting key from cache",
+			name: "valid case, conditional delete",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(true, nil).Once()
-				moc.On("Delete", mock.Anything).Return(test.CacheDeleteKeyErr).Once()
+				moc.On("RunScript", cache.DeleteAtRiskEvent, mock.Anything, "1684231487", mock.AnythingOfType("int64"), "6", "").Return([]interface{}{int64(1), int64(92), "45:scan:1dc13ds5c1651"}, nil).Once()
 				return moc
 			},
-			wantScore: datatypes.AtRiskResponse{},
-			wantErr:   test.CacheDeleteKeyErr,
+			deleteAtRiskEvent: deleted,
+			precondition:      datatypes.Precondition{IfMatch: "6"},
+			wantScore:         datatypes.AtRiskResponse{AtRiskScore: 92, Scoring: datatypes.ScoringStrategy{Strategy: "flat"}},
+			wantErr:           nil,
 		},
 		{
-			name: "fail case, error getting cache keys",
+			name: "fail case, precondition failed",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(true, nil).Once()
-				moc.On("Delete", mock.Anything).Return(nil).Once()
-				moc.On("GetKeys", mock.Anything).Return([]string{}, test.CacheGetKeysErr).Once()
+				moc.On("RunScript", cache.DeleteAtRiskEvent, mock.Anything, "1684231487", mock.AnythingOfType("int64"), "6", "").Return([]interface{}{int64(-1), int64(92), nil, int64(9)}, nil).Once()
 				return moc
 			},
-			wantScore: datatypes.AtRiskResponse{},
-			wantErr:   test.CacheGetKeysErr,
+			deleteAtRiskEvent: notDeleted,
+			precondition:      datatypes.Precondition{IfMatch: "6"},
+			wantScore:         datatypes.AtRiskResponse{},
+			wantErr:           constants.PreconditionFailed,
 		},
 		{
-			name: "fail case, error getting cache value",
+			name: "fail case, key doesn't exists in cache",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(true, nil).Once()
-				moc.On("Delete", mock.Anything).Return(nil).Once()
-				moc.On("GetKeys", mock.Anything).Return([]string{"key1"}, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("", test.CacheGetValueErr).Once()
+				moc.On("RunScript", cache.DeleteAtRiskEvent, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{int64(0), int64(92)}, nil).Once()
 				return moc
 			},
-			wantScore: datatypes.AtRiskResponse{},
-			wantErr:   test.CacheGetValueErr,
+			deleteAtRiskEvent: notInLedger,
+			wantScore:         datatypes.AtRiskResponse{},
+			wantErr:           constants.ResourceNotFound,
 		},
 		{
-			name: "fail case, error invalid cache value",
+			name: "fail case, error deleting event from ledger, event is restored",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(true, nil).Once()
-				moc.On("Delete", mock.Anything).Return(nil).Once()
-				moc.On("GetKeys", mock.Anything).Return([]string{"key1"}, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("dede:scan:1dc13ds5c1651", nil).Once()
+				moc.On("RunScript", cache.DeleteAtRiskEvent, mock.Anything, "1684231487", mock.AnythingOfType("int64")).Return([]interface{}{int64(1), int64(47), "45:scan:1dc13ds5c1651"}, nil).Once()
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, "1684231487", "45:scan:1dc13ds5c1651", constants.AtRiskEventTTL, mock.AnythingOfType("int64"), "", "*").Return([]interface{}{int64(1), int64(92), nil, int64(11)}, nil).Once()
 				return moc
 			},
+			deleteAtRiskEvent: func(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error {
+				return test.DBSomethingWentWrongErr
+			},
 			wantScore: datatypes.AtRiskResponse{},
-			wantErr:   constants.InvalidKeyValue,
+			wantErr:   test.DBSomethingWentWrongErr,
+		},
+		{
+			name: "fail case, error deleting key from cache",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.DeleteAtRiskEvent, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheDeleteKeyErr).Once()
+				return moc
+			},
+			deleteAtRiskEvent: notDeleted,
+			wantScore:         datatypes.AtRiskResponse{},
+			wantErr:           test.CacheDeleteKeyErr,
+		},
+		{
+			name: "fail case, error invalid script reply",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.DeleteAtRiskEvent, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{"1", "92"}, nil).Once()
+				return moc
+			},
+			deleteAtRiskEvent: notDeleted,
+			wantScore:         datatypes.AtRiskResponse{},
+			wantErr:           constants.InvalidScriptReply,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			risk := RiskService{logger.ZapLogger{Logger: zap.NewExample()}, tc.redisClient(), nil}
-			score, err := risk.DeleteCache("key")
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient(), deleteAtRiskEvent: tc.deleteAtRiskEvent}
+			score, err := risk.DeleteCache("email@securly.com:1684231487", tc.scoring, "subject", tc.precondition)
 			if tc.wantScore != score {
-				t.Errorf("expected score %d got %d", tc.wantScore, score)
+				t.Errorf("expected score %+v got %+v", tc.wantScore, score)
 			}
 			if tc.wantErr != err {
 				t.Errorf("expected error %v got %v", tc.wantErr, err)
@@ -242,51 +376,159 @@ func TestDeleteCache(t *testing.T) {
 	}
 }
 
-func TestExtendTTL(t *testing.T) {
+func TestBatchCache(t *testing.T) {
 
 	type tests struct {
-		name        string
-		log         logger.ZapLogger
-		redisClient func() *mocks.RedisOps
-		wantErr     error
+		name              string
+		operations        []datatypes.BatchCacheOperation
+		redisClient       func() *mocks.RedisOps
+		saveAtRiskEvent   func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error
+		deleteAtRiskEvent func(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error
+		wantResp          datatypes.BatchCacheResponse
+		wantErr           error
 	}
 
+	saved := func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+		audited(t, "subject", entry)
+		return nil
+	}
+	notInLedger := func(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error {
+		if mustExist {
+			return constants.ResourceNotFound
+		}
+		audited(t, "subject", entry)
+		return nil
+	}
 	testCases := []tests{
 		{
 			name: "valid case",
+			operations: []datatypes.BatchCacheOperation{
+				{Action: "create", AtRiskKey: "user@securly.com:1684231487", AtRiskValue: "45:scan:1dc13ds5c1651"},
+				{Action: "delete", AtRiskKey: "user@securly.com:1684231400"},
+				{Action: "delete", AtRiskKey: "other@securly.com:1684231400"},
+				{Action: "create", AtRiskKey: "invalid", AtRiskValue: "45:scan:1dc13ds5c1651"},
+			},
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("GetKeys", mock.Anything).Return([]string{"key1", "key2"}, nil).Once()
-				moc.On("SetTTL", mock.Anything, mock.AnythingOfType("int")).Return(nil).Twice()
+				moc.On("Pipeline", mock.AnythingOfType("[]cache.Operation")).Return([]cache.OperationResult{
+					{Key: "user@securly.com:1684231487", Reply: []interface{}{int64(1), int64(60)}},
+					{Key: "user@securly.com:1684231400", Reply: []interface{}{int64(1), int64(45)}},
+					{Key: "other@securly.com:1684231400", Reply: []interface{}{int64(0), int64(10)}},
+				}, nil).Once()
 				return moc
 			},
+			saveAtRiskEvent:   saved,
+			deleteAtRiskEvent: notInLedger,
+			wantResp: datatypes.BatchCacheResponse{
+				Results: []datatypes.BatchCacheResult{
+					{Action: "create", AtRiskKey: "user@securly.com:1684231487", Status: constants.BatchStatusCreated},
+					{Action: "delete", AtRiskKey: "user@securly.com:1684231400", Status: constants.BatchStatusDeleted},
+					{Action: "delete", AtRiskKey: "other@securly.com:1684231400", Status: constants.BatchStatusNotFound, Message: "key doesn't exists"},
+					{Action: "create", AtRiskKey: "invalid", Status: constants.BatchStatusInvalid, Message: constants.InvalidKeyLength.Error()},
+				},
+				TotalAtRiskScores: map[string]int{"user@securly.com": 45},
+			},
 			wantErr: nil,
 		},
 		{
-			name: "fail case, error getting cache keys",
+			name: "valid case, ledger failures are reported and taken back out of cache",
+			operations: []datatypes.BatchCacheOperation{
+				{Action: "create", AtRiskKey: "user@securly.com:1684231487", AtRiskValue: "45:scan:1dc13ds5c1651"},
+				{Action: "delete", AtRiskKey: "user@securly.com:1684231400"},
+				{Action: "delete", AtRiskKey: "other@securly.com:1684231400"},
+			},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Pipeline", mock.MatchedBy(func(ops []cache.Operation) bool { return len(ops) == 3 })).Return([]cache.OperationResult{
+					{Key: "user@securly.com:1684231487", Reply: []interface{}{int64(1), int64(60), nil, int64(12)}},
+					{Key: "user@securly.com:1684231400", Reply: []interface{}{int64(1), int64(45), "15:chat:5gf8d54ss45s8"}},
+					{Key: "other@securly.com:1684231400", Reply: []interface{}{int64(0), int64(0)}},
+				}, nil).Once()
+				moc.On("RunScript", cache.DeleteAtRiskEvent, mock.Anything, "1684231487", mock.AnythingOfType("int64"), "12", "").Return([]interface{}{int64(1), int64(15), "45:scan:1dc13ds5c1651"}, nil).Once()
+				moc.On("RunScript", cache.SetAtRiskEvent, mock.Anything, "1684231400", "15:chat:5gf8d54ss45s8", constants.AtRiskEventTTL, mock.AnythingOfType("int64"), "", "*").Return([]interface{}{int64(1), int64(15), nil, int64(13)}, nil).Once()
+				return moc
+			},
+			saveAtRiskEvent: func(record datatypes.AtRiskEventRecord, entry datatypes.AtRiskAuditEntry) error {
+				return test.DBSomethingWentWrongErr
+			},
+			deleteAtRiskEvent: func(email, timestamp string, entry datatypes.AtRiskAuditEntry, mustExist bool) error {
+				if email == "user@securly.com" {
+					return test.DBSomethingWentWrongErr
+				}
+				return nil
+			},
+			wantResp: datatypes.BatchCacheResponse{
+				Results: []datatypes.BatchCacheResult{
+					{Action: "create", AtRiskKey: "user@securly.com:1684231487", Status: constants.BatchStatusFailed, Message: test.DBSomethingWentWrongErr.Error()},
+					{Action: "delete", AtRiskKey: "user@securly.com:1684231400", Status: constants.BatchStatusFailed, Message: test.DBSomethingWentWrongErr.Error()},
+					{Action: "delete", AtRiskKey: "other@securly.com:1684231400", Status: constants.BatchStatusDeleted},
+				},
+				TotalAtRiskScores: map[string]int{"other@securly.com": 0},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "valid case, failed operation is reported",
+			operations: []datatypes.BatchCacheOperation{
+				{Action: "create", AtRiskKey: "user@securly.com:1684231487", AtRiskValue: "45:scan:1dc13ds5c1651"},
+			},
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("GetKeys", mock.Anything).Return([]string{}, test.CacheGetKeysErr).Once()
+				moc.On("Pipeline", mock.AnythingOfType("[]cache.Operation")).Return([]cache.OperationResult{
+					{Key: "user@securly.com:1684231487", Err: test.CacheSetErr},
+				}, nil).Once()
 				return moc
 			},
-			wantErr: test.CacheGetKeysErr,
+			saveAtRiskEvent:   saved,
+			deleteAtRiskEvent: notInLedger,
+			wantResp: datatypes.BatchCacheResponse{
+				Results: []datatypes.BatchCacheResult{
+					{Action: "create", AtRiskKey: "user@securly.com:1684231487", Status: constants.BatchStatusFailed, Message: test.CacheSetErr.Error()},
+				},
+				TotalAtRiskScores: map[string]int{},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "valid case, invalid action is not sent to cache",
+			operations: []datatypes.BatchCacheOperation{
+				{Action: "update", AtRiskKey: "user@securly.com:1684231487"},
+			},
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			saveAtRiskEvent:   saved,
+			deleteAtRiskEvent: notInLedger,
+			wantResp: datatypes.BatchCacheResponse{
+				Results: []datatypes.BatchCacheResult{
+					{Action: "update", AtRiskKey: "user@securly.com:1684231487", Status: constants.BatchStatusInvalid, Message: constants.InvalidBatchAction.Error()},
+				},
+				TotalAtRiskScores: map[string]int{},
+			},
+			wantErr: nil,
 		},
 		{
-			name: "fail case, error setting ttl for cache keys",
+			name: "fail case, error executing pipeline",
+			operations: []datatypes.BatchCacheOperation{
+				{Action: "delete", AtRiskKey: "user@securly.com:1684231487"},
+			},
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("GetKeys", mock.Anything).Return([]string{"key1"}, nil).Once()
-				moc.On("SetTTL", mock.Anything, mock.AnythingOfType("int")).Return(test.CacheSetTTLErr).Once()
+				moc.On("Pipeline", mock.AnythingOfType("[]cache.Operation")).Return(nil, test.CacheSetErr).Once()
 				return moc
 			},
-			wantErr: test.CacheSetTTLErr,
+			saveAtRiskEvent:   saved,
+			deleteAtRiskEvent: notInLedger,
+			wantResp:          datatypes.BatchCacheResponse{},
+			wantErr:           test.CacheSetErr,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			risk := RiskService{logger.ZapLogger{Logger: zap.NewExample()}, tc.redisClient(), nil}
-			err := risk.ExtendTTL("email", 10)
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient(), saveAtRiskEvent: tc.saveAtRiskEvent, deleteAtRiskEvent: tc.deleteAtRiskEvent}
+			resp, err := risk.BatchCache(tc.operations, "subject")
+			assert.Equal(t, tc.wantResp, resp)
 			if tc.wantErr != err {
 				t.Errorf("expected error %v got %v", tc.wantErr, err)
 			}
@@ -294,151 +536,190 @@ func TestExtendTTL(t *testing.T) {
 	}
 }
 
-func TestGetEventScore(t *testing.T) {
+func TestExtendTTL(t *testing.T) {
 
 	type tests struct {
-		name        string
-		log         logger.ZapLogger
-		redisClient func() *mocks.RedisOps
-		timestamp   string
-		wantKey     string
-		wantValue   string
-		wantScore   int
-		wantErr     error
+		name               string
+		log                logger.ZapLogger
+		redisClient        func() *mocks.RedisOps
+		extendAtRiskEvents func(email, category string, expiresAt, now int64, entry datatypes.AtRiskAuditEntry) error
+		category           string
+		dryRun             bool
+		wantResp           datatypes.ExtendTTLResponse
+		wantErr            error
 	}
 
+	extended := func(email, category string, expiresAt, now int64, entry datatypes.AtRiskAuditEntry) error {
+		audited(t, "subject", entry)
+		return nil
+	}
+	notExtended := func(email, category string, expiresAt, now int64, entry datatypes.AtRiskAuditEntry) error {
+		t.Errorf("unexpected ledger extend %s %d %d", email, expiresAt, now)
+		return nil
+	}
+	bothKeys := []datatypes.ExtendedKeyTTL{{AtRiskKey: "email:1684231400", OldTTL: 300, NewTTL: 10}, {AtRiskKey: "email:1684231487", OldTTL: 5184000, NewTTL: 10}}
 	testCases := []tests{
 		{
-			name: "valid case without mid",
+			name: "valid case",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(true, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("78:email:5gf8d54ss45s8", nil).Once()
+				moc.On("RunScript", cache.ListAtRiskExpiry, []string{"atrisk:events:email", "atrisk:expiry:email", "atrisk:totals:email", "atrisk:timeline:email", "atrisk:mids:email", "atrisk:versions:email"}, mock.AnythingOfType("int64")).Return([]interface{}{"1684231400", int64(300), "1684231487", int64(5184000)}, nil).Once()
+				moc.On("GetKeys", "email:*").Return([]string{}, nil).Once()
+				moc.On("GetTTL", "email:1684231400").Return(300, nil).Once()
+				moc.On("GetTTL", "email:1684231487").Return(5184000, nil).Once()
+				moc.On("RunScript", cache.ExpireAtRiskEvents, []string{"atrisk:events:email", "atrisk:expiry:email", "atrisk:totals:email", "atrisk:timeline:email", "atrisk:mids:email", "atrisk:versions:email", "atrisk:domain:scores:", "atrisk:domain:events:", "atrisk:domain:expiry:", "atrisk:domain:totals:", "atrisk:domain:due:", "email:1684231400", "email:1684231487"}, mock.AnythingOfType("int64"), mock.AnythingOfType("int64"), "1684231400", "1684231487").Return(int64(2), nil).Once()
+				moc.On("RunScript", cache.AtRiskTotal, []string{"atrisk:events:email", "atrisk:expiry:email", "atrisk:totals:email", "atrisk:timeline:email", "atrisk:mids:email", "atrisk:versions:email"}, mock.AnythingOfType("int64")).Return(int64(55), nil).Once()
 				return moc
 			},
-			timestamp: "1684231487",
-			wantKey:   "email:1684231487",
-			wantValue: "78:email:5gf8d54ss45s8",
-			wantScore: 78,
-			wantErr:   nil,
+			extendAtRiskEvents: func(email, category string, expiresAt, now int64, entry datatypes.AtRiskAuditEntry) error {
+				if email != "email" || category != "" || expiresAt != now+10 || entry.TotalAtRiskScore != 55 {
+					t.Errorf("unexpected ledger extend %s %d %d %+v", email, expiresAt, now, entry)
+				}
+				return nil
+			},
+			wantResp: datatypes.ExtendTTLResponse{UserEmail: "email", Keys: bothKeys},
+			wantErr:  nil,
 		},
 		{
-			name: "valid case with mid",
+			name: "valid case, dry run",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(false, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("", nil).Once()
-				moc.On("GetKeys", mock.Anything).Return([]string{"key1", "key2"}, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("46:scan:<<mid", nil).Once()
-
+				moc.On("RunScript", cache.ListAtRiskExpiry, []string{"atrisk:events:email", "atrisk:expiry:email", "atrisk:totals:email", "atrisk:timeline:email", "atrisk:mids:email", "atrisk:versions:email"}, mock.AnythingOfType("int64")).Return([]interface{}{"1684231400", int64(300), "1684231487", int64(5184000)}, nil).Once()
+				moc.On("GetKeys", "email:*").Return([]string{}, nil).Once()
+				moc.On("GetTTL", "email:1684231400").Return(300, nil).Once()
+				moc.On("GetTTL", "email:1684231487").Return(5184000, nil).Once()
 				return moc
 			},
-			timestamp: "1684231487",
-			wantKey:   "key1",
-			wantValue: "46:scan:<<mid",
-			wantScore: 46,
-			wantErr:   nil,
+			extendAtRiskEvents: notExtended,
+			dryRun:             true,
+			wantResp:           datatypes.ExtendTTLResponse{UserEmail: "email", DryRun: true, Keys: bothKeys},
+			wantErr:            nil,
 		},
 		{
-			name: "fail case, error checking if key exists in cache",
+			name: "valid case, category",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(false, test.CacheKeyExistsErr).Once()
+				moc.On("RunScript", cache.ListAtRiskExpiry, []string{"atrisk:events:email", "atrisk:expiry:email", "atrisk:totals:email", "atrisk:timeline:email", "atrisk:mids:email", "atrisk:versions:email"}, mock.AnythingOfType("int64")).Return([]interface{}{"1684231400", int64(300), "1684231487", int64(5184000)}, nil).Once()
+				moc.On("GetKeys", "email:*").Return([]string{}, nil).Once()
+				moc.On("GetTTL", "email:1684231400").Return(300, nil).Once()
+				moc.On("GetTTL", "email:1684231487").Return(5184000, nil).Once()
+				moc.On("RunScript", cache.ListAtRiskEvents, mock.Anything, mock.AnythingOfType("int64"), "-inf", "+inf", "asc", -1).Return([]interface{}{
+					"1684231400", "27:chat:5gf8d54ss45s8",
+					"1684231487", "45:scan:1dc13ds5c1651",
+				}, nil).Once()
+				moc.On("RunScript", cache.ExpireAtRiskEvents, mock.Anything, mock.AnythingOfType("int64"), mock.AnythingOfType("int64"), "1684231487").Return(int64(1), nil).Once()
+				moc.On("RunScript", cache.AtRiskTotal, mock.Anything, mock.AnythingOfType("int64")).Return(int64(72), nil).Once()
 				return moc
 			},
-			timestamp: "1684231487",
-			wantKey:   "",
-			wantValue: "",
-			wantScore: 0,
-			wantErr:   test.CacheKeyExistsErr,
+			extendAtRiskEvents: func(email, category string, expiresAt, now int64, entry datatypes.AtRiskAuditEntry) error {
+				if category != "Scan" {
+					t.Errorf("unexpected ledger extend of category %s", category)
+				}
+				return nil
+			},
+			category: "Scan",
+			wantResp: datatypes.ExtendTTLResponse{UserEmail: "email", Category: "Scan", Keys: bothKeys[1:]},
+			wantErr:  nil,
 		},
 		{
-			name: "fail case, invalid timestamp value",
+			name: "fail case, error fetching events of category",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(false, nil).Once()
+				moc.On("RunScript", cache.ListAtRiskExpiry, []string{"atrisk:events:email", "atrisk:expiry:email", "atrisk:totals:email", "atrisk:timeline:email", "atrisk:mids:email", "atrisk:versions:email"}, mock.AnythingOfType("int64")).Return([]interface{}{"1684231487", int64(5184000)}, nil).Once()
+				moc.On("GetKeys", "email:*").Return([]string{}, nil).Once()
+				moc.On("GetTTL", "email:1684231487").Return(5184000, nil).Once()
+				moc.On("RunScript", cache.ListAtRiskEvents, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheGetValueErr).Once()
 				return moc
 			},
-			timestamp: "16fs54dfs5d46",
-			wantKey:   "",
-			wantValue: "",
-			wantScore: 0,
-			wantErr:   constants.InvalidTimestampValue,
+			extendAtRiskEvents: extended,
+			category:           "scan",
+			dryRun:             true,
+			wantErr:            test.CacheGetValueErr,
 		},
 		{
-			name: "fail case, error getting cache value",
+			name: "valid case, expired event is pruned from the index",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(true, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("", test.CacheGetValueErr).Once()
+				moc.On("RunScript", cache.ListAtRiskExpiry, []string{"atrisk:events:email", "atrisk:expiry:email", "atrisk:totals:email", "atrisk:timeline:email", "atrisk:mids:email", "atrisk:versions:email"}, mock.AnythingOfType("int64")).Return([]interface{}{"1684231400", int64(300)}, nil).Once()
+				moc.On("GetKeys", "email:*").Return([]string{}, nil).Once()
+				moc.On("GetTTL", "email:1684231400").Return(300, nil).Once()
+				moc.On("RunScript", cache.ExpireAtRiskEvents, mock.Anything, mock.AnythingOfType("int64"), mock.AnythingOfType("int64"), "1684231400").Return(int64(1), nil).Once()
+				moc.On("RunScript", cache.AtRiskTotal, mock.Anything, mock.AnythingOfType("int64")).Return(int64(55), nil).Once()
 				return moc
 			},
-			timestamp: "1684231487",
-			wantKey:   "",
-			wantValue: "",
-			wantScore: 0,
-			wantErr:   test.CacheGetValueErr,
+			extendAtRiskEvents: extended,
+			wantResp:           datatypes.ExtendTTLResponse{UserEmail: "email", Keys: bothKeys[:1]},
+			wantErr:            nil,
 		},
 		{
-			name: "fail case, error getting keys from cache",
+			name: "valid case, no keys",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(false, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("", nil).Once()
-				moc.On("GetKeys", mock.Anything).Return([]string{}, test.CacheGetKeysErr).Once()
+				moc.On("RunScript", cache.ListAtRiskExpiry, []string{"atrisk:events:email", "atrisk:expiry:email", "atrisk:totals:email", "atrisk:timeline:email", "atrisk:mids:email", "atrisk:versions:email"}, mock.AnythingOfType("int64")).Return([]interface{}{}, nil).Once()
+				moc.On("GetKeys", "email:*").Return([]string{}, nil).Once()
+				moc.On("RunScript", cache.AtRiskTotal, mock.Anything, mock.AnythingOfType("int64")).Return(int64(0), nil).Once()
 				return moc
 			},
-			timestamp: "1684231487",
-			wantKey:   "",
-			wantValue: "",
-			wantScore: 0,
-			wantErr:   test.CacheGetKeysErr,
+			extendAtRiskEvents: extended,
+			wantResp:           datatypes.ExtendTTLResponse{UserEmail: "email", Keys: []datatypes.ExtendedKeyTTL{}},
+			wantErr:            nil,
 		},
 		{
-			name: "fail case, error getting value from cache",
+			name: "fail case, error extending events in ledger",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(false, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("", nil).Once()
-				moc.On("GetKeys", mock.Anything).Return([]string{"key1", "key2"}, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("", test.CacheGetValueErr).Once()
+				moc.On("RunScript", cache.ListAtRiskExpiry, mock.Anything, mock.AnythingOfType("int64")).Return([]interface{}{"1684231487", int64(100)}, nil).Once()
+				moc.On("GetKeys", "email:*").Return([]string{}, nil).Once()
+				moc.On("GetTTL", "email:1684231487").Return(100, nil).Once()
+				moc.On("RunScript", cache.AtRiskTotal, mock.Anything, mock.AnythingOfType("int64")).Return(int64(45), nil).Once()
 				return moc
 			},
-			timestamp: "1684231487",
-			wantKey:   "",
-			wantValue: "",
-			wantScore: 0,
-			wantErr:   test.CacheGetValueErr,
+			extendAtRiskEvents: func(email, category string, expiresAt, now int64, entry datatypes.AtRiskAuditEntry) error {
+				return test.DBSomethingWentWrongErr
+			},
+			wantErr: test.DBSomethingWentWrongErr,
 		},
 		{
-			name: "fail case, error converting cache value to integer",
+			name: "fail case, error reading expiry index",
 			redisClient: func() *mocks.RedisOps {
 				moc := mocks.NewRedisOps(t)
-				moc.On("Exists", mock.Anything).Return(true, nil).Once()
-				moc.On("GetValue", mock.Anything).Return("fd1b5df:email:5gf8d54ss45s8", nil).Once()
+				moc.On("RunScript", cache.ListAtRiskExpiry, []string{"atrisk:events:email", "atrisk:expiry:email", "atrisk:totals:email", "atrisk:timeline:email", "atrisk:mids:email", "atrisk:versions:email"}, mock.AnythingOfType("int64")).Return(nil, test.CacheGetKeysErr).Once()
 				return moc
 			},
-			timestamp: "1684231487",
-			wantKey:   "",
-			wantValue: "",
-			wantScore: 0,
-			wantErr:   constants.InvalidScoreValue,
+			extendAtRiskEvents: extended,
+			wantErr:            test.CacheGetKeysErr,
+		},
+		{
+			name: "fail case, invalid reply of expiry index",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskExpiry, []string{"atrisk:events:email", "atrisk:expiry:email", "atrisk:totals:email", "atrisk:timeline:email", "atrisk:mids:email", "atrisk:versions:email"}, mock.AnythingOfType("int64")).Return([]interface{}{"1684231487"}, nil).Once()
+				return moc
+			},
+			extendAtRiskEvents: extended,
+			wantErr:            constants.InvalidScriptReply,
+		},
+		{
+			name: "fail case, error updating expiry of events",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskExpiry, []string{"atrisk:events:email", "atrisk:expiry:email", "atrisk:totals:email", "atrisk:timeline:email", "atrisk:mids:email", "atrisk:versions:email"}, mock.AnythingOfType("int64")).Return([]interface{}{"1684231487", int64(100)}, nil).Once()
+				moc.On("GetKeys", "email:*").Return([]string{}, nil).Once()
+				moc.On("GetTTL", "email:1684231487").Return(100, nil).Once()
+				moc.On("RunScript", cache.AtRiskTotal, mock.Anything, mock.AnythingOfType("int64")).Return(int64(45), nil).Once()
+				moc.On("RunScript", cache.ExpireAtRiskEvents, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheSetTTLErr).Once()
+				return moc
+			},
+			extendAtRiskEvents: extended,
+			wantErr:            test.CacheSetTTLErr,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			risk := RiskService{logger.ZapLogger{Logger: zap.NewExample()}, tc.redisClient(), nil}
-			score, err := risk.GetEventScore("email", tc.timestamp, "<<mid")
-			if tc.wantKey != score.AtRiskKey {
-				t.Errorf("expected key %s got %s", tc.wantKey, score.AtRiskKey)
-			}
-			if tc.wantValue != score.AtRiskValue {
-				t.Errorf("expected value %s got %s", tc.wantValue, score.AtRiskValue)
-			}
-			if tc.wantScore != score.AtRiskScore {
-				t.Errorf("expected score %d got %d", tc.wantScore, score.AtRiskScore)
-			}
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient(), extendAtRiskEvents: tc.extendAtRiskEvents}
+			resp, err := risk.ExtendTTL("email", 10, tc.category, "subject", tc.dryRun)
+			assert.Equal(t, tc.wantResp, resp)
 			if tc.wantErr != err {
 				t.Errorf("expected error %v got %v", tc.wantErr, err)
 			}
@@ -446,46 +727,841 @@ func TestGetEventScore(t *testing.T) {
 	}
 }
 
-func TestGetScore(t *testing.T) {
+func TestGetTTL(t *testing.T) {
 
 	type tests struct {
-		name           string
-		getAtRiskScore func(email string) ([]datatypes.RiskScore, error)
-		wantResp       []datatypes.RiskScore
-		wantErr        error
+		name        string
+		email       string
+		redisClient func() *mocks.RedisOps
+		wantResp    datatypes.AtRiskTTLResponse
+		wantErr     error
 	}
 
+	indexKeys := []string{"atrisk:events:a@b.com", "atrisk:expiry:a@b.com", "atrisk:totals:a@b.com", "atrisk:timeline:a@b.com", "atrisk:mids:a@b.com", "atrisk:versions:a@b.com"}
 	testCases := []tests{
 		{
-			name: "valid case",
-			getAtRiskScore: func(email string) ([]datatypes.RiskScore, error) {
-				return []datatypes.RiskScore{
-					{Email: "email1", SelfHarmScore: "65"},
-					{Email: "email2", SelfHarmScore: "47"},
-				}, nil
+			name:  "valid case",
+			email: "a@b.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskExpiry, indexKeys, mock.AnythingOfType("int64")).Return([]interface{}{"1684231400", int64(300), "1684231487", int64(5184000)}, nil).Once()
+				moc.On("GetKeys", "a@b.com:*").Return([]string{"a@b.com:1684231400", "a@b.com:1684231487"}, nil).Once()
+				moc.On("GetTTL", "a@b.com:1684231400").Return(280, nil).Once()
+				moc.On("GetTTL", "a@b.com:1684231487").Return(5184000, nil).Once()
+				return moc
+			},
+			wantResp: datatypes.AtRiskTTLResponse{UserEmail: "a@b.com", Keys: []datatypes.AtRiskKeyTTL{{AtRiskKey: "a@b.com:1684231400", TTL: 280}, {AtRiskKey: "a@b.com:1684231487", TTL: 5184000}}},
+			wantErr:  nil,
+		},
+		{
+			name:  "valid case, key missing from the index and key without expiry",
+			email: "a@b.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskExpiry, indexKeys, mock.AnythingOfType("int64")).Return([]interface{}{"1684231487", int64(300)}, nil).Once()
+				moc.On("GetKeys", "a@b.com:*").Return([]string{"a@b.com:1684231400", "a@b.com:1684231487", "a@b.com:privacy"}, nil).Once()
+				moc.On("GetTTL", "a@b.com:1684231400").Return(-1, nil).Once()
+				moc.On("GetTTL", "a@b.com:1684231487").Return(300, nil).Once()
+				return moc
 			},
-			wantResp: []datatypes.RiskScore{
-				{Email: "email1", SelfHarmScore: "65"},
-				{Email: "email2", SelfHarmScore: "47"},
+			wantResp: datatypes.AtRiskTTLResponse{UserEmail: "a@b.com", Keys: []datatypes.AtRiskKeyTTL{{AtRiskKey: "a@b.com:1684231400", TTL: -1}, {AtRiskKey: "a@b.com:1684231487", TTL: 300}}},
+			wantErr:  nil,
+		},
+		{
+			name:  "valid case, indexed key expired before its ttl was read",
+			email: "a@b.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskExpiry, indexKeys, mock.AnythingOfType("int64")).Return([]interface{}{"1684231487", int64(1)}, nil).Once()
+				moc.On("GetKeys", "a@b.com:*").Return([]string{}, nil).Once()
+				moc.On("GetTTL", "a@b.com:1684231487").Return(0, constants.ResourceNotFound).Once()
+				return moc
 			},
-			wantErr: nil,
+			wantResp: datatypes.AtRiskTTLResponse{UserEmail: "a@b.com", Keys: []datatypes.AtRiskKeyTTL{}},
+			wantErr:  nil,
 		},
 		{
-			name: "fail case",
-			getAtRiskScore: func(email string) ([]datatypes.RiskScore, error) {
-				return nil, test.DBSomethingWentWrongErr
+			name:  "valid case, no keys",
+			email: "a@b.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskExpiry, indexKeys, mock.AnythingOfType("int64")).Return([]interface{}{}, nil).Once()
+				moc.On("GetKeys", "a@b.com:*").Return([]string{}, nil).Once()
+				return moc
 			},
-			wantResp: nil,
-			wantErr:  test.DBSomethingWentWrongErr,
+			wantResp: datatypes.AtRiskTTLResponse{UserEmail: "a@b.com", Keys: []datatypes.AtRiskKeyTTL{}},
+			wantErr:  nil,
 		},
-	}
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			risk := RiskService{logger.ZapLogger{Logger: zap.NewExample()}, nil, tc.getAtRiskScore}
-			resp, err := risk.GetScore("key")
-			if !assert.Equal(t, tc.wantResp, resp) {
-				t.Errorf("expected resp %+v got %+v", tc.wantResp, resp)
-			}
+		{
+			name:  "fail case, error reading expiry index",
+			email: "a@b.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskExpiry, indexKeys, mock.AnythingOfType("int64")).Return(nil, test.CacheGetKeysErr).Once()
+				return moc
+			},
+			wantResp: datatypes.AtRiskTTLResponse{},
+			wantErr:  test.CacheGetKeysErr,
+		},
+		{
+			name:  "fail case, invalid timestamp in expiry index",
+			email: "a@b.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskExpiry, indexKeys, mock.AnythingOfType("int64")).Return([]interface{}{int64(1684231487), int64(300)}, nil).Once()
+				return moc
+			},
+			wantResp: datatypes.AtRiskTTLResponse{},
+			wantErr:  constants.InvalidScriptReply,
+		},
+		{
+			name:  "fail case, error scanning keys",
+			email: "a@b.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskExpiry, indexKeys, mock.AnythingOfType("int64")).Return([]interface{}{}, nil).Once()
+				moc.On("GetKeys", "a@b.com:*").Return([]string{}, test.CacheGetKeysErr).Once()
+				return moc
+			},
+			wantResp: datatypes.AtRiskTTLResponse{},
+			wantErr:  test.CacheGetKeysErr,
+		},
+		{
+			name:  "fail case, error reading ttl",
+			email: "a@b.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskExpiry, indexKeys, mock.AnythingOfType("int64")).Return([]interface{}{"1684231487", int64(300)}, nil).Once()
+				moc.On("GetKeys", "a@b.com:*").Return([]string{}, nil).Once()
+				moc.On("GetTTL", "a@b.com:1684231487").Return(0, test.CacheGetKeysErr).Once()
+				return moc
+			},
+			wantResp: datatypes.AtRiskTTLResponse{},
+			wantErr:  test.CacheGetKeysErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient()}
+			resp, err := risk.GetTTL(tc.email)
+			assert.Equal(t, tc.wantResp, resp)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRebuildIndex(t *testing.T) {
+
+	type tests struct {
+		name        string
+		redisClient func() *mocks.RedisOps
+		wantEmails  int
+		wantErr     error
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "*:*").Return([]string{"user@securly.com:1684231487", "atrisk:totals:user@securly.com", "user@securly.com:1684231400", "other@securly.com:abc"}, nil).Once()
+				moc.On("RunScript", cache.RebuildAtRiskIndex, []string{"atrisk:events:user@securly.com", "atrisk:expiry:user@securly.com", "atrisk:totals:user@securly.com", "atrisk:timeline:user@securly.com", "atrisk:mids:user@securly.com", "atrisk:versions:user@securly.com", "atrisk:domain:scores:securly.com", "atrisk:domain:events:securly.com", "atrisk:domain:expiry:securly.com", "atrisk:domain:totals:securly.com", "atrisk:domain:due:securly.com", "user@securly.com:1684231487", "user@securly.com:1684231400"}, mock.AnythingOfType("int64"), "1684231487", "1684231400").Return(int64(2), nil).Once()
+				return moc
+			},
+			wantEmails: 1,
+			wantErr:    nil,
+		},
+		{
+			name: "fail case, error getting cache keys",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "*:*").Return([]string{}, test.CacheGetKeysErr).Once()
+				return moc
+			},
+			wantEmails: 0,
+			wantErr:    test.CacheGetKeysErr,
+		},
+		{
+			name: "fail case, error rebuilding aggregates",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "*:*").Return([]string{"user@securly.com:1684231487"}, nil).Once()
+				moc.On("RunScript", cache.RebuildAtRiskIndex, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheSetErr).Once()
+				return moc
+			},
+			wantEmails: 0,
+			wantErr:    test.CacheSetErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient()}
+			emails, err := risk.RebuildIndex()
+			if tc.wantEmails != emails {
+				t.Errorf("expected emails %d got %d", tc.wantEmails, emails)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRebuildCache(t *testing.T) {
+
+	type tests struct {
+		name                 string
+		emails               []string
+		redisClient          func() *mocks.RedisOps
+		getAtRiskEvents      func(email string, now int64) ([]datatypes.AtRiskEventRecord, error)
+		getAtRiskEventEmails func(now int64) ([]string, error)
+		wantEvents           int
+		wantErr              error
+	}
+
+	indexKeys := []string{"atrisk:events:user@securly.com", "atrisk:expiry:user@securly.com", "atrisk:totals:user@securly.com", "atrisk:timeline:user@securly.com", "atrisk:mids:user@securly.com", "atrisk:versions:user@securly.com", "atrisk:domain:scores:securly.com", "atrisk:domain:events:securly.com", "atrisk:domain:expiry:securly.com", "atrisk:domain:totals:securly.com", "atrisk:domain:due:securly.com"}
+	ledger := func(email string, now int64) ([]datatypes.AtRiskEventRecord, error) {
+		return []datatypes.AtRiskEventRecord{
+			{UserEmail: email, EventTimestamp: "1684231487", AtRiskValue: "45:scan:1dc13ds5c1651", ExpiresAt: now + 100},
+			{UserEmail: email, EventTimestamp: "1684231400", AtRiskValue: "10:chat:5gf8d54ss45s8", ExpiresAt: now + 200},
+		}, nil
+	}
+	testCases := []tests{
+		{
+			name:   "valid case, single email",
+			emails: []string{"user@securly.com"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Pipeline", []cache.Operation{
+					{Action: cache.SetAction, Key: "user@securly.com:1684231487", Value: "45:scan:1dc13ds5c1651", TTL: 100 * time.Second},
+					{Action: cache.SetAction, Key: "user@securly.com:1684231400", Value: "10:chat:5gf8d54ss45s8", TTL: 200 * time.Second},
+				}).Return([]cache.OperationResult{{Key: "user@securly.com:1684231487", Affected: 1}, {Key: "user@securly.com:1684231400", Affected: 1}}, nil).Once()
+				moc.On("GetKeys", "user@securly.com:*").Return([]string{"user@securly.com:1684231487", "user@securly.com:1684231400"}, nil).Once()
+				moc.On("RunScript", cache.RebuildAtRiskIndex, append(indexKeys, "user@securly.com:1684231487", "user@securly.com:1684231400"), mock.AnythingOfType("int64"), "1684231487", "1684231400").Return(int64(2), nil).Once()
+				return moc
+			},
+			getAtRiskEvents: ledger,
+			wantEvents:      2,
+			wantErr:         nil,
+		},
+		{
+			name:   "valid case, all emails of the ledger",
+			emails: nil,
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetKeys", "user@securly.com:*").Return([]string{}, nil).Once()
+				moc.On("RunScript", cache.RebuildAtRiskIndex, indexKeys, mock.AnythingOfType("int64")).Return(int64(0), nil).Once()
+				return moc
+			},
+			getAtRiskEvents: func(email string, now int64) ([]datatypes.AtRiskEventRecord, error) {
+				return []datatypes.AtRiskEventRecord{}, nil
+			},
+			getAtRiskEventEmails: func(now int64) ([]string, error) {
+				return []string{"user@securly.com"}, nil
+			},
+			wantEvents: 0,
+			wantErr:    nil,
+		},
+		{
+			name:   "fail case, error fetching emails from ledger",
+			emails: nil,
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			getAtRiskEventEmails: func(now int64) ([]string, error) {
+				return nil, test.DBSomethingWentWrongErr
+			},
+			wantEvents: 0,
+			wantErr:    test.DBSomethingWentWrongErr,
+		},
+		{
+			name:   "fail case, error fetching events from ledger",
+			emails: []string{"user@securly.com"},
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			getAtRiskEvents: func(email string, now int64) ([]datatypes.AtRiskEventRecord, error) {
+				return nil, test.DBSomethingWentWrongErr
+			},
+			wantEvents: 0,
+			wantErr:    test.DBSomethingWentWrongErr,
+		},
+		{
+			name:   "fail case, error restoring event into cache",
+			emails: []string{"user@securly.com"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Pipeline", mock.AnythingOfType("[]cache.Operation")).Return([]cache.OperationResult{{Key: "user@securly.com:1684231487", Err: test.CacheSetErr}, {Key: "user@securly.com:1684231400", Affected: 1}}, nil).Once()
+				return moc
+			},
+			getAtRiskEvents: ledger,
+			wantEvents:      0,
+			wantErr:         test.CacheSetErr,
+		},
+		{
+			name:   "fail case, error rebuilding aggregates",
+			emails: []string{"user@securly.com"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Pipeline", mock.AnythingOfType("[]cache.Operation")).Return([]cache.OperationResult{{Key: "user@securly.com:1684231487", Affected: 1}, {Key: "user@securly.com:1684231400", Affected: 1}}, nil).Once()
+				moc.On("GetKeys", "user@securly.com:*").Return([]string{"user@securly.com:1684231487", "user@securly.com:1684231400"}, nil).Once()
+				moc.On("RunScript", cache.RebuildAtRiskIndex, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheSetErr).Once()
+				return moc
+			},
+			getAtRiskEvents: ledger,
+			wantEvents:      0,
+			wantErr:         test.CacheSetErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient(), getAtRiskEvents: tc.getAtRiskEvents, getAtRiskEventEmails: tc.getAtRiskEventEmails}
+			events, err := risk.RebuildCache(tc.emails...)
+			if tc.wantEvents != events {
+				t.Errorf("expected events %d got %d", tc.wantEvents, events)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGetEventScore(t *testing.T) {
+
+	type tests struct {
+		name        string
+		log         logger.ZapLogger
+		redisClient func() *mocks.RedisOps
+		timestamp   string
+		category    string
+		wantKey     string
+		wantValue   string
+		wantScore   int
+		wantVersion int64
+		wantErr     error
+	}
+
+	eventKeys := []string{"email:1684231487", "atrisk:events:email", "atrisk:expiry:email", "atrisk:totals:email", "atrisk:timeline:email", "atrisk:mids:email", "atrisk:versions:email"}
+	testCases := []tests{
+		{
+			name: "valid case without mid",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Exists", mock.Anything).Return(true, nil).Once()
+				moc.On("RunScript", cache.GetAtRiskEvent, eventKeys, "1684231487").Return([]interface{}{"78:email:5gf8d54ss45s8", int64(3)}, nil).Once()
+				return moc
+			},
+			timestamp:   "1684231487",
+			wantKey:     "email:1684231487",
+			wantValue:   "78:email:5gf8d54ss45s8",
+			wantScore:   78,
+			wantVersion: 3,
+			wantErr:     nil,
+		},
+		{
+			name: "valid case with mid",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Exists", "email:1684231487000").Return(false, nil).Once()
+				moc.On("RunScript", cache.GetAtRiskEvent, eventKeys, "1684231487").Return(nil, nil).Once()
+				moc.On("RunScript", cache.FindAtRiskEvent, eventKeys[1:], mock.AnythingOfType("int64"), "<<mid").Return([]interface{}{"1684231400", "46:scan:<<mid", int64(2)}, nil).Once()
+				return moc
+			},
+			timestamp:   "1684231487000",
+			wantKey:     "email:1684231400",
+			wantValue:   "46:scan:<<mid",
+			wantScore:   46,
+			wantVersion: 2,
+			wantErr:     nil,
+		},
+		{
+			name: "valid case, event in category",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Exists", mock.Anything).Return(true, nil).Once()
+				moc.On("RunScript", cache.GetAtRiskEvent, eventKeys, "1684231487").Return([]interface{}{"46:scan:5gf8d54ss45s8", int64(3)}, nil).Once()
+				return moc
+			},
+			timestamp:   "1684231487",
+			category:    "SCAN",
+			wantKey:     "email:1684231487",
+			wantValue:   "46:scan:5gf8d54ss45s8",
+			wantScore:   46,
+			wantVersion: 3,
+			wantErr:     nil,
+		},
+		{
+			name: "fail case, event not in category",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Exists", mock.Anything).Return(true, nil).Once()
+				moc.On("RunScript", cache.GetAtRiskEvent, eventKeys, "1684231487").Return([]interface{}{"46:scan:5gf8d54ss45s8", int64(3)}, nil).Once()
+				return moc
+			},
+			timestamp: "1684231487",
+			category:  "chat",
+			wantErr:   constants.ResourceNotFound,
+		},
+		{
+			name: "fail case, error checking if key exists in cache",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Exists", mock.Anything).Return(false, test.CacheKeyExistsErr).Once()
+				return moc
+			},
+			timestamp: "1684231487",
+			wantKey:   "",
+			wantValue: "",
+			wantScore: 0,
+			wantErr:   test.CacheKeyExistsErr,
+		},
+		{
+			name: "fail case, invalid timestamp value",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Exists", mock.Anything).Return(false, nil).Once()
+				return moc
+			},
+			timestamp: "16fs54dfs5d46",
+			wantKey:   "",
+			wantValue: "",
+			wantScore: 0,
+			wantErr:   constants.InvalidTimestampValue,
+		},
+		{
+			name: "fail case, error getting cache value",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Exists", mock.Anything).Return(true, nil).Once()
+				moc.On("RunScript", cache.GetAtRiskEvent, mock.Anything, mock.Anything).Return(nil, test.CacheGetValueErr).Once()
+				return moc
+			},
+			timestamp: "1684231487",
+			wantKey:   "",
+			wantValue: "",
+			wantScore: 0,
+			wantErr:   test.CacheGetValueErr,
+		},
+		{
+			name: "fail case, error looking up mid in cache",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Exists", mock.Anything).Return(false, nil).Once()
+				moc.On("RunScript", cache.GetAtRiskEvent, mock.Anything, mock.Anything).Return(nil, nil).Once()
+				moc.On("RunScript", cache.FindAtRiskEvent, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheGetValueErr).Once()
+				return moc
+			},
+			timestamp: "1684231487",
+			wantKey:   "",
+			wantValue: "",
+			wantScore: 0,
+			wantErr:   test.CacheGetValueErr,
+		},
+		{
+			name: "fail case, mid not found in cache",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Exists", mock.Anything).Return(false, nil).Once()
+				moc.On("RunScript", cache.GetAtRiskEvent, mock.Anything, mock.Anything).Return(nil, nil).Once()
+				moc.On("RunScript", cache.FindAtRiskEvent, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+				return moc
+			},
+			timestamp: "1684231487",
+			wantKey:   "",
+			wantValue: "",
+			wantScore: 0,
+			wantErr:   constants.ResourceNotFound,
+		},
+		{
+			name: "fail case, error converting cache value to integer",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Exists", mock.Anything).Return(true, nil).Once()
+				moc.On("RunScript", cache.GetAtRiskEvent, mock.Anything, mock.Anything).Return([]interface{}{"fd1b5df:email:5gf8d54ss45s8", int64(1)}, nil).Once()
+				return moc
+			},
+			timestamp: "1684231487",
+			wantKey:   "",
+			wantValue: "",
+			wantScore: 0,
+			wantErr:   constants.InvalidScoreValue,
+		},
+		{
+			name: "fail case, invalid event reply",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Exists", mock.Anything).Return(true, nil).Once()
+				moc.On("RunScript", cache.GetAtRiskEvent, mock.Anything, mock.Anything).Return("78:email:5gf8d54ss45s8", nil).Once()
+				return moc
+			},
+			timestamp: "1684231487",
+			wantKey:   "",
+			wantValue: "",
+			wantScore: 0,
+			wantErr:   constants.InvalidScriptReply,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient()}
+			score, err := risk.GetEventScore("email", tc.timestamp, "<<mid", tc.category)
+			if tc.wantKey != score.AtRiskKey {
+				t.Errorf("expected key %s got %s", tc.wantKey, score.AtRiskKey)
+			}
+			if tc.wantValue != score.AtRiskValue {
+				t.Errorf("expected value %s got %s", tc.wantValue, score.AtRiskValue)
+			}
+			if tc.wantScore != score.AtRiskScore {
+				t.Errorf("expected score %d got %d", tc.wantScore, score.AtRiskScore)
+			}
+			if tc.wantVersion != score.Version {
+				t.Errorf("expected version %d got %d", tc.wantVersion, score.Version)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGetEventByMid(t *testing.T) {
+
+	type tests struct {
+		name        string
+		redisClient func() *mocks.RedisOps
+		wantResp    datatypes.EventScoreResponse
+		wantErr     error
+	}
+
+	indexKeys := []string{"atrisk:events:email@securly.com", "atrisk:expiry:email@securly.com", "atrisk:totals:email@securly.com", "atrisk:timeline:email@securly.com", "atrisk:mids:email@securly.com", "atrisk:versions:email@securly.com"}
+	testCases := []tests{
+		{
+			name: "valid case",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.FindAtRiskEvent, indexKeys, mock.AnythingOfType("int64"), "<mid@mail>").Return([]interface{}{"1684231487", "45:scan:<mid@mail>", int64(4)}, nil).Once()
+				return moc
+			},
+			wantResp: datatypes.EventScoreResponse{
+				AtRiskKey:   "email@securly.com:1684231487",
+				AtRiskValue: "45:scan:<mid@mail>",
+				AtRiskScore: 45,
+				Event:       datatypes.AtRiskEvent{Score: 45, Category: "scan", MessageID: "<mid@mail>"},
+				Version:     4,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, mid not found",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.FindAtRiskEvent, indexKeys, mock.AnythingOfType("int64"), "<mid@mail>").Return(nil, nil).Once()
+				return moc
+			},
+			wantResp: datatypes.EventScoreResponse{},
+			wantErr:  constants.ResourceNotFound,
+		},
+		{
+			name: "fail case, error running script",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.FindAtRiskEvent, indexKeys, mock.AnythingOfType("int64"), "<mid@mail>").Return(nil, test.CacheGetValueErr).Once()
+				return moc
+			},
+			wantResp: datatypes.EventScoreResponse{},
+			wantErr:  test.CacheGetValueErr,
+		},
+		{
+			name: "fail case, invalid script reply",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.FindAtRiskEvent, indexKeys, mock.AnythingOfType("int64"), "<mid@mail>").Return("1684231487", nil).Once()
+				return moc
+			},
+			wantResp: datatypes.EventScoreResponse{},
+			wantErr:  constants.InvalidScriptReply,
+		},
+		{
+			name: "fail case, invalid cached value",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.FindAtRiskEvent, indexKeys, mock.AnythingOfType("int64"), "<mid@mail>").Return([]interface{}{"1684231487", "ab:scan:<mid@mail>", int64(4)}, nil).Once()
+				return moc
+			},
+			wantResp: datatypes.EventScoreResponse{},
+			wantErr:  constants.InvalidScoreValue,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient()}
+			resp, err := risk.GetEventByMid("email@securly.com", "<mid@mail>")
+			if !assert.Equal(t, tc.wantResp, resp) {
+				t.Errorf("expected resp %+v got %+v", tc.wantResp, resp)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGetScore(t *testing.T) {
+
+	type tests struct {
+		name           string
+		emails         []string
+		category       string
+		redisClient    func() *mocks.RedisOps
+		getAtRiskScore func(emails []string) ([]datatypes.RiskScore, error)
+		wantResp       datatypes.RiskScoresResponse
+		wantErr        error
+	}
+
+	totals := []interface{}{"scan", int64(50), int64(2), "chat", int64(27), int64(1)}
+	testCases := []tests{
+		{
+			name:   "valid case",
+			emails: []string{"email1@securly.com", "Email2@securly.com", "email3@securly.com"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.AtRiskCategoryTotals, []string{"atrisk:events:email1@securly.com", "atrisk:expiry:email1@securly.com", "atrisk:totals:email1@securly.com", "atrisk:timeline:email1@securly.com", "atrisk:mids:email1@securly.com", "atrisk:versions:email1@securly.com"}, mock.AnythingOfType("int64")).Return(totals, nil).Once()
+				moc.On("RunScript", cache.AtRiskCategoryTotals, mock.Anything, mock.AnythingOfType("int64")).Return([]interface{}{}, nil).Twice()
+				return moc
+			},
+			getAtRiskScore: func(emails []string) ([]datatypes.RiskScore, error) {
+				return []datatypes.RiskScore{
+					{Email: "email2@securly.com", SelfHarmScore: "47"},
+					{Email: "email1@securly.com", SelfHarmScore: "65"},
+					{Email: "email2@securly.com", SelfHarmScore: "16"},
+				}, nil
+			},
+			wantResp: datatypes.RiskScoresResponse{
+				AtRiskScores: []datatypes.EmailRiskScores{
+					{UserEmail: "email1@securly.com", SelfHarmScores: []string{"65"}, TotalAtRiskScore: 77, Categories: []datatypes.CategoryScore{{Category: "chat", Score: 27, Events: 1}, {Category: "scan", Score: 50, Events: 2}}},
+					{UserEmail: "Email2@securly.com", SelfHarmScores: []string{"47", "16"}, Categories: []datatypes.CategoryScore{}},
+				},
+				NotFound: []string{"email3@securly.com"},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "valid case, cached events without scores",
+			emails: []string{"email1@securly.com"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.AtRiskCategoryTotals, mock.Anything, mock.AnythingOfType("int64")).Return(totals, nil).Once()
+				return moc
+			},
+			getAtRiskScore: func(emails []string) ([]datatypes.RiskScore, error) {
+				return []datatypes.RiskScore{}, nil
+			},
+			wantResp: datatypes.RiskScoresResponse{
+				AtRiskScores: []datatypes.EmailRiskScores{
+					{UserEmail: "email1@securly.com", SelfHarmScores: []string{}, TotalAtRiskScore: 77, Categories: []datatypes.CategoryScore{{Category: "chat", Score: 27, Events: 1}, {Category: "scan", Score: 50, Events: 2}}},
+				},
+				NotFound: []string{},
+			},
+			wantErr: nil,
+		},
+		{
+			name:     "valid case, category",
+			emails:   []string{"email1@securly.com"},
+			category: "Scan",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.AtRiskCategoryTotals, mock.Anything, mock.AnythingOfType("int64")).Return(totals, nil).Once()
+				return moc
+			},
+			getAtRiskScore: func(emails []string) ([]datatypes.RiskScore, error) {
+				return []datatypes.RiskScore{{Email: "email1@securly.com", SelfHarmScore: "65"}}, nil
+			},
+			wantResp: datatypes.RiskScoresResponse{
+				Category: "Scan",
+				AtRiskScores: []datatypes.EmailRiskScores{
+					{UserEmail: "email1@securly.com", SelfHarmScores: []string{"65"}, TotalAtRiskScore: 50, Categories: []datatypes.CategoryScore{{Category: "scan", Score: 50, Events: 2}}},
+				},
+				NotFound: []string{},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "fail case, error fetching category totals",
+			emails: []string{"email1@securly.com"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.AtRiskCategoryTotals, mock.Anything, mock.Anything).Return(nil, test.CacheGetValueErr).Once()
+				return moc
+			},
+			getAtRiskScore: func(emails []string) ([]datatypes.RiskScore, error) {
+				return []datatypes.RiskScore{{Email: "email1@securly.com", SelfHarmScore: "65"}}, nil
+			},
+			wantResp: datatypes.RiskScoresResponse{},
+			wantErr:  test.CacheGetValueErr,
+		},
+		{
+			name:   "fail case, invalid category totals",
+			emails: []string{"email1@securly.com"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.AtRiskCategoryTotals, mock.Anything, mock.Anything).Return([]interface{}{"scan", int64(50)}, nil).Once()
+				return moc
+			},
+			getAtRiskScore: func(emails []string) ([]datatypes.RiskScore, error) {
+				return []datatypes.RiskScore{{Email: "email1@securly.com", SelfHarmScore: "65"}}, nil
+			},
+			wantResp: datatypes.RiskScoresResponse{},
+			wantErr:  constants.InvalidScriptReply,
+		},
+		{
+			name:   "valid case, no scores",
+			emails: []string{"email1@securly.com"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.AtRiskCategoryTotals, mock.Anything, mock.AnythingOfType("int64")).Return([]interface{}{}, nil).Once()
+				return moc
+			},
+			getAtRiskScore: func(emails []string) ([]datatypes.RiskScore, error) {
+				return []datatypes.RiskScore{}, nil
+			},
+			wantResp: datatypes.RiskScoresResponse{AtRiskScores: []datatypes.EmailRiskScores{}, NotFound: []string{"email1@securly.com"}},
+			wantErr:  nil,
+		},
+		{
+			name:   "fail case",
+			emails: []string{"email1@securly.com"},
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			getAtRiskScore: func(emails []string) ([]datatypes.RiskScore, error) {
+				return nil, test.DBSomethingWentWrongErr
+			},
+			wantResp: datatypes.RiskScoresResponse{},
+			wantErr:  test.DBSomethingWentWrongErr,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient(), getAtRiskScore: tc.getAtRiskScore}
+			resp, err := risk.GetScore(tc.emails, tc.category)
+			if !assert.Equal(t, tc.wantResp, resp) {
+				t.Errorf("expected resp %+v got %+v", tc.wantResp, resp)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGetEvents(t *testing.T) {
+
+	type tests struct {
+		name        string
+		request     datatypes.AtRiskEventsRequest
+		redisClient func() *mocks.RedisOps
+		wantResp    datatypes.AtRiskEventsResponse
+		wantErr     error
+	}
+
+	indexKeys := []string{"atrisk:events:email@securly.com", "atrisk:expiry:email@securly.com", "atrisk:totals:email@securly.com", "atrisk:timeline:email@securly.com", "atrisk:mids:email@securly.com", "atrisk:versions:email@securly.com"}
+	testCases := []tests{
+		{
+			name:    "valid case, next page available",
+			request: datatypes.AtRiskEventsRequest{UserEmail: "email@securly.com", Order: "desc", Limit: 2},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskEvents, indexKeys, mock.AnythingOfType("int64"), "-inf", "+inf", "desc", 3).Return([]interface{}{
+					"1684231487", "45:scan:<mid1>",
+					"1684231400", "27:docs:<mid2>",
+					"1684231300", "12:docs:<mid3>",
+				}, nil).Once()
+				return moc
+			},
+			wantResp: datatypes.AtRiskEventsResponse{
+				Events: []datatypes.AtRiskEventItem{
+					{AtRiskKey: "email@securly.com:1684231487", Timestamp: "1684231487", Event: datatypes.AtRiskEvent{Score: 45, Category: "scan", MessageID: "<mid1>"}},
+					{AtRiskKey: "email@securly.com:1684231400", Timestamp: "1684231400", Event: datatypes.AtRiskEvent{Score: 27, Category: "docs", MessageID: "<mid2>"}},
+				},
+				NextCursor: "1684231400",
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "valid case, next page available after an invalid value",
+			request: datatypes.AtRiskEventsRequest{UserEmail: "email@securly.com", Order: "desc", Limit: 2},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskEvents, indexKeys, mock.AnythingOfType("int64"), "-inf", "+inf", "desc", 3).Return([]interface{}{
+					"1684231487", "45:scan:<mid1>",
+					"1684231400", "invalid",
+					"1684231300", "12:docs:<mid3>",
+				}, nil).Once()
+				return moc
+			},
+			wantResp: datatypes.AtRiskEventsResponse{
+				Events: []datatypes.AtRiskEventItem{
+					{AtRiskKey: "email@securly.com:1684231487", Timestamp: "1684231487", Event: datatypes.AtRiskEvent{Score: 45, Category: "scan", MessageID: "<mid1>"}},
+				},
+				NextCursor: "1684231400",
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "valid case, ascending page after cursor",
+			request: datatypes.AtRiskEventsRequest{UserEmail: "email@securly.com", From: "1684231000", To: "1684231999", Order: "asc", Cursor: "1684231300", Limit: 2},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskEvents, indexKeys, mock.AnythingOfType("int64"), "(1684231300", "1684231999", "asc", 3).Return([]interface{}{
+					"1684231400", "27:docs:<mid2>",
+					"1684231450", "invalid",
+				}, nil).Once()
+				return moc
+			},
+			wantResp: datatypes.AtRiskEventsResponse{
+				Events: []datatypes.AtRiskEventItem{
+					{AtRiskKey: "email@securly.com:1684231400", Timestamp: "1684231400", Event: datatypes.AtRiskEvent{Score: 27, Category: "docs", MessageID: "<mid2>"}},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "fail case, error fetching events",
+			request: datatypes.AtRiskEventsRequest{UserEmail: "email@securly.com", Order: "desc", Limit: 2},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskEvents, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheGetValueErr).Once()
+				return moc
+			},
+			wantResp: datatypes.AtRiskEventsResponse{},
+			wantErr:  test.CacheGetValueErr,
+		},
+		{
+			name:    "fail case, invalid script reply",
+			request: datatypes.AtRiskEventsRequest{UserEmail: "email@securly.com", Order: "desc", Limit: 2},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.ListAtRiskEvents, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{"1684231400"}, nil).Once()
+				return moc
+			},
+			wantResp: datatypes.AtRiskEventsResponse{},
+			wantErr:  constants.InvalidScriptReply,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient()}
+			resp, err := risk.GetEvents(tc.request)
+			assert.Equal(t, tc.wantResp, resp)
 			if tc.wantErr != err {
 				t.Errorf("expected error %v got %v", tc.wantErr, err)
 			}
@@ -0,0 +1,152 @@
+package atrisk
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/pkg/cache"
+)
+
+// GetDomainSummary returns the students of a domain with the highest totals, the number
+// of students in each score band and the number of live events. It reads the domain
+// aggregates kept by the event write scripts, a student is dropped once the last event
+// expires and a student whose next event expired is scored again before the read
+func (s RiskService) GetDomainSummary(domain string, limit int, bands []int) (datatypes.AtRiskDomainSummaryResponse, error) {
+	domain = strings.ToLower(domain)
+	bands = append([]int{}, bands...)
+	sort.Ints(bands)
+
+	now := time.Now().Unix()
+	domainKeys := atRiskDomainKeys(domain)
+	due, err := s.dueStudents(domain, now)
+	if err != nil {
+		return datatypes.AtRiskDomainSummaryResponse{}, err
+	}
+
+	scriptKeys := append([]string{}, domainKeys...)
+	for _, email := range due {
+		scriptKeys = append(scriptKeys, atRiskIndexKeys(email)...)
+	}
+
+	args := []interface{}{now, limit}
+	for _, band := range bands {
+		args = append(args, band)
+	}
+
+	reply, err := s.redis.RunScript(cache.AtRiskDomainSummary, scriptKeys, args...)
+	if err != nil {
+		s.log.Error("unable to fetch domain summary from redis", map[string]interface{}{"domain": domain, "error": err})
+		return datatypes.AtRiskDomainSummaryResponse{}, err
+	}
+
+	response, err := parseDomainSummary(reply, bands)
+	if err != nil {
+		s.log.Error("invalid domain summary received from redis", map[string]interface{}{"domain": domain, "reply": reply, "error": err})
+		return datatypes.AtRiskDomainSummaryResponse{}, err
+	}
+
+	response.Domain = domain
+	return response, nil
+}
+
+// dueStudents returns the students of a domain whose next event expired since they were scored
+func (s RiskService) dueStudents(domain string, now int64) ([]string, error) {
+	reply, err := s.redis.RunScript(cache.DueAtRiskDomainUsers, atRiskDomainKeys(domain), now)
+	if err != nil {
+		s.log.Error("unable to fetch due students of domain from redis", map[string]interface{}{"domain": domain, "error": err})
+		return nil, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok {
+		s.log.Error("invalid due students received from redis", map[string]interface{}{"domain": domain, "reply": reply})
+		return nil, constants.InvalidScriptReply
+	}
+
+	due := []string{}
+	for _, value := range values {
+		email, ok := value.(string)
+		if !ok {
+			s.log.Error("invalid due student received from redis", map[string]interface{}{"domain": domain, "email": value})
+			return nil, constants.InvalidScriptReply
+		}
+		due = append(due, email)
+	}
+	return due, nil
+}
+
+// parseDomainSummary reads the {events, students, top students, bands} reply of the summary script
+func parseDomainSummary(reply interface{}, bands []int) (datatypes.AtRiskDomainSummaryResponse, error) {
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 4 {
+		return datatypes.AtRiskDomainSummaryResponse{}, constants.InvalidScriptReply
+	}
+
+	events, ok := values[0].(int64)
+	if !ok {
+		return datatypes.AtRiskDomainSummaryResponse{}, constants.InvalidScriptReply
+	}
+	students, ok := values[1].(int64)
+	if !ok {
+		return datatypes.AtRiskDomainSummaryResponse{}, constants.InvalidScriptReply
+	}
+	top, ok := values[2].([]interface{})
+	if !ok || len(top)%3 != 0 {
+		return datatypes.AtRiskDomainSummaryResponse{}, constants.InvalidScriptReply
+	}
+	counts, ok := values[3].([]interface{})
+	if !ok || len(counts) != len(bands) {
+		return datatypes.AtRiskDomainSummaryResponse{}, constants.InvalidScriptReply
+	}
+
+	response := datatypes.AtRiskDomainSummaryResponse{
+		Students:     int(students),
+		ActiveEvents: int(events),
+		TopStudents:  []datatypes.AtRiskStudent{},
+		ScoreBands:   []datatypes.ScoreBand{},
+	}
+	for i := 0; i < len(top); i += 3 {
+		email, _ := top[i].(string)
+		total, err := replyInt(top[i+1])
+		if err != nil {
+			return datatypes.AtRiskDomainSummaryResponse{}, err
+		}
+		active, err := replyInt(top[i+2])
+		if err != nil {
+			return datatypes.AtRiskDomainSummaryResponse{}, err
+		}
+		response.TopStudents = append(response.TopStudents, datatypes.AtRiskStudent{UserEmail: email, TotalAtRiskScore: total, ActiveEvents: active})
+	}
+
+	for i, count := range counts {
+		students, ok := count.(int64)
+		if !ok {
+			return datatypes.AtRiskDomainSummaryResponse{}, constants.InvalidScriptReply
+		}
+		band := datatypes.ScoreBand{Min: bands[i], Students: int(students)}
+		if i+1 < len(bands) {
+			max := bands[i+1]
+			band.Max = &max
+		}
+		response.ScoreBands = append(response.ScoreBands, band)
+	}
+
+	return response, nil
+}
+
+// replyInt converts a sorted set score returned by a script into int
+func replyInt(value interface{}) (int, error) {
+	score, ok := value.(string)
+	if !ok {
+		return 0, constants.InvalidScriptReply
+	}
+
+	parsed, err := strconv.ParseFloat(score, 64)
+	if err != nil {
+		return 0, constants.InvalidScriptReply
+	}
+	return int(parsed), nil
+}
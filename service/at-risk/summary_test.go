@@ -0,0 +1,126 @@
+package atrisk
+
+import (
+	"reflect"
+	"testing"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestGetDomainSummary(t *testing.T) {
+
+	type tests struct {
+		name        string
+		domain      string
+		redisClient func() *mocks.RedisOps
+		want        datatypes.AtRiskDomainSummaryResponse
+		wantErr     error
+	}
+
+	domainKeys := []string{"atrisk:domain:scores:securly.com", "atrisk:domain:events:securly.com", "atrisk:domain:expiry:securly.com", "atrisk:domain:totals:securly.com", "atrisk:domain:due:securly.com"}
+	dueKeys := append(append([]string{}, domainKeys...), "atrisk:events:first@securly.com", "atrisk:expiry:first@securly.com", "atrisk:totals:first@securly.com", "atrisk:timeline:first@securly.com", "atrisk:mids:first@securly.com", "atrisk:versions:first@securly.com")
+	max25, max50 := 25, 50
+	testCases := []tests{
+		{
+			name:   "valid case",
+			domain: "Securly.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.DueAtRiskDomainUsers, domainKeys, mock.AnythingOfType("int64")).Return([]interface{}{"first@securly.com"}, nil).Once()
+				moc.On("RunScript", cache.AtRiskDomainSummary, dueKeys, mock.AnythingOfType("int64"), 2, 0, 25, 50).Return([]interface{}{
+					int64(4), int64(3),
+					[]interface{}{"first@securly.com", "60", "2", "second@securly.com", "10", "1"},
+					[]interface{}{int64(2), int64(0), int64(1)},
+				}, nil).Once()
+				return moc
+			},
+			want: datatypes.AtRiskDomainSummaryResponse{
+				Domain:       "securly.com",
+				Students:     3,
+				ActiveEvents: 4,
+				TopStudents: []datatypes.AtRiskStudent{
+					{UserEmail: "first@securly.com", TotalAtRiskScore: 60, ActiveEvents: 2},
+					{UserEmail: "second@securly.com", TotalAtRiskScore: 10, ActiveEvents: 1},
+				},
+				ScoreBands: []datatypes.ScoreBand{{Min: 0, Max: &max25, Students: 2}, {Min: 25, Max: &max50, Students: 0}, {Min: 50, Students: 1}},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "valid case, no students",
+			domain: "securly.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.DueAtRiskDomainUsers, domainKeys, mock.AnythingOfType("int64")).Return([]interface{}{}, nil).Once()
+				moc.On("RunScript", cache.AtRiskDomainSummary, domainKeys, mock.AnythingOfType("int64"), 2, 0, 25, 50).Return([]interface{}{
+					int64(0), int64(0), []interface{}{}, []interface{}{int64(0), int64(0), int64(0)},
+				}, nil).Once()
+				return moc
+			},
+			want: datatypes.AtRiskDomainSummaryResponse{
+				Domain:      "securly.com",
+				TopStudents: []datatypes.AtRiskStudent{},
+				ScoreBands:  []datatypes.ScoreBand{{Min: 0, Max: &max25}, {Min: 25, Max: &max50}, {Min: 50}},
+			},
+			wantErr: nil,
+		},
+		{
+			name:   "fail case, error fetching due students",
+			domain: "securly.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.DueAtRiskDomainUsers, mock.Anything, mock.Anything).Return(nil, test.CacheGetValueErr).Once()
+				return moc
+			},
+			want:    datatypes.AtRiskDomainSummaryResponse{},
+			wantErr: test.CacheGetValueErr,
+		},
+		{
+			name:   "fail case, redis error",
+			domain: "securly.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.DueAtRiskDomainUsers, mock.Anything, mock.Anything).Return([]interface{}{}, nil).Once()
+				moc.On("RunScript", cache.AtRiskDomainSummary, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, test.CacheGetValueErr).Once()
+				return moc
+			},
+			want:    datatypes.AtRiskDomainSummaryResponse{},
+			wantErr: test.CacheGetValueErr,
+		},
+		{
+			name:   "fail case, invalid reply",
+			domain: "securly.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("RunScript", cache.DueAtRiskDomainUsers, mock.Anything, mock.Anything).Return([]interface{}{}, nil).Once()
+				moc.On("RunScript", cache.AtRiskDomainSummary, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]interface{}{
+					int64(1), int64(1), []interface{}{"first@securly.com", "sixty", "1"}, []interface{}{int64(1), int64(0), int64(0)},
+				}, nil).Once()
+				return moc
+			},
+			want:    datatypes.AtRiskDomainSummaryResponse{},
+			wantErr: constants.InvalidScriptReply,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			risk := RiskService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient()}
+			got, err := risk.GetDomainSummary(tc.domain, 2, []int{50, 0, 25})
+			if err != tc.wantErr {
+				t.Errorf("GetDomainSummary() error = %v, wantErr %v", err, tc.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("GetDomainSummary() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package student
+
+import (
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/pkg/cache"
+	"www-api/pkg/lookup"
+)
+
+// WithLookupCache returns a copy of the service reading the timezone, notification and
+// filter type of a fid through the lookups redis, ttls maps an entity to how long its lookups
+// are kept and negativeTTL is how long a lookup that was not found is kept. The lookups redis
+// must be pinned to a db of its own the way s.redis is pinned to the privacy db
+func (s CustomerService) WithLookupCache(lookups cache.RedisOps, ttls map[string]int, negativeTTL int) CustomerService {
+	ttl := func(entity string) time.Duration {
+		if seconds := ttls[entity]; seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		return constants.DefaultLookupTTLSeconds * time.Second
+	}
+	negative := constants.DefaultLookupNegativeTTLSeconds * time.Second
+	if negativeTTL > 0 {
+		negative = time.Duration(negativeTTL) * time.Second
+	}
+
+	timezones := lookup.NewCache(lookups, constants.LookupTimezone, ttl(constants.LookupTimezone), negative, s.getTimezoneFromUser, s.log)
+	notifications := lookup.NewCache(lookups, constants.LookupNotification, ttl(constants.LookupNotification), negative, s.getNotification, s.log)
+	filters := lookup.NewCache(lookups, constants.LookupFilterType, ttl(constants.LookupFilterType), negative, s.getFilter, s.log)
+
+	s.getTimezoneFromUser = timezones.Get
+	s.getNotification = notifications.Get
+	s.getFilter = filters.Get
+	s.lookups = []lookup.Cached{timezones, notifications, filters}
+	return s
+}
+
+// InvalidateLookups drops the cached lookups of fid for entities, every entity when none is
+// given, and returns the entities invalidated
+func (s CustomerService) InvalidateLookups(fid string, entities []string) ([]string, error) {
+	caches := map[string]lookup.Cached{}
+	for _, cached := range s.lookups {
+		caches[cached.Entity()] = cached
+	}
+	if len(entities) == 0 {
+		for _, cached := range s.lookups {
+			entities = append(entities, cached.Entity())
+		}
+	}
+	for _, entity := range entities {
+		if _, ok := caches[entity]; !ok {
+			s.log.Error("unknown lookup entity", map[string]interface{}{"fid": fid, "entity": entity})
+			return nil, constants.InvalidLookupEntity
+		}
+	}
+
+	invalidated := []string{}
+	for _, entity := range entities {
+		err := caches[entity].Invalidate(fid)
+		if err != nil {
+			return nil, err
+		}
+		invalidated = append(invalidated, entity)
+	}
+
+	s.log.Info("invalidated lookups", map[string]interface{}{"fid": fid, "entities": invalidated})
+	return invalidated, nil
+}
+
+// LookupStats returns the counters of every cached entity
+func (s CustomerService) LookupStats() []datatypes.LookupStats {
+	stats := []datatypes.LookupStats{}
+	for _, cached := range s.lookups {
+		stats = append(stats, cached.Stats())
+	}
+	return stats
+}
@@ -0,0 +1,151 @@
+package student
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestWithLookupCache(t *testing.T) {
+	moc := mocks.NewRedisOps(t)
+	moc.On("GetValue", "lookup:timezone:admin@securly.com").Return("", constants.ResourceNotFound).Once()
+	moc.On("SetWithTTL", "lookup:timezone:admin@securly.com", `{"found":true,"value":"Asia/Kolkata"}`, 24*time.Hour).Return(nil).Once()
+	moc.On("GetValue", "lookup:timezone:admin@securly.com").Return(`{"found":true,"value":"Asia/Kolkata"}`, nil).Once()
+	moc.On("GetValue", "lookup:filter-type:admin@securly.com").Return(`{"found":false}`, nil).Once()
+
+	fetched := 0
+	cust := CustomerService{
+		log: logger.ZapLogger{Logger: zap.NewExample()},
+		getTimezoneFromUser: func(fid string) (string, error) {
+			fetched++
+			return "Asia/Kolkata", nil
+		},
+	}.WithLookupCache(moc, map[string]int{"timezone": 86400}, 0)
+
+	for i := 0; i < 2; i++ {
+		timezone, err := cust.Timezone("admin@securly.com")
+		if err != nil || timezone.Tz != "Asia/Kolkata" {
+			t.Errorf("expected timezone Asia/Kolkata got %v, error %v", timezone, err)
+		}
+	}
+	if fetched != 1 {
+		t.Errorf("expected timezone to be fetched once got %d", fetched)
+	}
+
+	_, err := cust.GetFilterType("admin@securly.com")
+	if err != constants.ResourceNotFound {
+		t.Errorf("expected error %v got %v", constants.ResourceNotFound, err)
+	}
+
+	assert.Equal(t, []datatypes.LookupStats{
+		{Entity: "timezone", TTLSeconds: 86400, NegativeTTLSeconds: 300, Hits: 1, Misses: 1},
+		{Entity: "notification", TTLSeconds: 3600, NegativeTTLSeconds: 300},
+		{Entity: "filter-type", TTLSeconds: 3600, NegativeTTLSeconds: 300, NegativeHits: 1},
+	}, cust.LookupStats())
+}
+
+func TestLookupCacheAfterPrivacyStatus(t *testing.T) {
+	shared := mocks.NewRedisOps(t)
+	shared.On("GetValue", "securly.com:PF:ENHANCED_PRIVACY").Return("1", nil).Once()
+	lookups := mocks.NewRedisOps(t)
+	lookups.On("GetValue", "lookup:timezone:admin@securly.com").Return(`{"found":true,"value":"Asia/Kolkata"}`, nil).Once()
+
+	cust := CustomerService{
+		log:   logger.ZapLogger{Logger: zap.NewExample()},
+		redis: shared,
+		getTimezoneFromUser: func(fid string) (string, error) {
+			t.Errorf("expected timezone of %s to be read from the lookup cache", fid)
+			return "", nil
+		},
+	}.WithLookupCache(lookups, nil, 0)
+
+	_, err := cust.ProuctPrivacyStatus("admin@securly.com")
+	if err != nil {
+		t.Errorf("expected error %v got %v", nil, err)
+	}
+
+	timezone, err := cust.Timezone("admin@securly.com")
+	if err != nil || timezone.Tz != "Asia/Kolkata" {
+		t.Errorf("expected timezone Asia/Kolkata got %v, error %v", timezone, err)
+	}
+	assert.Equal(t, int64(1), cust.LookupStats()[0].Hits)
+}
+
+func TestInvalidateLookups(t *testing.T) {
+
+	type tests struct {
+		name        string
+		entities    []string
+		redisClient func() *mocks.RedisOps
+		want        []string
+		wantErr     error
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case, every entity",
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Delete", "lookup:timezone:admin@securly.com").Return(nil).Once()
+				moc.On("Delete", "lookup:notification:admin@securly.com").Return(nil).Once()
+				moc.On("Delete", "lookup:filter-type:admin@securly.com").Return(nil).Once()
+				return moc
+			},
+			want:    []string{"timezone", "notification", "filter-type"},
+			wantErr: nil,
+		},
+		{
+			name:     "valid case, given entities",
+			entities: []string{"notification"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Delete", "lookup:notification:admin@securly.com").Return(nil).Once()
+				return moc
+			},
+			want:    []string{"notification"},
+			wantErr: nil,
+		},
+		{
+			name:     "fail case, unknown entity",
+			entities: []string{"notification", "privacy"},
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			want:    nil,
+			wantErr: constants.InvalidLookupEntity,
+		},
+		{
+			name:     "fail case, error deleting key",
+			entities: []string{"timezone"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Delete", "lookup:timezone:admin@securly.com").Return(test.CacheDeleteKeyErr).Once()
+				return moc
+			},
+			want:    nil,
+			wantErr: test.CacheDeleteKeyErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}}.WithLookupCache(tc.redisClient(), nil, 0)
+
+			invalidated, err := cust.InvalidateLookups("admin@securly.com", tc.entities)
+			if !reflect.DeepEqual(tc.want, invalidated) {
+				t.Errorf("expected invalidated %v got %v", tc.want, invalidated)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package student
+
+import (
+	"strings"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+)
+
+// NotificationConfigs gets every awareEmailNotification config of fid
+func (s CustomerService) NotificationConfigs(fid string) ([]datatypes.NotificationConfig, error) {
+	notifications, err := s.getNotifications(fid)
+	if err != nil {
+		s.log.Error("error occured while fetching notification configs", map[string]interface{}{"error": err, "fid": fid})
+		return nil, err
+	}
+
+	configs := []datatypes.NotificationConfig{}
+	for _, notification := range notifications {
+		configs = append(configs, datatypes.NewNotificationConfig(notification))
+	}
+	return configs, nil
+}
+
+// CreateNotificationConfig saves a new awareEmailNotification config of fid sending to emails
+func (s CustomerService) CreateNotificationConfig(fid string, emails []string) (datatypes.NotificationConfig, error) {
+	notification := datatypes.Notification{
+		Fid:               fid,
+		NotificationEmail: strings.Join(notificationEmails(emails), constants.NotificationEmailSeparator),
+		Basegen:           constants.InitialNotificationBasegen,
+	}
+	id, err := s.saveNotification(notification)
+	if err != nil {
+		s.log.Error("error occured while saving notification config", map[string]interface{}{"error": err, "fid": fid})
+		return datatypes.NotificationConfig{}, err
+	}
+	notification.ID = int(id)
+
+	s.forgetNotification(fid)
+	s.log.Info("created notification config", map[string]interface{}{"fid": fid, "id": id})
+	return datatypes.NewNotificationConfig(notification), nil
+}
+
+// UpdateNotificationConfig replaces the recipients of the config id of fid with emails when the config
+// is still at basegen, constants.NotificationChanged is returned when it was updated in between
+func (s CustomerService) UpdateNotificationConfig(id int, fid string, emails []string, basegen int) (datatypes.NotificationConfig, error) {
+	notification := datatypes.Notification{
+		ID:                id,
+		Fid:               fid,
+		NotificationEmail: strings.Join(notificationEmails(emails), constants.NotificationEmailSeparator),
+		Basegen:           basegen,
+	}
+	affected, err := s.updateNotification(notification)
+	if err != nil {
+		s.log.Error("error occured while updating notification config", map[string]interface{}{"error": err, "fid": fid, "id": id})
+		return datatypes.NotificationConfig{}, err
+	}
+	if affected == 0 {
+		current, err := s.getNotificationByID(id, fid)
+		if err != nil {
+			return datatypes.NotificationConfig{}, err
+		}
+		s.log.Error("notification config changed", map[string]interface{}{"fid": fid, "id": id, "basegen": basegen, "currentBasegen": current.Basegen})
+		return datatypes.NotificationConfig{}, constants.NotificationChanged
+	}
+	notification.Basegen++
+
+	s.forgetNotification(fid)
+	s.log.Info("updated notification config", map[string]interface{}{"fid": fid, "id": id, "basegen": notification.Basegen})
+	return datatypes.NewNotificationConfig(notification), nil
+}
+
+// DeleteNotificationConfig deletes the config id of fid, basegen must be its current basegen
+// like for an update
+func (s CustomerService) DeleteNotificationConfig(id int, fid string, basegen int) error {
+	affected, err := s.deleteNotification(id, fid, basegen)
+	if err != nil {
+		s.log.Error("error occured while deleting notification config", map[string]interface{}{"error": err, "fid": fid, "id": id})
+		return err
+	}
+	if affected == 0 {
+		current, err := s.getNotificationByID(id, fid)
+		if err != nil {
+			return err
+		}
+		s.log.Error("notification config changed", map[string]interface{}{"fid": fid, "id": id, "basegen": basegen, "currentBasegen": current.Basegen})
+		return constants.NotificationChanged
+	}
+
+	s.forgetNotification(fid)
+	s.log.Info("deleted notification config", map[string]interface{}{"fid": fid, "id": id})
+	return nil
+}
+
+// forgetNotification drops the cached notification lookup of fid, a failure only
+// leaves the previous config cached until its ttl
+func (s CustomerService) forgetNotification(fid string) {
+	for _, cached := range s.lookups {
+		if cached.Entity() != constants.LookupNotification {
+			continue
+		}
+		err := cached.Invalidate(fid)
+		if err != nil {
+			s.log.Warn("error invalidating notification lookup", map[string]interface{}{"error": err, "fid": fid})
+		}
+	}
+}
+
+// notificationEmails trims emails and drops the repeated ones, keeping the first spelling of each
+func notificationEmails(emails []string) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		if seen[strings.ToLower(email)] {
+			continue
+		}
+		seen[strings.ToLower(email)] = true
+		unique = append(unique, email)
+	}
+	return unique
+}
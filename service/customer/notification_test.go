@@ -0,0 +1,248 @@
+package student
+
+import (
+	"reflect"
+	"testing"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"go.uber.org/zap"
+)
+
+func TestNotificationConfigs(t *testing.T) {
+	type tests struct {
+		name             string
+		getNotifications func(fid string) ([]datatypes.Notification, error)
+		want             []datatypes.NotificationConfig
+		wantErr          error
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case",
+			getNotifications: func(fid string) ([]datatypes.Notification, error) {
+				return []datatypes.Notification{{ID: 1, Fid: fid, NotificationEmail: "a@securly.com,b@securly.com", Basegen: 3}}, nil
+			},
+			want:    []datatypes.NotificationConfig{{ID: 1, Fid: "admin@securly.com", Emails: []string{"a@securly.com", "b@securly.com"}, Basegen: 3}},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, getNotifications error out",
+			getNotifications: func(fid string) ([]datatypes.Notification, error) {
+				return nil, test.InternalServerErr
+			},
+			want:    nil,
+			wantErr: test.InternalServerErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}, getNotifications: tc.getNotifications}
+			configs, err := cust.NotificationConfigs("admin@securly.com")
+			if !reflect.DeepEqual(tc.want, configs) {
+				t.Errorf("expected configs %v got %v", tc.want, configs)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCreateNotificationConfig(t *testing.T) {
+	type tests struct {
+		name             string
+		saveNotification func(notification datatypes.Notification) (int64, error)
+		want             datatypes.NotificationConfig
+		wantErr          error
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case",
+			saveNotification: func(notification datatypes.Notification) (int64, error) {
+				if notification.NotificationEmail != "a@securly.com,b@securly.com" || notification.Basegen != 1 {
+					t.Errorf("unexpected notification %v", notification)
+				}
+				return 7, nil
+			},
+			want:    datatypes.NotificationConfig{ID: 7, Fid: "admin@securly.com", Emails: []string{"a@securly.com", "b@securly.com"}, Basegen: 1},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, saveNotification error out",
+			saveNotification: func(notification datatypes.Notification) (int64, error) {
+				return 0, test.InternalServerErr
+			},
+			want:    datatypes.NotificationConfig{},
+			wantErr: test.InternalServerErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}, saveNotification: tc.saveNotification}
+			config, err := cust.CreateNotificationConfig("admin@securly.com", []string{" a@securly.com", "b@securly.com", "A@securly.com"})
+			if !reflect.DeepEqual(tc.want, config) {
+				t.Errorf("expected config %v got %v", tc.want, config)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUpdateNotificationConfig(t *testing.T) {
+	type tests struct {
+		name                string
+		updateNotification  func(notification datatypes.Notification) (int64, error)
+		getNotificationByID func(id int, fid string) (datatypes.Notification, error)
+		want                datatypes.NotificationConfig
+		wantErr             error
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case",
+			updateNotification: func(notification datatypes.Notification) (int64, error) {
+				return 1, nil
+			},
+			want:    datatypes.NotificationConfig{ID: 7, Fid: "admin@securly.com", Emails: []string{"a@securly.com"}, Basegen: 3},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, config changed",
+			updateNotification: func(notification datatypes.Notification) (int64, error) {
+				return 0, nil
+			},
+			getNotificationByID: func(id int, fid string) (datatypes.Notification, error) {
+				return datatypes.Notification{ID: id, Fid: fid, NotificationEmail: "b@securly.com", Basegen: 4}, nil
+			},
+			want:    datatypes.NotificationConfig{},
+			wantErr: constants.NotificationChanged,
+		},
+		{
+			name: "fail case, config not found",
+			updateNotification: func(notification datatypes.Notification) (int64, error) {
+				return 0, nil
+			},
+			getNotificationByID: func(id int, fid string) (datatypes.Notification, error) {
+				return datatypes.Notification{}, constants.ResourceNotFound
+			},
+			want:    datatypes.NotificationConfig{},
+			wantErr: constants.ResourceNotFound,
+		},
+		{
+			name: "fail case, updateNotification error out",
+			updateNotification: func(notification datatypes.Notification) (int64, error) {
+				return 0, test.InternalServerErr
+			},
+			want:    datatypes.NotificationConfig{},
+			wantErr: test.InternalServerErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}, updateNotification: tc.updateNotification, getNotificationByID: tc.getNotificationByID}
+			config, err := cust.UpdateNotificationConfig(7, "admin@securly.com", []string{"a@securly.com"}, 2)
+			if !reflect.DeepEqual(tc.want, config) {
+				t.Errorf("expected config %v got %v", tc.want, config)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDeleteNotificationConfig(t *testing.T) {
+	type tests struct {
+		name                string
+		deleteNotification  func(id int, fid string, basegen int) (int64, error)
+		getNotificationByID func(id int, fid string) (datatypes.Notification, error)
+		redisClient         func() *mocks.RedisOps
+		wantErr             error
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case, cached lookup is invalidated",
+			deleteNotification: func(id int, fid string, basegen int) (int64, error) {
+				if basegen != 2 {
+					t.Errorf("expected basegen 2 got %d", basegen)
+				}
+				return 1, nil
+			},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Delete", "lookup:notification:admin@securly.com").Return(nil).Once()
+				return moc
+			},
+			wantErr: nil,
+		},
+		{
+			name: "valid case, error invalidating cached lookup",
+			deleteNotification: func(id int, fid string, basegen int) (int64, error) {
+				return 1, nil
+			},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("Delete", "lookup:notification:admin@securly.com").Return(test.CacheDeleteKeyErr).Once()
+				return moc
+			},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, config not found",
+			deleteNotification: func(id int, fid string, basegen int) (int64, error) {
+				return 0, nil
+			},
+			getNotificationByID: func(id int, fid string) (datatypes.Notification, error) {
+				return datatypes.Notification{}, constants.ResourceNotFound
+			},
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			wantErr: constants.ResourceNotFound,
+		},
+		{
+			name: "fail case, basegen changed",
+			deleteNotification: func(id int, fid string, basegen int) (int64, error) {
+				return 0, nil
+			},
+			getNotificationByID: func(id int, fid string) (datatypes.Notification, error) {
+				return datatypes.Notification{ID: id, Fid: fid, NotificationEmail: "a@securly.com", Basegen: 3}, nil
+			},
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			wantErr: constants.NotificationChanged,
+		},
+		{
+			name: "fail case, deleteNotification error out",
+			deleteNotification: func(id int, fid string, basegen int) (int64, error) {
+				return 0, test.InternalServerErr
+			},
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			wantErr: test.InternalServerErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}, deleteNotification: tc.deleteNotification, getNotificationByID: tc.getNotificationByID}.WithLookupCache(tc.redisClient(), nil, 0)
+			err := cust.DeleteNotificationConfig(7, "admin@securly.com", 2)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
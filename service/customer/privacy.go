@@ -0,0 +1,257 @@
+package student
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/pkg/cache"
+)
+
+// privacyFlag is a named bit of one of the privacy keys, key indexes privacyKeys
+type privacyFlag struct {
+	key int
+	bit int
+}
+
+// privacyFlags names every flag that can be written, the privacy modes of the domain and
+// the bits of the fid read to decide 24 and Responder eligibility
+var privacyFlags = map[string]privacyFlag{
+	constants.PrivacyModes[constants.FILTER_PRIVACY]:      {key: 0, bit: constants.FILTER_PRIVACY},
+	constants.PrivacyModes[constants.AWARE_PRIVACY]:       {key: 0, bit: constants.AWARE_PRIVACY},
+	constants.PrivacyModes[constants.RESPONDER_PRIVACY]:   {key: 0, bit: constants.RESPONDER_PRIVACY},
+	constants.PrivacyModes[constants.TWENTY_FOUR_PRIVACY]: {key: 0, bit: constants.TWENTY_FOUR_PRIVACY},
+	constants.PrivacyModes[constants.SUPPRESS_BULLY]:      {key: 0, bit: constants.SUPPRESS_BULLY},
+	"PN:9":           {key: 1, bit: 9},
+	"PN:10":          {key: 1, bit: 10},
+	"PF:3:0":         {key: 2, bit: 0},
+	"PF:3:1":         {key: 2, bit: 1},
+	"PF:3:2":         {key: 2, bit: 2},
+	"PF:3:3":         {key: 2, bit: 3},
+	"PN:RESPONDER:0": {key: 3, bit: 0},
+}
+
+// privacyRule is a combination of flags every write has to keep, it is only checked when
+// the write sets or clears one of flags since other writes can't break it
+type privacyRule struct {
+	flags   []string
+	message string
+	holds   func(on func(flag string) bool) bool
+}
+
+var privacyRules = []privacyRule{
+	{
+		flags:   []string{"24", "PF:3:3"},
+		message: "24 requires integration enabled (PF:3:3)",
+		holds:   func(on func(string) bool) bool { return !on("24") || on("PF:3:3") },
+	},
+	{
+		flags:   []string{"24", "PN:9", "PN:10", "PF:3:0", "PF:3:1", "PF:3:2"},
+		message: "24 requires PN:9 with PF:3:0 and PF:3:1 or PN:10 with PF:3:0 and PF:3:2",
+		holds: func(on func(string) bool) bool {
+			return !on("24") || (on("PN:9") && on("PF:3:0") && on("PF:3:1")) || (on("PN:10") && on("PF:3:0") && on("PF:3:2"))
+		},
+	},
+	{
+		flags:   []string{"Responder", "PN:RESPONDER:0"},
+		message: "Responder requires the responder enabled (PN:RESPONDER:0)",
+		holds:   func(on func(string) bool) bool { return !on("Responder") || on("PN:RESPONDER:0") },
+	},
+}
+
+// privacyKeys returns the privacy keys of fid in the order privacyFlag indexes them
+func privacyKeys(domain, fid string) []string {
+	return []string{
+		fmt.Sprintf(constants.PrivacyDomainKey, domain),
+		fmt.Sprintf(constants.PrivacyPNKey, fid),
+		fmt.Sprintf(constants.PrivacyPF3Key, fid),
+		fmt.Sprintf(constants.PrivacyResponderKey, fid),
+	}
+}
+
+// ReplacePrivacyFlags sets flags and clears every other privacy flag of fid and its domain
+func (s CustomerService) ReplacePrivacyFlags(fid string, flags []string, actor string) (datatypes.PrivacyFlagsResponse, error) {
+	set := map[string]bool{}
+	for _, flag := range flags {
+		set[flag] = true
+	}
+
+	clear := []string{}
+	for flag := range privacyFlags {
+		if !set[flag] {
+			clear = append(clear, flag)
+		}
+	}
+	return s.UpdatePrivacyFlags(fid, flags, clear, actor)
+}
+
+// UpdatePrivacyFlags sets and clears privacy flags of fid and its domain in one atomic write
+// recorded in the privacy audit stream under actor, and returns the privacy status after it.
+// A write breaking a combination of flags is rejected with the combinations it breaks
+func (s CustomerService) UpdatePrivacyFlags(fid string, set, clear []string, actor string) (datatypes.PrivacyFlagsResponse, error) {
+	domainName := strings.Split(fid, "@")
+	if len(domainName) < 2 || domainName[1] == "" {
+		s.log.Error("empty domain", nil)
+		return datatypes.PrivacyFlagsResponse{}, constants.EmptyFid
+	}
+	err := checkPrivacyFlags(set, clear)
+	if err != nil {
+		s.log.Error("invalid privacy flags", map[string]interface{}{"fid": fid, "set": set, "clear": clear, "error": err})
+		return datatypes.PrivacyFlagsResponse{}, err
+	}
+
+	keys := privacyKeys(domainName[1], fid)
+	for attempt := 0; attempt < constants.PrivacyWriteAttempts; attempt++ {
+		response, written, err := s.writePrivacyFlags(fid, keys, set, clear, actor)
+		if err != nil {
+			return response, err
+		}
+		if !written {
+			s.log.Info("privacy flags changed while being written, retrying", map[string]interface{}{"fid": fid, "attempt": attempt})
+			continue
+		}
+
+		response.Status, err = s.ProuctPrivacyStatus(fid)
+		if err != nil {
+			return datatypes.PrivacyFlagsResponse{}, err
+		}
+		return response, nil
+	}
+
+	s.log.Error("privacy flags kept changing while being written", map[string]interface{}{"fid": fid, "set": set, "clear": clear})
+	return datatypes.PrivacyFlagsResponse{}, constants.PrivacyFlagsChanged
+}
+
+// writePrivacyFlags reads the privacy keys, applies the flags and writes the keys back
+// unless they changed meanwhile, written is false when they did
+func (s CustomerService) writePrivacyFlags(fid string, keys, set, clear []string, actor string) (datatypes.PrivacyFlagsResponse, bool, error) {
+	before := make([]string, len(keys))
+	values := make([]int, len(keys))
+	for i, key := range keys {
+		value, found, err := s.bitVector(key)
+		if err != nil {
+			return datatypes.PrivacyFlagsResponse{}, false, err
+		}
+		if found {
+			before[i] = strconv.Itoa(value)
+		}
+		values[i] = value
+	}
+
+	was := func(flag string) bool {
+		return datatypes.PrivacyFlags(values[privacyFlags[flag].key]).Has(privacyFlags[flag].bit)
+	}
+	after := append([]int{}, values...)
+	for _, flag := range set {
+		after[privacyFlags[flag].key] |= 1 << privacyFlags[flag].bit
+	}
+	for _, flag := range clear {
+		after[privacyFlags[flag].key] &^= 1 << privacyFlags[flag].bit
+	}
+	on := func(flag string) bool {
+		return datatypes.PrivacyFlags(after[privacyFlags[flag].key]).Has(privacyFlags[flag].bit)
+	}
+
+	response := datatypes.PrivacyFlagsResponse{Fid: fid, Changed: []string{}}
+	for flag := range privacyFlags {
+		if was(flag) != on(flag) {
+			response.Changed = append(response.Changed, flag)
+		}
+	}
+	sort.Strings(response.Changed)
+
+	touched := map[string]bool{}
+	for _, flag := range append(append([]string{}, set...), clear...) {
+		touched[flag] = true
+	}
+	for _, rule := range privacyRules {
+		for _, flag := range rule.flags {
+			if touched[flag] {
+				if !rule.holds(on) {
+					response.Violations = append(response.Violations, rule.message)
+				}
+				break
+			}
+		}
+	}
+	if len(response.Violations) != 0 {
+		s.log.Error("privacy flags write breaks combinations", map[string]interface{}{"fid": fid, "set": set, "clear": clear, "violations": response.Violations})
+		return response, false, constants.InvalidPrivacyCombination
+	}
+
+	if len(response.Changed) == 0 {
+		return response, true, nil
+	}
+
+	args := []interface{}{len(keys)}
+	for _, value := range before {
+		args = append(args, value)
+	}
+	written := make([]string, len(keys))
+	for i, value := range after {
+		if before[i] != "" || value != 0 {
+			written[i] = strconv.Itoa(value)
+		}
+		args = append(args, written[i])
+	}
+	changed, _ := json.Marshal(response.Changed)
+	previous, _ := json.Marshal(before)
+	current, _ := json.Marshal(written)
+	args = append(args, constants.PrivacyAuditMaxLen,
+		"actor", actor,
+		"fid", fid,
+		"changed", string(changed),
+		"before", string(previous),
+		"after", string(current),
+		"changedAt", time.Now().Unix(),
+	)
+
+	reply, err := s.redis.RunScript(cache.SetPrivacyFlags, append(append([]string{}, keys...), constants.PrivacyAuditStream), args...)
+	if err != nil {
+		s.log.Error("unable to write privacy flags", map[string]interface{}{"fid": fid, "error": err})
+		return datatypes.PrivacyFlagsResponse{}, false, err
+	}
+	result, ok := reply.([]interface{})
+	if !ok || len(result) == 0 {
+		s.log.Error("invalid reply received from redis", map[string]interface{}{"fid": fid, "reply": reply})
+		return datatypes.PrivacyFlagsResponse{}, false, constants.InvalidScriptReply
+	}
+	if status, _ := result[0].(int64); status != 1 {
+		return datatypes.PrivacyFlagsResponse{}, false, nil
+	}
+	if len(result) > 1 {
+		response.AuditID, _ = result[1].(string)
+	}
+
+	s.log.Info("privacy flags written", map[string]interface{}{"fid": fid, "actor": actor, "changed": response.Changed, "auditId": response.AuditID})
+	return response, true, nil
+}
+
+// checkPrivacyFlags makes sure there is a flag to write, every flag is known and none is
+// both set and cleared
+func checkPrivacyFlags(set, clear []string) error {
+	if len(set) == 0 && len(clear) == 0 {
+		return constants.NoPrivacyFlags
+	}
+
+	setFlags := map[string]bool{}
+	for _, flag := range set {
+		if _, ok := privacyFlags[flag]; !ok {
+			return constants.InvalidPrivacyFlag
+		}
+		setFlags[flag] = true
+	}
+	for _, flag := range clear {
+		if _, ok := privacyFlags[flag]; !ok {
+			return constants.InvalidPrivacyFlag
+		}
+		if setFlags[flag] {
+			return constants.ConflictingPrivacyFlags
+		}
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package student
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/cache"
+	"www-api/pkg/cache/mocks"
+	"www-api/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestUpdatePrivacyFlags(t *testing.T) {
+
+	type tests struct {
+		name        string
+		set         []string
+		clear       []string
+		redisClient func() *mocks.RedisOps
+		want        datatypes.PrivacyFlagsResponse
+		wantErr     error
+	}
+
+	fid := "checkemail@rtqa1securly.com"
+	keys := []string{"rtqa1securly.com:PF:ENHANCED_PRIVACY", fid + ":PN", fid + ":PF:3", fid + ":PN:RESPONDER"}
+	scriptKeys := append(append([]string{}, keys...), "privacy:audit")
+	read := func(moc *mocks.RedisOps, values ...string) {
+		for i, key := range keys {
+			if values[i] == "" {
+				moc.On("GetValue", key).Return("", constants.ResourceNotFound).Once()
+			} else {
+				moc.On("GetValue", key).Return(values[i], nil).Once()
+			}
+		}
+	}
+	write := func(moc *mocks.RedisOps, changed, before, after string, reply interface{}, err error) {
+		args := []interface{}{"RunScript", cache.SetPrivacyFlags, scriptKeys, 4}
+		for _, value := range append(strings.Split(before, ","), strings.Split(after, ",")...) {
+			args = append(args, value)
+		}
+		args = append(args, constants.PrivacyAuditMaxLen, "actor", "subject", "fid", fid, "changed", changed, "before", toJSON(before), "after", toJSON(after), "changedAt", mock.AnythingOfType("int64"))
+		moc.On(args[0].(string), args[1:]...).Return(reply, err).Once()
+	}
+	filter := datatypes.PrivacyStatus{Filter: 1, BitVector: 1, Modes: []string{"Filter"}, Eligibility: datatypes.PrivacyEligibility{Reasons: []string{"Aware privacy is not set"}}}
+	testCases := []tests{
+		{
+			name:  "valid case",
+			set:   []string{"Filter"},
+			clear: []string{"suppBully"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				read(moc, "16", "", "", "")
+				write(moc, `["Filter","suppBully"]`, "16,,,", "1,,,", []interface{}{int64(1), "1684231487000-0"}, nil)
+				moc.On("GetValue", keys[0]).Return("1", nil).Once()
+				return moc
+			},
+			want:    datatypes.PrivacyFlagsResponse{Fid: fid, Changed: []string{"Filter", "suppBully"}, AuditID: "1684231487000-0", Status: filter},
+			wantErr: nil,
+		},
+		{
+			name: "valid case, retried when the flags changed meanwhile",
+			set:  []string{"Filter"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				read(moc, "", "", "", "")
+				write(moc, `["Filter"]`, ",,,", "1,,,", []interface{}{int64(0)}, nil)
+				read(moc, "0", "", "", "")
+				write(moc, `["Filter"]`, "0,,,", "1,,,", []interface{}{int64(1), "1684231487000-0"}, nil)
+				moc.On("GetValue", keys[0]).Return("1", nil).Once()
+				return moc
+			},
+			want:    datatypes.PrivacyFlagsResponse{Fid: fid, Changed: []string{"Filter"}, AuditID: "1684231487000-0", Status: filter},
+			wantErr: nil,
+		},
+		{
+			name: "valid case, nothing changed",
+			set:  []string{"Filter"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				read(moc, "1", "", "", "")
+				moc.On("GetValue", keys[0]).Return("1", nil).Once()
+				return moc
+			},
+			want:    datatypes.PrivacyFlagsResponse{Fid: fid, Changed: []string{}, Status: filter},
+			wantErr: nil,
+		},
+		{
+			name: "fail case, 24 without integration",
+			set:  []string{"24", "PN:9", "PF:3:0", "PF:3:1"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				read(moc, "2", "", "", "")
+				return moc
+			},
+			want: datatypes.PrivacyFlagsResponse{Fid: fid, Changed: []string{"24", "PF:3:0", "PF:3:1", "PN:9"}, Violations: []string{
+				"24 requires integration enabled (PF:3:3)",
+			}},
+			wantErr: constants.InvalidPrivacyCombination,
+		},
+		{
+			name:  "fail case, clearing the responder of a Responder domain",
+			clear: []string{"PN:RESPONDER:0"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				read(moc, "4", "", "", "1")
+				return moc
+			},
+			want: datatypes.PrivacyFlagsResponse{Fid: fid, Changed: []string{"PN:RESPONDER:0"}, Violations: []string{
+				"Responder requires the responder enabled (PN:RESPONDER:0)",
+			}},
+			wantErr: constants.InvalidPrivacyCombination,
+		},
+		{
+			name: "fail case, unknown flag",
+			set:  []string{"Unknown"},
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			want:    datatypes.PrivacyFlagsResponse{},
+			wantErr: constants.InvalidPrivacyFlag,
+		},
+		{
+			name:  "fail case, flag set and cleared",
+			set:   []string{"Filter"},
+			clear: []string{"Filter"},
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			want:    datatypes.PrivacyFlagsResponse{},
+			wantErr: constants.ConflictingPrivacyFlags,
+		},
+		{
+			name: "fail case, no flags",
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			want:    datatypes.PrivacyFlagsResponse{},
+			wantErr: constants.NoPrivacyFlags,
+		},
+		{
+			name: "fail case, flags kept changing",
+			set:  []string{"Filter"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				for i := 0; i < constants.PrivacyWriteAttempts; i++ {
+					read(moc, "", "", "", "")
+					write(moc, `["Filter"]`, ",,,", "1,,,", []interface{}{int64(0)}, nil)
+				}
+				return moc
+			},
+			want:    datatypes.PrivacyFlagsResponse{},
+			wantErr: constants.PrivacyFlagsChanged,
+		},
+		{
+			name: "fail case, error reading flags",
+			set:  []string{"Filter"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				moc.On("GetValue", keys[0]).Return("", test.CacheGetValueErr).Once()
+				return moc
+			},
+			want:    datatypes.PrivacyFlagsResponse{},
+			wantErr: test.CacheGetValueErr,
+		},
+		{
+			name: "fail case, error writing flags",
+			set:  []string{"Filter"},
+			redisClient: func() *mocks.RedisOps {
+				moc := mocks.NewRedisOps(t)
+				read(moc, "", "", "", "")
+				write(moc, `["Filter"]`, ",,,", "1,,,", nil, test.CacheSetErr)
+				return moc
+			},
+			want:    datatypes.PrivacyFlagsResponse{},
+			wantErr: test.CacheSetErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient()}
+			response, err := cust.UpdatePrivacyFlags(fid, tc.set, tc.clear, "subject")
+			assert.Equal(t, tc.want, response)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReplacePrivacyFlags(t *testing.T) {
+	fid := "checkemail@rtqa1securly.com"
+	keys := []string{"rtqa1securly.com:PF:ENHANCED_PRIVACY", fid + ":PN", fid + ":PF:3", fid + ":PN:RESPONDER"}
+
+	moc := mocks.NewRedisOps(t)
+	moc.On("GetValue", keys[0]).Return("17", nil).Once()
+	moc.On("GetValue", keys[1]).Return("512", nil).Once()
+	moc.On("GetValue", keys[2]).Return("", constants.ResourceNotFound).Once()
+	moc.On("GetValue", keys[3]).Return("", constants.ResourceNotFound).Once()
+	moc.On("RunScript", cache.SetPrivacyFlags, append(append([]string{}, keys...), "privacy:audit"), 4, "17", "512", "", "", "1", "0", "", "",
+		constants.PrivacyAuditMaxLen, "actor", "subject", "fid", fid, "changed", `["PN:9","suppBully"]`, "before", `["17","512","",""]`, "after", `["1","0","",""]`, "changedAt", mock.AnythingOfType("int64"),
+	).Return([]interface{}{int64(1), "1684231487000-0"}, nil).Once()
+	moc.On("GetValue", keys[0]).Return("1", nil).Once()
+
+	cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: moc}
+	response, err := cust.ReplacePrivacyFlags(fid, []string{"Filter"}, "subject")
+	if err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+	assert.Equal(t, []string{"PN:9", "suppBully"}, response.Changed)
+	assert.Equal(t, 1, response.Status.BitVector)
+}
+
+// toJSON returns the json array of a comma separated list of privacy key values
+func toJSON(values string) string {
+	body, _ := json.Marshal(strings.Split(values, ","))
+	return string(body)
+}
@@ -2,6 +2,7 @@ package student
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -10,8 +11,8 @@ import (
 	"www-api/internal/logger"
 	"www-api/pkg/cache"
 	"www-api/pkg/database"
+	"www-api/pkg/lookup"
 	"www-api/pkg/model"
-	"www-api/utils"
 )
 
 const (
@@ -25,6 +26,12 @@ type CustomerService struct {
 	getTimezoneFromUser func(fid string) (string, error)
 	getNotification     func(fid string) (datatypes.Notification, error)
 	getFilter           func(fid string) (datatypes.FilterType, error)
+	getNotifications    func(fid string) ([]datatypes.Notification, error)
+	getNotificationByID func(id int, fid string) (datatypes.Notification, error)
+	saveNotification    func(notification datatypes.Notification) (int64, error)
+	updateNotification  func(notification datatypes.Notification) (int64, error)
+	deleteNotification  func(id int, fid string, basegen int) (int64, error)
+	lookups             []lookup.Cached
 }
 
 // NewCustomerService returns an instance of RiskService struct
@@ -33,140 +40,139 @@ func NewCustomerService(log logger.ZapLogger, connections *datatypes.Connections
 	// connections.Redis[constants.AtRiskWriteRedisKey].Options().DB = constants.RedisDB15
 
 	readinterface := model.NewReadModel(log, database.NewDatabase(connections.DB[constants.SchoolsReadDBKey]))
-	//writeinterface := model.NewWriteModel(log, database.NewDatabase(writeconn))
+	writeinterface := model.NewWriteModel(log, database.NewDatabase(connections.DB[constants.SchoolsWriteDBKey]))
 
 	return CustomerService{
 		log:                 log,
-		redis:               cache.NewRedis(connections.Redis[constants.WWWReadRedisKey], connections.Redis[constants.WWWWriteRedisKey], log, context.Background()),
+		redis:               cache.NewPinnedRedis(connections.Redis[constants.WWWReadRedisKey], connections.Redis[constants.WWWWriteRedisKey], constants.RedisDB21, log, context.Background()),
 		getTimezoneFromUser: readinterface.GetUserTimezone,
 		getNotification:     readinterface.GetAwareNotification,
 		getFilter:           readinterface.GetFilterType,
+		getNotifications:    readinterface.GetAwareNotifications,
+		getNotificationByID: readinterface.GetAwareNotificationByID,
+		saveNotification:    writeinterface.SaveAwareNotification,
+		updateNotification:  writeinterface.UpdateAwareNotification,
+		deleteNotification:  writeinterface.DeleteAwareNotification,
 	}
 }
 
-// ProuctPrivacyStatus gets info of a student based on email and fid (if available)
-func (s CustomerService) ProuctPrivacyStatus(fid string) (map[string]int, error) {
-	privacyMode := map[string]int{
-		"Filter":    0,
-		"Aware":     0,
-		"24":        0,
-		"Responder": 0,
-		"suppBully": 0,
-	}
+// ProuctPrivacyStatus decodes every privacy mode set in the ENHANCED_PRIVACY bit vector of
+// the domain of fid, an Aware domain also gets 24 and Responder when its customer is eligible
+func (s CustomerService) ProuctPrivacyStatus(fid string) (datatypes.PrivacyStatus, error) {
 	domainName := strings.Split(fid, "@")
-	if domainName[1] == "" {
+	if len(domainName) < 2 || domainName[1] == "" {
 		s.log.Error("empty domain", nil)
-		return privacyMode, constants.EmptyFid
+		return datatypes.PrivacyStatus{}, constants.EmptyFid
 	}
-	s.redis.SetDB(constants.RedisDB21)
-	privacyKey := domainName[1] + ":PF:ENHANCED_PRIVACY"
-	value, err := s.redis.GetValue(privacyKey)
+	privacyKey := fmt.Sprintf(constants.PrivacyDomainKey, domainName[1])
+	flags, found, err := s.bitVector(privacyKey)
 	if err != nil {
-		if err == constants.ResourceNotFound {
-			return privacyMode, nil
-		}
-		s.log.Error("error fetching value from redis", map[string]interface{}{"error": err})
-		return privacyMode, err
+		return datatypes.PrivacyStatus{}, err
 	}
 
-	privacyValue, err := strconv.Atoi(value)
-	if err != nil {
-		s.log.Error("error converting to int", map[string]interface{}{"error": err})
-		return privacyMode, constants.InvalidCoversionToInt
+	status := datatypes.NewPrivacyStatus(datatypes.PrivacyFlags(flags))
+	s.log.Info("value", map[string]interface{}{"privacy": flags, "modes": status.Modes})
+	switch {
+	case !found:
+		status.Eligibility.Reasons = append(status.Eligibility.Reasons, privacyKey+" is not set")
+		return status, nil
+	case status.Aware != 1:
+		status.Eligibility.Reasons = append(status.Eligibility.Reasons, "Aware privacy is not set")
+		return status, nil
+	case status.TwentyFour == 1 && status.Responder == 1:
+		status.Eligibility.Reasons = append(status.Eligibility.Reasons, "24 and Responder privacy are set")
+		return status, nil
 	}
-	s.log.Info("value", map[string]interface{}{"privacy": privacyValue})
 
-	if utils.IsBitSet(privacyValue, constants.FILTER_PRIVACY) {
-		privacyMode["Filter"] = 1
-	} else if utils.IsBitSet(privacyValue, constants.AWARE_PRIVACY) {
-		privacyMode["Aware"] = 1
-	} else if utils.IsBitSet(privacyValue, constants.TWENTY_FOUR_PRIVACY) {
-		privacyMode["24"] = 1
-	} else if utils.IsBitSet(privacyValue, constants.RESPONDER_PRIVACY) {
-		privacyMode["Responder"] = 1
-	} else if utils.IsBitSet(privacyValue, constants.SUPPRESS_BULLY) {
-		privacyMode["suppBully"] = 1
+	status.Eligibility, err = s.awareEligibility(fid)
+	if err != nil {
+		return datatypes.PrivacyStatus{}, err
+	}
+	if status.Eligibility.TwentyFour && status.Eligibility.Responder {
+		status.TwentyFour = 1
+		status.Responder = 1
 	}
 
-	if privacyMode["Aware"] == 1 && (privacyMode["24"] != 1 || privacyMode["Responder"] != 1) {
-		has24 := 0
-		hasRespond := 0
-		integrationEnabled := 0
+	return status, nil
+}
 
-		pnBitVector, err := s.redis.GetValue(fid + ":PN")
+// awareEligibility decides whether fid gets 24 and Responder from its PN, PF:3 and
+// PN:RESPONDER bit vectors, both need 24 (PN bit 9 with PF:3 bits 0 and 1 or PN bit 10
+// with PF:3 bits 0 and 2), the responder (PN:RESPONDER bit 0) and the integration (PF:3 bit 3)
+func (s CustomerService) awareEligibility(fid string) (datatypes.PrivacyEligibility, error) {
+	eligibility := datatypes.PrivacyEligibility{Checked: true, Reasons: []string{}}
+	vectors := map[string]datatypes.PrivacyFlags{}
+	keys := []string{fmt.Sprintf(constants.PrivacyPNKey, fid), fmt.Sprintf(constants.PrivacyPF3Key, fid), fmt.Sprintf(constants.PrivacyResponderKey, fid)}
+	for _, key := range keys {
+		value, found, err := s.bitVector(key)
 		if err != nil {
-			if err == constants.ResourceNotFound {
-				return privacyMode, nil
-			}
-			s.log.Error("error fetching value from redis", map[string]interface{}{"error": err})
-			return privacyMode, err
+			return datatypes.PrivacyEligibility{}, err
 		}
-		pnBitVectorValue, err := strconv.Atoi(pnBitVector)
-		if err != nil {
-			s.log.Error("error converting to int", map[string]interface{}{"error": err})
-			return privacyMode, constants.InvalidCoversionToInt
+		if !found {
+			eligibility.Reasons = append(eligibility.Reasons, key+" is not set")
 		}
+		vectors[key] = datatypes.PrivacyFlags(value)
+	}
+	pn, pf, respond := vectors[keys[0]], vectors[keys[1]], vectors[keys[2]]
+
+	has24 := true
+	switch {
+	case pn.Has(9) && pf.Has(1) && pf.Has(0):
+		eligibility.Reasons = append(eligibility.Reasons, "24 is enabled by PN bit 9 with PF:3 bits 0 and 1")
+	case pn.Has(10) && pf.Has(2) && pf.Has(0):
+		eligibility.Reasons = append(eligibility.Reasons, "24 is enabled by PN bit 10 with PF:3 bits 0 and 2")
+	default:
+		has24 = false
+		eligibility.Reasons = append(eligibility.Reasons, "24 is not enabled, needs PN bit 9 with PF:3 bits 0 and 1 or PN bit 10 with PF:3 bits 0 and 2")
+	}
 
-		pfBitVector, err := s.redis.GetValue(fid + ":PF:3")
-		if err != nil {
-			if err == constants.ResourceNotFound {
-				return privacyMode, nil
-			}
-			s.log.Error("error fetching value from redis", map[string]interface{}{"error": err})
-			return privacyMode, err
-		}
-		pfBitVectorValue, err := strconv.Atoi(pfBitVector)
-		if err != nil {
-			s.log.Error("error converting to int", map[string]interface{}{"error": err})
-			return privacyMode, constants.InvalidCoversionToInt
-		}
+	hasRespond := respond.Has(0)
+	if hasRespond {
+		eligibility.Reasons = append(eligibility.Reasons, "responder is enabled by PN:RESPONDER bit 0")
+	} else {
+		eligibility.Reasons = append(eligibility.Reasons, "responder is not enabled, PN:RESPONDER bit 0 is not set")
+	}
 
-		respondVector, err := s.redis.GetValue(fid + ":PN:RESPONDER")
-		if err != nil {
-			if err == constants.ResourceNotFound {
-				return privacyMode, nil
-			}
-			s.log.Error("error fetching value from redis", map[string]interface{}{"error": err})
-			return privacyMode, err
-		}
-		respondVectorValue, err := strconv.Atoi(respondVector)
-		if err != nil {
-			s.log.Error("error converting to int", map[string]interface{}{"error": err})
-			return privacyMode, constants.InvalidCoversionToInt
-		}
-		if (utils.IsBitSet(pnBitVectorValue, 9) && utils.IsBitSet(pfBitVectorValue, 1) && utils.IsBitSet(pfBitVectorValue, 0)) ||
-			(utils.IsBitSet(pnBitVectorValue, 10) && utils.IsBitSet(pfBitVectorValue, 2) && utils.IsBitSet(pfBitVectorValue, 0)) {
-			has24 = 1
-		}
-		if utils.IsBitSet(respondVectorValue, 0) {
-			hasRespond = 1
-		}
-		if utils.IsBitSet(pfBitVectorValue, 3) {
-			integrationEnabled = 1
-		}
-		if has24 == 1 && hasRespond == 1 && integrationEnabled == 1 {
-			privacyMode["24"] = 1
-			privacyMode["Responder"] = 1
+	integrationEnabled := pf.Has(3)
+	if integrationEnabled {
+		eligibility.Reasons = append(eligibility.Reasons, "integration is enabled by PF:3 bit 3")
+	} else {
+		eligibility.Reasons = append(eligibility.Reasons, "integration is not enabled, PF:3 bit 3 is not set")
+	}
+
+	eligibility.TwentyFour = has24 && hasRespond && integrationEnabled
+	eligibility.Responder = eligibility.TwentyFour
+	return eligibility, nil
+}
+
+// bitVector reads the bit vector kept at key, found is false when the key does not exist
+func (s CustomerService) bitVector(key string) (int, bool, error) {
+	value, err := s.redis.GetValue(key)
+	if err != nil {
+		if err == constants.ResourceNotFound {
+			return 0, false, nil
 		}
+		s.log.Error("error fetching value from redis", map[string]interface{}{"key": key, "error": err})
+		return 0, false, err
 	}
 
-	return privacyMode, nil
+	vector, err := strconv.Atoi(value)
+	if err != nil {
+		s.log.Error("error converting to int", map[string]interface{}{"key": key, "value": value, "error": err})
+		return 0, false, constants.InvalidCoversionToInt
+	}
+	return vector, true, nil
 }
 
-// Timezone gets info of a student based on email and fid (if available)
+// Timezone gets the timezone of a fid with its current offset, DST and local time and
+// its next transition, the status tells when the fid has no timezone or an invalid one
 func (s CustomerService) Timezone(fid string) (datatypes.TimezoneResponse, error) {
 	location, err := s.getTimezoneFromUser(fid)
 	if err != nil {
 		s.log.Error("error occured while fetching timezone", map[string]interface{}{"error": err})
 		return datatypes.TimezoneResponse{}, err
 	}
-	loc, err := time.LoadLocation(location)
-	if err != nil {
-		s.log.Error("location not found", map[string]interface{}{"error": err})
-		return datatypes.TimezoneResponse{Tz: location, TzAbbr: ""}, nil
-	}
-	timezone, _ := time.Now().In(loc).Zone()
-	return datatypes.TimezoneResponse{Tz: location, TzAbbr: timezone}, nil
+	return s.timezoneAt(location, time.Now()), nil
 }
 
 // Notification gets notification based on fid
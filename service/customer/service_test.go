@@ -1,6 +1,7 @@
 package student
 
 import (
+	"reflect"
 	"testing"
 	"www-api/internal/constants"
 	"www-api/internal/datatypes"
@@ -11,7 +12,6 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
 )
 
@@ -28,11 +28,12 @@ func TestNewCustomerService(t *testing.T) {
 			log:  logger.ZapLogger{},
 			connections: &datatypes.Connections{
 				DB: map[string]*sqlx.DB{
-					constants.SchoolsReadDBKey: &sqlx.DB{},
+					constants.SchoolsReadDBKey:  &sqlx.DB{},
+					constants.SchoolsWriteDBKey: &sqlx.DB{},
 				},
 				Redis: map[string]*redis.Client{
-					constants.WWWReadRedisKey:  &redis.Client{},
-					constants.WWWWriteRedisKey: &redis.Client{},
+					constants.WWWReadRedisKey:  redis.NewClient(&redis.Options{}),
+					constants.WWWWriteRedisKey: redis.NewClient(&redis.Options{}),
 				},
 				Elastic: nil,
 			},
@@ -56,6 +57,21 @@ func TestNewCustomerService(t *testing.T) {
 			if riskService.getFilter == nil {
 				t.Errorf("expected getFilter but got nil")
 			}
+			if riskService.getNotifications == nil {
+				t.Errorf("expected getNotifications but got nil")
+			}
+			if riskService.getNotificationByID == nil {
+				t.Errorf("expected getNotificationByID but got nil")
+			}
+			if riskService.saveNotification == nil {
+				t.Errorf("expected saveNotification but got nil")
+			}
+			if riskService.updateNotification == nil {
+				t.Errorf("expected updateNotification but got nil")
+			}
+			if riskService.deleteNotification == nil {
+				t.Errorf("expected deleteNotification but got nil")
+			}
 		})
 	}
 }
@@ -66,60 +82,144 @@ func TestProuctPrivacyStatus(t *testing.T) {
 		name        string
 		fid         string
 		redisClient func() *mocks.RedisOps
-		want        map[string]int
+		want        datatypes.PrivacyStatus
 		wantErr     error
 	}
 
+	domain := func(value string, err error) *mocks.RedisOps {
+		moc := mocks.NewRedisOps(t)
+		moc.On("GetValue", "rtqa1securly.com:PF:ENHANCED_PRIVACY").Return(value, err).Once()
+		return moc
+	}
 	testCases := []tests{
 		{
-			name: "valid case",
+			name: "valid case, every set mode is reported",
+			fid:  "checkemail@rtqa1securly.com",
+			redisClient: func() *mocks.RedisOps {
+				return domain("17", nil)
+			},
+			want: datatypes.PrivacyStatus{Filter: 1, SuppressBully: 1, BitVector: 17, Modes: []string{"Filter", "suppBully"}, Eligibility: datatypes.PrivacyEligibility{
+				Reasons: []string{"Aware privacy is not set"},
+			}},
+			wantErr: nil,
+		},
+		{
+			name: "valid case, aware domain eligible for 24 and responder",
+			fid:  "checkemail@rtqa1securly.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := domain("2", nil)
+				moc.On("GetValue", "checkemail@rtqa1securly.com:PN").Return("512", nil).Once()
+				moc.On("GetValue", "checkemail@rtqa1securly.com:PF:3").Return("11", nil).Once()
+				moc.On("GetValue", "checkemail@rtqa1securly.com:PN:RESPONDER").Return("1", nil).Once()
+				return moc
+			},
+			want: datatypes.PrivacyStatus{Aware: 1, TwentyFour: 1, Responder: 1, BitVector: 2, Modes: []string{"Aware"}, Eligibility: datatypes.PrivacyEligibility{
+				Checked:    true,
+				TwentyFour: true,
+				Responder:  true,
+				Reasons: []string{
+					"24 is enabled by PN bit 9 with PF:3 bits 0 and 1",
+					"responder is enabled by PN:RESPONDER bit 0",
+					"integration is enabled by PF:3 bit 3",
+				},
+			}},
+			wantErr: nil,
+		},
+		{
+			name: "valid case, aware domain not eligible",
 			fid:  "checkemail@rtqa1securly.com",
 			redisClient: func() *mocks.RedisOps {
-				moc := mocks.NewRedisOps(t)
-				moc.On("GetValue", mock.Anything).Return("1", nil).Once()
+				moc := domain("2", nil)
+				moc.On("GetValue", "checkemail@rtqa1securly.com:PN").Return("", constants.ResourceNotFound).Once()
+				moc.On("GetValue", "checkemail@rtqa1securly.com:PF:3").Return("5", nil).Once()
+				moc.On("GetValue", "checkemail@rtqa1securly.com:PN:RESPONDER").Return("1", nil).Once()
 				return moc
 			},
-			want:    map[string]int{"24": 0, "Aware": 0, "Filter": 1, "Responder": 0, "suppBully": 0},
+			want: datatypes.PrivacyStatus{Aware: 1, BitVector: 2, Modes: []string{"Aware"}, Eligibility: datatypes.PrivacyEligibility{
+				Checked: true,
+				Reasons: []string{
+					"checkemail@rtqa1securly.com:PN is not set",
+					"24 is not enabled, needs PN bit 9 with PF:3 bits 0 and 1 or PN bit 10 with PF:3 bits 0 and 2",
+					"responder is enabled by PN:RESPONDER bit 0",
+					"integration is not enabled, PF:3 bit 3 is not set",
+				},
+			}},
+			wantErr: nil,
+		},
+		{
+			name: "valid case, 24 and responder set on the domain",
+			fid:  "checkemail@rtqa1securly.com",
+			redisClient: func() *mocks.RedisOps {
+				return domain("14", nil)
+			},
+			want: datatypes.PrivacyStatus{Aware: 1, TwentyFour: 1, Responder: 1, BitVector: 14, Modes: []string{"Aware", "Responder", "24"}, Eligibility: datatypes.PrivacyEligibility{
+				Reasons: []string{"24 and Responder privacy are set"},
+			}},
+			wantErr: nil,
+		},
+		{
+			name: "valid case, privacy not set on the domain",
+			fid:  "checkemail@rtqa1securly.com",
+			redisClient: func() *mocks.RedisOps {
+				return domain("", constants.ResourceNotFound)
+			},
+			want: datatypes.PrivacyStatus{Modes: []string{}, Eligibility: datatypes.PrivacyEligibility{
+				Reasons: []string{"rtqa1securly.com:PF:ENHANCED_PRIVACY is not set"},
+			}},
 			wantErr: nil,
 		},
 		{
 			name: "invalid case, invalid fid",
 			fid:  "checkemail@",
 			redisClient: func() *mocks.RedisOps {
-				moc := mocks.NewRedisOps(t)
-				// moc.On("GetValue", mock.Anything).Return("1", nil).Once()
-				return moc
+				return mocks.NewRedisOps(t)
 			},
-			want:    map[string]int{"24": 0, "Aware": 0, "Filter": 0, "Responder": 0, "suppBully": 0},
+			want:    datatypes.PrivacyStatus{},
+			wantErr: constants.EmptyFid,
+		},
+		{
+			name: "invalid case, fid without domain",
+			fid:  "checkemail",
+			redisClient: func() *mocks.RedisOps {
+				return mocks.NewRedisOps(t)
+			},
+			want:    datatypes.PrivacyStatus{},
 			wantErr: constants.EmptyFid,
 		},
 		{
 			name: "invalid case, redis call error out",
 			fid:  "checkemail@rtqa1securly.com",
 			redisClient: func() *mocks.RedisOps {
-				moc := mocks.NewRedisOps(t)
-				moc.On("GetValue", mock.Anything).Return("", test.CacheGetValueErr).Once()
-				return moc
+				return domain("", test.CacheGetValueErr)
 			},
-			want:    map[string]int{"24": 0, "Aware": 0, "Filter": 0, "Responder": 0, "suppBully": 0},
+			want:    datatypes.PrivacyStatus{},
 			wantErr: test.CacheGetValueErr,
 		},
 		{
 			name: "invalid case, string to int conversion failed",
 			fid:  "checkemail@rtqa1securly.com",
 			redisClient: func() *mocks.RedisOps {
-				moc := mocks.NewRedisOps(t)
-				moc.On("GetValue", mock.Anything).Return("invalid", nil).Once()
-				return moc
+				return domain("invalid", nil)
 			},
-			want:    map[string]int{"24": 0, "Aware": 0, "Filter": 0, "Responder": 0, "suppBully": 0},
+			want:    datatypes.PrivacyStatus{},
 			wantErr: constants.InvalidCoversionToInt,
 		},
+		{
+			name: "invalid case, error fetching PN bit vector",
+			fid:  "checkemail@rtqa1securly.com",
+			redisClient: func() *mocks.RedisOps {
+				moc := domain("2", nil)
+				moc.On("GetValue", "checkemail@rtqa1securly.com:PN").Return("", test.CacheGetValueErr).Once()
+				return moc
+			},
+			want:    datatypes.PrivacyStatus{},
+			wantErr: test.CacheGetValueErr,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			cust := CustomerService{logger.ZapLogger{Logger: zap.NewExample()}, tc.redisClient(), nil, nil, nil}
+			cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}, redis: tc.redisClient()}
 			status, err := cust.ProuctPrivacyStatus(tc.fid)
 			if !assert.Equal(t, tc.want, status) {
 				t.Errorf("expected status %v got %v", tc.want, status)
@@ -147,8 +247,11 @@ func TestTimezone(t *testing.T) {
 				return "Asia/Kolkata", nil
 			},
 			wantlocation: datatypes.TimezoneResponse{
-				Tz:     "",
-				TzAbbr: "Asia/Kolkata",
+				Tz:            "Asia/Kolkata",
+				TzAbbr:        "IST",
+				Status:        "ok",
+				Offset:        "+05:30",
+				OffsetSeconds: 19800,
 			},
 			wantErr: nil,
 		},
@@ -165,8 +268,18 @@ func TestTimezone(t *testing.T) {
 				return "Asia/invalid", nil
 			},
 			wantlocation: datatypes.TimezoneResponse{
-				Tz:     "",
-				TzAbbr: "Asia/Kolkata",
+				Tz:     "Asia/invalid",
+				Status: "invalid",
+			},
+			wantErr: nil,
+		},
+		{
+			name: "valid case, no location",
+			getTimezoneFromUser: func(fid string) (string, error) {
+				return "", nil
+			},
+			wantlocation: datatypes.TimezoneResponse{
+				Status: "unknown",
 			},
 			wantErr: nil,
 		},
@@ -174,9 +287,10 @@ func TestTimezone(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			cust := CustomerService{logger.ZapLogger{Logger: zap.NewExample()}, nil, tc.getTimezoneFromUser, nil, nil}
+			cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}, getTimezoneFromUser: tc.getTimezoneFromUser}
 			location, err := cust.Timezone("")
-			if tc.wantlocation != location {
+			location.LocalTime = ""
+			if !reflect.DeepEqual(tc.wantlocation, location) {
 				t.Errorf("expected response %v got %v", tc.wantlocation, location)
 			}
 			if tc.wantErr != err {
@@ -214,7 +328,7 @@ func TestNotification(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			cust := CustomerService{logger.ZapLogger{Logger: zap.NewExample()}, nil, nil, tc.getNotification, nil}
+			cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}, getNotification: tc.getNotification}
 			notification, err := cust.Notification("")
 			if !assert.Equal(t, tc.want, notification) {
 				t.Errorf("expected notification %v got %v", tc.want, notification)
@@ -278,7 +392,7 @@ func TestGetFilterType(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			cust := CustomerService{logger.ZapLogger{Logger: zap.NewExample()}, nil, nil, nil, tc.getFilter}
+			cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}, getFilter: tc.getFilter}
 			notification, err := cust.GetFilterType("")
 			if !assert.Equal(t, tc.want, notification) {
 				t.Errorf("expected notification %v got %v", tc.want, notification)
@@ -0,0 +1,105 @@
+package student
+
+import (
+	"fmt"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+)
+
+// Timezones gets the timezone of every fid, a fid that is not found is returned
+// with status constants.TimezoneUnknown instead of failing the whole lookup
+func (s CustomerService) Timezones(fids []string) ([]datatypes.TimezoneResponse, error) {
+	now := time.Now()
+	timezones := []datatypes.TimezoneResponse{}
+	for _, fid := range fids {
+		location, err := s.getTimezoneFromUser(fid)
+		if err != nil && err != constants.ResourceNotFound {
+			s.log.Error("error occured while fetching timezone", map[string]interface{}{"error": err, "fid": fid})
+			return nil, err
+		}
+		timezone := s.timezoneAt(location, now)
+		timezone.Fid = fid
+		timezones = append(timezones, timezone)
+	}
+	return timezones, nil
+}
+
+// timezoneAt describes location at now, an empty location is unknown and a location
+// that cannot be loaded is invalid
+func (s CustomerService) timezoneAt(location string, now time.Time) datatypes.TimezoneResponse {
+	if location == "" {
+		return datatypes.TimezoneResponse{Status: constants.TimezoneUnknown}
+	}
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		s.log.Error("location not found", map[string]interface{}{"error": err, "location": location})
+		return datatypes.TimezoneResponse{Tz: location, Status: constants.TimezoneInvalid}
+	}
+
+	local := now.In(loc)
+	abbr, offset := local.Zone()
+	timezone := datatypes.TimezoneResponse{
+		Tz:            location,
+		TzAbbr:        abbr,
+		Status:        constants.TimezoneOK,
+		Offset:        formatOffset(offset),
+		OffsetSeconds: offset,
+		IsDST:         local.IsDST(),
+		LocalTime:     local.Format(time.RFC3339),
+	}
+
+	at, found := nextTransition(local)
+	if found {
+		abbr, offset := at.Zone()
+		timezone.NextTransition = &datatypes.TimezoneTransition{
+			At:            at.Format(time.RFC3339),
+			TzAbbr:        abbr,
+			Offset:        formatOffset(offset),
+			OffsetSeconds: offset,
+			IsDST:         at.IsDST(),
+		}
+	}
+	return timezone
+}
+
+// nextTransition returns the first second after t at which the abbreviation or offset
+// of its location changes, it is searched day by day and then narrowed down to the second
+func nextTransition(t time.Time) (time.Time, bool) {
+	name, offset := t.Zone()
+	changed := func(u time.Time) bool {
+		n, o := u.Zone()
+		return n != name || o != offset
+	}
+
+	previous := t
+	for day := 1; day <= constants.TimezoneTransitionHorizonDays; day++ {
+		next := t.Add(time.Duration(day) * 24 * time.Hour)
+		if !changed(next) {
+			previous = next
+			continue
+		}
+
+		low, high := previous.Unix(), next.Unix()
+		for high-low > 1 {
+			mid := low + (high-low)/2
+			if changed(time.Unix(mid, 0).In(t.Location())) {
+				high = mid
+			} else {
+				low = mid
+			}
+		}
+		return time.Unix(high, 0).In(t.Location()), true
+	}
+	return time.Time{}, false
+}
+
+// formatOffset formats an offset in seconds east of UTC as +hh:mm
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d:%02d", sign, seconds/3600, seconds%3600/60)
+}
@@ -0,0 +1,161 @@
+package student
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/test"
+
+	"go.uber.org/zap"
+)
+
+func TestTimezones(t *testing.T) {
+
+	type tests struct {
+		name                string
+		getTimezoneFromUser func(fid string) (string, error)
+		wantStatus          []string
+		wantErr             error
+	}
+
+	testCases := []tests{
+		{
+			name: "valid case",
+			getTimezoneFromUser: func(fid string) (string, error) {
+				switch fid {
+				case "a@securly.com":
+					return "America/New_York", nil
+				case "b@securly.com":
+					return "", constants.ResourceNotFound
+				}
+				return "Asia/invalid", nil
+			},
+			wantStatus: []string{"ok", "unknown", "invalid"},
+			wantErr:    nil,
+		},
+		{
+			name: "fail case, getTimezoneFromUser error out",
+			getTimezoneFromUser: func(fid string) (string, error) {
+				return "", test.InternalServerErr
+			},
+			wantStatus: nil,
+			wantErr:    test.InternalServerErr,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}, getTimezoneFromUser: tc.getTimezoneFromUser}
+			timezones, err := cust.Timezones([]string{"a@securly.com", "b@securly.com", "c@securly.com"})
+			var status []string
+			for i, timezone := range timezones {
+				if timezone.Fid != []string{"a@securly.com", "b@securly.com", "c@securly.com"}[i] {
+					t.Errorf("expected timezones in the order of fids got %v", timezones)
+				}
+				status = append(status, timezone.Status)
+			}
+			if !reflect.DeepEqual(tc.wantStatus, status) {
+				t.Errorf("expected status %v got %v", tc.wantStatus, status)
+			}
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTimezoneAt(t *testing.T) {
+
+	type tests struct {
+		name     string
+		location string
+		now      time.Time
+		want     datatypes.TimezoneResponse
+	}
+
+	testCases := []tests{
+		{
+			name:     "valid case, standard time",
+			location: "America/New_York",
+			now:      time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+			want: datatypes.TimezoneResponse{
+				Tz:            "America/New_York",
+				TzAbbr:        "EST",
+				Status:        "ok",
+				Offset:        "-05:00",
+				OffsetSeconds: -18000,
+				IsDST:         false,
+				LocalTime:     "2024-01-15T07:00:00-05:00",
+				NextTransition: &datatypes.TimezoneTransition{
+					At:            "2024-03-10T03:00:00-04:00",
+					TzAbbr:        "EDT",
+					Offset:        "-04:00",
+					OffsetSeconds: -14400,
+					IsDST:         true,
+				},
+			},
+		},
+		{
+			name:     "valid case, daylight saving time",
+			location: "America/New_York",
+			now:      time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC),
+			want: datatypes.TimezoneResponse{
+				Tz:            "America/New_York",
+				TzAbbr:        "EDT",
+				Status:        "ok",
+				Offset:        "-04:00",
+				OffsetSeconds: -14400,
+				IsDST:         true,
+				LocalTime:     "2024-07-01T08:00:00-04:00",
+				NextTransition: &datatypes.TimezoneTransition{
+					At:            "2024-11-03T01:00:00-05:00",
+					TzAbbr:        "EST",
+					Offset:        "-05:00",
+					OffsetSeconds: -18000,
+					IsDST:         false,
+				},
+			},
+		},
+		{
+			name:     "valid case, no transition",
+			location: "Asia/Kolkata",
+			now:      time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+			want: datatypes.TimezoneResponse{
+				Tz:            "Asia/Kolkata",
+				TzAbbr:        "IST",
+				Status:        "ok",
+				Offset:        "+05:30",
+				OffsetSeconds: 19800,
+				LocalTime:     "2024-01-15T17:30:00+05:30",
+			},
+		},
+		{
+			name:     "valid case, invalid location",
+			location: "Asia/invalid",
+			now:      time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+			want:     datatypes.TimezoneResponse{Tz: "Asia/invalid", Status: "invalid"},
+		},
+		{
+			name:     "valid case, unknown location",
+			location: "",
+			now:      time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+			want:     datatypes.TimezoneResponse{Status: "unknown"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cust := CustomerService{log: logger.ZapLogger{Logger: zap.NewExample()}}
+			timezone := cust.timezoneAt(tc.location, tc.now)
+			if !reflect.DeepEqual(tc.want, timezone) {
+				t.Errorf("expected response %+v got %+v", tc.want, timezone)
+			}
+			if tc.want.NextTransition != nil && !reflect.DeepEqual(*tc.want.NextTransition, *timezone.NextTransition) {
+				t.Errorf("expected next transition %+v got %+v", *tc.want.NextTransition, *timezone.NextTransition)
+			}
+		})
+	}
+}
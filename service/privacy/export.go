@@ -0,0 +1,200 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/pkg/aws/s3"
+)
+
+// WithExport returns a copy of the service that uploads export bundles to bucket under prefix
+func (s PrivacyService) WithExport(store s3.S3Action, bucket, prefix string) PrivacyService {
+	if prefix == "" {
+		prefix = constants.DefaultExportPrefix
+	}
+	s.exportStore = store
+	s.exportBucket = bucket
+	s.exportPrefix = prefix
+	return s
+}
+
+// ExportStudent bundles everything kept about a student as json, or as a zip with a file
+// per section, along with the settings of the customer fid when given. An uploaded bundle
+// is not returned, its reference tells where it can be downloaded from. Every export is
+// recorded in the audit log under actor
+func (s PrivacyService) ExportStudent(request datatypes.StudentExportRequest, actor string) ([]byte, datatypes.StudentExportReference, error) {
+	format := request.Format
+	if format == "" {
+		format = constants.ExportFormatJSON
+	}
+	if format != constants.ExportFormatJSON && format != constants.ExportFormatZip {
+		return nil, datatypes.StudentExportReference{}, constants.InvalidExportFormat
+	}
+	if request.Upload && s.exportStore == nil {
+		return nil, datatypes.StudentExportReference{}, constants.ExportStoreNotConfigured
+	}
+
+	exportID, err := newID()
+	if err != nil {
+		s.log.Error("unable to generate export id", map[string]interface{}{"error": err})
+		return nil, datatypes.StudentExportReference{}, err
+	}
+
+	export, err := s.gatherExport(exportID, request.UserEmail, request.Fid)
+	if err != nil {
+		return nil, datatypes.StudentExportReference{}, err
+	}
+
+	reference := datatypes.StudentExportReference{ExportID: exportID, UserEmail: request.UserEmail, Format: format, ContentType: "application/json"}
+	var bundle []byte
+	if format == constants.ExportFormatZip {
+		reference.ContentType = "application/zip"
+		bundle, err = zipExport(export)
+	} else {
+		bundle, err = json.Marshal(export)
+	}
+	if err != nil {
+		s.log.Error("unable to encode export bundle", map[string]interface{}{"email": request.UserEmail, "format": format, "error": err})
+		return nil, datatypes.StudentExportReference{}, err
+	}
+	reference.Size = len(bundle)
+
+	if request.Upload {
+		reference.Bucket = s.exportBucket
+		reference.Key = fmt.Sprintf("%s/%s/%s.%s", s.exportPrefix, request.UserEmail, exportID, format)
+		reference.Location = fmt.Sprintf("s3://%s/%s", reference.Bucket, reference.Key)
+		err = s.exportStore.PutObject(reference.Bucket, reference.Key, bundle)
+		if err != nil {
+			s.log.Error("unable to upload export bundle", map[string]interface{}{"email": request.UserEmail, "location": reference.Location, "error": err})
+			return nil, datatypes.StudentExportReference{}, err
+		}
+		bundle = nil
+	}
+
+	//like an erasure, an export is only handed out once it is recorded in the audit log
+	err = s.saveAtRiskAudit(datatypes.AtRiskAuditEntry{Actor: actor, Action: constants.AuditActionExport, UserEmail: request.UserEmail, AtRiskKey: request.UserEmail + ":*", AfterValue: &exportID, CreatedAt: export.ExportedAt})
+	if err != nil {
+		s.log.Error("PRIVACY_AUDIT_FAILED. unable to save export audit entry", map[string]interface{}{"reference": reference, "error": err})
+		return nil, datatypes.StudentExportReference{}, err
+	}
+
+	s.log.Info("exported student", map[string]interface{}{"email": request.UserEmail, "fid": request.Fid, "actor": actor, "reference": reference})
+	return bundle, reference, nil
+}
+
+// eraseExports deletes the uploaded export bundles of a student and returns their keys, a dry
+// run only lists them. There are none without an export store
+func (s PrivacyService) eraseExports(email string, dryRun bool) ([]string, error) {
+	if s.exportStore == nil {
+		return []string{}, nil
+	}
+
+	objects, err := s.exportStore.ListObjects(s.exportBucket, s.exportPrefix+"/"+email+"/")
+	if err != nil {
+		s.log.Error("unable to list export bundles of student", map[string]interface{}{"email": email, "error": err})
+		return nil, err
+	}
+	objects = append([]string{}, objects...)
+	if dryRun {
+		return objects, nil
+	}
+
+	for _, object := range objects {
+		err = s.exportStore.DeleteObject(s.exportBucket, object)
+		if err != nil {
+			s.log.Error("unable to delete export bundle of student", map[string]interface{}{"email": email, "object": object, "error": err})
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// gatherExport collects the student info, at-risk data and customer settings of a student,
+// a student or setting that is not found is left out
+func (s PrivacyService) gatherExport(exportID, email, fid string) (datatypes.StudentExport, error) {
+	export := datatypes.StudentExport{ExportID: exportID, UserEmail: email, ExportedAt: time.Now().Unix()}
+
+	info, err := s.studentInfo(fid, email)
+	if err != nil && err != constants.ResourceNotFound {
+		return datatypes.StudentExport{}, err
+	}
+	if err == nil {
+		export.Student = &info
+	}
+
+	export.AtRisk, err = s.exportAtRisk(email)
+	if err != nil {
+		return datatypes.StudentExport{}, err
+	}
+
+	if fid == "" {
+		return export, nil
+	}
+
+	settings := datatypes.CustomerSettings{Fid: fid}
+	settings.PrivacyStatus, err = s.privacyStatus(fid)
+	if err != nil {
+		return datatypes.StudentExport{}, err
+	}
+
+	timezone, err := s.timezone(fid)
+	if err != nil && err != constants.ResourceNotFound {
+		return datatypes.StudentExport{}, err
+	}
+	if err == nil {
+		settings.Timezone = &timezone
+	}
+
+	notification, err := s.notification(fid)
+	if err != nil && err != constants.ResourceNotFound {
+		return datatypes.StudentExport{}, err
+	}
+	if err == nil {
+		settings.AwareNotification = &notification
+	}
+
+	settings.FilterType, err = s.filterType(fid)
+	if err != nil && err != constants.ResourceNotFound {
+		return datatypes.StudentExport{}, err
+	}
+
+	export.Customer = &settings
+	return export, nil
+}
+
+// zipExport writes a zip with export.json describing the export and a json file per section
+func zipExport(export datatypes.StudentExport) ([]byte, error) {
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"export.json", map[string]interface{}{"exportId": export.ExportID, "userEmail": export.UserEmail, "exportedAt": export.ExportedAt}},
+		{"student.json", export.Student},
+		{"at-risk-events.json", export.AtRisk.Events},
+		{"at-risk-scores.json", export.AtRisk.Scores},
+		{"customer.json", export.Customer},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		err = json.NewEncoder(writer).Encode(file.content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := archive.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,243 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/aws/s3"
+	"www-api/test"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestExportStudent(t *testing.T) {
+
+	type tests struct {
+		name          string
+		request       datatypes.StudentExportRequest
+		withStore     bool
+		studentInfo   func(fid, email string) (datatypes.StudentInfo, error)
+		exportAtRisk  func(email string) (datatypes.AtRiskExport, error)
+		timezone      func(fid string) (datatypes.TimezoneResponse, error)
+		auditErr      error
+		want          datatypes.StudentExport
+		wantReference datatypes.StudentExportReference
+		wantErr       error
+	}
+
+	atRisk := datatypes.AtRiskExport{
+		Events: []datatypes.AtRiskEventItem{{AtRiskKey: "email@securly.com:1684231487", Timestamp: "1684231487", Event: datatypes.AtRiskEvent{Score: 45, Category: "scan", MessageID: "1dc13ds5c1651"}}},
+		Scores: []string{"45"},
+	}
+	exportAtRisk := func(email string) (datatypes.AtRiskExport, error) { return atRisk, nil }
+	studentInfo := func(fid, email string) (datatypes.StudentInfo, error) {
+		return datatypes.StudentInfo{GivenName: "Jane", FamilyName: "Doe"}, nil
+	}
+	timezone := func(fid string) (datatypes.TimezoneResponse, error) {
+		return datatypes.TimezoneResponse{Tz: "America/New_York", TzAbbr: "EST"}, nil
+	}
+	settings := &datatypes.CustomerSettings{
+		Fid:               "admin@securly.com",
+		PrivacyStatus:     datatypes.NewPrivacyStatus(1),
+		Timezone:          &datatypes.TimezoneResponse{Tz: "America/New_York", TzAbbr: "EST"},
+		AwareNotification: &datatypes.Notification{ID: 1, Fid: "admin@securly.com", NotificationEmail: "alerts@securly.com", Basegen: 1},
+		FilterType:        "ou",
+	}
+	testCases := []tests{
+		{
+			name:          "valid case, json with customer settings",
+			request:       datatypes.StudentExportRequest{UserEmail: "email@securly.com", Fid: "admin@securly.com"},
+			studentInfo:   studentInfo,
+			exportAtRisk:  exportAtRisk,
+			timezone:      timezone,
+			want:          datatypes.StudentExport{UserEmail: "email@securly.com", Student: &datatypes.StudentInfo{GivenName: "Jane", FamilyName: "Doe"}, AtRisk: atRisk, Customer: settings},
+			wantReference: datatypes.StudentExportReference{UserEmail: "email@securly.com", Format: "json", ContentType: "application/json"},
+			wantErr:       nil,
+		},
+		{
+			name:    "valid case, student and timezone not found",
+			request: datatypes.StudentExportRequest{UserEmail: "email@securly.com", Fid: "admin@securly.com", Format: "json"},
+			studentInfo: func(fid, email string) (datatypes.StudentInfo, error) {
+				return datatypes.StudentInfo{}, constants.ResourceNotFound
+			},
+			exportAtRisk: exportAtRisk,
+			timezone: func(fid string) (datatypes.TimezoneResponse, error) {
+				return datatypes.TimezoneResponse{}, constants.ResourceNotFound
+			},
+			want: datatypes.StudentExport{UserEmail: "email@securly.com", AtRisk: atRisk, Customer: &datatypes.CustomerSettings{
+				Fid:               "admin@securly.com",
+				PrivacyStatus:     datatypes.NewPrivacyStatus(1),
+				AwareNotification: settings.AwareNotification,
+				FilterType:        "ou",
+			}},
+			wantReference: datatypes.StudentExportReference{UserEmail: "email@securly.com", Format: "json", ContentType: "application/json"},
+			wantErr:       nil,
+		},
+		{
+			name:          "valid case, zip without fid",
+			request:       datatypes.StudentExportRequest{UserEmail: "email@securly.com", Format: "zip"},
+			studentInfo:   studentInfo,
+			exportAtRisk:  exportAtRisk,
+			want:          datatypes.StudentExport{UserEmail: "email@securly.com", Student: &datatypes.StudentInfo{GivenName: "Jane", FamilyName: "Doe"}, AtRisk: atRisk},
+			wantReference: datatypes.StudentExportReference{UserEmail: "email@securly.com", Format: "zip", ContentType: "application/zip"},
+			wantErr:       nil,
+		},
+		{
+			name:          "valid case, uploaded",
+			request:       datatypes.StudentExportRequest{UserEmail: "email@securly.com", Upload: true},
+			withStore:     true,
+			studentInfo:   studentInfo,
+			exportAtRisk:  exportAtRisk,
+			want:          datatypes.StudentExport{UserEmail: "email@securly.com", Student: &datatypes.StudentInfo{GivenName: "Jane", FamilyName: "Doe"}, AtRisk: atRisk},
+			wantReference: datatypes.StudentExportReference{UserEmail: "email@securly.com", Format: "json", ContentType: "application/json", Bucket: "export-bucket"},
+			wantErr:       nil,
+		},
+		{
+			name:    "fail case, invalid format",
+			request: datatypes.StudentExportRequest{UserEmail: "email@securly.com", Format: "csv"},
+			wantErr: constants.InvalidExportFormat,
+		},
+		{
+			name:    "fail case, upload without export bucket",
+			request: datatypes.StudentExportRequest{UserEmail: "email@securly.com", Upload: true},
+			wantErr: constants.ExportStoreNotConfigured,
+		},
+		{
+			name:    "fail case, error fetching student info",
+			request: datatypes.StudentExportRequest{UserEmail: "email@securly.com"},
+			studentInfo: func(fid, email string) (datatypes.StudentInfo, error) {
+				return datatypes.StudentInfo{}, test.DBSomethingWentWrongErr
+			},
+			wantErr: test.DBSomethingWentWrongErr,
+		},
+		{
+			name:        "fail case, error exporting at-risk data",
+			request:     datatypes.StudentExportRequest{UserEmail: "email@securly.com"},
+			studentInfo: studentInfo,
+			exportAtRisk: func(email string) (datatypes.AtRiskExport, error) {
+				return datatypes.AtRiskExport{}, test.CacheGetValueErr
+			},
+			wantErr: test.CacheGetValueErr,
+		},
+		{
+			name:         "fail case, error saving audit entry",
+			request:      datatypes.StudentExportRequest{UserEmail: "email@securly.com"},
+			studentInfo:  studentInfo,
+			exportAtRisk: exportAtRisk,
+			auditErr:     test.DBSomethingWentWrongErr,
+			wantErr:      test.DBSomethingWentWrongErr,
+		},
+		{
+			name:         "fail case, error fetching timezone",
+			request:      datatypes.StudentExportRequest{UserEmail: "email@securly.com", Fid: "admin@securly.com"},
+			studentInfo:  studentInfo,
+			exportAtRisk: exportAtRisk,
+			timezone: func(fid string) (datatypes.TimezoneResponse, error) {
+				return datatypes.TimezoneResponse{}, test.DBSomethingWentWrongErr
+			},
+			wantErr: test.DBSomethingWentWrongErr,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := s3.NewFileSystem(t.TempDir())
+			var audited []datatypes.AtRiskAuditEntry
+			privacy := PrivacyService{
+				log:           logger.ZapLogger{Logger: zap.NewExample()},
+				exportAtRisk:  tc.exportAtRisk,
+				studentInfo:   tc.studentInfo,
+				privacyStatus: func(fid string) (datatypes.PrivacyStatus, error) { return datatypes.NewPrivacyStatus(1), nil },
+				timezone:      tc.timezone,
+				notification: func(fid string) (datatypes.Notification, error) {
+					return datatypes.Notification{ID: 1, Fid: fid, NotificationEmail: "alerts@securly.com", Basegen: 1}, nil
+				},
+				filterType: func(fid string) (string, error) { return "ou", nil },
+				saveAtRiskAudit: func(entry datatypes.AtRiskAuditEntry) error {
+					audited = append(audited, entry)
+					return tc.auditErr
+				},
+			}
+			if tc.withStore {
+				privacy = privacy.WithExport(store, "export-bucket", "")
+			}
+
+			bundle, reference, err := privacy.ExportStudent(tc.request, "subject")
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+			if err != nil {
+				assert.Nil(t, bundle)
+				assert.Equal(t, datatypes.StudentExportReference{}, reference)
+				return
+			}
+			assert.Len(t, reference.ExportID, 32)
+			if assert.Len(t, audited, 1) {
+				assert.Equal(t, "subject", audited[0].Actor)
+				assert.Equal(t, constants.AuditActionExport, audited[0].Action)
+				assert.Equal(t, "email@securly.com", audited[0].UserEmail)
+				assert.Equal(t, reference.ExportID, *audited[0].AfterValue)
+			}
+
+			if tc.request.Upload {
+				assert.Nil(t, bundle)
+				assert.Equal(t, "privacy-exports/email@securly.com/"+reference.ExportID+".json", reference.Key)
+				assert.Equal(t, "s3://export-bucket/"+reference.Key, reference.Location)
+				bundle, _ = store.GetObject("export-bucket", reference.Key)
+			}
+			assert.Equal(t, len(bundle), reference.Size)
+
+			var export datatypes.StudentExport
+			if tc.request.Format == "zip" {
+				export = unzipExport(t, bundle)
+			} else {
+				_ = json.Unmarshal(bundle, &export)
+			}
+			assert.Equal(t, reference.ExportID, export.ExportID)
+			assert.NotZero(t, export.ExportedAt)
+
+			//the id and time change on every run
+			export.ExportID, export.ExportedAt = "", 0
+			reference.ExportID, reference.Size, reference.Key, reference.Location = "", 0, "", ""
+			assert.Equal(t, tc.want, export)
+			assert.Equal(t, tc.wantReference, reference)
+		})
+	}
+}
+
+// unzipExport reads back the sections of a zip export
+func unzipExport(t *testing.T, bundle []byte) datatypes.StudentExport {
+	archive, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		t.Fatalf("unable to open zip export: %v", err)
+	}
+
+	var export datatypes.StudentExport
+	sections := map[string]interface{}{
+		"export.json":         &export,
+		"student.json":        &export.Student,
+		"at-risk-events.json": &export.AtRisk.Events,
+		"at-risk-scores.json": &export.AtRisk.Scores,
+		"customer.json":       &export.Customer,
+	}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("unable to open %s: %v", file.Name, err)
+		}
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		err = json.Unmarshal(content, sections[file.Name])
+		if err != nil {
+			t.Fatalf("unable to decode %s: %v", file.Name, err)
+		}
+		delete(sections, file.Name)
+	}
+	assert.Empty(t, sections)
+	return export
+}
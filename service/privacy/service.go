@@ -0,0 +1,202 @@
+package privacy
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/alert"
+	"www-api/pkg/aws/s3"
+	"www-api/pkg/database"
+	"www-api/pkg/elastic"
+	"www-api/pkg/model"
+	atrisk "www-api/service/at-risk"
+	customer "www-api/service/customer"
+	"www-api/service/student"
+)
+
+type PrivacyService struct {
+	log             logger.ZapLogger
+	eraseAtRisk     func(email string, dryRun bool) (datatypes.AtRiskErasure, error)
+	saveAtRiskAudit func(entry datatypes.AtRiskAuditEntry) error
+	elastic         elastic.ElasticActions
+	elasticIndices  []string
+	elasticField    string
+	receiptSecret   string
+	exportAtRisk    func(email string) (datatypes.AtRiskExport, error)
+	studentInfo     func(fid, email string) (datatypes.StudentInfo, error)
+	privacyStatus   func(fid string) (datatypes.PrivacyStatus, error)
+	timezone        func(fid string) (datatypes.TimezoneResponse, error)
+	notification    func(fid string) (datatypes.Notification, error)
+	filterType      func(fid string) (string, error)
+	exportStore     s3.S3Action
+	exportBucket    string
+	exportPrefix    string
+}
+
+// NewPrivacyService returns an instance of PrivacyService erasing and exporting the at-risk
+// data kept by risk, the erasure receipts are signed with secret
+func NewPrivacyService(log logger.ZapLogger, connections *datatypes.Connections, risk atrisk.RiskService, secret string) PrivacyService {
+	writeinterface := model.NewWriteModel(log, database.NewDatabase(connections.DB[constants.AtRiskWriteDBKey]))
+	students := student.NewStudentService(log, connections)
+	customers := customer.NewCustomerService(log, connections)
+
+	return PrivacyService{
+		log:             log,
+		eraseAtRisk:     risk.EraseEmail,
+		saveAtRiskAudit: writeinterface.SaveAtRiskAudit,
+		receiptSecret:   secret,
+		exportAtRisk:    risk.ExportEmail,
+		studentInfo:     students.StudentInfo,
+		privacyStatus:   customers.ProuctPrivacyStatus,
+		timezone:        customers.Timezone,
+		notification:    customers.Notification,
+		filterType:      customers.GetFilterType,
+	}
+}
+
+// WithElastic returns a copy of the service that also erases the documents of indices whose
+// field is the email of the student
+func (s PrivacyService) WithElastic(client elastic.ElasticActions, indices []string, field string) PrivacyService {
+	if field == "" {
+		field = constants.DefaultPrivacyElasticField
+	}
+	s.elastic = client
+	s.elasticIndices = indices
+	s.elasticField = field
+	return s
+}
+
+// EraseStudent erases everything kept about a student, its uploaded export bundles included,
+// and returns a signed receipt of what was erased, the erasure is recorded in the at-risk
+// audit log under actor. A dry run erases
+// nothing and returns a receipt of what would be erased. An erasure that failed half way can
+// be run again
+func (s PrivacyService) EraseStudent(email, actor string, dryRun bool) (datatypes.ErasureReceipt, error) {
+	if s.receiptSecret == "" {
+		return datatypes.ErasureReceipt{}, constants.ReceiptSecretNotConfigured
+	}
+
+	receiptID, err := newID()
+	if err != nil {
+		s.log.Error("unable to generate receipt id", map[string]interface{}{"error": err})
+		return datatypes.ErasureReceipt{}, err
+	}
+
+	atRisk, err := s.eraseAtRisk(email, dryRun)
+	if err != nil {
+		return datatypes.ErasureReceipt{}, err
+	}
+
+	documents, err := s.eraseDocuments(email, dryRun)
+	if err != nil {
+		return datatypes.ErasureReceipt{}, err
+	}
+
+	exports, err := s.eraseExports(email, dryRun)
+	if err != nil {
+		return datatypes.ErasureReceipt{}, err
+	}
+
+	receipt := datatypes.ErasureReceipt{
+		ReceiptID:        receiptID,
+		UserEmail:        email,
+		Actor:            actor,
+		DryRun:           dryRun,
+		ErasedAt:         time.Now().Unix(),
+		AtRisk:           atRisk,
+		ElasticIndices:   s.indices(),
+		ElasticDocuments: documents,
+		ExportObjects:    exports,
+	}
+
+	//the audit entry is the record that the erasure happened, a request without it fails so that it is run again
+	if !dryRun {
+		err = s.saveAtRiskAudit(datatypes.AtRiskAuditEntry{Actor: actor, Action: constants.AuditActionErase, UserEmail: email, AtRiskKey: email + ":*", AfterValue: &receiptID, CreatedAt: receipt.ErasedAt})
+		if err != nil {
+			s.log.Error("PRIVACY_AUDIT_FAILED. unable to save erasure audit entry", map[string]interface{}{"receipt": receipt, "error": err})
+			return datatypes.ErasureReceipt{}, err
+		}
+	}
+
+	body, err := json.Marshal(receipt)
+	if err != nil {
+		s.log.Error("unable to encode erasure receipt", map[string]interface{}{"receipt": receipt, "error": err})
+		return datatypes.ErasureReceipt{}, err
+	}
+	receipt.Signature = constants.ReceiptSignaturePrefix + alert.Sign(s.receiptSecret, strconv.FormatInt(receipt.ErasedAt, 10), body)
+
+	s.log.Info("erased student", map[string]interface{}{"email": email, "actor": actor, "dryRun": dryRun, "receiptId": receiptID})
+	return receipt, nil
+}
+
+// eraseDocuments deletes the documents of the student from the configured indices and
+// returns their number, a dry run only counts them
+func (s PrivacyService) eraseDocuments(email string, dryRun bool) (int64, error) {
+	if s.elastic == nil || len(s.elasticIndices) == 0 {
+		return 0, nil
+	}
+
+	query, err := json.Marshal(map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{s.elasticField: email},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	request := s.elastic.DeleteByQuery
+	if dryRun {
+		request = s.elastic.Count
+	}
+	res, err := request(s.elasticIndices, bytes.NewReader(query))
+	if err != nil {
+		s.log.Error("unable to reach elastic", map[string]interface{}{"email": email, "indices": s.elasticIndices, "error": err})
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		s.log.Error("elastic rejected erasure request", map[string]interface{}{"email": email, "indices": s.elasticIndices, "response": res.String()})
+		return 0, constants.ElasticRequestFailed
+	}
+
+	var reply struct {
+		Count   int64 `json:"count"`
+		Deleted int64 `json:"deleted"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&reply)
+	if err != nil {
+		s.log.Error("invalid reply received from elastic", map[string]interface{}{"email": email, "error": err})
+		return 0, err
+	}
+
+	if dryRun {
+		return reply.Count, nil
+	}
+	return reply.Deleted, nil
+}
+
+// indices returns the indices documents are erased from, none without elastic
+func (s PrivacyService) indices() []string {
+	if s.elastic == nil {
+		return []string{}
+	}
+	return append([]string{}, s.elasticIndices...)
+}
+
+// newID returns a random hex id for an erasure receipt or an export
+func newID() (string, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
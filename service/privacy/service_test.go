@@ -0,0 +1,182 @@
+package privacy
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"www-api/internal/constants"
+	"www-api/internal/datatypes"
+	"www-api/internal/logger"
+	"www-api/pkg/alert"
+	"www-api/pkg/aws/s3"
+	"www-api/pkg/elastic/mocks"
+	"www-api/test"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestEraseStudent(t *testing.T) {
+
+	type tests struct {
+		name            string
+		dryRun          bool
+		secret          string
+		elastic         func() *mocks.ElasticActions
+		eraseAtRisk     func(email string, dryRun bool) (datatypes.AtRiskErasure, error)
+		saveAtRiskAudit func(entry datatypes.AtRiskAuditEntry) error
+		want            datatypes.ErasureReceipt
+		wantExports     int
+		wantErr         error
+	}
+
+	reply := func(status int, body string) *esapi.Response {
+		return &esapi.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+	}
+	query := mock.MatchedBy(func(body io.Reader) bool {
+		content, _ := io.ReadAll(body)
+		return string(content) == `{"query":{"term":{"user_email":"email@securly.com"}}}`
+	})
+	erased := datatypes.AtRiskErasure{RedisKeys: []string{"email@securly.com:1684231487"}, AtRiskScoreRows: 2, LedgerRows: 3, ArchiveObjects: []string{}}
+	eraseAtRisk := func(email string, dryRun bool) (datatypes.AtRiskErasure, error) {
+		return erased, nil
+	}
+	exports := []string{"privacy-exports/email@securly.com/5f1c2b.json"}
+	testCases := []tests{
+		{
+			name:   "valid case",
+			secret: "secret",
+			elastic: func() *mocks.ElasticActions {
+				moc := mocks.NewElasticActions(t)
+				moc.On("DeleteByQuery", []string{"messages"}, query).Return(reply(200, `{"deleted":4}`), nil).Once()
+				return moc
+			},
+			eraseAtRisk: eraseAtRisk,
+			saveAtRiskAudit: func(entry datatypes.AtRiskAuditEntry) error {
+				assert.Equal(t, "subject", entry.Actor)
+				assert.Equal(t, constants.AuditActionErase, entry.Action)
+				assert.Equal(t, "email@securly.com:*", entry.AtRiskKey)
+				return nil
+			},
+			want:        datatypes.ErasureReceipt{UserEmail: "email@securly.com", Actor: "subject", AtRisk: erased, ElasticIndices: []string{"messages"}, ElasticDocuments: 4, ExportObjects: exports},
+			wantExports: 0,
+			wantErr:     nil,
+		},
+		{
+			name:   "valid case, dry run",
+			dryRun: true,
+			secret: "secret",
+			elastic: func() *mocks.ElasticActions {
+				moc := mocks.NewElasticActions(t)
+				moc.On("Count", []string{"messages"}, query).Return(reply(200, `{"count":5}`), nil).Once()
+				return moc
+			},
+			eraseAtRisk: func(email string, dryRun bool) (datatypes.AtRiskErasure, error) {
+				assert.True(t, dryRun)
+				return erased, nil
+			},
+			want:        datatypes.ErasureReceipt{UserEmail: "email@securly.com", Actor: "subject", DryRun: true, AtRisk: erased, ElasticIndices: []string{"messages"}, ElasticDocuments: 5, ExportObjects: exports},
+			wantExports: 1,
+			wantErr:     nil,
+		},
+		{
+			name:   "valid case, without elastic",
+			secret: "secret",
+			elastic: func() *mocks.ElasticActions {
+				return nil
+			},
+			eraseAtRisk:     eraseAtRisk,
+			saveAtRiskAudit: func(entry datatypes.AtRiskAuditEntry) error { return nil },
+			want:            datatypes.ErasureReceipt{UserEmail: "email@securly.com", Actor: "subject", AtRisk: erased, ElasticIndices: []string{}, ExportObjects: exports},
+			wantExports:     0,
+			wantErr:         nil,
+		},
+		{
+			name:   "fail case, receipt secret not configured",
+			secret: "",
+			elastic: func() *mocks.ElasticActions {
+				return mocks.NewElasticActions(t)
+			},
+			want:        datatypes.ErasureReceipt{},
+			wantExports: 1,
+			wantErr:     constants.ReceiptSecretNotConfigured,
+		},
+		{
+			name:   "fail case, error erasing at-risk data",
+			secret: "secret",
+			elastic: func() *mocks.ElasticActions {
+				return mocks.NewElasticActions(t)
+			},
+			eraseAtRisk: func(email string, dryRun bool) (datatypes.AtRiskErasure, error) {
+				return datatypes.AtRiskErasure{}, test.DBSomethingWentWrongErr
+			},
+			want:        datatypes.ErasureReceipt{},
+			wantExports: 1,
+			wantErr:     test.DBSomethingWentWrongErr,
+		},
+		{
+			name:   "fail case, elastic rejects the request",
+			secret: "secret",
+			elastic: func() *mocks.ElasticActions {
+				moc := mocks.NewElasticActions(t)
+				moc.On("DeleteByQuery", []string{"messages"}, query).Return(reply(404, `{"error":"index_not_found_exception"}`), nil).Once()
+				return moc
+			},
+			eraseAtRisk: eraseAtRisk,
+			want:        datatypes.ErasureReceipt{},
+			wantExports: 1,
+			wantErr:     constants.ElasticRequestFailed,
+		},
+		{
+			name:   "fail case, error saving audit entry",
+			secret: "secret",
+			elastic: func() *mocks.ElasticActions {
+				moc := mocks.NewElasticActions(t)
+				moc.On("DeleteByQuery", []string{"messages"}, query).Return(reply(200, `{"deleted":4}`), nil).Once()
+				return moc
+			},
+			eraseAtRisk:     eraseAtRisk,
+			saveAtRiskAudit: func(entry datatypes.AtRiskAuditEntry) error { return test.DBSomethingWentWrongErr },
+			want:            datatypes.ErasureReceipt{},
+			wantErr:         test.DBSomethingWentWrongErr,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := s3.NewFileSystem(t.TempDir())
+			_ = store.PutObject("export-bucket", "privacy-exports/email@securly.com/5f1c2b.json", []byte("{}"))
+			_ = store.PutObject("export-bucket", "privacy-exports/email@securly.community/5f1c2c.json", []byte("{}"))
+
+			privacy := PrivacyService{log: logger.ZapLogger{Logger: zap.NewExample()}, eraseAtRisk: tc.eraseAtRisk, saveAtRiskAudit: tc.saveAtRiskAudit, receiptSecret: tc.secret}.WithExport(store, "export-bucket", "")
+			if client := tc.elastic(); client != nil {
+				privacy = privacy.WithElastic(client, []string{"messages"}, "")
+			}
+
+			receipt, err := privacy.EraseStudent("email@securly.com", "subject", tc.dryRun)
+			if tc.wantErr != err {
+				t.Errorf("expected error %v got %v", tc.wantErr, err)
+			}
+
+			left, _ := store.ListObjects("export-bucket", "privacy-exports/email@securly.com/")
+			assert.Len(t, left, tc.wantExports)
+			if err != nil {
+				assert.Equal(t, tc.want, receipt)
+				return
+			}
+
+			//the id, time and signature change on every run, the signature has to match the rest of the receipt
+			signature := receipt.Signature
+			receipt.Signature = ""
+			body, _ := json.Marshal(receipt)
+			assert.Equal(t, constants.ReceiptSignaturePrefix+alert.Sign(tc.secret, strconv.FormatInt(receipt.ErasedAt, 10), body), signature)
+			assert.Len(t, receipt.ReceiptID, 32)
+
+			receipt.ReceiptID, receipt.ErasedAt = "", 0
+			assert.Equal(t, tc.want, receipt)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 	"www-api/internal/constants"
+	"www-api/internal/datatypes"
 	"www-api/internal/logger"
 
 	awsec2metadata "github.com/aws/aws-sdk-go/aws/ec2metadata"
@@ -73,24 +74,19 @@ func ValidateAtRiskKey(key string, log logger.ZapLogger) error {
 }
 
 func ValidateAtRiskValue(value string, log logger.ZapLogger) error {
-	if value == "" {
+	_, err := datatypes.ParseAtRiskEvent(value)
+	switch err {
+	case nil:
+		return nil
+	case constants.BlankAtRiskValue:
 		log.Error("blank atRiskValue query param", map[string]interface{}{"atRiskValue": value})
-		return constants.BlankAtRiskValue
-	}
-
-	split := strings.Split(value, ":")
-	if len(split) != 3 {
+	case constants.InvalidValueLength:
 		log.Error("atRiskValue should be seperated by two colons", map[string]interface{}{"atRiskValue": value})
-		return constants.InvalidKeyLength
-	}
-
-	_, err := strconv.Atoi(split[0])
-	if err != nil {
+	default:
 		log.Error("first part of atRiskValue should be numeric", map[string]interface{}{"error": err, "atRiskValue": value})
-		return constants.InvalidValueScore
 	}
 
-	return nil
+	return err
 }
 
 func ValidateTimestamp(timestamp string, log logger.ZapLogger) error {
@@ -123,6 +119,48 @@ func ValidateEmail(email string, log logger.ZapLogger) error {
 	return nil
 }
 
+func ValidateMid(mid string, log logger.ZapLogger) error {
+	if strings.TrimSpace(mid) == "" {
+		log.Error("blank mid in request body", map[string]interface{}{"mid": mid})
+		return constants.BlankMid
+	}
+
+	return nil
+}
+
+// ValidateCategory checks a category filter, it must be usable as the middle part of an atRiskValue
+func ValidateCategory(category string, log logger.ZapLogger) error {
+	if strings.TrimSpace(category) == "" || strings.Contains(category, ":") {
+		log.Error("invalid category in request body", map[string]interface{}{"category": category})
+		return constants.InvalidCategoryParam
+	}
+
+	return nil
+}
+
+func ValidateScoringStrategy(scoring datatypes.ScoringStrategy, log logger.ZapLogger) error {
+	switch scoring.Strategy {
+	case constants.ScoringFlat:
+		return nil
+	case constants.ScoringWindow:
+		//events expire after 60 days, a longer window is the flat sum
+		if scoring.WindowDays < 1 || scoring.WindowDays > constants.AtRiskEventTTL/86400 {
+			log.Error("invalid scoring window", map[string]interface{}{"windowDays": scoring.WindowDays})
+			return constants.InvalidScoringWindow
+		}
+		return nil
+	case constants.ScoringDecay:
+		if scoring.HalfLifeDays <= 0 {
+			log.Error("invalid scoring half life", map[string]interface{}{"halfLifeDays": scoring.HalfLifeDays})
+			return constants.InvalidScoringHalfLife
+		}
+		return nil
+	}
+
+	log.Error("invalid scoring strategy", map[string]interface{}{"strategy": scoring.Strategy})
+	return constants.InvalidScoringStrategy
+}
+
 func ValidateFid(fid string, log logger.ZapLogger) error {
 	if fid == "" {
 		log.Error("blank fid in request body", map[string]interface{}{"fid": fid})
@@ -138,6 +176,92 @@ func ValidateFid(fid string, log logger.ZapLogger) error {
 	return nil
 }
 
+// ValidateNotificationEmails checks that emails has at least one and at most
+// constants.MaxNotificationEmails plain email addresses
+func ValidateNotificationEmails(emails []string, log logger.ZapLogger) error {
+	if len(emails) == 0 {
+		log.Error("blank emails in request body", map[string]interface{}{"emails": emails})
+		return constants.BlankNotificationEmails
+	}
+	if len(emails) > constants.MaxNotificationEmails {
+		log.Error("too many emails in request body", map[string]interface{}{"emails": len(emails), "maxEmails": constants.MaxNotificationEmails})
+		return constants.InvalidEmailBatchSize
+	}
+
+	for _, email := range emails {
+		address, err := mail.ParseAddress(email)
+		if err != nil || address.Address != strings.TrimSpace(email) {
+			log.Error("invalid email address in request body", map[string]interface{}{"error": err, "email": email})
+			return constants.InvalidNotificationEmail
+		}
+	}
+
+	return nil
+}
+
+func ValidateDomain(domain string, log logger.ZapLogger) error {
+	if strings.TrimSpace(domain) == "" {
+		log.Error("blank domain in request body", map[string]interface{}{"domain": domain})
+		return constants.BlankDomain
+	}
+
+	//a domain is valid when an address can be built on it
+	_, err := mail.ParseAddress("user@" + domain)
+	if err != nil || strings.Contains(domain, "@") {
+		log.Error("invalid domain in request body", map[string]interface{}{"error": err, "domain": domain})
+		return constants.InvalidDomainParam
+	}
+
+	return nil
+}
+
+// ParsePrecondition reads the If-Match and If-None-Match headers of a conditional write
+func ParsePrecondition(ifMatch, ifNoneMatch string, log logger.ZapLogger) (datatypes.Precondition, error) {
+	matched, err := parseETags(ifMatch)
+	if err != nil {
+		log.Error("invalid If-Match header", map[string]interface{}{"ifMatch": ifMatch})
+		return datatypes.Precondition{}, err
+	}
+
+	noneMatched, err := parseETags(ifNoneMatch)
+	if err != nil {
+		log.Error("invalid If-None-Match header", map[string]interface{}{"ifNoneMatch": ifNoneMatch})
+		return datatypes.Precondition{}, err
+	}
+
+	return datatypes.Precondition{IfMatch: matched, IfNoneMatch: noneMatched}, nil
+}
+
+// parseETags turns a list of quoted versions into comma separated versions, "*" is kept as is
+func parseETags(header string) (string, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == constants.AnyETag {
+		return header, nil
+	}
+
+	versions := []string{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			return "", constants.InvalidETag
+		}
+
+		version := tag[1 : len(tag)-1]
+		_, err := strconv.ParseUint(version, 10, 64)
+		if err != nil {
+			return "", constants.InvalidETag
+		}
+		versions = append(versions, version)
+	}
+
+	return strings.Join(versions, ","), nil
+}
+
+// FormatETag returns the ETag of an event version
+func FormatETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
 func IsBitSet(val int, idx int) bool {
 	if idx < 0 {
 		return false